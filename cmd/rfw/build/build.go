@@ -18,10 +18,12 @@ import (
 	"github.com/rfwlab/rfw/v2/cmd/rfw/plugins"
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/assets"   // Register the assets build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/bundler"  // Register the bundler build plugin.
+	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/check"    // Register the template check build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/copy"     // Register the copy build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/docs"     // Register the docs build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/env"      // Register the environment build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/pages"    // Register the pages build plugin.
+	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/rtml"     // Register the RTML compiler build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/seo"      // Register the SEO build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/tailwind" // Register the Tailwind build plugin.
 	_ "github.com/rfwlab/rfw/v2/cmd/rfw/plugins/test"     // Register the test build plugin.
@@ -43,6 +45,8 @@ func Build() error {
 	if err := plugins.Configure(manifest.Plugins); err != nil {
 		return fmt.Errorf("failed to configure plugins: %w", err)
 	}
+	// files plugins generate into the project go away on every exit path
+	defer plugins.Cleanup()
 	if err := plugins.PreBuild(); err != nil {
 		return fmt.Errorf("pre build failed: %w", err)
 	}
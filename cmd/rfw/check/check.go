@@ -0,0 +1,200 @@
+//go:build !js
+
+// Package check reports broken references in RTML templates before they ship.
+//
+// Templates are parsed with rtmlast and every store, include, handler, prop,
+// signal and host reference, and every function an expression calls, is
+// matched against the names the project's Go code declares. Names are collected across the whole project, so a reference
+// is reported only when nothing in the project could satisfy it; a kind
+// registered under a computed name is not checked at all.
+package check
+
+import (
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/cmd/rfw/project"
+)
+
+// Kind is a kind of name a template refers to.
+type Kind int
+
+const (
+	// KindStore names a store as module.store.
+	KindStore Kind = iota
+	// KindInclude names a dependency of @include:name.
+	KindInclude
+	// KindComponent names a registered component of @include:Name:{…} and
+	// rt-is.
+	KindComponent
+	// KindHandler names an event handler of @on:event:handler.
+	KindHandler
+	// KindProp names a prop, signal or host variable.
+	KindProp
+	// KindHost names a host component.
+	KindHost
+	// KindFunc names a function registered with core.RegisterTemplateFunc,
+	// called from an expression.
+	KindFunc
+
+	kindCount
+)
+
+// Diagnostic is one problem found in a template or in the Go code wiring it.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Col     int
+	Message string
+}
+
+// String formats the diagnostic as file:line:col: message.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Col, d.Message)
+}
+
+// Project is what a project's Go code declares for its templates.
+type Project struct {
+	root      string
+	syms      *symbols
+	templates []string
+	diags     []Diagnostic
+}
+
+// Load reads the Go code under dir. A Go file that does not parse is kept as
+// a diagnostic; the error reports a directory that could not be read.
+func Load(dir string) (*Project, error) {
+	p := &Project{root: dir, syms: newSymbols()}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && project.SkipDir(name) {
+				return filepath.SkipDir
+			}
+			p.syms.routeParam(path, name)
+			return nil
+		}
+		switch {
+		case filepath.Ext(name) == ".rtml":
+			p.templates = append(p.templates, path)
+		case project.IsGoSource(name):
+			p.syms.routeParam(path, strings.TrimSuffix(name, ".go"))
+			f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+			if err != nil {
+				p.diags = append(p.diags, goErrors(path, err)...)
+				return nil
+			}
+			p.syms.collect(fset, filepath.Dir(path), f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.syms.resolve()
+	p.diags = append(p.diags, p.syms.verify()...)
+	return p, nil
+}
+
+// Diagnostics returns the problems found in the Go code.
+func (p *Project) Diagnostics() []Diagnostic { return p.diags }
+
+// Templates returns the .rtml files found under the project root.
+func (p *Project) Templates() []string { return p.templates }
+
+// CheckTemplate reports the problems in the template file holding src.
+func (p *Project) CheckTemplate(file, src string) []Diagnostic {
+	dir, _ := project.PackageDir(filepath.Dir(file), p.root)
+	return checkTemplate(file, src, p.syms, dir)
+}
+
+// Names returns the declared names of kind k, sorted.
+func (p *Project) Names(k Kind) []string {
+	return sortedNames(&p.syms.kinds[k])
+}
+
+// Decls returns where name of kind k is declared.
+func (p *Project) Decls(k Kind, name string) []Decl {
+	return p.syms.kinds[k].names[name]
+}
+
+// StoreKeys returns the keys the Go code sets or reads on store, given as
+// module.store, sorted.
+func (p *Project) StoreKeys(store string) []string {
+	keys := p.syms.keys[store]
+	if keys == nil {
+		return nil
+	}
+	return sortedNames(keys)
+}
+
+// KeyDecls returns where key of store is set or read.
+func (p *Project) KeyDecls(store, key string) []Decl {
+	keys := p.syms.keys[store]
+	if keys == nil {
+		return nil
+	}
+	return keys.names[key]
+}
+
+func sortedNames(s *nameSet) []string {
+	names := make([]string, 0, len(s.names))
+	for name := range s.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run checks every template under dir and returns the problems sorted by
+// position. The error reports a directory or file that could not be read.
+func Run(dir string) ([]Diagnostic, error) {
+	p, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	diags := append([]Diagnostic(nil), p.diags...)
+	for _, path := range p.templates {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, p.CheckTemplate(path, string(src))...)
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return diags, nil
+}
+
+// goErrors turns a Go syntax error into diagnostics, so a file that does not
+// parse is reported in place rather than aborting the run.
+func goErrors(path string, err error) []Diagnostic {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return []Diagnostic{{File: path, Line: 1, Col: 1, Message: err.Error()}}
+	}
+	diags := make([]Diagnostic, 0, len(list))
+	for _, e := range list {
+		diags = append(diags, Diagnostic{File: path, Line: e.Pos.Line, Col: e.Pos.Column, Message: e.Msg})
+	}
+	return diags
+}
@@ -0,0 +1,147 @@
+//go:build !js
+
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunReportsBrokenReferences verifies that the fixture project's valid
+// template passes and every seeded typo is reported at its position.
+func TestRunReportsBrokenReferences(t *testing.T) {
+	diags, err := Run(filepath.Join("testdata", "app"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got := make([]string, len(diags))
+	for i, d := range diags {
+		got[i] = filepath.ToSlash(strings.TrimPrefix(d.String(), filepath.Join("testdata", "app")+string(filepath.Separator)))
+	}
+	want := []string{
+		`components/templates/broken.rtml:2:6: unknown store "app.defualt"`,
+		`components/templates/broken.rtml:2:31: malformed store reference "@store:counter.count", want @store:module.store.key`,
+		`components/templates/broken.rtml:3:3: unknown include "Hedaer"`,
+		`components/templates/broken.rtml:4:11: unknown handler "saev"`,
+		`components/templates/broken.rtml:5:8: unknown component "Bagde"`,
+		`components/templates/broken.rtml:6:3: @if without @endif`,
+		`components/templates/broken.rtml:6:7: unknown prop "titel"`,
+		`components/templates/broken.rtml:7:6: unknown signal "opne"`,
+		`components/templates/broken.rtml:7:19: unknown host variable "nwo"`,
+		`components/templates/broken.rtml:8:16: unknown prop "itemz"`,
+		`components/templates/broken.rtml:11:3: @endfor without @for`,
+		`components/templates/broken.rtml:12:9: unknown template func "uper"`,
+		`components/templates/broken.rtml:12:31: expression "(len(items) +)": unexpected ")"`,
+		`components/templates/broken.rtml:13:3: @case outside @switch`,
+		`components/templates/broken.rtml:14:10: unknown resource "profil"`,
+		`widgets/widget.rtml:1:4: {h:now} needs a host component: call AddHostComponent`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestRunSkipsComputedNames verifies that a kind registered under a name the
+// checker cannot read is not checked, while the other kinds still are.
+func TestRunSkipsComputedNames(t *testing.T) {
+	dir := t.TempDir()
+	src := `package app
+
+import "github.com/rfwlab/rfw/v2/dom"
+
+func register(name string) {
+	dom.RegisterHandlerFunc(name, func() {})
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tpl := "<button @on:click:anything>@store:app.missing.key</button>\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.rtml"), []byte(tpl), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	diags, err := Run(dir)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Message != `unknown store "app.missing"` {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+}
+
+// TestGoSyntaxErrorIsReported verifies that a Go file that does not parse is
+// reported as a diagnostic instead of failing the run.
+func TestGoSyntaxErrorIsReported(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package app\nfunc {\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	diags, err := Run(dir)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(diags) == 0 || diags[0].Line != 2 {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+}
+
+// TestTypedHostVarsFollowOut verifies that a template of a component linked
+// with AddTypedHostComponent[Out] may only use the fields of Out, those
+// promoted from embedded structs included.
+func TestTypedHostVarsFollowOut(t *testing.T) {
+	dir := t.TempDir()
+	src := `package app
+
+import "github.com/rfwlab/rfw/v2/core"
+
+type Base struct {
+	ID   int ` + "`json:\"id\"`" + `
+	Name string
+}
+
+type Meta struct {
+	Region string
+	Name   string
+}
+
+type ClockVars struct {
+	Base
+	*Meta
+	Now   string ` + "`json:\"now\"`" + `
+	Zone  string
+	inner int
+}
+
+var unrelated = map[string]any{"later": 1}
+
+func newClock(c *core.HTMLComponent) error {
+	return core.AddTypedHostComponent[ClockVars](c, "Clock")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tpl := "<p>{h:now} {h:Zone} {h:id} {h:Region} {h:later} {h:inner} {h:Base} {h:Name}</p>\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.rtml"), []byte(tpl), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	diags, err := Run(dir)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	want := []string{
+		`unknown host variable "later": not a field of ClockVars`,
+		`unknown host variable "inner": not a field of ClockVars`,
+		`unknown host variable "Base": not a field of ClockVars`,
+		`unknown host variable "Name": not a field of ClockVars`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,615 @@
+//go:build !js
+
+package check
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/internal/jsonfields"
+)
+
+const (
+	pathCore       = "github.com/rfwlab/rfw/v2/core"
+	pathDom        = "github.com/rfwlab/rfw/v2/dom"
+	pathHost       = "github.com/rfwlab/rfw/v2/host"
+	pathHostclient = "github.com/rfwlab/rfw/v2/hostclient"
+	pathState      = "github.com/rfwlab/rfw/v2/state"
+	pathTypes      = "github.com/rfwlab/rfw/v2/types"
+)
+
+var hostTypes = map[string]bool{
+	"HInt": true, "HString": true, "HBool": true, "HFloat": true,
+	"HAny": true, "HSlice": true, "HMap": true,
+}
+
+// handlerTypes are the handler signatures of the dom registration functions.
+var handlerTypes = map[string]string{
+	"RegisterHandler":              "func(this js.Value, args []js.Value) any",
+	"RegisterHandlerFunc":          "func()",
+	"RegisterHandlerEvent":         "func(js.Value)",
+	"RegisterHandlerElem":          "func(el dom.Element, evt dom.Event)",
+	"RegisterComponentHandler":     "func(this js.Value, args []js.Value) any",
+	"RegisterComponentHandlerFunc": "func()",
+}
+
+// Decl is where the Go code declares a name a template refers to. Names the
+// framework provides itself carry no position.
+type Decl struct {
+	Pos  token.Position
+	Type string // Go type, when the source spells it out
+}
+
+// nameSet is the set of names declared for one kind of reference. An open set
+// saw a name it could not read statically and accepts everything.
+type nameSet struct {
+	names map[string][]Decl
+	open  bool
+}
+
+func (s *nameSet) add(name string, d Decl) {
+	if s.names == nil {
+		s.names = map[string][]Decl{}
+	}
+	s.names[name] = append(s.names[name], d)
+}
+
+// addExpr records a string literal name and opens the set for anything else.
+func (s *nameSet) addExpr(e ast.Expr, d Decl) {
+	if name, ok := stringLit(e); ok {
+		s.add(name, d)
+		return
+	}
+	s.open = true
+}
+
+func (s *nameSet) has(name string) bool { return s.open || len(s.names[name]) > 0 }
+
+// hostRef is an AddHostComponent call, checked once every host is known.
+type hostRef struct {
+	name string
+	pos  token.Position
+}
+
+// typedHostLink is an AddTypedHostComponent[Out] call: the templates of dir
+// may only use the fields of Out as host variables.
+type typedHostLink struct {
+	dir, out string
+}
+
+// keyUse is a Set, Get or OnChange call on a variable, resolved to a store
+// once every file of the package has been read.
+type keyUse struct {
+	dir, ident, key string
+	decl            Decl
+}
+
+// symbols holds what the project's Go code declares.
+type symbols struct {
+	kinds     [kindCount]nameSet
+	keys      map[string]*nameSet // store keys by module.store
+	storeVars map[string]string   // package dir + "." + variable -> module.store
+	keyUses   []keyUse
+	hostLinks map[string]bool
+	hostRefs  []hostRef
+	// untypedHosts are the package dirs linking a host component whose
+	// variables are not known statically
+	untypedHosts map[string]bool
+	typedLinks   []typedHostLink
+	// typeDecls holds the declared types, by dir + "." + name and by bare
+	// name for references from other packages
+	typeDecls map[string][]typeDecl
+	// hostVars are the host variables of the packages linking typed host
+	// components only, and hostOuts the Out types they come from
+	hostVars map[string]*nameSet
+	hostOuts map[string][]string
+}
+
+func newSymbols() *symbols {
+	s := &symbols{
+		keys:      map[string]*nameSet{},
+		storeVars: map[string]string{},
+		hostLinks: map[string]bool{},
+
+		untypedHosts: map[string]bool{},
+		typeDecls:    map[string][]typeDecl{},
+		hostVars:     map[string]*nameSet{},
+		hostOuts:     map[string][]string{},
+	}
+	// every view falls back to the app default store, and composition views
+	// receive the router's template data
+	s.kinds[KindStore].add("app.default", Decl{Type: "*state.Store"})
+	s.kinds[KindProp].add("ActivePath", Decl{Type: "*state.Signal[string]"})
+	s.kinds[KindProp].add("NavItems", Decl{Type: "map[string]any"})
+	s.kinds[KindFunc].add("len", Decl{Type: "func(any) int"})
+	return s
+}
+
+var reRouteParam = regexp.MustCompile(`/:(\w+)`)
+
+// routeParam records a pages plugin parameter segment such as [id]; route
+// parameters reach the component as props.
+func (s *symbols) routeParam(path, name string) {
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		pos := token.Position{Filename: path, Line: 1, Column: 1}
+		s.kinds[KindProp].add(name[1:len(name)-1], Decl{Pos: pos, Type: "string"})
+	}
+}
+
+// collect records the declarations of one Go file living in dir.
+func (s *symbols) collect(fset *token.FileSet, dir string, f *ast.File) {
+	imports := map[string]string{}
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	at := func(n ast.Node, typ string) Decl { return Decl{Pos: fset.Position(n.Pos()), Type: typ} }
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			s.call(fset, dir, imports, n)
+		case *ast.ValueSpec:
+			for i, v := range n.Values {
+				if i < len(n.Names) {
+					s.storeVar(dir, imports, n.Names[i], v)
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, v := range n.Rhs {
+					if id, ok := n.Lhs[i].(*ast.Ident); ok {
+						s.storeVar(dir, imports, id, v)
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			if m, ok := n.Type.(*ast.MapType); ok && isIdent(m.Key, "string") {
+				for _, el := range n.Elts {
+					if kv, ok := el.(*ast.KeyValueExpr); ok {
+						if key, ok := stringLit(kv.Key); ok {
+							s.kinds[KindProp].add(key, at(kv, exprType(imports, kv.Value)))
+						}
+					}
+				}
+			}
+		case *ast.IndexExpr:
+			if sel, ok := n.X.(*ast.SelectorExpr); ok && sel.Sel.Name == "Props" {
+				s.kinds[KindProp].addExpr(n.Index, at(n, ""))
+			}
+		case *ast.BasicLit:
+			if v, ok := stringLit(n); ok {
+				for _, m := range reRouteParam.FindAllStringSubmatch(v, -1) {
+					s.kinds[KindProp].add(m[1], at(n, "string"))
+				}
+			}
+		case *ast.TypeSpec:
+			// an alias may name a struct declared anywhere, so it stays
+			// unknown
+			if !n.Assign.IsValid() {
+				decl := typeDecl{dir: dir}
+				decl.st, _ = n.Type.(*ast.StructType)
+				s.typeDecls[dir+"."+n.Name.Name] = append(s.typeDecls[dir+"."+n.Name.Name], decl)
+				s.typeDecls[n.Name.Name] = append(s.typeDecls[n.Name.Name], decl)
+			}
+		case *ast.StructType:
+			s.structFields(fset, dir, imports, n)
+		case *ast.FuncDecl:
+			// composition binds every exported niladic method as a handler
+			if n.Recv != nil && n.Name.IsExported() && n.Type.Params.NumFields() == 0 && n.Type.Results.NumFields() == 0 {
+				s.kinds[KindHandler].add(n.Name.Name, at(n.Name, "func()"))
+			}
+		}
+		return true
+	})
+}
+
+func (s *symbols) call(fset *token.FileSet, dir string, imports map[string]string, call *ast.CallExpr) {
+	pkg, fn := callee(imports, call.Fun)
+	args := call.Args
+	arg := func(i int) ast.Expr {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+	at := func(typ string) Decl { return Decl{Pos: fset.Position(call.Pos()), Type: typ} }
+	switch {
+	case pkg == "" && fn == "AddDependency":
+		s.kinds[KindInclude].addExpr(arg(0), at(exprType(imports, arg(1))))
+	case pkg == pathCore && (fn == "RegisterComponent" || fn == "MustRegisterComponent"):
+		s.kinds[KindComponent].addExpr(arg(0), at("core.Component"))
+	case pkg == pathCore && fn == "RegisterTemplateFunc":
+		s.kinds[KindFunc].addExpr(arg(0), at(exprType(imports, arg(1))))
+	case pkg == "" && fn == "On" && len(args) == 2:
+		s.kinds[KindHandler].addExpr(arg(0), at("func()"))
+	case pkg == pathDom && strings.HasPrefix(fn, "RegisterComponentHandler"):
+		s.kinds[KindHandler].addExpr(arg(1), at(handlerTypes[fn]))
+	case pkg == pathDom && strings.HasPrefix(fn, "RegisterHandler"):
+		s.kinds[KindHandler].addExpr(arg(0), at(handlerTypes[fn]))
+	case pkg == "" && fn == "Prop" && len(args) == 2:
+		s.kinds[KindProp].addExpr(arg(0), at(exprType(imports, arg(1))))
+	case pkg == pathHost && (fn == "NewHostComponent" || fn == "NewHostComponentWithSession" || fn == "NewTypedComponent"):
+		s.kinds[KindHost].addExpr(arg(0), at("*host.HostComponent"))
+	case pkg == pathCore && fn == "AddTypedHostComponent":
+		s.hostLinks[dir] = true
+		if ix, ok := call.Fun.(*ast.IndexExpr); ok {
+			s.typedLinks = append(s.typedLinks, typedHostLink{dir: dir, out: types.ExprString(ix.Index)})
+		} else {
+			s.untypedHosts[dir] = true
+		}
+		if name, ok := stringLit(arg(1)); ok {
+			s.hostRefs = append(s.hostRefs, hostRef{name: name, pos: fset.Position(call.Pos())})
+		}
+	case pkg == "" && fn == "AddHostComponent":
+		s.hostLinks[dir] = true
+		s.untypedHosts[dir] = true
+		if name, ok := stringLit(arg(0)); ok {
+			s.hostRefs = append(s.hostRefs, hostRef{name: name, pos: fset.Position(call.Pos())})
+		}
+	case pkg == "" && (fn == "Set" || fn == "Get" || fn == "OnChange"):
+		sel, _ := call.Fun.(*ast.SelectorExpr)
+		if sel == nil {
+			return
+		}
+		id, isVar := sel.X.(*ast.Ident)
+		key, isLit := stringLit(arg(0))
+		if !isVar || !isLit {
+			return
+		}
+		typ := ""
+		if fn == "Set" {
+			typ = exprType(imports, arg(1))
+		}
+		s.keyUses = append(s.keyUses, keyUse{dir: dir, ident: id.Name, key: key, decl: at(typ)})
+	default:
+		if store, typ, ok := storeCall(imports, call); ok {
+			if store == "" {
+				s.kinds[KindStore].open = true
+				return
+			}
+			s.kinds[KindStore].add(store, at(typ))
+		}
+	}
+}
+
+// storeCall resolves a store constructor call to the module.store it
+// creates. The store is "" when its name or module is computed.
+func storeCall(imports map[string]string, call *ast.CallExpr) (store, typ string, ok bool) {
+	pkg, fn := callee(imports, call.Fun)
+	args := call.Args
+	from := func(name, opts int) string {
+		if name >= len(args) {
+			return ""
+		}
+		return storeName(imports, args[name], args[min(opts, len(args)):])
+	}
+	switch {
+	case fn == "NewStore" && (pkg == pathState || pkg == ""):
+		return from(0, 1), "*state.Store", true
+	case pkg == pathState && fn == "NewTypedStore":
+		typ = "*state.TypedStore"
+		if ix, ok := call.Fun.(*ast.IndexExpr); ok {
+			typ += "[" + types.ExprString(ix.Index) + "]"
+		}
+		return from(0, 2), typ, true
+	case pkg == pathState && fn == "NewTypedStoreIn":
+		return from(1, 3), "*state.TypedStore", true
+	case pkg == pathHostclient && fn == "OpenSharedDoc":
+		return from(0, 1), "*state.Store", true
+	}
+	return "", "", false
+}
+
+// storeName reads the module.store a constructor names, in the module its
+// WithModule option names or the "default" module, and returns "" when
+// either is computed.
+func storeName(imports map[string]string, nameExpr ast.Expr, opts []ast.Expr) string {
+	name, ok := stringLit(nameExpr)
+	if !ok {
+		return ""
+	}
+	module := "default"
+	for _, opt := range opts {
+		call, ok := opt.(*ast.CallExpr)
+		if !ok {
+			if _, variadic := opt.(*ast.Ident); variadic {
+				// opts... forwarded from elsewhere: the module is unknown
+				return ""
+			}
+			continue
+		}
+		if pkg, fn := callee(imports, call.Fun); pkg == pathState && fn == "WithModule" && len(call.Args) == 1 {
+			m, ok := stringLit(call.Args[0])
+			if !ok {
+				return ""
+			}
+			module = m
+		}
+	}
+	return module + "." + name
+}
+
+// storeVar remembers which store a variable holds, so its Set and Get calls
+// name that store's keys.
+func (s *symbols) storeVar(dir string, imports map[string]string, id *ast.Ident, v ast.Expr) {
+	call, ok := v.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	if store, _, ok := storeCall(imports, call); ok && store != "" {
+		s.storeVars[dir+"."+id.Name] = store
+	}
+}
+
+// structFields records composition struct fields: each exported field may
+// become a signal or prop, store fields live in the app module, view fields
+// are includes under their lower-cased name and host fields link a host
+// component.
+func (s *symbols) structFields(fset *token.FileSet, dir string, imports map[string]string, st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		typeStr := types.ExprString(field.Type)
+		if field.Tag != nil {
+			tag, _ := strconv.Unquote(field.Tag.Value)
+			if name, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ","); name != "" && name != "-" {
+				s.kinds[KindProp].add(name, Decl{Pos: fset.Position(field.Pos()), Type: typeStr})
+			}
+		}
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		pkg, typeName := callee(imports, typ)
+		for _, id := range field.Names {
+			if !id.IsExported() {
+				continue
+			}
+			d := Decl{Pos: fset.Position(id.Pos()), Type: typeStr}
+			s.kinds[KindProp].add(id.Name, d)
+			switch {
+			case pkg == pathState && typeName == "Store":
+				s.kinds[KindStore].add("app."+id.Name, d)
+			case pkg == pathTypes && typeName == "View":
+				s.kinds[KindInclude].add(strings.ToLower(id.Name), d)
+			case pkg == pathTypes && hostTypes[typeName]:
+				s.hostLinks[dir] = true
+				s.untypedHosts[dir] = true
+			}
+		}
+	}
+}
+
+// resolve attaches the recorded key uses to their stores.
+func (s *symbols) resolve() {
+	for _, use := range s.keyUses {
+		store, ok := s.storeVars[use.dir+"."+use.ident]
+		if !ok {
+			continue
+		}
+		keys := s.keys[store]
+		if keys == nil {
+			keys = &nameSet{}
+			s.keys[store] = keys
+		}
+		keys.add(use.key, use.decl)
+	}
+	s.keyUses = nil
+	for _, link := range s.typedLinks {
+		if s.untypedHosts[link.dir] {
+			continue
+		}
+		vars := s.hostVars[link.dir]
+		if vars == nil {
+			vars = &nameSet{}
+			s.hostVars[link.dir] = vars
+		}
+		s.hostOuts[link.dir] = append(s.hostOuts[link.dir], link.out)
+		fields, ok := s.outFields(link)
+		if !ok {
+			vars.open = true
+			continue
+		}
+		for _, name := range fields {
+			vars.add(name, Decl{Type: link.out})
+		}
+	}
+}
+
+// typeDecl is a declared type: st is nil unless it is a struct.
+type typeDecl struct {
+	dir string
+	st  *ast.StructType
+}
+
+// outFields returns the host variables of the Out type of link: the JSON
+// field names of a struct declared in the package, or of the only struct of
+// that name in the project when Out is qualified.
+func (s *symbols) outFields(link typedHostLink) ([]string, bool) {
+	decl, ok := s.lookupType(link.dir, strings.TrimPrefix(link.out, "*"))
+	if !ok || decl.st == nil {
+		return nil, false
+	}
+	fields, ok := s.jsonFields(decl, nil)
+	if !ok {
+		return nil, false
+	}
+	return jsonfields.Names(fields), true
+}
+
+// lookupType finds the type name refers to from the package in dir.
+func (s *symbols) lookupType(dir, name string) (typeDecl, bool) {
+	if decls, ok := s.typeDecls[dir+"."+name]; ok && len(decls) == 1 {
+		return decls[0], true
+	}
+	if _, bare, qualified := strings.Cut(name, "."); qualified {
+		if decls := s.typeDecls[bare]; len(decls) == 1 {
+			return decls[0], true
+		}
+	}
+	return typeDecl{}, false
+}
+
+// jsonFields describes the fields of decl for jsonfields.Names, resolving
+// embedded types to their declarations. It reports false when an embedded
+// type is not declared in the project, as its promoted fields are unknown.
+func (s *symbols) jsonFields(decl typeDecl, outer []*ast.StructType) ([]jsonfields.Field, bool) {
+	outer = append(outer, decl.st)
+	var fields []jsonfields.Field
+	for _, field := range decl.st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw).Get("json")
+		}
+		for _, id := range field.Names {
+			fields = append(fields, jsonfields.Field{Name: id.Name, Tag: tag, Exported: id.IsExported()})
+		}
+		if field.Names != nil {
+			continue
+		}
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		var ref, name string
+		switch t := typ.(type) {
+		case *ast.Ident:
+			ref, name = t.Name, t.Name
+		case *ast.SelectorExpr:
+			if pkg, ok := t.X.(*ast.Ident); ok {
+				ref, name = pkg.Name+"."+t.Sel.Name, t.Sel.Name
+			}
+		}
+		embedded, ok := s.lookupType(decl.dir, ref)
+		if !ok {
+			return nil, false
+		}
+		f := jsonfields.Field{Name: name, Tag: tag, Exported: ast.IsExported(name), Embedded: true}
+		switch {
+		case embedded.st == nil:
+		case slices.Contains(outer, embedded.st):
+			f.Struct = []jsonfields.Field{}
+		default:
+			if f.Struct, ok = s.jsonFields(embedded, outer); !ok {
+				return nil, false
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, true
+}
+
+// verify reports the problems visible in the Go code alone.
+func (s *symbols) verify() []Diagnostic {
+	var diags []Diagnostic
+	hosts := &s.kinds[KindHost]
+	if len(hosts.names) == 0 && !hosts.open {
+		// the host lives outside this project
+		return nil
+	}
+	for _, ref := range s.hostRefs {
+		if !hosts.has(ref.name) {
+			diags = append(diags, Diagnostic{
+				File: ref.pos.Filename, Line: ref.pos.Line, Col: ref.pos.Column,
+				Message: "unknown host component " + strconv.Quote(ref.name),
+			})
+		}
+	}
+	return diags
+}
+
+// exprType reads the Go type of a value expression where the source spells
+// it out, and returns "" otherwise.
+func exprType(imports map[string]string, e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			return "string"
+		case token.INT:
+			return "int"
+		case token.FLOAT:
+			return "float64"
+		case token.CHAR:
+			return "rune"
+		}
+	case *ast.Ident:
+		if e.Name == "true" || e.Name == "false" {
+			return "bool"
+		}
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return types.ExprString(e.Type)
+		}
+	case *ast.FuncLit:
+		return types.ExprString(e.Type)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			if t := exprType(imports, e.X); t != "" {
+				return "*" + t
+			}
+		}
+	case *ast.CallExpr:
+		pkg, fn := callee(imports, e.Fun)
+		if pkg == pathState && fn == "NewSignal" {
+			if ix, ok := e.Fun.(*ast.IndexExpr); ok {
+				return "*state.Signal[" + types.ExprString(ix.Index) + "]"
+			}
+			if len(e.Args) == 1 {
+				if t := exprType(imports, e.Args[0]); t != "" {
+					return "*state.Signal[" + t + "]"
+				}
+			}
+			return "*state.Signal"
+		}
+		if _, typ, ok := storeCall(imports, e); ok {
+			return typ
+		}
+	}
+	return ""
+}
+
+// callee resolves a function or type expression to its import path and
+// name. A method call, or a call of a local function, has an empty path.
+func callee(imports map[string]string, e ast.Expr) (pkg, name string) {
+	switch e := e.(type) {
+	case *ast.IndexExpr:
+		return callee(imports, e.X)
+	case *ast.IndexListExpr:
+		return callee(imports, e.X)
+	case *ast.Ident:
+		return "", e.Name
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok {
+			if path, ok := imports[id.Name]; ok {
+				return path, e.Sel.Name
+			}
+		}
+		return "", e.Sel.Name
+	}
+	return "", ""
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	v, err := strconv.Unquote(lit.Value)
+	return v, err == nil
+}
+
+func isIdent(e ast.Expr, name string) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == name
+}
@@ -0,0 +1,400 @@
+//go:build !js
+
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/rtmlast"
+)
+
+// The reference patterns mirror the ones core resolves at render time, so a
+// reference the checker accepts is one the renderer would substitute.
+var (
+	reStoreRef   = regexp.MustCompile(`@(?:raw)?store:[\w.]*`)
+	reStoreParts = regexp.MustCompile(`^@(?:raw)?store:(\w+)\.(\w+)\.(\w+)`)
+	reSignalRef  = regexp.MustCompile(`@signal:(\w+)`)
+	rePropRef    = regexp.MustCompile(`@(?:raw)?prop:(\w+)`)
+	reIncludeRef = regexp.MustCompile(`@include:([\w-]+)(:\{)?`)
+	reEventRef   = regexp.MustCompile(`@on:(\w+(?:\.\w+)*):(\w+)`)
+	reHostVar    = regexp.MustCompile(`\{h:(\w+)\}`)
+	reHostCmd    = regexp.MustCompile(`@h:(\w+)`)
+	reRtIs       = regexp.MustCompile(`rt-is="([^"]+)"`)
+	reForHeader  = regexp.MustCompile(`^@for:(\w+(?:,\w+)?)\s+in\s+(\S+)`)
+
+	// condition operands carry no "@"
+	reCondStore  = regexp.MustCompile(`\bstore:(\w+)\.(\w+)\.\w+`)
+	reCondSignal = regexp.MustCompile(`\bsignal:(\w+)`)
+	reCondProp   = regexp.MustCompile(`\bprop:(\w+)`)
+)
+
+// block is an open @if, @for, @slot, @switch or @await.
+type block struct {
+	kind    string
+	pos     int
+	aliases []string
+}
+
+// loopScope is the span of a loop, @await or scoped slot body, where its
+// aliases are props.
+type loopScope struct {
+	start, end int
+	aliases    []string
+}
+
+type templateChecker struct {
+	file   string
+	src    string
+	lines  []int
+	syms   *symbols
+	pkgDir string
+	scopes []loopScope
+	// operands wait for every loop scope to be known
+	operands []func()
+	diags    []Diagnostic
+}
+
+// checkTemplate reports the problems in one template. pkgDir is the package
+// the template belongs to, or "" when it has none.
+func checkTemplate(file, src string, syms *symbols, pkgDir string) []Diagnostic {
+	t := &templateChecker{file: file, src: src, syms: syms, pkgDir: pkgDir, lines: []int{0}}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			t.lines = append(t.lines, i+1)
+		}
+	}
+	if _, err := rtmlast.Parse(src); err != nil {
+		t.report(0, "%v", err)
+		return t.diags
+	}
+	tokens := rtmlast.NewLexer(src).Lex()
+	t.blocks(tokens)
+	for _, check := range t.operands {
+		check()
+	}
+	t.references()
+	t.expressions(tokens)
+	return t.diags
+}
+
+func (t *templateChecker) report(off int, format string, args ...any) {
+	line := sort.Search(len(t.lines), func(i int) bool { return t.lines[i] > off })
+	t.diags = append(t.diags, Diagnostic{
+		File:    t.file,
+		Line:    line,
+		Col:     off - t.lines[line-1] + 1,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// blocks matches every block command with its end and queues the operands
+// of conditions and loop headers for checking.
+func (t *templateChecker) blocks(tokens []rtmlast.Token) {
+	var stack []block
+	closeBlock := func(kind string, off int) *block {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].kind != kind {
+				continue
+			}
+			for _, open := range stack[i+1:] {
+				t.report(open.pos, "@%s without @end%s", open.kind, open.kind)
+			}
+			b := stack[i]
+			stack = stack[:i]
+			return &b
+		}
+		t.report(off, "@end%s without @%s", kind, kind)
+		return nil
+	}
+	within := func(off int, kind, cmd string) {
+		if len(stack) == 0 || stack[len(stack)-1].kind != kind {
+			t.report(off, "@%s outside @%s", cmd, kind)
+		}
+	}
+
+	for _, tok := range tokens {
+		if tok.Type != rtmlast.TokenCommand {
+			continue
+		}
+		cmd, off := tok.Value, tok.Pos
+		switch {
+		case strings.HasPrefix(cmd, "if:"):
+			stack = append(stack, block{kind: "if", pos: off})
+			t.operands = append(t.operands, func() { t.condition(off, cmd) })
+		case strings.HasPrefix(cmd, "else-if:"):
+			within(off, "if", "else-if")
+			t.operands = append(t.operands, func() { t.condition(off, cmd) })
+		case isCommand(cmd, "else"):
+			within(off, "if", "else")
+		case isCommand(cmd, "endif"):
+			closeBlock("if", off)
+		case strings.HasPrefix(cmd, "for:"):
+			m := reForHeader.FindStringSubmatchIndex(t.src[off:])
+			if m == nil {
+				t.report(off, "malformed @for, want @for:item in collection")
+				stack = append(stack, block{kind: "for", pos: off})
+				continue
+			}
+			aliases := strings.Split(t.src[off+m[2]:off+m[3]], ",")
+			t.operands = append(t.operands, func() { t.collection(off+m[4], t.src[off+m[4]:off+m[5]]) })
+			stack = append(stack, block{kind: "for", pos: off, aliases: aliases})
+		case isCommand(cmd, "endfor"):
+			if b := closeBlock("for", off); b != nil && b.aliases != nil {
+				t.scopes = append(t.scopes, loopScope{start: b.pos, end: off, aliases: b.aliases})
+			}
+		case strings.HasPrefix(cmd, "slot:"):
+			// the names content for a scoped slot lists are props inside it
+			b := block{kind: "slot", pos: off}
+			header := strings.TrimPrefix(cmd, "slot:")
+			if i := strings.IndexAny(header, " \t"); i >= 0 {
+				b.aliases = rtmlast.SlotParams(header[i:])
+			}
+			stack = append(stack, b)
+		case isCommand(cmd, "endslot"):
+			if b := closeBlock("slot", off); b != nil && b.aliases != nil {
+				t.scopes = append(t.scopes, loopScope{start: b.pos, end: off, aliases: b.aliases})
+			}
+		case strings.HasPrefix(cmd, "switch:"):
+			stack = append(stack, block{kind: "switch", pos: off})
+			t.operands = append(t.operands, func() { t.condition(off, cmd) })
+		case strings.HasPrefix(cmd, "case:"):
+			within(off, "switch", "case")
+			t.operands = append(t.operands, func() { t.condition(off, cmd) })
+		case isCommand(cmd, "default"):
+			within(off, "switch", "default")
+		case isCommand(cmd, "endswitch"):
+			closeBlock("switch", off)
+		case strings.HasPrefix(cmd, "await:"):
+			stack = append(stack, block{kind: "await", pos: off})
+			if name := strings.Fields(strings.TrimPrefix(cmd, "await:")); len(name) > 0 {
+				t.operands = append(t.operands, func() { t.prop(off+len("@await:"), "resource", name[0]) })
+			}
+		case isCommand(cmd, "pending"):
+			within(off, "await", "pending")
+		case strings.HasPrefix(cmd, "then:"), strings.HasPrefix(cmd, "catch:"):
+			// the name is a prop for the rest of the @await
+			section, rest, _ := strings.Cut(cmd, ":")
+			within(off, "await", section)
+			if name := strings.Fields(rest); len(name) > 0 && len(stack) > 0 && stack[len(stack)-1].kind == "await" {
+				stack[len(stack)-1].aliases = append(stack[len(stack)-1].aliases, name[0])
+			}
+		case isCommand(cmd, "endawait"):
+			if b := closeBlock("await", off); b != nil && b.aliases != nil {
+				t.scopes = append(t.scopes, loopScope{start: b.pos, end: off, aliases: b.aliases})
+			}
+		}
+	}
+	for _, open := range stack {
+		t.report(open.pos, "@%s without @end%s", open.kind, open.kind)
+	}
+}
+
+// isCommand reports whether a lexed command is the bare command name; the
+// lexer keeps the text up to the next tag, so words may follow.
+func isCommand(cmd, name string) bool {
+	return cmd == name || strings.HasPrefix(cmd, name+" ") || strings.HasPrefix(cmd, name+"\t")
+}
+
+// condition checks the store, signal and prop operands of an @if or
+// @else-if at off.
+func (t *templateChecker) condition(off int, cmd string) {
+	base := off + 1
+	for _, m := range reCondStore.FindAllStringSubmatchIndex(cmd, -1) {
+		t.store(base+m[0], cmd[m[2]:m[3]]+"."+cmd[m[4]:m[5]])
+	}
+	for _, m := range reCondSignal.FindAllStringSubmatchIndex(cmd, -1) {
+		t.prop(base+m[0], "signal", cmd[m[2]:m[3]])
+	}
+	for _, m := range reCondProp.FindAllStringSubmatchIndex(cmd, -1) {
+		t.prop(base+m[0], "prop", cmd[m[2]:m[3]])
+	}
+}
+
+// collection checks what a loop iterates: a store key, a range or a prop.
+func (t *templateChecker) collection(off int, expr string) {
+	switch {
+	case strings.HasPrefix(expr, "store:"):
+		parts := strings.Split(strings.TrimPrefix(expr, "store:"), ".")
+		if len(parts) != 3 {
+			t.report(off, "malformed store reference %q, want store:module.store.key", expr)
+			return
+		}
+		t.store(off, parts[0]+"."+parts[1])
+	case strings.Contains(expr, ".."):
+	default:
+		t.prop(off, "prop", expr)
+	}
+}
+
+// references checks every reference written with its "@" or brace syntax.
+func (t *templateChecker) references() {
+	src := t.src
+	for _, m := range reStoreRef.FindAllStringIndex(src, -1) {
+		ref := src[m[0]:m[1]]
+		parts := reStoreParts.FindStringSubmatch(ref)
+		if parts == nil {
+			t.report(m[0], "malformed store reference %q, want @store:module.store.key", ref)
+			continue
+		}
+		t.store(m[0], parts[1]+"."+parts[2])
+	}
+	for _, m := range reSignalRef.FindAllStringSubmatchIndex(src, -1) {
+		t.prop(m[0], "signal", src[m[2]:m[3]])
+	}
+	for _, m := range rePropRef.FindAllStringSubmatchIndex(src, -1) {
+		t.prop(m[0], "prop", src[m[2]:m[3]])
+	}
+	for _, m := range reIncludeRef.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		if m[4] >= 0 {
+			// inline props instantiate the component from the registry
+			if !t.syms.kinds[KindComponent].has(name) {
+				t.report(m[0], "unknown component %q", name)
+			}
+			continue
+		}
+		if !t.syms.kinds[KindInclude].has(name) {
+			t.report(m[0], "unknown include %q", name)
+		}
+	}
+	for _, m := range reEventRef.FindAllStringSubmatchIndex(src, -1) {
+		if name := src[m[4]:m[5]]; !t.syms.kinds[KindHandler].has(name) {
+			t.report(m[0], "unknown handler %q", name)
+		}
+	}
+	for _, m := range reRtIs.FindAllStringSubmatchIndex(src, -1) {
+		if name := src[m[2]:m[3]]; !t.syms.kinds[KindComponent].has(name) {
+			t.report(m[0], "unknown component %q", name)
+		}
+	}
+	for _, m := range reHostVar.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		if !t.hostLinked(m[0], "{h:"+name+"}") {
+			continue
+		}
+		if vars := t.syms.hostVars[t.pkgDir]; vars != nil {
+			if !vars.has(name) {
+				t.report(m[0], "unknown host variable %q: not a field of %s", name, strings.Join(t.syms.hostOuts[t.pkgDir], " or "))
+			}
+			continue
+		}
+		if !t.syms.kinds[KindProp].has(name) {
+			t.report(m[0], "unknown host variable %q", name)
+		}
+	}
+	for _, m := range reHostCmd.FindAllStringSubmatchIndex(src, -1) {
+		t.hostLinked(m[0], "@h:"+src[m[2]:m[3]])
+	}
+}
+
+// expressions checks the syntax of every @expr, {{ }} interpolation and
+// condition, and that the functions they call are registered.
+func (t *templateChecker) expressions(tokens []rtmlast.Token) {
+	for _, tok := range tokens {
+		switch {
+		case tok.Type == rtmlast.TokenVarOpen:
+			t.expression(tok.Pos+2, t.src[tok.Pos+2:], func(s string) int {
+				return strings.Index(s, "}}")
+			})
+		case tok.Type != rtmlast.TokenCommand:
+		case strings.HasPrefix(tok.Value, "expr:"):
+			off := tok.Pos + len("@expr:")
+			if t.inTag(tok.Pos) {
+				// an attribute value ends at its closing quote
+				t.expression(off, tok.Value[len("expr:"):], attrExprEnd)
+			} else {
+				t.expression(off, tok.Value[len("expr:"):], rtmlast.ExprEnd)
+			}
+		case strings.HasPrefix(tok.Value, "if:"), strings.HasPrefix(tok.Value, "else-if:"),
+			strings.HasPrefix(tok.Value, "switch:"), strings.HasPrefix(tok.Value, "case:"):
+			// a condition naming other directives is only read once they
+			// are replaced
+			if cond := tok.Value[strings.IndexByte(tok.Value, ':')+1:]; !strings.Contains(cond, "@") {
+				t.expression(tok.Pos+len(tok.Value)-len(cond)+1, cond, func(s string) int { return len(s) })
+			}
+		}
+	}
+}
+
+// expression checks the expression that src starts with, ending where end
+// says, at off in the template.
+func (t *templateChecker) expression(off int, src string, end func(string) int) {
+	n := end(src)
+	if n < 0 {
+		return
+	}
+	source := strings.TrimSpace(src[:n])
+	if source == "" {
+		return
+	}
+	off += strings.Index(src, source)
+	rtmlast.Inspect(rtmlast.ParseExpr(source), func(e rtmlast.Expr) bool {
+		switch e := e.(type) {
+		case rtmlast.BadExpr:
+			t.report(off, "expression %q: %v", e.Source, e.Err)
+		case rtmlast.CallExpr:
+			if !t.syms.kinds[KindFunc].has(e.Fn) {
+				t.report(off, "unknown template func %q", e.Fn)
+			}
+		}
+		return true
+	})
+}
+
+// inTag reports whether off lies inside a tag, in an attribute.
+func (t *templateChecker) inTag(off int) bool {
+	return strings.LastIndexByte(t.src[:off], '<') > strings.LastIndexByte(t.src[:off], '>')
+}
+
+// attrExprEnd ends an @expr in an attribute at the first double quote outside
+// single quotes, as core does for class attributes.
+func attrExprEnd(s string) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			quoted = !quoted
+		case '"', '<', '@':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// hostLinked reports whether the template's package links a host component;
+// without one the renderer leaves host references as written.
+func (t *templateChecker) hostLinked(off int, ref string) bool {
+	if t.pkgDir == "" || t.syms.hostLinks[t.pkgDir] {
+		return true
+	}
+	t.report(off, "%s needs a host component: call AddHostComponent", ref)
+	return false
+}
+
+func (t *templateChecker) store(off int, name string) {
+	if !t.syms.kinds[KindStore].has(name) {
+		t.report(off, "unknown store %q", name)
+	}
+}
+
+// prop checks a prop or signal name, which inside a loop body may be one of
+// the loop's aliases.
+func (t *templateChecker) prop(off int, kind, name string) {
+	for _, s := range t.scopes {
+		if off > s.start && off < s.end {
+			for _, alias := range s.aliases {
+				if alias == name {
+					return
+				}
+			}
+		}
+	}
+	if !t.syms.kinds[KindProp].has(name) {
+		t.report(off, "unknown %s %q", kind, name)
+	}
+}
@@ -0,0 +1,36 @@
+package components
+
+import (
+	_ "embed"
+
+	"github.com/rfwlab/rfw/v2/core"
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+//go:embed templates/app.rtml
+var appTpl []byte
+
+var counter = state.NewStore("counter", state.WithModule("app"))
+
+func NewApp() *core.HTMLComponent {
+	c := core.NewHTMLComponent("App", appTpl, map[string]any{
+		"title":   "Todos",
+		"items":   []any{},
+		"open":    state.NewSignal(false),
+		"profile": state.NewResource[string](nil, state.WithoutImmediateLoad()),
+	})
+	c.AddDependency("header", core.NewHTMLComponent("Header", nil, nil))
+	c.AddHostComponent("Clock")
+	c.On("save", func() {})
+	dom.RegisterHandlerFunc("increment", func() {})
+	return c
+}
+
+func init() {
+	core.RegisterTemplateFunc("upper", func(s string) string { return s })
+}
+
+func init() {
+	core.MustRegisterComponent("Badge", func() core.Component { return NewApp() })
+}
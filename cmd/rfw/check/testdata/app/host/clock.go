@@ -0,0 +1,9 @@
+package host
+
+import "github.com/rfwlab/rfw/v2/host"
+
+func Register() {
+	host.Register(host.NewHostComponent("Clock", func(map[string]any) any {
+		return map[string]any{"now": "12:00"}
+	}))
+}
@@ -0,0 +1,40 @@
+//go:build !js
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/mirkobrombin/go-cli-builder/v1/command"
+	"github.com/rfwlab/rfw/v2/cmd/rfw/check"
+)
+
+// NewCheckCommand returns the check command.
+func NewCheckCommand() *command.Command {
+	cmd := &command.Command{
+		Name:        "check",
+		Usage:       "check [dir]",
+		Description: "Report broken references in the project's templates",
+		Run:         runCheck,
+	}
+	return cmd
+}
+
+func runCheck(cmd *command.Command, _ *command.RootFlags, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	diags, err := check.Run(dir)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("%d template problem(s)", len(diags))
+	}
+	cmd.Logger.Success("Templates OK")
+	return nil
+}
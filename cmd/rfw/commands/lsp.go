@@ -0,0 +1,25 @@
+//go:build !js
+
+package commands
+
+import (
+	"os"
+
+	"github.com/mirkobrombin/go-cli-builder/v1/command"
+	"github.com/rfwlab/rfw/v2/cmd/rfw/lsp"
+)
+
+// NewLSPCommand returns the lsp command.
+func NewLSPCommand() *command.Command {
+	cmd := &command.Command{
+		Name:        "lsp",
+		Usage:       "lsp",
+		Description: "Run the RTML language server over stdio",
+		Run:         runLSP,
+	}
+	return cmd
+}
+
+func runLSP(_ *command.Command, _ *command.RootFlags, _ []string) error {
+	return lsp.Serve(os.Stdin, os.Stdout)
+}
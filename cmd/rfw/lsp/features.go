@@ -0,0 +1,233 @@
+//go:build !js
+
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/cmd/rfw/check"
+)
+
+// Completion contexts: the text before the cursor ends in a partial
+// reference.
+var (
+	reCompleteStore   = regexp.MustCompile(`(?:@(?:raw)?|\b)store:([\w.]*)$`)
+	reCompleteHandler = regexp.MustCompile(`@on:\w+(?:\.\w+)*:(\w*)$`)
+	reCompleteInclude = regexp.MustCompile(`@include:([\w-]*)$`)
+	reCompleteProp    = regexp.MustCompile(`(?:(?:@(?:raw)?|\b)(?:prop|signal):|\{h:)(\w*)$`)
+)
+
+// Reference patterns, matched around the cursor for hover and definition.
+var (
+	reRefStore   = regexp.MustCompile(`(?:@(?:raw)?)?store:(\w+)\.(\w+)(?:\.(\w+))?`)
+	reRefHandler = regexp.MustCompile(`@on:\w+(?:\.\w+)*:(\w+)`)
+	reRefInclude = regexp.MustCompile(`@include:([\w-]+)(:\{)?`)
+	reRefProp    = regexp.MustCompile(`(?:(?:@(?:raw)?)?(?:prop|signal):|\{h:)(\w+)`)
+	reRefRtIs    = regexp.MustCompile(`rt-is="([^"]+)"`)
+)
+
+// complete lists the names that can finish the reference before pos.
+func (s *Server) complete(text string, pos position) []completionItem {
+	items := []completionItem{}
+	if s.project == nil {
+		return items
+	}
+	line := lineText(text, pos.Line)
+	prefix := line[:byteColumn(line, pos.Character)]
+	// edit replaces the partial word the client typed so far
+	edit := func(partial, name string) *textEdit {
+		start := len(prefix) - len(partial)
+		return &textEdit{
+			Range: lspRange{
+				Start: position{Line: pos.Line, Character: utf16Column(line, start)},
+				End:   pos,
+			},
+			NewText: name,
+		}
+	}
+	add := func(partial, name string, kind int, detail string) {
+		items = append(items, completionItem{Label: name, Kind: kind, Detail: detail, TextEdit: edit(partial, name)})
+	}
+
+	switch {
+	case reCompleteStore.MatchString(prefix):
+		path := reCompleteStore.FindStringSubmatch(prefix)[1]
+		segs := strings.Split(path, ".")
+		partial := segs[len(segs)-1]
+		stores := s.project.Names(check.KindStore)
+		switch len(segs) {
+		case 1:
+			seen := map[string]bool{}
+			for _, store := range stores {
+				module, _, _ := strings.Cut(store, ".")
+				if !seen[module] {
+					seen[module] = true
+					add(partial, module, itemModule, "module")
+				}
+			}
+		case 2:
+			for _, store := range stores {
+				if module, name, _ := strings.Cut(store, "."); module == segs[0] {
+					add(partial, name, itemStruct, declType(s.project.Decls(check.KindStore, store)))
+				}
+			}
+		case 3:
+			store := segs[0] + "." + segs[1]
+			for _, key := range s.project.StoreKeys(store) {
+				add(partial, key, itemField, declType(s.project.KeyDecls(store, key)))
+			}
+		}
+	case reCompleteHandler.MatchString(prefix):
+		partial := reCompleteHandler.FindStringSubmatch(prefix)[1]
+		for _, name := range s.project.Names(check.KindHandler) {
+			add(partial, name, itemFunction, declType(s.project.Decls(check.KindHandler, name)))
+		}
+	case reCompleteInclude.MatchString(prefix):
+		partial := reCompleteInclude.FindStringSubmatch(prefix)[1]
+		for _, name := range s.project.Names(check.KindInclude) {
+			add(partial, name, itemClass, declType(s.project.Decls(check.KindInclude, name)))
+		}
+		for _, name := range s.project.Names(check.KindComponent) {
+			add(partial, name, itemClass, "registered component")
+		}
+	case reCompleteProp.MatchString(prefix):
+		partial := reCompleteProp.FindStringSubmatch(prefix)[1]
+		for _, name := range s.project.Names(check.KindProp) {
+			add(partial, name, itemVariable, declType(s.project.Decls(check.KindProp, name)))
+		}
+	}
+	return items
+}
+
+// reference is the template reference under the cursor.
+type reference struct {
+	kind  string // store, key, handler, include, component or prop
+	name  string
+	decls []check.Decl
+	rng   lspRange
+}
+
+// referenceAt finds the reference pos falls in.
+func (s *Server) referenceAt(text string, pos position) (reference, bool) {
+	if s.project == nil {
+		return reference{}, false
+	}
+	line := lineText(text, pos.Line)
+	col := byteColumn(line, pos.Character)
+	span := func(m []int) lspRange {
+		return lspRange{
+			Start: position{Line: pos.Line, Character: utf16Column(line, m[0])},
+			End:   position{Line: pos.Line, Character: utf16Column(line, m[1])},
+		}
+	}
+	at := func(re *regexp.Regexp) []int {
+		for _, m := range re.FindAllStringSubmatchIndex(line, -1) {
+			if col >= m[0] && col <= m[1] {
+				return m
+			}
+		}
+		return nil
+	}
+	p := s.project
+	if m := at(reRefStore); m != nil {
+		store := line[m[2]:m[3]] + "." + line[m[4]:m[5]]
+		if m[6] >= 0 && col >= m[6] {
+			key := line[m[6]:m[7]]
+			return reference{kind: "key", name: store + "." + key, decls: p.KeyDecls(store, key), rng: span(m)}, true
+		}
+		return reference{kind: "store", name: store, decls: p.Decls(check.KindStore, store), rng: span(m)}, true
+	}
+	if m := at(reRefHandler); m != nil {
+		name := line[m[2]:m[3]]
+		return reference{kind: "handler", name: name, decls: p.Decls(check.KindHandler, name), rng: span(m)}, true
+	}
+	if m := at(reRefInclude); m != nil {
+		name := line[m[2]:m[3]]
+		if m[4] >= 0 {
+			return reference{kind: "component", name: name, decls: p.Decls(check.KindComponent, name), rng: span(m)}, true
+		}
+		return reference{kind: "include", name: name, decls: p.Decls(check.KindInclude, name), rng: span(m)}, true
+	}
+	if m := at(reRefRtIs); m != nil {
+		name := line[m[2]:m[3]]
+		return reference{kind: "component", name: name, decls: p.Decls(check.KindComponent, name), rng: span(m)}, true
+	}
+	if m := at(reRefProp); m != nil {
+		name := line[m[2]:m[3]]
+		return reference{kind: "prop", name: name, decls: p.Decls(check.KindProp, name), rng: span(m)}, true
+	}
+	return reference{}, false
+}
+
+// hover shows the Go type of the reference under the cursor and where it is
+// declared.
+func (s *Server) hover(text string, pos position) *hover {
+	ref, ok := s.referenceAt(text, pos)
+	if !ok {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "```go\n(%s) %s", ref.kind, ref.name)
+	if typ := declType(ref.decls); typ != "" {
+		b.WriteString(" " + typ)
+	}
+	b.WriteString("\n```")
+	if len(ref.decls) == 0 {
+		b.WriteString("\n\nNot declared in this project.")
+	}
+	for _, d := range ref.decls {
+		if d.Pos.IsValid() {
+			fmt.Fprintf(&b, "\n\n%s:%d", s.relative(d.Pos.Filename), d.Pos.Line)
+		}
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: b.String()}, Range: &ref.rng}
+}
+
+// definition jumps from the reference under the cursor to the Go code
+// declaring it.
+func (s *Server) definition(text string, pos position) []location {
+	locs := []location{}
+	ref, ok := s.referenceAt(text, pos)
+	if !ok {
+		return locs
+	}
+	for _, d := range ref.decls {
+		if !d.Pos.IsValid() {
+			continue
+		}
+		start := position{Line: d.Pos.Line - 1}
+		if src, err := os.ReadFile(d.Pos.Filename); err == nil {
+			start.Character = utf16Column(lineText(string(src), d.Pos.Line-1), d.Pos.Column-1)
+		} else {
+			start.Character = d.Pos.Column - 1
+		}
+		locs = append(locs, location{URI: pathToURI(d.Pos.Filename), Range: lspRange{Start: start, End: start}})
+	}
+	return locs
+}
+
+// declType joins the distinct Go types of decls.
+func declType(decls []check.Decl) string {
+	seen := map[string]bool{}
+	var types []string
+	for _, d := range decls {
+		if d.Type != "" && !seen[d.Type] {
+			seen[d.Type] = true
+			types = append(types, d.Type)
+		}
+	}
+	sort.Strings(types)
+	return strings.Join(types, " | ")
+}
+
+func (s *Server) relative(path string) string {
+	if rel, err := filepath.Rel(s.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,231 @@
+//go:build !js
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const appGo = `package app
+
+import (
+	"github.com/rfwlab/rfw/v2/core"
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+var counter = state.NewStore("counter", state.WithModule("app"))
+
+func New() *core.HTMLComponent {
+	counter.Set("count", 0)
+	c := core.NewHTMLComponent("App", nil, map[string]any{"title": "Todos"})
+	dom.RegisterHandlerFunc("increment", func() {})
+	return c
+}
+`
+
+const appRTML = `<root>
+  <h1>@prop:title</h1>
+  <p>@store:app.counter.count</p>
+  <button @on:click:increment>+1</button>
+  <button @on:click:saev>save</button>
+</root>
+`
+
+// client drives a server over pipes the way an editor does.
+type client struct {
+	t   *testing.T
+	in  io.WriteCloser
+	out chan *message
+	seq int
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(sr, sw)
+		_ = sw.Close()
+	}()
+	// drain the server on its own goroutine: pipes are unbuffered, and the
+	// server publishes diagnostics while the client is sending
+	out := make(chan *message, 64)
+	go func() {
+		defer close(out)
+		r := bufio.NewReader(cr)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			out <- msg
+		}
+	}()
+	t.Cleanup(func() {
+		_ = cw.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return &client{t: t, in: cw, out: out}
+}
+
+func (c *client) send(method string, id bool, params any) {
+	c.t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := &message{Method: method, Params: data}
+	if id {
+		c.seq++
+		msg.ID = json.RawMessage(strings.TrimSpace(string(mustJSON(c.t, c.seq))))
+	}
+	if err := writeMessage(c.in, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// wait reads until a message matching method (a notification) or carrying
+// the last request id arrives.
+func (c *client) wait(method string) *message {
+	c.t.Helper()
+	for {
+		msg, ok := <-c.out
+		if !ok {
+			c.t.Fatal("server closed the stream")
+		}
+		if method != "" && msg.Method == method {
+			return msg
+		}
+		if method == "" && msg.ID != nil && string(msg.ID) == string(mustJSON(c.t, c.seq)) {
+			return msg
+		}
+	}
+}
+
+func (c *client) call(method string, params any, result any) {
+	c.t.Helper()
+	c.send(method, true, params)
+	msg := c.wait("")
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", method, msg.Error.Message)
+	}
+	if err := json.Unmarshal(msg.Result, result); err != nil {
+		c.t.Fatalf("%s result: %v", method, err)
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func at(uri string, line, char int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": char},
+	}
+}
+
+// TestServerSession verifies diagnostics, completion, hover and definition
+// over one session against a small project.
+func TestServerSession(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(appGo), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tplPath := filepath.Join(dir, "app.rtml")
+	uri := pathToURI(tplPath)
+
+	c := newClient(t)
+	var init struct {
+		Capabilities struct {
+			HoverProvider bool `json:"hoverProvider"`
+		} `json:"capabilities"`
+	}
+	c.call("initialize", map[string]any{"rootUri": pathToURI(dir)}, &init)
+	if !init.Capabilities.HoverProvider {
+		t.Fatalf("hover not advertised: %+v", init)
+	}
+	c.send("initialized", false, map[string]any{})
+
+	c.send("textDocument/didOpen", false, map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "rtml", "version": 1, "text": appRTML},
+	})
+	var diags struct {
+		URI         string       `json:"uri"`
+		Diagnostics []diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(c.wait("textDocument/publishDiagnostics").Params, &diags); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Message != `unknown handler "saev"` {
+		t.Fatalf("diagnostics: %+v", diags.Diagnostics)
+	}
+	if r := diags.Diagnostics[0].Range; r.Start.Line != 4 || r.Start.Character != 10 || r.End.Character != 24 {
+		t.Fatalf("diagnostic range: %+v", r)
+	}
+
+	// completion after "@store:app." offers the stores of module app
+	edited := strings.Replace(appRTML, "@store:app.counter.count", "@store:app.", 1)
+	c.send("textDocument/didChange", false, map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": edited}},
+	})
+	var items []completionItem
+	c.call("textDocument/completion", at(uri, 2, len("  <p>@store:app.")), &items)
+	labels := map[string]bool{}
+	for _, it := range items {
+		labels[it.Label] = true
+	}
+	if !labels["counter"] || !labels["default"] {
+		t.Fatalf("store completion: %+v", items)
+	}
+
+	edited = strings.Replace(appRTML, "@store:app.counter.count", "@store:app.counter.", 1)
+	c.send("textDocument/didChange", false, map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 3},
+		"contentChanges": []map[string]any{{"text": edited}},
+	})
+	c.call("textDocument/completion", at(uri, 2, len("  <p>@store:app.counter.")), &items)
+	if len(items) != 1 || items[0].Label != "count" || items[0].Detail != "int" {
+		t.Fatalf("key completion: %+v", items)
+	}
+
+	c.send("textDocument/didChange", false, map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 4},
+		"contentChanges": []map[string]any{{"text": appRTML}},
+	})
+	c.call("textDocument/completion", at(uri, 3, len("  <button @on:click:inc")), &items)
+	if len(items) != 1 || items[0].Label != "increment" || items[0].TextEdit.Range.Start.Character != len("  <button @on:click:") {
+		t.Fatalf("handler completion: %+v", items)
+	}
+
+	var h hover
+	c.call("textDocument/hover", at(uri, 1, len("  <h1>@prop:ti")), &h)
+	if !strings.Contains(h.Contents.Value, "(prop) title string") || !strings.Contains(h.Contents.Value, "app.go:13") {
+		t.Fatalf("hover: %q", h.Contents.Value)
+	}
+
+	var locs []location
+	c.call("textDocument/definition", at(uri, 3, len("  <button @on:click:incr")), &locs)
+	if len(locs) != 1 || locs[0].URI != pathToURI(filepath.Join(dir, "app.go")) || locs[0].Range.Start.Line != 13 || locs[0].Range.Start.Character != 1 {
+		t.Fatalf("definition: %+v", locs)
+	}
+
+	var none any
+	c.call("shutdown", nil, &none)
+	c.send("exit", false, nil)
+}
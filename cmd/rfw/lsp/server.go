@@ -0,0 +1,229 @@
+//go:build !js
+
+// Package lsp implements a language server for RTML templates.
+//
+// It speaks the Language Server Protocol over stdio: templates are checked
+// as they are edited, and store paths, props, signals, includes and handlers
+// complete, hover and jump to their Go declarations. The Go side is read
+// with the template checker's project index, reloaded whenever a file is
+// saved.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/rfwlab/rfw/v2/cmd/rfw/check"
+	"github.com/rfwlab/rfw/v2/core"
+)
+
+// Server is one language server session.
+type Server struct {
+	w       io.Writer
+	root    string
+	project *check.Project
+	docs    map[string]string // open documents by URI
+	exiting bool
+}
+
+// Serve answers the client on r and w until it sends exit or closes the
+// stream.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{w: w, docs: map[string]string{}}
+	in := bufio.NewReader(r)
+	for !s.exiting {
+		msg, err := readMessage(in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(msg *message) error {
+	result, err := s.dispatch(msg)
+	if msg.ID == nil {
+		// notifications get no answer, not even an error
+		return nil
+	}
+	resp := &message{ID: msg.ID}
+	switch {
+	case err != nil:
+		resp.Error = err
+	default:
+		data, mErr := json.Marshal(result)
+		if mErr != nil {
+			return mErr
+		}
+		resp.Result = data
+	}
+	return writeMessage(s.w, resp)
+}
+
+func (s *Server) dispatch(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			RootURI  string `json:"rootUri"`
+			RootPath string `json:"rootPath"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.root = params.RootPath
+		if params.RootURI != "" {
+			s.root = uriToPath(params.RootURI)
+		}
+		if s.root == "" {
+			s.root, _ = os.Getwd()
+		}
+		s.reload()
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full text
+					"save":      true,
+				},
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{":", "."},
+				},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "rfw", "version": core.Version()},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "exit":
+		s.exiting = true
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			s.docs[params.TextDocument.URI] = params.TextDocument.Text
+			s.publish(params.TextDocument.URI)
+		}
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   textDocumentIdentifier `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			s.docs[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.publish(params.TextDocument.URI)
+		}
+	case "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		// a saved Go file may declare what the open templates refer to
+		s.reload()
+		for uri := range s.docs {
+			s.publish(uri)
+		}
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument textDocumentIdentifier `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.docs, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", map[string]any{
+				"uri": params.TextDocument.URI, "diagnostics": []diagnostic{},
+			})
+		}
+	case "textDocument/completion", "textDocument/hover", "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		text, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			data, err := os.ReadFile(uriToPath(params.TextDocument.URI))
+			if err != nil {
+				return nil, nil
+			}
+			text = string(data)
+		}
+		switch msg.Method {
+		case "textDocument/completion":
+			return s.complete(text, params.Position), nil
+		case "textDocument/hover":
+			return s.hover(text, params.Position), nil
+		default:
+			return s.definition(text, params.Position), nil
+		}
+	default:
+		if msg.ID != nil {
+			return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// reload indexes the Go code under the workspace root again. A root that
+// cannot be read leaves the previous index in place.
+func (s *Server) reload() {
+	if p, err := check.Load(s.root); err == nil {
+		s.project = p
+	}
+}
+
+// publish sends the diagnostics of the open document uri.
+func (s *Server) publish(uri string) {
+	text := s.docs[uri]
+	diags := []diagnostic{}
+	if s.project != nil {
+		for _, d := range s.project.CheckTemplate(uriToPath(uri), text) {
+			line := lineText(text, d.Line-1)
+			start := d.Col - 1
+			end := start
+			for end < len(line) && !isBoundary(line[end]) {
+				end++
+			}
+			diags = append(diags, diagnostic{
+				Range: lspRange{
+					Start: position{Line: d.Line - 1, Character: utf16Column(line, start)},
+					End:   position{Line: d.Line - 1, Character: utf16Column(line, end)},
+				},
+				Severity: 1,
+				Source:   "rfw",
+				Message:  d.Message,
+			})
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diags})
+}
+
+// isBoundary reports whether b ends a reference in a template.
+func isBoundary(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '<', '>', '"', '\'':
+		return true
+	}
+	return false
+}
+
+func (s *Server) notify(method string, params any) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	_ = writeMessage(s.w, &message{Method: method, Params: data})
+}
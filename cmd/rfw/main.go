@@ -21,6 +21,8 @@ func main() {
 	rootCmd.AddCommand(commands.NewInitCommand())
 	rootCmd.AddCommand(commands.NewDevCommand())
 	rootCmd.AddCommand(commands.NewBuildCommand())
+	rootCmd.AddCommand(commands.NewCheckCommand())
+	rootCmd.AddCommand(commands.NewLSPCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
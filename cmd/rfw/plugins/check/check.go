@@ -0,0 +1,54 @@
+//go:build !js
+
+// Package check registers the template checker as a build step.
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	rfwcheck "github.com/rfwlab/rfw/v2/cmd/rfw/check"
+	"github.com/rfwlab/rfw/v2/cmd/rfw/plugins"
+)
+
+type plugin struct{}
+
+func init() { plugins.Register(&plugin{}) }
+
+func (p *plugin) Name() string { return "check" }
+
+// Priority runs the checker ahead of the code generators, so a broken
+// template stops the build before anything is written.
+func (p *plugin) Priority() int { return -10 }
+
+// PreBuild checks every template under the configured directory and fails
+// the build when any reference is broken.
+func (p *plugin) PreBuild(raw json.RawMessage) error {
+	cfg := struct {
+		Dir string `json:"dir"`
+	}{Dir: "."}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &cfg)
+	}
+	diags, err := rfwcheck.Run(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+	lines := make([]string, len(diags))
+	for i, d := range diags {
+		lines[i] = d.String()
+	}
+	return fmt.Errorf("template check failed:\n  %s", strings.Join(lines, "\n  "))
+}
+
+func (p *plugin) Build(json.RawMessage) error { return nil }
+
+func (p *plugin) PostBuild(json.RawMessage) error { return nil }
+
+func (p *plugin) ShouldRebuild(path string) bool {
+	return strings.HasSuffix(path, ".rtml")
+}
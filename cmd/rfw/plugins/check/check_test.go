@@ -0,0 +1,35 @@
+//go:build !js
+
+package check
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPreBuildFailsOnBrokenTemplate verifies that the plugin stops the build
+// with the checker's diagnostics and lets a clean project through.
+func TestPreBuildFailsOnBrokenTemplate(t *testing.T) {
+	dir := t.TempDir()
+	tpl := filepath.Join(dir, "app.rtml")
+	if err := os.WriteFile(tpl, []byte("<p>@store:app.defualt.count</p>\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(map[string]string{"dir": dir})
+
+	p := &plugin{}
+	err := p.PreBuild(raw)
+	if err == nil || !strings.Contains(err.Error(), `app.rtml:1:4: unknown store "app.defualt"`) {
+		t.Fatalf("PreBuild error = %v", err)
+	}
+
+	if err := os.WriteFile(tpl, []byte("<p>@store:app.default.count</p>\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PreBuild(raw); err != nil {
+		t.Fatalf("PreBuild: %v", err)
+	}
+}
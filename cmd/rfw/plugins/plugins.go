@@ -25,6 +25,10 @@ type Builder interface{ Build(json.RawMessage) error }
 // PostBuilder is implemented by plugins that run after the build completes.
 type PostBuilder interface{ PostBuild(json.RawMessage) error }
 
+// Cleaner is implemented by plugins that leave files in the project during a
+// build. Cleanup runs once the build ends, whether it succeeded or not.
+type Cleaner interface{ Cleanup() }
+
 type entry struct {
 	Plugin Plugin
 	cfg    json.RawMessage
@@ -121,3 +125,12 @@ func PostBuild() error {
 	}
 	return nil
 }
+
+// Cleanup calls Cleanup on all plugins that implement Cleaner.
+func Cleanup() {
+	for _, e := range active {
+		if c, ok := e.Plugin.(Cleaner); ok {
+			c.Cleanup()
+		}
+	}
+}
@@ -16,6 +16,7 @@ type mockPlugin struct {
 	pre      bool
 	build    bool
 	post     bool
+	cleaned  bool
 }
 
 func (m *mockPlugin) Name() string                { return m.name }
@@ -33,6 +34,7 @@ func (m *mockPlugin) PostBuild(json.RawMessage) error {
 	m.post = true
 	return nil
 }
+func (m *mockPlugin) Cleanup() { m.cleaned = true }
 
 // TestLifecycle verifies registration, ordering and lifecycle invocation of
 // plugins as well as the NeedsRebuild helper.
@@ -72,6 +74,10 @@ func TestLifecycle(t *testing.T) {
 	if !p1.post || !p0.post {
 		t.Fatalf("postbuild not invoked")
 	}
+	Cleanup()
+	if !p1.cleaned || !p0.cleaned {
+		t.Fatalf("cleanup not invoked")
+	}
 
 	if !NeedsRebuild("a.go") {
 		t.Fatalf("expected rebuild for a.go")
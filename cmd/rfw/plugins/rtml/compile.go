@@ -0,0 +1,574 @@
+//go:build !js
+
+package rtml
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rfwlab/rfw/v2/rtmlast"
+)
+
+// The compiled form of a template. Text nodes are pointers because directive
+// lines are stripped from them in place once the tree is built.
+type node interface{}
+
+type textNode struct{ text string }
+
+type rootAttrsNode struct{}
+
+type rowMarkersNode struct{}
+
+type storeNode struct {
+	module, store, key string
+	input, raw         bool
+}
+
+type signalNode struct {
+	name  string
+	input bool
+}
+
+type propNode struct {
+	path string
+	raw  bool
+}
+
+type exprNode struct{ source string }
+
+type ifNode struct{ branches []branch }
+
+type branch struct {
+	condition string
+	body      []node
+}
+
+type forNode struct {
+	aliases    []string
+	collection string
+	body       string
+	row        []node
+}
+
+// unsupported lists the constructs left to the interpreter. They either
+// depend on other components at render time or are rewritten by passes that
+// run over the whole template before the directives are resolved.
+var unsupported = []struct{ marker, what string }{
+	{"{{", "{{prop}} interpolation"},
+	{"@include", "@include"},
+	{"@slot", "@slot"},
+	{"@endslot", "@endslot"},
+	{"@switch:", "@switch"},
+	{"@await:", "@await"},
+	{"rt-is=", "rt-is"},
+	{"{plugin:", "plugin variables"},
+	{"@plugin:", "plugin commands"},
+	{"{h:", "host variables"},
+	{"@h:", "host commands"},
+}
+
+var (
+	reClassExpr = regexp.MustCompile(`class="[^"]*@expr:`)
+	reForHeader = regexp.MustCompile(`^(\w+(?:,\w+)?)\s+in\s+(\S+)$`)
+	reStorePath = regexp.MustCompile(`^(\w+)\.(\w+)\.(\w+)(:w)?`)
+	reSignal    = regexp.MustCompile(`^(\w+)(:w)?`)
+	rePropPath  = regexp.MustCompile(`^\w+(?:\.\w+)*`)
+	reRawText   = regexp.MustCompile(`@rawstore:(\w+)\.(\w+)\.(\w+)|@rawprop:(\w+(?:\.\w+)*)`)
+	reTagName   = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)`)
+)
+
+// compile turns template source into the tree the generator writes out. An
+// error explains why the template has to stay with the interpreter; the
+// generated renderer must never differ from it.
+func compile(src string) ([]node, error) {
+	for _, u := range unsupported {
+		if strings.Contains(src, u.marker) {
+			return nil, fmt.Errorf("%s is not compiled", u.what)
+		}
+	}
+	if reClassExpr.MatchString(src) {
+		return nil, fmt.Errorf("@expr in a class attribute is not compiled")
+	}
+	// the interpreter splits the template into lines and writes every line
+	// back with its newline, the last one included
+	src += "\n"
+	if err := checkBlocks(rtmlast.NewLexer(src).Lex()); err != nil {
+		return nil, err
+	}
+	ast, err := rtmlast.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{}
+	nodes, err := b.build(ast)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, err = placeRootAttrs(nodes); err != nil {
+		return nil, err
+	}
+	if err := stripDirectiveLines(nodes, true); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// checkBlocks verifies that @if and @for blocks are balanced and that no
+// command merely starts like a block keyword. The parser drops or misreads
+// both where the interpreter keeps them as text.
+func checkBlocks(tokens []rtmlast.Token) error {
+	var ifs []bool // whether each open @if has reached its @else
+	loopDepth := -1
+	for _, t := range tokens {
+		if t.Type != rtmlast.TokenCommand {
+			continue
+		}
+		v := t.Value
+		switch {
+		case strings.HasPrefix(v, "if:"):
+			ifs = append(ifs, false)
+		case strings.HasPrefix(v, "else-if:"):
+			if len(ifs) == 0 || ifs[len(ifs)-1] {
+				return fmt.Errorf("@else-if outside an @if block")
+			}
+		case v == "else":
+			if len(ifs) == 0 || ifs[len(ifs)-1] {
+				return fmt.Errorf("@else outside an @if block")
+			}
+			ifs[len(ifs)-1] = true
+		case v == "endif":
+			if len(ifs) == 0 {
+				return fmt.Errorf("@endif without @if")
+			}
+			ifs = ifs[:len(ifs)-1]
+		case strings.HasPrefix(v, "for:"):
+			if loopDepth >= 0 {
+				return fmt.Errorf("nested @for loops are not compiled")
+			}
+			loopDepth = len(ifs)
+		case v == "endfor":
+			if loopDepth != len(ifs) {
+				return fmt.Errorf("@endfor without @for")
+			}
+			loopDepth = -1
+		case strings.HasPrefix(v, "else"), strings.HasPrefix(v, "endif"), strings.HasPrefix(v, "endfor"):
+			return fmt.Errorf("@%s is ambiguous", v)
+		}
+	}
+	if len(ifs) > 0 {
+		return fmt.Errorf("@if without @endif")
+	}
+	if loopDepth >= 0 {
+		return fmt.Errorf("@for without @endfor")
+	}
+	return nil
+}
+
+type builder struct {
+	out *[]node
+	// swallow is set after an @expr running to the end of its command: the
+	// interpreter's expression pattern goes on through the whitespace that
+	// follows, so that whitespace belongs to the expression.
+	swallow bool
+}
+
+func (b *builder) build(ast []rtmlast.Node) ([]node, error) {
+	var nodes []node
+	prev := b.out
+	b.out = &nodes
+	defer func() { b.out = prev }()
+	for _, n := range ast {
+		if err := b.node(n); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func (b *builder) emit(n node) { *b.out = append(*b.out, n) }
+
+func (b *builder) node(n rtmlast.Node) error {
+	switch n := n.(type) {
+	case rtmlast.TextNode:
+		b.text(n.Text)
+	case rtmlast.CommandNode:
+		return b.command(n)
+	case rtmlast.ExprNode:
+		return b.expr(n.Source)
+	case rtmlast.IfNode:
+		return b.ifBlock(n)
+	case rtmlast.ForNode:
+		return b.forBlock(n)
+	default:
+		return fmt.Errorf("%T is not compiled", n)
+	}
+	return nil
+}
+
+// text emits literal text, splitting out the @rawstore and @rawprop
+// directives the lexer leaves inside it.
+func (b *builder) text(s string) {
+	if b.swallow {
+		i := strings.IndexFunc(s, isExprStop)
+		if i < 0 {
+			return
+		}
+		b.swallow = false
+		s = s[i:]
+	}
+	for {
+		loc := reRawText.FindStringSubmatchIndex(s)
+		if loc == nil {
+			break
+		}
+		if loc[0] > 0 {
+			b.emit(&textNode{text: s[:loc[0]]})
+		}
+		if loc[2] >= 0 {
+			b.emit(&storeNode{module: s[loc[2]:loc[3]], store: s[loc[4]:loc[5]], key: s[loc[6]:loc[7]], raw: true})
+		} else {
+			b.emit(&propNode{path: s[loc[8]:loc[9]], raw: true})
+		}
+		s = s[loc[1]:]
+	}
+	if s != "" {
+		b.emit(&textNode{text: s})
+	}
+}
+
+func isExprStop(r rune) bool {
+	return r == '<' || r == '@' || r == '|' || r == '\n' || r == ')'
+}
+
+// command handles a command the lexer cut at the next tag or line end. The
+// directive only covers the start of it; the rest is template again.
+func (b *builder) command(n rtmlast.CommandNode) error {
+	if b.swallow {
+		return fmt.Errorf("@expr runs into @%s", n.Kind)
+	}
+	switch n.Kind {
+	case "store":
+		if m := reStorePath.FindStringSubmatch(n.Value); m != nil {
+			b.emit(&storeNode{module: m[1], store: m[2], key: m[3], input: m[4] != ""})
+			return b.rest(n.Value[len(m[0]):])
+		}
+	case "signal":
+		if m := reSignal.FindStringSubmatch(n.Value); m != nil {
+			b.emit(&signalNode{name: m[1], input: m[2] != ""})
+			return b.rest(n.Value[len(m[0]):])
+		}
+	case "prop":
+		if path := rePropPath.FindString(n.Value); path != "" {
+			b.emit(&propNode{path: path})
+			return b.rest(n.Value[len(path):])
+		}
+	}
+	// anything else stays as written: @on: handlers are expanded on the
+	// rendered markup, and a directive the pattern does not match is text
+	b.text("@" + n.Kind + ":")
+	return b.rest(n.Value)
+}
+
+// rest compiles the text following a directive inside one command. Blocks
+// cannot start there: the interpreter only sees them on lines of their own.
+func (b *builder) rest(s string) error {
+	if s == "" {
+		return nil
+	}
+	for _, t := range rtmlast.NewLexer(s).Lex() {
+		if t.Type == rtmlast.TokenCommand && isBlockKeyword(t.Value) {
+			return fmt.Errorf("@%s must start its own line", t.Value)
+		}
+	}
+	ast, err := rtmlast.Parse(s)
+	if err != nil {
+		return err
+	}
+	for _, n := range ast {
+		if err := b.node(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isBlockKeyword(cmd string) bool {
+	for _, k := range []string{"if:", "else", "endif", "for:", "endfor"} {
+		if strings.HasPrefix(cmd, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// expr emits an @expr directive. The interpreter ends the expression where
+// rtmlast.ExprEnd does, so the command may hold more than the expression.
+func (b *builder) expr(source string) error {
+	if b.swallow {
+		return fmt.Errorf("@expr runs into another @expr")
+	}
+	end := rtmlast.ExprEnd(source)
+	if end == len(source) {
+		b.swallow = true
+	}
+	expr := strings.TrimSpace(source[:end])
+	if expr == "" && !b.swallow {
+		// nothing for the pattern to match
+		b.text("@expr:")
+		return b.rest(source)
+	}
+	b.emit(&exprNode{source: expr})
+	return b.rest(source[end:])
+}
+
+func (b *builder) ifBlock(n rtmlast.IfNode) error {
+	if b.swallow {
+		return fmt.Errorf("@expr runs into @if")
+	}
+	conds := []string{n.Source}
+	bodies := [][]rtmlast.Node{n.Then}
+	for _, br := range n.ElseIf {
+		conds = append(conds, br.Source)
+		bodies = append(bodies, br.Body)
+	}
+	var blk ifNode
+	for i, cond := range conds {
+		// the interpreter reads the condition off the rendered line, after
+		// every other directive on it has been replaced
+		if strings.Contains(cond, "@") {
+			return fmt.Errorf("directive inside @if condition %q", cond)
+		}
+		body, err := b.block(bodies[i])
+		if err != nil {
+			return err
+		}
+		directive := "@if:"
+		if i > 0 {
+			directive = "@else-if:"
+		}
+		blk.branches = append(blk.branches, branch{condition: directive + cond, body: body})
+	}
+	if n.Else != nil {
+		body, err := b.block(n.Else)
+		if err != nil {
+			return err
+		}
+		blk.branches = append(blk.branches, branch{body: body})
+	}
+	b.emit(&blk)
+	return nil
+}
+
+func (b *builder) forBlock(n rtmlast.ForNode) error {
+	if b.swallow {
+		return fmt.Errorf("@expr runs into @for")
+	}
+	m := reForHeader.FindStringSubmatch(n.Source)
+	if m == nil {
+		return fmt.Errorf("@for:%s is not a loop header the interpreter reads", n.Source)
+	}
+	// the interpreter's header pattern runs on through the body unless
+	// whitespace ends the collection
+	if n.BodySource == "" || !unicode.IsSpace(rune(n.BodySource[0])) {
+		return fmt.Errorf("@for:%s must be followed by whitespace", n.Source)
+	}
+	if strings.Contains(n.BodySource, "@for:") || strings.Contains(n.BodySource, "@endfor") {
+		return fmt.Errorf("nested @for loops are not compiled")
+	}
+	row, err := b.block(n.Body)
+	if err != nil {
+		return err
+	}
+	// rows are stamped right after the first tag name, which has to be
+	// static for the stamp to land where the interpreter puts it
+	first, ok := firstText(row)
+	loc := []int(nil)
+	if ok {
+		loc = reTagName.FindStringIndex(first.text)
+	}
+	if loc == nil {
+		return fmt.Errorf("@for body must start with an element")
+	}
+	head := &textNode{text: first.text[:loc[1]]}
+	first.text = first.text[loc[1]:]
+	row = append([]node{head, &rowMarkersNode{}}, row...)
+	b.emit(&forNode{aliases: strings.Split(m[1], ","), collection: m[2], body: n.BodySource, row: row})
+	return nil
+}
+
+func firstText(nodes []node) (*textNode, bool) {
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	t, ok := nodes[0].(*textNode)
+	return t, ok
+}
+
+// block builds the body of a branch or loop. An @expr may not swallow text
+// across its end.
+func (b *builder) block(ast []rtmlast.Node) ([]node, error) {
+	nodes, err := b.build(ast)
+	if err != nil {
+		return nil, err
+	}
+	if b.swallow {
+		return nil, fmt.Errorf("@expr at the end of a block")
+	}
+	return nodes, nil
+}
+
+// placeRootAttrs marks where the interpreter adds the component id: after the
+// first "<root" of the template.
+func placeRootAttrs(nodes []node) ([]node, error) {
+	var found *textNode
+	nested := false
+	var walk func([]node, bool)
+	walk = func(ns []node, inner bool) {
+		for _, n := range ns {
+			if found != nil {
+				return
+			}
+			switch n := n.(type) {
+			case *textNode:
+				if strings.Contains(n.text, "<root") {
+					found, nested = n, inner
+				}
+			case *ifNode:
+				for _, br := range n.branches {
+					walk(br.body, true)
+				}
+			case *forNode:
+				walk(n.row, true)
+			}
+		}
+	}
+	walk(nodes, false)
+	if found == nil {
+		return nodes, nil
+	}
+	if nested {
+		return nil, fmt.Errorf("<root inside a block is not compiled")
+	}
+	for i, n := range nodes {
+		if n != node(found) {
+			continue
+		}
+		at := strings.Index(found.text, "<root") + len("<root")
+		out := append([]node(nil), nodes[:i]...)
+		out = append(out, &textNode{text: found.text[:at]}, &rootAttrsNode{}, &textNode{text: found.text[at:]})
+		return append(out, nodes[i+1:]...), nil
+	}
+	return nodes, nil
+}
+
+// A line item is what a line of the rendered template is made of: text, a
+// block directive or anything else that renders markup.
+type lineItem struct {
+	text      *textNode
+	directive bool
+}
+
+// lineItems flattens nodes in source order. Loop rows are checked on their
+// own, since the interpreter repeats them.
+func lineItems(nodes []node, loops *[]*forNode) []lineItem {
+	var items []lineItem
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *textNode:
+			items = append(items, lineItem{text: n})
+		case *ifNode:
+			for _, br := range n.branches {
+				items = append(items, lineItem{directive: true})
+				items = append(items, lineItems(br.body, loops)...)
+			}
+			items = append(items, lineItem{directive: true})
+		case *forNode:
+			*loops = append(*loops, n)
+			items = append(items, lineItem{})
+		default:
+			items = append(items, lineItem{})
+		}
+	}
+	return items
+}
+
+// stripDirectiveLines removes the lines holding block directives, as the
+// interpreter drops them. A directive has to be alone on its line; within a
+// loop row the line must also end inside the row. top tells whether nodes
+// start the template, which starts a line.
+func stripDirectiveLines(nodes []node, top bool) error {
+	var loops []*forNode
+	items := lineItems(nodes, &loops)
+	for i, it := range items {
+		if !it.directive {
+			continue
+		}
+		if err := stripBefore(items[:i], top); err != nil {
+			return err
+		}
+		if err := stripAfter(items[i+1:]); err != nil {
+			return err
+		}
+	}
+	for _, loop := range loops {
+		if err := stripDirectiveLines(loop.row, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripBefore removes the indentation in front of a directive.
+func stripBefore(items []lineItem, top bool) error {
+	for j := len(items) - 1; j >= 0; j-- {
+		it := items[j]
+		switch {
+		case it.directive:
+			// the previous directive took its newline with it
+			return nil
+		case it.text == nil:
+			return errNotOwnLine
+		}
+		t := it.text.text
+		start := strings.LastIndexByte(t, '\n') + 1
+		if strings.TrimSpace(t[start:]) != "" {
+			return errNotOwnLine
+		}
+		it.text.text = t[:start]
+		if start > 0 {
+			return nil
+		}
+	}
+	if !top {
+		return errNotOwnLine
+	}
+	return nil
+}
+
+// stripAfter removes the rest of a directive's line, newline included.
+func stripAfter(items []lineItem) error {
+	for _, it := range items {
+		if it.text == nil {
+			return errNotOwnLine
+		}
+		t := it.text.text
+		end := strings.IndexByte(t, '\n')
+		if end < 0 {
+			end = len(t)
+		}
+		if strings.TrimSpace(t[:end]) != "" {
+			return errNotOwnLine
+		}
+		if end < len(t) {
+			it.text.text = t[end+1:]
+			return nil
+		}
+		it.text.text = ""
+	}
+	return errNotOwnLine
+}
+
+var errNotOwnLine = errors.New("@if, @else-if, @else and @endif must stand alone on their lines")
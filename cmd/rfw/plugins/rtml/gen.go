@@ -0,0 +1,150 @@
+//go:build !js
+
+package rtml
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rfwlab/rfw/v2/rtmlast"
+)
+
+// template is one .rtml file of a package.
+type template struct {
+	name string // path relative to the package directory, slash separated
+	src  string
+}
+
+// generate returns the Go file registering the compiled renderers of the
+// templates of package pkg, or nil when none of them compiles. skipped lists
+// the templates left to the interpreter with the reason.
+func generate(pkg string, templates []template) (code []byte, skipped []string, err error) {
+	sort.Slice(templates, func(i, j int) bool { return templates[i].name < templates[j].name })
+	var funcs strings.Builder
+	var regs strings.Builder
+	used := map[string]bool{}
+	for _, tpl := range templates {
+		// components render the template their scoped style was moved out of
+		src, _, _ := rtmlast.ScopeStyle(tpl.src)
+		nodes, err := compile(src)
+		if err != nil {
+			skipped = append(skipped, tpl.name+": "+err.Error())
+			fmt.Fprintf(&regs, "// %s is interpreted: %v\n", tpl.name, err)
+			continue
+		}
+		fn := funcName(tpl.name, used)
+		fmt.Fprintf(&regs, "// %s\ncore.RegisterCompiledTemplate(core.CompiledTemplate{Hash: %q, File: %q, Render: %s})\n", tpl.name, rtmlast.Fingerprint(src), tpl.name, fn)
+		fmt.Fprintf(&funcs, "\nfunc %s(r *core.TemplateRenderer) {\n", fn)
+		writeNodes(&funcs, nodes)
+		funcs.WriteString("}\n")
+	}
+	if len(used) == 0 {
+		return nil, skipped, nil
+	}
+	var b strings.Builder
+	b.WriteString("//go:build js && wasm\n\n")
+	b.WriteString(generatedHeader + "\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import \"github.com/rfwlab/rfw/v2/core\"\n\n")
+	b.WriteString("func init() {\n")
+	b.WriteString(regs.String())
+	b.WriteString("}\n")
+	b.WriteString(funcs.String())
+	code, err = format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return code, skipped, nil
+}
+
+func writeNodes(b *strings.Builder, nodes []node) {
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			fmt.Fprintf(b, "r.Text(%q)\n", text.String())
+			text.Reset()
+		}
+	}
+	for _, n := range nodes {
+		if t, ok := n.(*textNode); ok {
+			text.WriteString(t.text)
+			continue
+		}
+		flush()
+		switch n := n.(type) {
+		case *rootAttrsNode:
+			b.WriteString("r.RootAttrs()\n")
+		case *rowMarkersNode:
+			b.WriteString("r.RowMarkers()\n")
+		case *storeNode:
+			method := "Store"
+			if n.raw {
+				method = "RawStore"
+			} else if n.input {
+				method = "StoreInput"
+			}
+			fmt.Fprintf(b, "r.%s(%q, %q, %q)\n", method, n.module, n.store, n.key)
+		case *signalNode:
+			method := "Signal"
+			if n.input {
+				method = "SignalInput"
+			}
+			fmt.Fprintf(b, "r.%s(%q)\n", method, n.name)
+		case *propNode:
+			method := "Prop"
+			if n.raw {
+				method = "RawProp"
+			}
+			fmt.Fprintf(b, "r.%s(%q)\n", method, n.path)
+		case *exprNode:
+			fmt.Fprintf(b, "r.Expr(%q)\n", n.source)
+		case *ifNode:
+			b.WriteString("r.If(\n")
+			for _, br := range n.branches {
+				if br.condition == "" {
+					b.WriteString("core.TemplateBranch{Render: func(r *core.TemplateRenderer) {\n")
+				} else {
+					fmt.Fprintf(b, "core.TemplateBranch{Condition: %q, Render: func(r *core.TemplateRenderer) {\n", br.condition)
+				}
+				writeNodes(b, br.body)
+				b.WriteString("}},\n")
+			}
+			b.WriteString(")\n")
+		case *forNode:
+			b.WriteString("r.For(core.TemplateLoop{\n")
+			fmt.Fprintf(b, "Aliases: %#v,\n", n.aliases)
+			fmt.Fprintf(b, "Collection: %q,\n", n.collection)
+			fmt.Fprintf(b, "Body: %q,\n", n.body)
+			b.WriteString("Row: func(r *core.TemplateRenderer) {\n")
+			writeNodes(b, n.row)
+			b.WriteString("},\n})\n")
+		}
+	}
+	flush()
+}
+
+// funcName derives the renderer name from the template path, e.g.
+// templates/todo_item.rtml becomes renderTemplatesTodoItem.
+func funcName(name string, used map[string]bool) string {
+	name = strings.TrimSuffix(name, ".rtml")
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	b.WriteString("render")
+	for _, p := range parts {
+		r, size := utf8.DecodeRuneInString(p)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(p[size:])
+	}
+	fn := b.String()
+	for i := 2; used[fn]; i++ {
+		fn = fmt.Sprintf("%s%d", b.String(), i)
+	}
+	used[fn] = true
+	return fn
+}
@@ -0,0 +1,169 @@
+//go:build !js
+
+// Package rtml registers ahead-of-time compilation of RTML templates.
+package rtml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/cmd/rfw/plugins"
+	"github.com/rfwlab/rfw/v2/cmd/rfw/project"
+)
+
+type plugin struct {
+	files []string
+}
+
+func init() { plugins.Register(&plugin{}) }
+
+func (p *plugin) Name() string { return "rtml" }
+
+func (p *plugin) Priority() int { return 0 }
+
+// PreBuild compiles every .rtml file under the configured directory and
+// writes one rtml_gen.go per Go package holding templates. A template lives in
+// the package of the nearest directory above it with Go files. The files are
+// removed once the build ends, and an rtml_gen.go the plugin did not generate
+// is never overwritten.
+func (p *plugin) PreBuild(raw json.RawMessage) (err error) {
+	defer func() {
+		if err != nil {
+			p.Cleanup()
+		}
+	}()
+	cfg := struct {
+		Dir    string `json:"dir"`
+		Strict bool   `json:"strict"`
+	}{Dir: "."}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &cfg)
+	}
+	packages := map[string][]template{}
+	err = filepath.WalkDir(cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != cfg.Dir && project.SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".rtml" {
+			return nil
+		}
+		dir, ok := project.PackageDir(filepath.Dir(path), cfg.Dir)
+		if !ok {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		packages[dir] = append(packages[dir], template{name: filepath.ToSlash(rel), src: string(src)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]string, 0, len(packages))
+	for dir := range packages {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	var skipped []string
+	for _, dir := range dirs {
+		pkg, err := packageName(dir)
+		if err != nil {
+			return err
+		}
+		code, skip, err := generate(pkg, packages[dir])
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		for _, s := range skip {
+			skipped = append(skipped, filepath.Join(dir, s))
+		}
+		if code == nil {
+			continue
+		}
+		file := filepath.Join(dir, "rtml_gen.go")
+		if err := checkGenerated(file); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, code, 0o600); err != nil {
+			return err
+		}
+		p.files = append(p.files, file)
+	}
+	if cfg.Strict && len(skipped) > 0 {
+		return fmt.Errorf("templates left to the interpreter:\n  %s", strings.Join(skipped, "\n  "))
+	}
+	return nil
+}
+
+func (p *plugin) Build(json.RawMessage) error { return nil }
+
+func (p *plugin) PostBuild(json.RawMessage) error {
+	p.Cleanup()
+	return nil
+}
+
+// Cleanup removes the rtml_gen.go files PreBuild wrote.
+func (p *plugin) Cleanup() {
+	for _, file := range p.files {
+		_ = os.Remove(file)
+	}
+	p.files = nil
+}
+
+// generatedHeader marks every file the plugin writes.
+const generatedHeader = "// Code generated by rtml plugin. DO NOT EDIT."
+
+// checkGenerated reports an error when path holds a file the plugin did not
+// generate. A leftover from an interrupted build may be replaced; a file of
+// the project's own may not.
+func checkGenerated(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(data, []byte(generatedHeader)) {
+		return fmt.Errorf("%s exists and was not generated by the rtml plugin; rename it", path)
+	}
+	return nil
+}
+
+func (p *plugin) ShouldRebuild(path string) bool {
+	return filepath.Ext(path) == ".rtml"
+}
+
+func packageName(dir string) (string, error) {
+	files := project.GoFiles(dir)
+	if len(files) == 0 {
+		return "", fmt.Errorf("%s: no Go files", dir)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), files[0], nil, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	return f.Name.Name, nil
+}
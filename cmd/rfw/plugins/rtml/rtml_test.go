@@ -0,0 +1,190 @@
+//go:build !js
+
+package rtml
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The core package checks every generated renderer against the interpreter.
+// Its fixture is the output of generate for core/testdata/rtml; set
+// RTML_UPDATE=1 to rewrite it after changing the generator.
+func TestCoreFixtureUpToDate(t *testing.T) {
+	coreDir := filepath.Join("..", "..", "..", "..", "core")
+	paths, err := filepath.Glob(filepath.Join(coreDir, "testdata", "rtml", "*.rtml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	var templates []template
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(coreDir, path)
+		templates = append(templates, template{name: filepath.ToSlash(rel), src: string(src)})
+	}
+	code, skipped, err := generate("core_test", templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0], "testdata/rtml/unsupported.rtml:") {
+		t.Fatalf("skipped = %v", skipped)
+	}
+	fixture := filepath.Join(coreDir, "rtml_compiled_gen_test.go")
+	if os.Getenv("RTML_UPDATE") != "" {
+		if err := os.WriteFile(fixture, code, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, want) {
+		t.Fatalf("%s is stale; rerun with RTML_UPDATE=1", fixture)
+	}
+}
+
+func TestCompileLeavesTemplatesToInterpreter(t *testing.T) {
+	tests := map[string]string{
+		"interpolation":       "<root>{{title}}</root>",
+		"include":             "<root>@include:child</root>",
+		"slot":                "<root>@slot:body\nx\n@endslot</root>",
+		"switch":              "<root>\n@switch:x\n@case:1\n<p>1</p>\n@endswitch\n</root>",
+		"await":               "<root>\n@await:r\n@then:v\n<p>@prop:v</p>\n@endawait\n</root>",
+		"class expr":          `<root><p class="@expr:on ? 'a' : 'b'">x</p></root>`,
+		"inline if":           "<root><p>@if:x</p>\n@endif\n</root>",
+		"unclosed if":         "<root>\n@if:x\n<p>x</p>\n</root>",
+		"stray endif":         "<root>\n@endif\n</root>",
+		"else after else":     "<root>\n@if:x\n@else\n@else\n@endif\n</root>",
+		"ambiguous keyword":   "<root>\n@if:x\n@elsewhere\n@endif\n</root>",
+		"nested for":          "<root>\n@for:a in as\n<p>@for:b in bs<i>@prop:b</i>@endfor</p>\n@endfor\n</root>",
+		"for header":          "<root>@for:a in as extra<p>x</p>@endfor</root>",
+		"row without tag":     "<root>@for:a in as@prop:a @endfor</root>",
+		"directive in cond":   "<root>\n@if:@prop:x\n<p>x</p>\n@endif\n</root>",
+		"root in block":       "<p>\n@if:x\n<root>x</root>\n@endif\n</p>",
+		"block after store":   "<root>@store:a.b.c @if:x\n@endif\n</root>",
+		"if in row same line": "<root>@for:a in as <li>\n@if:x\n</li>@endif\n@endfor</root>",
+	}
+	for name, src := range tests {
+		if _, err := compile(src); err == nil {
+			t.Errorf("%s: compiled %q", name, src)
+		}
+	}
+}
+
+func TestCompileStripsDirectiveLines(t *testing.T) {
+	nodes, err := compile("<root>\n  @if:x\n  <p>a</p>\n  @else\n  <p>b</p>\n  @endif\n</root>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	writeNodes(&b, nodes)
+	want := `r.Text("<root")
+r.RootAttrs()
+r.Text(">\n")
+r.If(
+core.TemplateBranch{Condition: "@if:x", Render: func(r *core.TemplateRenderer) {
+r.Text("  <p>a</p>\n")
+}},
+core.TemplateBranch{Render: func(r *core.TemplateRenderer) {
+r.Text("  <p>b</p>\n")
+}},
+)
+r.Text("</root>\n")
+`
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestCompileExprSwallowsTrailingSpace(t *testing.T) {
+	nodes, err := compile("<p>@expr:a + b  </p><i>@expr:c|d</i>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	writeNodes(&b, nodes)
+	want := `r.Text("<p>")
+r.Expr("a + b")
+r.Text("</p><i>")
+r.Expr("c")
+r.Text("|d</i>\n")
+`
+	if b.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestFuncName(t *testing.T) {
+	used := map[string]bool{}
+	if got := funcName("templates/todo_item.rtml", used); got != "renderTemplatesTodoItem" {
+		t.Fatalf("got %s", got)
+	}
+	if got := funcName("templates/todo-item.rtml", used); got != "renderTemplatesTodoItem2" {
+		t.Fatalf("got %s", got)
+	}
+	if got := funcName("templates/über_ansicht.rtml", used); got != "renderTemplatesÜberAnsicht" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPreBuildKeepsProjectFiles(t *testing.T) {
+	dir := t.TempDir()
+	own := "package app\n\nvar x = 1\n"
+	writeFiles(t, dir, map[string]string{
+		"app.go":      "package app\n",
+		"rtml_gen.go": own,
+		"a.rtml":      "<root><p>a</p></root>",
+	})
+	p := &plugin{}
+	if err := p.PreBuild([]byte(`{"dir":"` + filepath.ToSlash(dir) + `"}`)); err == nil {
+		t.Fatal("expected an error for a hand-written rtml_gen.go")
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "rtml_gen.go"))
+	if string(got) != own {
+		t.Fatalf("rtml_gen.go overwritten:\n%s", got)
+	}
+}
+
+func TestPreBuildRemovesFilesOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.go": "package app\n",
+		"a.rtml": "<root><p>a</p></root>",
+		"b.rtml": "<root>{{title}}</root>",
+	})
+	p := &plugin{}
+	if err := p.PreBuild([]byte(`{"dir":"` + filepath.ToSlash(dir) + `","strict":true}`)); err == nil {
+		t.Fatal("expected strict mode to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rtml_gen.go")); !os.IsNotExist(err) {
+		t.Fatalf("rtml_gen.go left behind: %v", err)
+	}
+
+	// a leftover from an interrupted build is replaced and then cleaned up
+	if err := p.PreBuild([]byte(`{"dir":"` + filepath.ToSlash(dir) + `"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PreBuild([]byte(`{"dir":"` + filepath.ToSlash(dir) + `"}`)); err != nil {
+		t.Fatal(err)
+	}
+	p.Cleanup()
+	if _, err := os.Stat(filepath.Join(dir, "rtml_gen.go")); !os.IsNotExist(err) {
+		t.Fatalf("rtml_gen.go left behind after cleanup: %v", err)
+	}
+}
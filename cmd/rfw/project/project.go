@@ -0,0 +1,61 @@
+//go:build !js
+
+// Package project walks the source tree of an RFW project the way the build
+// plugins and rfw check both see it.
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SkipDir reports whether a walk of the project skips the directory name:
+// build output, dependencies, test data and hidden directories.
+func SkipDir(name string) bool {
+	switch name {
+	case "build", "node_modules", "vendor", "testdata":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// IsGoSource reports whether the file name is Go source of its package. Tests
+// are not, and neither are the *_gen.go files plugins write for a build and
+// remove after it.
+func IsGoSource(name string) bool {
+	return filepath.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(name, "_gen.go")
+}
+
+// GoFiles returns the paths of the Go source files in dir.
+func GoFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && IsGoSource(e.Name()) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return files
+}
+
+// PackageDir walks up from dir, not above root, to the first directory
+// holding Go source.
+func PackageDir(dir, root string) (string, bool) {
+	for {
+		if len(GoFiles(dir)) > 0 {
+			return dir, true
+		}
+		if filepath.Clean(dir) == filepath.Clean(root) {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
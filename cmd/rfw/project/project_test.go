@@ -0,0 +1,45 @@
+//go:build !js
+
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageDirSkipsTestsAndGeneratedFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"app.go",
+		"components/rtml_gen.go",
+		"components/view_test.go",
+		"components/templates/view.rtml",
+	} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, ok := PackageDir(filepath.Join(root, "components", "templates"), root)
+	if !ok || dir != root {
+		t.Fatalf("PackageDir = %q, %v, want %q", dir, ok, root)
+	}
+	if _, ok := PackageDir(filepath.Join(root, "components"), filepath.Join(root, "components")); ok {
+		t.Fatal("PackageDir walked above root")
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	for name, want := range map[string]bool{
+		"build": true, "node_modules": true, ".git": true, "testdata": true,
+		"components": false, "pages": false,
+	} {
+		if got := SkipDir(name); got != want {
+			t.Errorf("SkipDir(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/rfwlab/rfw/v2/cmd/rfw/utils"
+	"github.com/rfwlab/rfw/v2/rtmlast"
 )
 
 type devMessage struct {
@@ -17,6 +18,10 @@ type devMessage struct {
 	Path      string `json:"path,omitempty"`
 	Component string `json:"component,omitempty"`
 	Markup    string `json:"markup,omitempty"`
+	// Scope and Style are the attribute and rules of the template's
+	// <style scoped> blocks, for core.UseScopedStyle.
+	Scope string `json:"scope,omitempty"`
+	Style string `json:"style,omitempty"`
 }
 
 func (s *Server) handleHMR(w http.ResponseWriter, r *http.Request) {
@@ -120,5 +125,6 @@ func (s *Server) broadcastTemplateUpdate(path, component, markup string) error {
 		}
 	}
 	utils.Debug(fmt.Sprintf("streaming template update for %s (%s)", rel, component))
-	return s.broadcast(devMessage{Type: "rtml", Path: rel, Component: component, Markup: markup})
+	_, style, scope := rtmlast.ScopeStyle(markup)
+	return s.broadcast(devMessage{Type: "rtml", Path: rel, Component: component, Markup: markup, Scope: scope, Style: style})
 }
@@ -3,7 +3,9 @@
 package server
 
 import (
+	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -48,3 +50,23 @@ func TestComponentNamesForTemplateNoMatch(t *testing.T) {
 		t.Fatalf("expected nil, got %v", names)
 	}
 }
+
+func TestBroadcastTemplateUpdateCarriesScopedStyle(t *testing.T) {
+	s := &Server{hmrClients: make(map[chan []byte]struct{})}
+	client := make(chan []byte, 1)
+	s.hmrClients[client] = struct{}{}
+	markup := "<root><p class=\"note\">Hi</p></root>\n<style scoped>.note { color: red }</style>\n"
+	if err := s.broadcastTemplateUpdate("note.rtml", "Note", markup); err != nil {
+		t.Fatal(err)
+	}
+	var msg devMessage
+	if err := json.Unmarshal(<-client, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Markup != markup || !strings.HasPrefix(msg.Scope, "data-s-") {
+		t.Fatalf("message = %+v", msg)
+	}
+	if want := ".note[" + msg.Scope + "] {color: red}"; !strings.Contains(msg.Style, want) {
+		t.Fatalf("style = %q, want %q", msg.Style, want)
+	}
+}
@@ -30,7 +30,9 @@ func RegisterFS(fsInstance *embed.FS) {
 	templateFS = append(templateFS, fsInstance)
 }
 
-func resolveTemplateByConvention(name string) string {
+// resolveTemplateByConvention returns the template registered for name and
+// the path it was read from.
+func resolveTemplateByConvention(name string) (string, string) {
 	candidates := []string{
 		name + ".rtml",
 		name + ".html",
@@ -38,12 +40,12 @@ func resolveTemplateByConvention(name string) string {
 	for _, fsInstance := range templateFS {
 		for _, c := range candidates {
 			if data, err := fsInstance.ReadFile(c); err == nil {
-				return string(data)
+				return string(data), c
 			}
 		}
 	}
 	for _, fsInstance := range templateFS {
-		var found string
+		var found, foundPath string
 		if err := fs.WalkDir(fsInstance, ".", func(path string, d fs.DirEntry, walkErr error) error {
 			if walkErr != nil || d.IsDir() {
 				return nil
@@ -51,7 +53,7 @@ func resolveTemplateByConvention(name string) string {
 			for _, c := range candidates {
 				if d.Name() == c {
 					if data, err := fsInstance.ReadFile(path); err == nil {
-						found = string(data)
+						found, foundPath = string(data), path
 						return fs.SkipAll
 					}
 				}
@@ -61,10 +63,10 @@ func resolveTemplateByConvention(name string) string {
 			continue
 		}
 		if found != "" {
-			return found
+			return found, foundPath
 		}
 	}
-	return ""
+	return "", ""
 }
 
 type signalAny interface{ Read() any }
@@ -98,8 +100,8 @@ func (c *Component) Prop(key string, sig signalAny) {
 }
 
 // NewRaw creates a view from an explicit template and props.
-func NewRaw(name string, tpl []byte, props map[string]any) *View {
-	hc := core.NewHTMLComponent(name, tpl, props)
+func NewRaw(name string, tpl []byte, props map[string]any, opts ...core.ComponentOption) *View {
+	hc := core.NewHTMLComponent(name, tpl, props, opts...)
 	defaultStore := state.GlobalStoreManager.GetStore("app", "default")
 	if defaultStore == nil {
 		defaultStore = state.NewStore("default", state.WithModule("app"))
@@ -126,7 +128,7 @@ func New(v any) (*View, error) {
 		return nil, fmt.Errorf("composition.New: scan failed: %w", err)
 	}
 
-	tpl := ""
+	tpl, tplFile := "", ""
 
 	// Check for optional Template() string method (type-based convention)
 	templateMethod := val.Addr().MethodByName("Template")
@@ -138,13 +140,13 @@ func New(v any) (*View, error) {
 
 	// Fallback: convention-based template lookup (StructName.rtml)
 	if tpl == "" && meta.TemplateName != "" {
-		tpl = resolveTemplateByConvention(meta.TemplateName)
+		tpl, tplFile = resolveTemplateByConvention(meta.TemplateName)
 	}
 	if tpl == "" {
 		return nil, fmt.Errorf("composition.New: no template found for %s; add a Template() string method or register a convention template", name)
 	}
 
-	hc := core.NewHTMLComponent(name, []byte(tpl), nil)
+	hc := core.NewHTMLComponent(name, []byte(tpl), nil, core.WithTemplateFile(tplFile))
 	defaultStore := state.GlobalStoreManager.GetStore("app", "default")
 	if defaultStore == nil {
 		defaultStore = state.NewStore("default", state.WithModule("app"))
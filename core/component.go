@@ -72,8 +72,8 @@ func LoadComponent(name string) Component {
 // NewComponent creates an HTMLComponent initialized with the provided
 // template and props. It sets itself as the underlying component and
 // performs initialization with the default store.
-func NewComponent(name string, templateFS []byte, props map[string]any) *HTMLComponent {
-	c := NewHTMLComponent(name, templateFS, props)
+func NewComponent(name string, templateFS []byte, props map[string]any, opts ...ComponentOption) *HTMLComponent {
+	c := NewHTMLComponent(name, templateFS, props, opts...)
 	c.SetComponent(c)
 	c.Init(nil)
 	return c
@@ -82,8 +82,8 @@ func NewComponent(name string, templateFS []byte, props map[string]any) *HTMLCom
 // NewComponentWith creates an HTMLComponent and binds it to the given
 // component implementation. This is useful when embedding HTMLComponent
 // inside another struct to override lifecycle hooks.
-func NewComponentWith[T Component](name string, templateFS []byte, props map[string]any, self T) *HTMLComponent {
-	c := NewHTMLComponent(name, templateFS, props)
+func NewComponentWith[T Component](name string, templateFS []byte, props map[string]any, self T, opts ...ComponentOption) *HTMLComponent {
+	c := NewHTMLComponent(name, templateFS, props, opts...)
 	if any(self) != nil {
 		c.SetComponent(self)
 	} else {
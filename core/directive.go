@@ -0,0 +1,332 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// Directive is the behaviour of a custom RTML attribute registered with
+// RegisterDirective. Every callback may be nil.
+type Directive struct {
+	// Mounted runs once the element is in the DOM, with the value of the
+	// attribute's expression.
+	Mounted func(el dom.Element, value any)
+	// Updated runs after something the expression reads changed.
+	Updated func(el dom.Element, value, old any)
+	// Unmounted runs when the element leaves the DOM or its component
+	// unmounts.
+	Unmounted func(el dom.Element)
+}
+
+var directiveRegistry = struct {
+	sync.RWMutex
+	byName map[string]Directive
+}{byName: make(map[string]Directive)}
+
+var reDirectiveName = regexp.MustCompile(`^\w+$`)
+
+// reservedDirectives are the @name: prefixes RTML reads itself.
+var reservedDirectives = map[string]bool{
+	"on": true, "expr": true, "store": true, "rawstore": true, "signal": true,
+	"prop": true, "rawprop": true, "include": true, "slot": true, "if": true,
+	"for": true, "switch": true, "case": true, "await": true, "then": true,
+	"catch": true, "h": true, "plugin": true,
+}
+
+// RegisterDirective gives every element carrying the attribute @name:expr
+// the behaviour of d:
+//
+//	core.RegisterDirective("tooltip", core.Directive{
+//		Mounted: func(el dom.Element, v any) { el.SetAttr("title", fmt.Sprint(v)) },
+//		Updated: func(el dom.Element, v, _ any) { el.SetAttr("title", fmt.Sprint(v)) },
+//	})
+//
+//	<button @tooltip:(saved then 'Saved' else 'Save')>Save</button>
+//
+// expr is an RTML expression. It ends at a space or the end of the tag, so
+// one with spaces goes in parentheses. Signals and store keys it reads are
+// tracked, and Updated runs each time they change. A directive stops with
+// the element or with the component's Scope.
+//
+// A registered name takes precedence over the @event:handler shorthand.
+// Registering a name again replaces the directive. It panics when name is
+// not a word or is one RTML reads itself, such as on or expr.
+func RegisterDirective(name string, d Directive) {
+	if !reDirectiveName.MatchString(name) || reservedDirectives[name] {
+		panic(fmt.Sprintf("core: %q cannot name a directive", name))
+	}
+	directiveRegistry.Lock()
+	directiveRegistry.byName[name] = d
+	directiveRegistry.Unlock()
+}
+
+// lookupDirective returns the directive registered under name.
+func lookupDirective(name string) (Directive, bool) {
+	directiveRegistry.RLock()
+	defer directiveRegistry.RUnlock()
+	d, ok := directiveRegistry.byName[name]
+	return d, ok
+}
+
+func hasDirectives() bool {
+	directiveRegistry.RLock()
+	defer directiveRegistry.RUnlock()
+	return len(directiveRegistry.byName) > 0
+}
+
+// directiveBinding is a directive attribute found by the last render.
+type directiveBinding struct {
+	name   string
+	source string
+}
+
+// mountedDirective is a directive bound to an element in the DOM.
+type mountedDirective struct {
+	directiveBinding
+	el    dom.Element
+	value any
+	stop  func()
+}
+
+// replaceDirectives rewrites the registered @name:expr attributes of every
+// tag into one data-directive attribute listing their binding ids, and
+// records the bindings on c for syncDirectives.
+func replaceDirectives(template string, c *HTMLComponent) string {
+	c.directiveBindings = nil
+	if !hasDirectives() || !strings.Contains(template, "@") {
+		return template
+	}
+	var b strings.Builder
+	idx := 0
+	i := 0
+	for {
+		loc := reTagName.FindStringIndex(template[i:])
+		if loc == nil {
+			break
+		}
+		nameEnd := i + loc[1]
+		end := nameEnd + scanAttr(template[nameEnd:], false)
+		attrs, ids := takeDirectives(template[nameEnd:end], c, &idx)
+		b.WriteString(template[i:nameEnd])
+		if len(ids) > 0 {
+			fmt.Fprintf(&b, ` data-directive="%s"`, strings.Join(ids, " "))
+		}
+		b.WriteString(attrs)
+		i = end
+	}
+	b.WriteString(template[i:])
+	return b.String()
+}
+
+// takeDirectives removes the registered directive attributes from the
+// attributes of one tag, binding each on c.
+func takeDirectives(attrs string, c *HTMLComponent, idx *int) (string, []string) {
+	var ids []string
+	for i := 1; i < len(attrs); i++ {
+		if attrs[i] != '@' || !isSpace(attrs[i-1]) {
+			continue
+		}
+		name, rest, ok := strings.Cut(attrs[i+1:], ":")
+		if !ok || !reDirectiveName.MatchString(name) {
+			continue
+		}
+		if _, ok := lookupDirective(name); !ok {
+			continue
+		}
+		end := scanAttr(rest, true)
+		source := rest[:end]
+		if source == "" {
+			continue
+		}
+		id := fmt.Sprintf("dir-%d", *idx)
+		*idx++
+		if c.directiveBindings == nil {
+			c.directiveBindings = make(map[string]directiveBinding)
+		}
+		c.directiveBindings[id] = directiveBinding{name: name, source: source}
+		ids = append(ids, id)
+		attrs = attrs[:i-1] + rest[end:]
+		i--
+	}
+	return attrs, ids
+}
+
+// scanAttr returns where the end of a tag is in s, just past its '>', or
+// with value set, where the attribute value s starts with ends: at a space,
+// '>' or "/>". Quotes and brackets are skipped either way.
+func scanAttr(s string, value bool) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case depth > 0:
+		case ch == '>':
+			if value {
+				return i
+			}
+			return i + 1
+		case value && (isSpace(ch) || ch == '/' && i+1 < len(s) && s[i+1] == '>'):
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+// registerDirectives makes the DOM updates of c mount and unmount the
+// directives of its elements, and its Scope unmount the rest.
+func (c *HTMLComponent) registerDirectives() {
+	if !hasDirectives() {
+		return
+	}
+	c.domHookStops = append(c.domHookStops, dom.RegisterLifecycleHook(c.ID, dom.LifecycleHook{
+		Mounted: func(dom.Element) func() {
+			syncDirectives(c)
+			return nil
+		},
+		Updated: func(dom.Element) { syncDirectives(c) },
+	}))
+	c.scope.Defer(func() { unmountDirectives(c) })
+}
+
+// syncDirectives mounts the directives of the elements of c in the DOM, and
+// unmounts those whose element is gone or was replaced by a patch.
+func syncDirectives(c *HTMLComponent) {
+	if len(c.directiveBindings) == 0 && len(c.mountedDirectives) == 0 {
+		return
+	}
+	root := dom.ComponentRoot(c.ID)
+	if root.IsNull() || root.IsUndefined() || root.Attr("data-component-id") != c.ID {
+		return
+	}
+	elements := []dom.Element{root}
+	nodes := root.QueryAll("[data-directive]")
+	for i := 0; i < nodes.Length(); i++ {
+		elements = append(elements, nodes.Index(i))
+	}
+	seen := make(map[string]bool)
+	for _, el := range elements {
+		attr := el.Attr("data-directive")
+		// a child component's elements carry its own ids
+		if attr == "" || el.Closest("[data-component-id]").Attr("data-component-id") != c.ID {
+			continue
+		}
+		for _, id := range strings.Fields(attr) {
+			binding, ok := c.directiveBindings[id]
+			if !ok {
+				continue
+			}
+			seen[id] = true
+			if m := c.mountedDirectives[id]; m != nil {
+				if m.el.Equal(el.Value) && m.directiveBinding == binding {
+					continue
+				}
+				unmountDirective(c, m)
+			}
+			mountDirective(c, id, binding, el)
+		}
+	}
+	for id, m := range c.mountedDirectives {
+		if !seen[id] {
+			unmountDirective(c, m)
+			delete(c.mountedDirectives, id)
+		}
+	}
+}
+
+// mountDirective calls Mounted for the binding on el and keeps calling
+// Updated while what its expression reads changes.
+func mountDirective(c *HTMLComponent, id string, binding directiveBinding, el dom.Element) {
+	d, ok := lookupDirective(binding.name)
+	if !ok {
+		return
+	}
+	m := &mountedDirective{directiveBinding: binding, el: el}
+	expr := parseTemplateExpr(c, binding.source)
+	update := func(v any) {
+		old := m.value
+		m.value = v
+		if d.Updated != nil {
+			c.runLifecycle("directive "+binding.name+" updated", func() { d.Updated(el, v, old) })
+		}
+	}
+
+	sigRefs := collectExprSignals(expr, c)
+	first := true
+	stops := []func(){state.Effect(func() func() {
+		v := evalTemplateExpr(c, binding.source, expr, sigRefs)
+		if first {
+			first = false
+			m.value = v
+			return nil
+		}
+		state.Untracked(func() bool {
+			update(v)
+			return true
+		})
+		return nil
+	})}
+	// store keys are not signals; subscribe to them as conditions do
+	for _, dep := range extractDependencies(binding.source) {
+		if dep.module == "" {
+			continue
+		}
+		store := state.GlobalStoreManager.GetStore(dep.module, dep.storeName)
+		if store == nil {
+			continue
+		}
+		stops = append(stops, store.OnChange(dep.key, func(any) {
+			update(evalTemplateExpr(c, binding.source, expr, sigRefs))
+		}))
+	}
+	m.stop = func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+
+	if c.mountedDirectives == nil {
+		c.mountedDirectives = make(map[string]*mountedDirective)
+	}
+	c.mountedDirectives[id] = m
+	if d.Mounted != nil {
+		c.runLifecycle("directive "+binding.name+" mounted", func() { d.Mounted(el, m.value) })
+	}
+}
+
+// unmountDirective stops m and calls Unmounted.
+func unmountDirective(c *HTMLComponent, m *mountedDirective) {
+	m.stop()
+	if d, ok := lookupDirective(m.name); ok && d.Unmounted != nil {
+		c.runLifecycle("directive "+m.name+" unmounted", func() { d.Unmounted(m.el) })
+	}
+}
+
+// unmountDirectives unmounts every directive of c.
+func unmountDirectives(c *HTMLComponent) {
+	for _, m := range c.mountedDirectives {
+		unmountDirective(c, m)
+	}
+	c.mountedDirectives = nil
+}
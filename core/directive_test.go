@@ -0,0 +1,76 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+func TestReplaceDirectivesRewritesRegisteredAttributes(t *testing.T) {
+	RegisterDirective("testTip", Directive{})
+	RegisterDirective("testFocus", Directive{})
+	tpl := `<root><button @testTip:(title + '>') @click:save>x</button><input @testFocus:true @testTip:title/></root>`
+	got, c := renderGolden(t, "Directives", tpl, map[string]any{"title": "t"})
+	want := fmt.Sprintf(`<root data-component-id="%s"><button data-directive="dir-0" data-on-click="save">x</button><input data-directive="dir-1 dir-2"/></root>
+`, c.ID)
+	expectGolden(t, got, want)
+	for id, source := range map[string]string{"dir-0": "(title + '>')", "dir-1": "true", "dir-2": "title"} {
+		if b := c.directiveBindings[id]; b.source != source {
+			t.Errorf("%s source = %q, want %q", id, b.source, source)
+		}
+	}
+}
+
+func TestRegisterDirectiveRejectsReservedNames(t *testing.T) {
+	for _, name := range []string{"on", "expr", "a-b", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterDirective(%q) did not panic", name)
+				}
+			}()
+			RegisterDirective(name, Directive{})
+		}()
+	}
+}
+
+func TestDirectiveLifecycle(t *testing.T) {
+	if dom.ByID("app").IsNull() {
+		host := dom.CreateElement("div")
+		host.SetAttr("id", "app")
+		dom.Doc().Body().AppendChild(host)
+	}
+	var calls []string
+	RegisterDirective("testTitle", Directive{
+		Mounted: func(el dom.Element, v any) {
+			el.SetAttr("title", fmt.Sprint(v))
+			calls = append(calls, fmt.Sprintf("mounted %v", v))
+		},
+		Updated: func(el dom.Element, v, old any) {
+			el.SetAttr("title", fmt.Sprint(v))
+			calls = append(calls, fmt.Sprintf("updated %v %v", v, old))
+		},
+		Unmounted: func(dom.Element) { calls = append(calls, "unmounted") },
+	})
+	label := state.NewSignal("a")
+	component := NewHTMLComponent("Directed", []byte(`<root><p @testTitle:label>x</p></root>`), map[string]any{"label": label})
+	component.SetComponent(component)
+	component.Init(nil)
+
+	dom.UpdateDOM(component.ID, component.Render())
+	component.Mount()
+	label.Set("b")
+	if title := dom.ComponentRoot(component.ID).Query("p").Attr("title"); title != "b" {
+		t.Fatalf("title = %q", title)
+	}
+	component.Unmount()
+	label.Set("c")
+
+	if got := fmt.Sprint(calls); got != "[mounted a updated b a unmounted]" {
+		t.Fatalf("calls = %s", got)
+	}
+}
@@ -3,6 +3,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"runtime/debug"
 	"strings"
@@ -14,7 +15,8 @@ var globalOverlay = &errorOverlay{}
 
 // ShowErrorOverlay displays a styled error recovery UI in the browser when a
 // panic occurs. It categorizes the error, shows the Go stack trace, and
-// provides actionable hints based on the panic message.
+// provides actionable hints based on the panic message. A *TemplateError also
+// shows the template lines it points at.
 func ShowErrorOverlay(err any, context string) {
 	globalOverlay.show(err, context)
 }
@@ -27,11 +29,16 @@ type errorOverlay struct {
 
 func (eo *errorOverlay) show(err any, context string) {
 	errStr := fmt.Sprintf("%v", err)
+	frame := ""
+	var te *TemplateError
+	if e, ok := err.(error); ok && errors.As(e, &te) {
+		errStr, frame = te.header(), te.Frame
+	}
 	goStack := string(debug.Stack())
 
 	if !eo.shown {
 		eo.shown = true
-		eo.createContainer(errStr, goStack, context)
+		eo.createContainer(errStr, frame, goStack, context)
 		return
 	}
 
@@ -43,11 +50,11 @@ func (eo *errorOverlay) show(err any, context string) {
 	}
 	item := doc.Call("createElement", "div")
 	item.Get("style").Set("borderTop", "1px solid #e5e7eb")
-	item.Set("innerHTML", eo.buildErrorItem(eo.errCount, errStr, goStack, context))
+	item.Set("innerHTML", eo.buildErrorItem(eo.errCount, errStr, frame, goStack, context))
 	list.Call("appendChild", item)
 }
 
-func (eo *errorOverlay) createContainer(errStr, goStack, context string) {
+func (eo *errorOverlay) createContainer(errStr, frame, goStack, context string) {
 	doc := js.Document()
 	body := doc.Get("body")
 
@@ -80,7 +87,7 @@ func (eo *errorOverlay) createContainer(errStr, goStack, context string) {
 	cs.Set("display", "flex")
 	cs.Set("flexDirection", "column")
 
-	card.Set("innerHTML", eo.buildMainHTML(errStr, goStack, context))
+	card.Set("innerHTML", eo.buildMainHTML(errStr, frame, goStack, context))
 	overlay.Call("appendChild", card)
 	body.Call("appendChild", overlay)
 
@@ -115,7 +122,7 @@ func (eo *errorOverlay) bindActions(js.Value) {
 	}
 }
 
-func (eo *errorOverlay) buildMainHTML(errStr, goStack, context string) string {
+func (eo *errorOverlay) buildMainHTML(errStr, frame, goStack, context string) string {
 	cat := eo.categorize(errStr)
 	hint := eo.hintHTML(errStr, context)
 
@@ -135,6 +142,7 @@ func (eo *errorOverlay) buildMainHTML(errStr, goStack, context string) string {
     <div style="background:#fef2f2;border-left:4px solid #ef4444;border-radius:6px;padding:16px;margin-bottom:16px;">
         <div style="font-family:ui-monospace,SFMono-Regular,Menlo,monospace;font-size:13px;color:#991b1b;word-break:break-word;line-height:1.5;">%s</div>
     </div>
+    %s
 </div>
 %s
 <div style="padding:0 24px;">
@@ -154,25 +162,26 @@ func (eo *errorOverlay) buildMainHTML(errStr, goStack, context string) string {
         rfw recovery mode &middot; %s
     </div>
 </div>
-    `, cat, htmlEscape(errStr), hint,
+    `, cat, htmlEscape(errStr), frameHTML(frame), hint,
 		htmlEscape(goStack),
-		htmlEscape(fmt.Sprintf("Error: %s\nContext: %s\n\n%s", errStr, context, goStack)),
+		htmlEscape(fmt.Sprintf("Error: %s\nContext: %s\n\n%s", errStr, context, fullFrame(frame)+goStack)),
 		versionStr)
 }
 
-func (eo *errorOverlay) buildErrorItem(n int, errStr, goStack, _ string) string {
+func (eo *errorOverlay) buildErrorItem(n int, errStr, frame, goStack, _ string) string {
 	return fmt.Sprintf(`
 <div style="padding:16px;">
     <div style="font-size:12px;font-weight:700;color:#6b7280;margin-bottom:8px;">Error #%d</div>
     <div style="background:#fef2f2;border-radius:6px;padding:12px;margin-bottom:8px;">
         <div style="font-family:monospace;font-size:12px;color:#991b1b;word-break:break-word;">%s</div>
     </div>
+    %s
     <details open>
         <summary style="cursor:pointer;font-size:12px;color:#6b7280;">Stack trace</summary>
         <pre style="background:#f9fafb;border-radius:6px;padding:8px;font-size:11px;color:#4b5563;margin-top:8px;">%s</pre>
     </details>
 </div>
-    `, n, htmlEscape(errStr), htmlEscape(goStack))
+    `, n, htmlEscape(errStr), frameHTML(frame), htmlEscape(goStack))
 }
 
 func (eo *errorOverlay) categorize(err string) string {
@@ -235,6 +244,36 @@ func (eo *errorOverlay) hintHTML(errStr, _ string) string {
 	return sb.String()
 }
 
+// frameHTML renders the code frame of a template error, with the offending
+// line and its caret highlighted. It returns "" for other errors.
+func frameHTML(frame string) string {
+	if frame == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<pre style="background:#111827;color:#d1d5db;border-radius:6px;padding:12px;overflow-x:auto;font-size:12px;line-height:1.5;margin:0 0 16px;tab-size:4;">`)
+	for i, line := range strings.Split(frame, "\n") {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		if strings.HasPrefix(line, ">") || strings.HasSuffix(line, "^") {
+			fmt.Fprintf(&sb, `<span style="color:#fca5a5;font-weight:700;">%s</span>`, htmlEscape(line))
+			continue
+		}
+		sb.WriteString(htmlEscape(line))
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// fullFrame is the code frame as it goes into the copied error text.
+func fullFrame(frame string) string {
+	if frame == "" {
+		return ""
+	}
+	return frame + "\n\n"
+}
+
 func htmlEscape(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
 	s = strings.ReplaceAll(s, "<", "&lt;")
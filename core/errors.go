@@ -3,6 +3,7 @@
 package core
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/rfwlab/rfw/v2/dom"
@@ -58,6 +59,35 @@ func ReportError(err any, context string) {
 	ShowErrorOverlay(err, context)
 }
 
+// reportTemplateError reports err, raised by the template expression source
+// of c, as a *TemplateError pointing into the template. A condition is
+// evaluated again on every change it depends on, so each distinct error is
+// reported once per component.
+func reportTemplateError(c *HTMLComponent, source string, err error) {
+	key := source + "\x00" + err.Error()
+	if c.templateErrors[key] {
+		return
+	}
+	if c.templateErrors == nil {
+		c.templateErrors = make(map[string]bool)
+	}
+	c.templateErrors[key] = true
+	file := c.TemplateFile
+	if file == "" {
+		file = compiledTemplateFile(c.Template)
+	}
+	te := newTemplateError(c.Name, file, c.Template, source, err)
+	ReportError(te, fmt.Sprintf("Template: %s (ID: %s)", c.Name, c.ID))
+}
+
+// recoveredError turns a recovered panic value into an error.
+func recoveredError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
 // Delegated event handlers recover panics inside the dom package; route them
 // into the same pipeline as every other capture point.
 func init() {
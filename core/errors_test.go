@@ -3,6 +3,7 @@
 package core
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -45,3 +46,38 @@ func TestReportErrorFansOutToSinks(t *testing.T) {
 		t.Fatalf("sink should be removed, got %v", got)
 	}
 }
+
+func TestTemplateErrorsReachSinks(t *testing.T) {
+	var got []*TemplateError
+	stop := OnError(func(err any, ctx string) {
+		var te *TemplateError
+		if e, ok := err.(error); ok && errors.As(e, &te) && strings.HasPrefix(ctx, "Template: Broken") {
+			got = append(got, te)
+		}
+	})
+	defer stop()
+
+	tpl := "<root>\n  @if:count >\n    <p>many</p>\n  @endif\n  <p>@expr:total(</p>\n</root>"
+	c := NewHTMLComponent("Broken", []byte(tpl), map[string]any{"count": 2}, WithTemplateFile("templates/broken.rtml"))
+	c.SetComponent(c)
+	c.Init(nil)
+	c.Render()
+	c.RenderFresh()
+
+	if len(got) != 2 {
+		t.Fatalf("expected one report per broken expression, got %d", len(got))
+	}
+	cond, expr := got[0], got[1]
+	if cond.Source != "count >" {
+		cond, expr = expr, cond
+	}
+	if cond.File != "templates/broken.rtml" || cond.Line != 2 || cond.Col != 7 || cond.Source != "count >" {
+		t.Fatalf("condition error = %+v", cond)
+	}
+	if !strings.Contains(cond.Frame, "> 2 |   @if:count >") {
+		t.Fatalf("condition frame =\n%s", cond.Frame)
+	}
+	if expr.Line != 5 || expr.Col != 12 || expr.Source != "total(" {
+		t.Fatalf("expression error = %+v", expr)
+	}
+}
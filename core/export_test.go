@@ -0,0 +1,6 @@
+//go:build js && wasm
+
+package core
+
+// UsesCompiledTemplate reports whether c renders through a compiled template.
+func UsesCompiledTemplate(c *HTMLComponent) bool { return c.compiledTemplate() != nil }
@@ -3,6 +3,7 @@
 package core
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/rfwlab/rfw/v2/dom"
 	hostclient "github.com/rfwlab/rfw/v2/hostclient"
+	"github.com/rfwlab/rfw/v2/rtmlast"
 	"github.com/rfwlab/rfw/v2/state"
 	"github.com/tdewolff/minify/v2"
 	"github.com/tdewolff/minify/v2/css"
@@ -41,10 +43,13 @@ func (u *unsubscribes) Run() {
 
 // HTMLComponent renders RTML templates and manages their component state.
 type HTMLComponent struct {
-	ID                string
-	Name              string
-	Template          string
-	TemplateFS        []byte
+	ID         string
+	Name       string
+	Template   string
+	TemplateFS []byte
+	// TemplateFile names the file Template was read from, for errors that
+	// point into the template. It is "" unless set with WithTemplateFile.
+	TemplateFile      string
 	Dependencies      map[string]Component
 	unsubscribes      unsubscribes
 	Store             *state.Store
@@ -82,6 +87,31 @@ type HTMLComponent struct {
 	totalRender       time.Duration
 	lastRender        time.Duration
 	timeline          []ComponentTimelineEntry
+
+	// compiled caches the compiled renderer found for compiledSource
+	compiled         *CompiledTemplate
+	compiledSource   string
+	compiledResolved bool
+
+	// templateErrors holds the template errors already reported, so a
+	// broken condition re-evaluated on every change is reported once
+	templateErrors map[string]bool
+	// directiveBindings are the directive attributes of the last render,
+	// by the id their element carries; mountedDirectives the ones in the DOM
+	directiveBindings map[string]directiveBinding
+	mountedDirectives map[string]*mountedDirective
+	// fieldErrors are the error signals handed out by FieldError
+	fieldErrors map[string]*state.Signal[string]
+}
+
+// ComponentOption configures an HTMLComponent at construction.
+type ComponentOption func(*HTMLComponent)
+
+// WithTemplateFile records the file the template was embedded from, such as
+// "templates/counter.rtml". Template errors then name it and give line and
+// column in it.
+func WithTemplateFile(name string) ComponentOption {
+	return func(c *HTMLComponent) { c.TemplateFile = name }
 }
 
 // ComponentStats contains aggregated render metrics for an HTML component.
@@ -101,7 +131,7 @@ type ComponentTimelineEntry struct {
 }
 
 // NewHTMLComponent creates a component from an RTML template and initial props.
-func NewHTMLComponent(name string, templateFs []byte, props map[string]any) *HTMLComponent {
+func NewHTMLComponent(name string, templateFs []byte, props map[string]any, opts ...ComponentOption) *HTMLComponent {
 	id := generateComponentID(name, props)
 	c := &HTMLComponent{
 		ID:                id,
@@ -116,6 +146,9 @@ func NewHTMLComponent(name string, templateFs []byte, props map[string]any) *HTM
 		exprContents:      make(map[string]string),
 		classExprContents: make(map[string]string),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
 	// Attempt automatic cleanup when component is garbage collected.
 	runtime.SetFinalizer(c, func(hc *HTMLComponent) { hc.Unmount() })
 	return c
@@ -128,9 +161,16 @@ func (c *HTMLComponent) Init(store *state.Store) {
 	}
 	template, err := LoadComponentTemplate(c.TemplateFS)
 	if err != nil {
+		if c.TemplateFile != "" {
+			panic(fmt.Sprintf("Error loading template %s for component %s: %v", c.TemplateFile, c.Name, err))
+		}
 		panic(fmt.Sprintf("Error loading template for component %s: %v", c.Name, err))
 	}
 	template = devOverrideTemplate(c, template)
+	template, rules, scope := rtmlast.ScopeStyle(template)
+	if rules != "" {
+		UseScopedStyle(c.Name, scope, rules)
+	}
 	c.Template = template
 	dom.RegisterBindings(c.ID, c.Name, template)
 	devRegisterComponent(c)
@@ -195,8 +235,27 @@ func (c *HTMLComponent) Render() (renderedTemplate string) {
 	}()
 
 	c.unsubscribes.Run()
+	defer state.SetDebugOwner(c.ID)()
+
+	if compiled, ok := c.renderCompiled(); ok {
+		renderedTemplate = compiled
+	} else {
+		renderedTemplate = c.renderInterpreted()
+	}
 
-	renderedTemplate = c.Template
+	for _, name := range c.hostComponentNames() {
+		hostclient.RegisterComponent(c.ID, name, c.hostVars)
+	}
+
+	c.cache[key] = renderedTemplate
+	c.lastCacheKey = key
+	return renderedTemplate
+}
+
+// renderInterpreted renders the template through the regex pipeline. It is
+// the path of every template without a compiled renderer.
+func (c *HTMLComponent) renderInterpreted() string {
+	renderedTemplate := c.Template
 	renderedTemplate = strings.Replace(renderedTemplate, "<root", fmt.Sprintf("<root data-component-id=\"%s\"", c.ID), 1)
 
 	// Extract slot contents destined for child components
@@ -205,6 +264,9 @@ func (c *HTMLComponent) Render() (renderedTemplate string) {
 	// Replace this component's slot placeholders with provided content or fallbacks
 	renderedTemplate = replaceSlotPlaceholders(renderedTemplate, c)
 
+	// Turn @switch and @await blocks into @if chains, binding await aliases
+	renderedTemplate = expandBlocks(renderedTemplate, c)
+
 	// {{prop}} substitutions are HTML-escaped like @prop; @rawprop remains the
 	// explicit escape hatch for trusted markup.
 	for key, value := range c.Props {
@@ -225,6 +287,7 @@ func (c *HTMLComponent) Render() (renderedTemplate string) {
 	renderedTemplate = replaceSignalPlaceholders(renderedTemplate, c)
 	renderedTemplate = replaceExprInClassAttr(renderedTemplate, c)
 	renderedTemplate = replaceExprPlaceholders(renderedTemplate, c)
+	renderedTemplate = replaceInterpolations(renderedTemplate, c)
 
 	// Handle @prop:propName syntax for props
 	renderedTemplate = replacePropPlaceholders(renderedTemplate, c)
@@ -237,6 +300,10 @@ func (c *HTMLComponent) Render() (renderedTemplate string) {
 		renderedTemplate = replaceHostPlaceholders(renderedTemplate, c)
 	}
 
+	// Handle @name:expr attributes of registered directives, before the
+	// @event:handler shorthand can claim them
+	renderedTemplate = replaceDirectives(renderedTemplate, c)
+
 	// Handle @if:condition syntax for conditional rendering
 	renderedTemplate = replaceConditionals(renderedTemplate, c)
 
@@ -252,15 +319,7 @@ func (c *HTMLComponent) Render() (renderedTemplate string) {
 	// Handle constructor decorators like [ref] and [key expr]
 	renderedTemplate = replaceConstructors(renderedTemplate)
 
-	for _, name := range c.hostComponentNames() {
-		hostclient.RegisterComponent(c.ID, name, c.hostVars)
-	}
-
-	renderedTemplate = minifyInline(renderedTemplate)
-
-	c.cache[key] = renderedTemplate
-	c.lastCacheKey = key
-	return renderedTemplate
+	return minifyInline(renderedTemplate)
 }
 
 const componentTimelineLimit = 64
@@ -386,12 +445,14 @@ func (c *HTMLComponent) Unmount() {
 
 // Mount activates the component and its child dependencies.
 func (c *HTMLComponent) Mount() {
+	defer state.SetDebugOwner(c.ID)()
 	c.mounted = true
 	if c.scope == nil || c.scope.Closed() {
 		c.scope = NewScope()
 	}
 	c.registerHandlers()
 	c.registerDOMHooks()
+	c.registerDirectives()
 	for _, dep := range c.Dependencies {
 		dependency := dep
 		c.runLifecycle("dependency mount", dependency.Mount)
@@ -424,8 +485,25 @@ func (c *HTMLComponent) Scope() *Scope {
 }
 
 // Effect registers a reactive effect that stops on unmount.
-func (c *HTMLComponent) Effect(fn func() func()) {
-	c.Scope().Defer(state.Effect(fn))
+func (c *HTMLComponent) Effect(fn func() func(), opts ...state.EffectOption) {
+	restore := state.SetDebugOwner(c.ID)
+	stop := state.Effect(fn, opts...)
+	restore()
+	c.Scope().Defer(stop)
+}
+
+// AsyncEffect registers a state.AsyncEffect whose runs are cancelled on
+// unmount. Errors and panics go to ReportError unless opts set a handler.
+func (c *HTMLComponent) AsyncEffect(fn func(context.Context) error, opts ...state.EffectOption) {
+	scope := c.Scope()
+	report := func(err error) {
+		ReportError(err, fmt.Sprintf("AsyncEffect: %s (ID: %s)", c.Name, c.ID))
+	}
+	opts = append([]state.EffectOption{state.WithEffectContext(scope.Context()), state.WithEffectErrorHandler(report)}, opts...)
+	restore := state.SetDebugOwner(c.ID)
+	stop := state.AsyncEffect(fn, opts...)
+	restore()
+	scope.Defer(stop)
 }
 
 // DOMHook registers root lifecycle callbacks owned by this component.
@@ -467,6 +545,33 @@ func (c *HTMLComponent) registerHandlers() {
 	for name, fn := range c.handlers {
 		dom.RegisterComponentHandlerFunc(c.ID, name, fn)
 	}
+	for field, sig := range c.fieldErrors {
+		dom.RegisterFieldError(c.ID, field, sig)
+	}
+}
+
+// FieldError returns a signal holding why the last input of a :w binding to
+// field could not be written, and "" once one could: 12a typed into an int
+// signal gives `"12a" is not a whole number`. field is the name of the bound
+// signal, or module.store.key for a store. Expose it as a prop to show it:
+//
+//	c.Props["ageError"] = c.FieldError("age")
+//
+//	<input value="@signal:age:w">
+//	@if:ageError != ''
+//	<p class="error">@signal:ageError</p>
+//	@endif
+func (c *HTMLComponent) FieldError(field string) *state.Signal[string] {
+	if sig, ok := c.fieldErrors[field]; ok {
+		return sig
+	}
+	if c.fieldErrors == nil {
+		c.fieldErrors = make(map[string]*state.Signal[string])
+	}
+	sig := state.NewSignal("")
+	c.fieldErrors[field] = sig
+	dom.RegisterFieldError(c.ID, field, sig)
+	return sig
 }
 
 // GetName returns the component name.
@@ -616,6 +721,21 @@ func (c *HTMLComponent) AddHostComponent(name string) {
 	}
 }
 
+// AddTypedHostComponent links c to a typed host component whose host
+// variables are the fields of Out, after checking that every `{h:name}`
+// placeholder in the template names one of them.
+func AddTypedHostComponent[Out any](c *HTMLComponent, name string) error {
+	template := c.Template
+	if template == "" {
+		template = string(c.TemplateFS)
+	}
+	if err := hostclient.CheckHostVars[Out](template); err != nil {
+		return fmt.Errorf("component %s: %w", c.Name, err)
+	}
+	c.AddHostComponent(name)
+	return nil
+}
+
 // hostComponentNames returns every host component linked to this component,
 // including a HostComponent assigned directly to the exported field.
 func (c *HTMLComponent) hostComponentNames() []string {
@@ -11,8 +11,8 @@ import (
 	"strings"
 
 	"github.com/rfwlab/rfw/v2/dom"
+	js "github.com/rfwlab/rfw/v2/js"
 	"github.com/rfwlab/rfw/v2/rtmlast"
-	"github.com/rfwlab/rfw/v2/rtmleval"
 	"github.com/rfwlab/rfw/v2/state"
 )
 
@@ -24,7 +24,6 @@ var (
 	reStore           = regexp.MustCompile(`@store:(\w+)\.(\w+)\.(\w+)(:w)?`)
 	reRawStore        = regexp.MustCompile(`@rawstore:(\w+)\.(\w+)\.(\w+)`)
 	reSignal          = regexp.MustCompile(`@signal:(\w+)(:w)?`)
-	reExpr            = regexp.MustCompile(`@expr:([^<@|\n)]+)`)
 	reProp            = regexp.MustCompile(`@prop:(\w+)`)
 	reRawProp         = regexp.MustCompile(`@rawprop:(\w+)`)
 	rePluginVar       = regexp.MustCompile(`\{plugin:(\w+)\.(\w+)\}`)
@@ -82,26 +81,43 @@ type ConditionDependency struct {
 
 // Render evaluates the conditional branches and renders the appropriate content.
 func (cn *ConditionalNode) Render(c *HTMLComponent) string {
-	var conditions []string
-	for _, br := range cn.Branches {
-		conditions = append(conditions, br.Condition)
+	conditions := make([]string, len(cn.Branches))
+	for i, br := range cn.Branches {
+		conditions[i] = br.Condition
 	}
+	conditionID := nextConditionID(c, conditions)
+	contents := make([]string, len(cn.Branches))
+	for i, br := range cn.Branches {
+		var sb strings.Builder
+		for _, n := range br.Nodes {
+			sb.WriteString(n.Render(c))
+		}
+		contents[i] = sb.String()
+	}
+	return mountConditional(c, conditionID, conditions, contents)
+}
+
+// nextConditionID allocates the positional id of a conditional block. Nested
+// blocks are numbered after the block containing them.
+func nextConditionID(c *HTMLComponent, conditions []string) string {
 	conditionHash := sha256.Sum256([]byte(strings.Join(conditions, "|")))
 	conditionID := fmt.Sprintf("cond-%x-%d", conditionHash[:20], c.condSeq)
 	c.condSeq++
+	return conditionID
+}
 
+// mountConditional records the rendered branches of a conditional block,
+// subscribes to the state its conditions read and returns the markup of the
+// branch that currently applies. An empty condition is the @else branch.
+func mountConditional(c *HTMLComponent, conditionID string, conditions, contents []string) string {
 	var content ConditionContent
 	var chosen string
-	for _, br := range cn.Branches {
-		var sb strings.Builder
-		for _, n := range br.Nodes {
-			sb.WriteString(n.Render(c))
-		}
-		branchContent := sb.String()
-		content.Branches = append(content.Branches, ConditionalBranchContent{Condition: br.Condition, Content: branchContent})
+	for i, condition := range conditions {
+		branchContent := contents[i]
+		content.Branches = append(content.Branches, ConditionalBranchContent{Condition: condition, Content: branchContent})
 
-		if br.Condition != "" {
-			result, _ := evaluateCondition(br.Condition, c)
+		if condition != "" {
+			result, _ := evaluateCondition(condition, c)
 			if chosen == "" && result {
 				chosen = branchContent
 			}
@@ -125,9 +141,9 @@ func (cn *ConditionalNode) Render(c *HTMLComponent) string {
 	}
 
 	unsub := state.Effect(func() func() {
-		for _, br := range cn.Branches {
-			if br.Condition != "" {
-				evaluateCondition(br.Condition, c)
+		for _, condition := range conditions {
+			if condition != "" {
+				evaluateCondition(condition, c)
 			}
 		}
 		updateConditionBindings(c, conditionID)
@@ -139,11 +155,11 @@ func (cn *ConditionalNode) Render(c *HTMLComponent) string {
 	// has to subscribe to it as well, otherwise a component whose template
 	// carries no other binding on that store (an @if and nothing else) renders
 	// once and never reacts.
-	for _, br := range cn.Branches {
-		if br.Condition == "" {
+	for _, condition := range conditions {
+		if condition == "" {
 			continue
 		}
-		deps, _ := getConditionDependencies(br.Condition)
+		deps, _ := getConditionDependencies(condition)
 		for _, dep := range deps {
 			if dep.module == "" || dep.storeName == "" || dep.key == "" {
 				continue
@@ -261,9 +277,19 @@ func replaceSlotPlaceholders(template string, c *HTMLComponent) string {
 			slotName = "default"
 		}
 		fallback := parts[2]
+		// a scoped slot lists the values it exposes on its own line
+		var exposed []string
+		if line, body, ok := strings.Cut(fallback, "\n"); ok {
+			if exposed = rtmlast.SlotParams(line); exposed != nil {
+				fallback = body
+			}
+		}
 		if content, ok := c.Slots[slotName]; ok {
 			switch v := content.(type) {
 			case string:
+				if exposed != nil {
+					return bindSlotParams(c, slotName, v, exposed)
+				}
 				return v
 			case Component:
 				placeholder := fmt.Sprintf("slot-%s-%d", slotName, idx)
@@ -278,6 +304,150 @@ func replaceSlotPlaceholders(template string, c *HTMLComponent) string {
 	})
 }
 
+// bindSlotParams binds the names slot content lists on its first line to the
+// values the slot exposes, by position, and returns the content below that
+// line. The content is written in the parent but rendered by the child,
+// inside its loops, so names are bound by renaming the references to them:
+// a listed name becomes the value the slot exposes, and any other name the
+// parent has a prop for becomes a child prop holding the parent's value. The
+// content's event handlers are forwarded to the parent the same way.
+// Exposed values shadow parent props of the same name:
+//
+//	@slot:cell row                  @slot:table.cell r
+//	<td>@prop:row.name</td>         <td @on:click:open><b>@prop:r.name</b> @prop:title</td>
+//	@endslot                        @endslot
+//
+// Content without names on its first line is returned as it is.
+func bindSlotParams(c *HTMLComponent, slot, content string, exposed []string) string {
+	line, body, ok := strings.Cut(content, "\n")
+	if !ok {
+		return content
+	}
+	params := rtmlast.SlotParams(line)
+	if params == nil {
+		return content
+	}
+	if len(params) > len(exposed) {
+		reportTemplateError(c, "@slot:"+slot, fmt.Errorf("slot %s exposes %d values, %d names given", slot, len(exposed), len(params)))
+		params = params[:len(exposed)]
+	}
+	parent := c.parent
+	names := make(map[string]string, len(params))
+	if parent != nil {
+		for name := range parent.Props {
+			names[name] = slotParentName(slot, name)
+		}
+	}
+	for i, param := range params {
+		if param != exposed[i] {
+			names[param] = exposed[i]
+		} else {
+			delete(names, param)
+		}
+	}
+	if len(names) > 0 {
+		body = renameSlotRefs(body, names)
+	}
+	if parent == nil {
+		return body
+	}
+	for name, value := range parent.Props {
+		alias := slotParentName(slot, name)
+		if names[name] == alias && strings.Contains(body, alias) {
+			if c.Props == nil {
+				c.Props = make(map[string]any)
+			}
+			c.Props[alias] = value
+		}
+	}
+	return forwardSlotHandlers(c, parent, slot, body)
+}
+
+// slotParentName is the child prop or handler holding the parent's name for
+// the content of slot.
+func slotParentName(slot, name string) string {
+	return "_slot_" + slot + "_" + name
+}
+
+var reSlotEvent = regexp.MustCompile(`@(on:)?(\w+(?:\.\w+)*):(\w+)([\s>/])`)
+
+// forwardSlotHandlers points the event handlers of slot content, which the
+// child renders, at the parent's handlers of the same name.
+func forwardSlotHandlers(c, parent *HTMLComponent, slot, body string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range reSlotEvent.FindAllStringSubmatchIndex(body, -1) {
+		// only attributes are events; @prop:x and friends in text are not
+		if strings.LastIndexByte(body[:m[0]], '<') <= strings.LastIndexByte(body[:m[0]], '>') {
+			continue
+		}
+		if m[2] < 0 {
+			if _, ok := lookupDirective(body[m[4]:m[5]]); ok {
+				continue
+			}
+		}
+		name := body[m[6]:m[7]]
+		alias := slotParentName(slot, name)
+		parentID := parent.ID
+		dom.RegisterComponentHandler(c.ID, alias, func(_ js.Value, args []js.Value) any {
+			if h := dom.GetComponentHandler(parentID, name); h.Truthy() {
+				forwarded := make([]any, len(args))
+				for i, arg := range args {
+					forwarded[i] = arg
+				}
+				h.Invoke(forwarded...)
+			}
+			return nil
+		})
+		b.WriteString(body[last:m[6]])
+		b.WriteString(alias)
+		last = m[7]
+	}
+	if last == 0 {
+		return body
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+var (
+	reSlotRef     = regexp.MustCompile(`@(?:raw)?prop:|@signal:|@expr:|@(?:if|else-if|switch|case|await):|@for:\w+(?:,\w+)?\s+in\s+|\{\{`)
+	reLeadingWord = regexp.MustCompile(`^\w*`)
+)
+
+// renameSlotRefs renames the names the props, signals and expressions of
+// body read.
+func renameSlotRefs(body string, names map[string]string) string {
+	var b strings.Builder
+	for {
+		loc := reSlotRef.FindStringIndex(body)
+		if loc == nil {
+			break
+		}
+		b.WriteString(body[:loc[1]])
+		marker, rest := body[loc[0]:loc[1]], body[loc[1]:]
+		var end int
+		switch {
+		case marker == "{{":
+			if end = strings.Index(rest, "}}"); end < 0 {
+				end = len(rest)
+			}
+		case marker == "@expr:":
+			end = rtmlast.ExprEnd(rest)
+		case strings.HasSuffix(marker, "prop:") || marker == "@signal:":
+			end = len(reLeadingWord.FindString(rest))
+		default:
+			if end = strings.IndexByte(rest, '\n'); end < 0 {
+				end = len(rest)
+			}
+		}
+		b.WriteString(rtmlast.RenameIdents(rest[:end], names))
+		body = rest[end:]
+	}
+	b.WriteString(body)
+	return b.String()
+}
+
 // escapeValue renders a substituted value HTML-escaped: template bindings are
 // text by default; @rawstore/@rawprop opt into trusted markup injection.
 func escapeValue(v any) string {
@@ -291,18 +461,10 @@ func replaceStorePlaceholders(template string, c *HTMLComponent) string {
 			return match
 		}
 		module, storeName, key := parts[1], parts[2], parts[3]
-		store := state.GlobalStoreManager.GetStore(module, storeName)
-		if store == nil {
+		value, ok := bindStoreKey(c, module, storeName, key)
+		if !ok {
 			return match
 		}
-		value := store.Get(key)
-		if value == nil {
-			value = ""
-		}
-		unsubscribe := store.OnChange(key, func(newValue any) {
-			updateStoreBindings(c, module, storeName, key, newValue)
-		})
-		c.unsubscribes.Add(unsubscribe)
 		return fmt.Sprintf(`<span data-store-raw="%s.%s.%s">%v</span>`, module, storeName, key, value)
 	})
 	storeRegex := reStore
@@ -317,18 +479,7 @@ func replaceStorePlaceholders(template string, c *HTMLComponent) string {
 		key := parts[3]
 		isWriteable := len(parts) == 5 && parts[4] == ":w"
 
-		store := state.GlobalStoreManager.GetStore(module, storeName)
-		if store != nil {
-			value := store.Get(key)
-			if value == nil {
-				value = ""
-			}
-
-			unsubscribe := store.OnChange(key, func(newValue any) {
-				updateStoreBindings(c, module, storeName, key, newValue)
-			})
-			c.unsubscribes.Add(unsubscribe)
-
+		if value, ok := bindStoreKey(c, module, storeName, key); ok {
 			if isWriteable {
 				return match
 			}
@@ -341,6 +492,24 @@ func replaceStorePlaceholders(template string, c *HTMLComponent) string {
 	})
 }
 
+// bindStoreKey reads a store key for display and keeps the markup bound to it.
+// A nil value reads as empty. It reports false when the store does not exist.
+func bindStoreKey(c *HTMLComponent, module, storeName, key string) (any, bool) {
+	store := state.GlobalStoreManager.GetStore(module, storeName)
+	if store == nil {
+		return nil, false
+	}
+	value := store.Get(key)
+	if value == nil {
+		value = ""
+	}
+	unsubscribe := store.OnChange(key, func(newValue any) {
+		updateStoreBindings(c, module, storeName, key, newValue)
+	})
+	c.unsubscribes.Add(unsubscribe)
+	return value, true
+}
+
 func replaceSignalPlaceholders(template string, c *HTMLComponent) string {
 	sigRegex := reSignal
 	return sigRegex.ReplaceAllStringFunc(template, func(match string) string {
@@ -350,21 +519,11 @@ func replaceSignalPlaceholders(template string, c *HTMLComponent) string {
 		}
 		name := parts[1]
 		isWriteable := len(parts) == 3 && parts[2] == ":w"
-		if prop, ok := c.Props[name]; ok {
-			if sig, ok := prop.(interface{ Read() any }); ok {
-				dom.RegisterSignal(c.ID, name, sig)
-				val := sig.Read()
-				unsub := state.Effect(func() func() {
-					v := sig.Read()
-					updateSignalBindings(c, name, v)
-					return nil
-				})
-				c.unsubscribes.Add(unsub)
-				if isWriteable {
-					return match
-				}
-				return fmt.Sprintf(`<span data-signal="%s">%s</span>`, name, escapeValue(val))
+		if val, ok := bindSignal(c, name); ok {
+			if isWriteable {
+				return match
 			}
+			return fmt.Sprintf(`<span data-signal="%s">%s</span>`, name, escapeValue(val))
 		}
 		if DevMode {
 			Log().Warn("signal '%s' not found in component %s", name, c.Name)
@@ -373,34 +532,103 @@ func replaceSignalPlaceholders(template string, c *HTMLComponent) string {
 	})
 }
 
+// bindSignal registers the signal prop name with the DOM and keeps its
+// bindings updated. It returns the current value, or false when the component
+// has no such signal.
+func bindSignal(c *HTMLComponent, name string) (any, bool) {
+	prop, ok := c.Props[name]
+	if !ok {
+		return nil, false
+	}
+	sig, ok := prop.(interface{ Read() any })
+	if !ok {
+		return nil, false
+	}
+	dom.RegisterSignal(c.ID, name, sig)
+	val := sig.Read()
+	unsub := state.Effect(func() func() {
+		v := sig.Read()
+		updateSignalBindings(c, name, v)
+		return nil
+	})
+	c.unsubscribes.Add(unsub)
+	return val, true
+}
+
+// replaceExprPlaceholders binds every @expr: in template. The expression runs
+// to where rtmlast.ExprEnd says, so a pipe or call inside it stays whole.
 func replaceExprPlaceholders(template string, c *HTMLComponent) string {
-	exprRegex := reExpr
+	const marker = "@expr:"
+	var b strings.Builder
 	idx := 0
-	return exprRegex.ReplaceAllStringFunc(template, func(match string) string {
-		parts := exprRegex.FindStringSubmatch(match)
-		if len(parts) < 2 {
-			return match
+	for {
+		i := strings.Index(template, marker)
+		if i < 0 {
+			b.WriteString(template)
+			return b.String()
+		}
+		b.WriteString(template[:i])
+		rest := template[i+len(marker):]
+		end := rtmlast.ExprEnd(rest)
+		source := strings.TrimSpace(rest[:end])
+		if source == "" {
+			b.WriteString(marker)
+			template = rest
+			continue
 		}
-
-		exprStr := strings.TrimSpace(parts[1])
 		exprID := fmt.Sprintf("expr-%d", idx)
 		idx++
+		b.WriteString(bindExpr(c, exprID, source))
+		template = rest[end:]
+	}
+}
 
-		astExpr := rtmlast.ParseExpr(exprStr)
-		initialVal := evalASTExprWithSigRefs(astExpr, c, nil)
-		c.exprContents[exprID] = exprToString(astExpr)
+// replaceInterpolations binds the {{ expr }} interpolations the plain
+// {{prop}} substitution left, such as {{ price | currency "EUR" }}. They
+// update like @expr.
+func replaceInterpolations(template string, c *HTMLComponent) string {
+	var b strings.Builder
+	idx := 0
+	for {
+		i := strings.Index(template, "{{")
+		if i < 0 {
+			b.WriteString(template)
+			return b.String()
+		}
+		end := strings.Index(template[i+2:], "}}")
+		if end < 0 {
+			b.WriteString(template)
+			return b.String()
+		}
+		b.WriteString(template[:i])
+		source := strings.TrimSpace(template[i+2 : i+2+end])
+		if source == "" {
+			b.WriteString(template[i : i+4+end])
+		} else {
+			b.WriteString(bindExpr(c, fmt.Sprintf("interp-%d", idx), source))
+			idx++
+		}
+		template = template[i+4+end:]
+	}
+}
 
-		sigRefs := collectExprSignals(astExpr, c)
+// bindExpr evaluates an @expr binding, keeps it updated and returns its
+// markup.
+func bindExpr(c *HTMLComponent, exprID, exprStr string) string {
+	astExpr := parseTemplateExpr(c, exprStr)
+	initialVal := evalTemplateExpr(c, exprStr, astExpr, nil)
+	c.exprContents[exprID] = exprStr
 
-		unsub := state.Effect(func() func() {
-			newVal := evalASTExprWithSigRefs(astExpr, c, sigRefs)
-			updateExprBindings(c, exprID, newVal)
-			return nil
-		})
-		c.unsubscribes.Add(unsub)
+	sigRefs := collectExprSignals(astExpr, c)
 
-		return fmt.Sprintf(`<span data-expr="%s">%s</span>`, exprID, escapeValue(initialVal))
+	unsub := state.Effect(func() func() {
+		newVal := evalTemplateExpr(c, exprStr, astExpr, sigRefs)
+		updateExprBindings(c, exprID, newVal)
+		return nil
 	})
+	c.unsubscribes.Add(unsub)
+
+	return fmt.Sprintf(`<span data-expr="%s">%s</span>`, exprID, escapeValue(initialVal))
 }
 
 func replaceExprInClassAttr(template string, c *HTMLComponent) string {
@@ -428,16 +656,16 @@ func replaceExprInClassAttr(template string, c *HTMLComponent) string {
 			idx++
 			exprIDs = append(exprIDs, exprID)
 
-			astExpr := rtmlast.ParseExpr(exprStr)
-			initialVal := evalASTExprWithSigRefs(astExpr, c, nil)
+			astExpr := parseTemplateExpr(c, exprStr)
+			initialVal := evalTemplateExpr(c, exprStr, astExpr, nil)
 			dynamicVal := strings.TrimSpace(fmt.Sprintf("%v", initialVal))
 			c.classExprContents[exprID] = dynamicVal
-			c.exprContents[exprID] = exprToString(astExpr)
+			c.exprContents[exprID] = exprStr
 
 			sigRefs := collectExprSignals(astExpr, c)
 
 			unsub := state.Effect(func() func() {
-				newVal := evalASTExprWithSigRefs(astExpr, c, sigRefs)
+				newVal := evalTemplateExpr(c, exprStr, astExpr, sigRefs)
 				updateClassExprBindings(c, exprID, newVal)
 				return nil
 			})
@@ -452,277 +680,82 @@ func replaceExprInClassAttr(template string, c *HTMLComponent) string {
 	return result
 }
 
-func collectExprSignals(expr rtmlast.Expr, c *HTMLComponent) map[string]any {
-	refs := make(map[string]any)
-	collectIdents(expr, c, refs)
-	return refs
+// parseTemplateExpr parses the @expr source of c. A source the parser
+// chokes on is reported as a template error and renders empty.
+func parseTemplateExpr(c *HTMLComponent, source string) (expr rtmlast.Expr) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportTemplateError(c, source, recoveredError(r))
+			expr = rtmlast.LiteralExpr{Value: ""}
+		}
+	}()
+	return rtmlast.ParseExpr(source)
 }
 
-func collectIdents(expr rtmlast.Expr, c *HTMLComponent, refs map[string]any) {
-	switch e := expr.(type) {
-	case rtmlast.IdentExpr:
-		name := e.Name
-		if strings.HasPrefix(name, "store:") || strings.HasPrefix(name, "signal:") {
-			return
-		}
-		if _, seen := refs[name]; !seen {
-			if prop, ok := c.Props[name]; ok {
-				refs[name] = prop
-			}
-		}
-	case rtmlast.BinaryExpr:
-		collectIdents(e.LHS, c, refs)
-		collectIdents(e.RHS, c, refs)
-	case rtmlast.UnaryExpr:
-		collectIdents(e.Expr, c, refs)
-	case rtmlast.FieldExpr:
-		collectIdents(e.Obj, c, refs)
-	case rtmlast.TernaryExpr:
-		collectIdents(e.Cond, c, refs)
-		collectIdents(e.Then, c, refs)
-		collectIdents(e.Else, c, refs)
+// evalTemplateExpr evaluates expr, parsed from source. An evaluation error or
+// panic is reported as a template error pointing at source instead of
+// failing the whole render, and the expression renders empty.
+func evalTemplateExpr(c *HTMLComponent, source string, expr rtmlast.Expr, sigRefs map[string]any) (v any) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportTemplateError(c, source, recoveredError(r))
+			v = nil
+		}
+	}()
+	v, err := rtmlast.Eval(expr, templateEnv(c, sigRefs, false))
+	if err != nil {
+		reportTemplateError(c, source, err)
+		return nil
 	}
+	return v
 }
 
-func evalASTExprWithSigRefs(expr rtmlast.Expr, c *HTMLComponent, sigRefs map[string]any) any {
-	switch e := expr.(type) {
-	case rtmlast.IdentExpr:
-		name := e.Name
-		if strings.HasPrefix(name, "store:") {
+// templateEnv resolves the names of an RTML expression of c: store:, signal:
+// and prop: references, then sigRefs, then the props of c. Calls go to the
+// functions registered with RegisterTemplateFunc.
+func templateEnv(c *HTMLComponent, sigRefs map[string]any, bareWords bool) rtmlast.Env {
+	lookup := func(name string) (any, bool) {
+		switch {
+		case strings.HasPrefix(name, "store:"):
 			parts := strings.Split(strings.TrimPrefix(name, "store:"), ".")
 			if len(parts) == 3 {
-				store := state.GlobalStoreManager.GetStore(parts[0], parts[1])
-				if store != nil {
-					return store.Get(parts[2])
+				if store := state.GlobalStoreManager.GetStore(parts[0], parts[1]); store != nil {
+					return store.Get(parts[2]), true
 				}
 			}
-			return nil
-		}
-		if strings.HasPrefix(name, "signal:") {
-			sigName := strings.TrimPrefix(name, "signal:")
-			if prop, ok := c.Props[sigName]; ok {
-				if sig, ok := prop.(interface{ Read() any }); ok {
-					return sig.Read()
-				}
-				return prop
+			return nil, false
+		case strings.HasPrefix(name, "signal:"):
+			prop, ok := c.Props[strings.TrimPrefix(name, "signal:")]
+			if _, isSig := prop.(interface{ Read() any }); !ok || !isSig {
+				return nil, false
 			}
-			return nil
+			return prop, true
+		case strings.HasPrefix(name, "prop:"):
+			v, ok := c.Props[strings.TrimPrefix(name, "prop:")]
+			return v, ok
 		}
 		if prop, ok := sigRefs[name]; ok {
-			if sig, ok := prop.(interface{ Read() any }); ok {
-				return sig.Read()
-			}
-			return prop
-		}
-		if prop, ok := c.Props[name]; ok {
-			if sig, ok := prop.(interface{ Read() any }); ok {
-				return sig.Read()
-			}
-			return prop
-		}
-		return nil
-	case rtmlast.LiteralExpr:
-		return e.Value
-	case rtmlast.BinaryExpr:
-		switch e.Op {
-		case rtmlast.OpEq:
-			return cmpASTEqual(evalASTExprWithSigRefs(e.LHS, c, sigRefs), evalASTExprWithSigRefs(e.RHS, c, sigRefs))
-		case rtmlast.OpNeq:
-			return !cmpASTEqual(evalASTExprWithSigRefs(e.LHS, c, sigRefs), evalASTExprWithSigRefs(e.RHS, c, sigRefs))
-		case rtmlast.OpAnd:
-			return toASTBool(evalASTExprWithSigRefs(e.LHS, c, sigRefs)) && toASTBool(evalASTExprWithSigRefs(e.RHS, c, sigRefs))
-		case rtmlast.OpOr:
-			return toASTBool(evalASTExprWithSigRefs(e.LHS, c, sigRefs)) || toASTBool(evalASTExprWithSigRefs(e.RHS, c, sigRefs))
-		case rtmlast.OpLt, rtmlast.OpGt, rtmlast.OpLte, rtmlast.OpGte:
-			return cmpASTValues(evalASTExprWithSigRefs(e.LHS, c, sigRefs), evalASTExprWithSigRefs(e.RHS, c, sigRefs), e.Op)
-		default:
-			lhs := toASTFloat(evalASTExprWithSigRefs(e.LHS, c, sigRefs))
-			rhs := toASTFloat(evalASTExprWithSigRefs(e.RHS, c, sigRefs))
-			switch e.Op {
-			case rtmlast.OpAdd:
-				return lhs + rhs
-			case rtmlast.OpSub:
-				return lhs - rhs
-			case rtmlast.OpMul:
-				return lhs * rhs
-			case rtmlast.OpDiv:
-				if rhs == 0 {
-					return 0.0
-				}
-				return lhs / rhs
-			}
+			return prop, true
 		}
-	case rtmlast.UnaryExpr:
-		val := evalASTExprWithSigRefs(e.Expr, c, sigRefs)
-		switch e.Op {
-		case rtmlast.UnaryNot:
-			return !toASTBool(val)
-		case rtmlast.UnaryNeg:
-			return -toASTFloat(val)
-		}
-	case rtmlast.FieldExpr:
-		obj := evalASTExprWithSigRefs(e.Obj, c, sigRefs)
-		if m, ok := obj.(map[string]any); ok {
-			return m[e.Field]
-		}
-		return nil
-	case rtmlast.TernaryExpr:
-		if toASTBool(evalASTExprWithSigRefs(e.Cond, c, sigRefs)) {
-			return evalASTExprWithSigRefs(e.Then, c, sigRefs)
-		}
-		return evalASTExprWithSigRefs(e.Else, c, sigRefs)
+		v, ok := c.Props[name]
+		return v, ok
 	}
-	return nil
+	return rtmlast.Env{Lookup: lookup, Func: templateFunc, BareWords: bareWords}
 }
 
-func cmpASTEqual(a, b any) bool {
-	switch av := a.(type) {
-	case string:
-		if bv, ok := b.(string); ok {
-			return av == bv
-		}
-	case bool:
-		if bv, ok := b.(bool); ok {
-			return av == bv
-		}
-	case int:
-		if bv, ok := b.(int); ok {
-			return av == bv
+func collectExprSignals(expr rtmlast.Expr, c *HTMLComponent) map[string]any {
+	refs := make(map[string]any)
+	rtmlast.Inspect(expr, func(e rtmlast.Expr) bool {
+		id, ok := e.(rtmlast.IdentExpr)
+		if !ok || strings.Contains(id.Name, ":") {
+			return true
 		}
-	case float64:
-		if bv, ok := b.(float64); ok {
-			return av == bv
+		if prop, ok := c.Props[id.Name]; ok {
+			refs[id.Name] = prop
 		}
-	}
-	af, aok := toASTFloatOk(a)
-	bf, bok := toASTFloatOk(b)
-	if aok && bok {
-		return af == bf
-	}
-	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
-}
-
-func cmpASTValues(a, b any, op rtmlast.BinOp) bool {
-	l, r := toASTFloat(a), toASTFloat(b)
-	switch op {
-	case rtmlast.OpLt:
-		return l < r
-	case rtmlast.OpGt:
-		return l > r
-	case rtmlast.OpLte:
-		return l <= r
-	case rtmlast.OpGte:
-		return l >= r
-	default:
-		return false
-	}
-}
-
-func toASTFloat(v any) float64 {
-	switch val := v.(type) {
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	case float64:
-		return val
-	case float32:
-		return float64(val)
-	case string:
-		f, err := strconv.ParseFloat(val, 64)
-		if err != nil {
-			return 0
-		}
-		return f
-	default:
-		return 0
-	}
-}
-
-func toASTFloatOk(v any) (float64, bool) {
-	switch val := v.(type) {
-	case int:
-		return float64(val), true
-	case int64:
-		return float64(val), true
-	case float64:
-		return val, true
-	case float32:
-		return float64(val), true
-	default:
-		return 0, false
-	}
-}
-
-func toASTBool(v any) bool {
-	switch val := v.(type) {
-	case bool:
-		return val
-	case string:
-		return val != ""
-	case int:
-		return val != 0
-	case float64:
-		return val != 0
-	default:
-		return v != nil
-	}
-}
-
-func exprToString(expr rtmlast.Expr) string {
-	switch e := expr.(type) {
-	case rtmlast.IdentExpr:
-		return e.Name
-	case rtmlast.LiteralExpr:
-		return fmt.Sprintf("%v", e.Value)
-	case rtmlast.BinaryExpr:
-		return fmt.Sprintf("(%s %s %s)", exprToString(e.LHS), binOpString(e.Op), exprToString(e.RHS))
-	case rtmlast.UnaryExpr:
-		switch e.Op {
-		case rtmlast.UnaryNot:
-			return fmt.Sprintf("!%s", exprToString(e.Expr))
-		case rtmlast.UnaryNeg:
-			return fmt.Sprintf("-%s", exprToString(e.Expr))
-		}
-	case rtmlast.FieldExpr:
-		return fmt.Sprintf("%s.%s", exprToString(e.Obj), e.Field)
-	case rtmlast.CallExpr:
-		return fmt.Sprintf("%s(%v)", e.Fn, e.Args)
-	case rtmlast.TernaryExpr:
-		return fmt.Sprintf("%s ? %s : %s", exprToString(e.Cond), exprToString(e.Then), exprToString(e.Else))
-	}
-	return ""
-}
-
-func binOpString(op rtmlast.BinOp) string {
-	switch op {
-	case rtmlast.OpEq:
-		return "=="
-	case rtmlast.OpNeq:
-		return "!="
-	case rtmlast.OpLt:
-		return "<"
-	case rtmlast.OpGt:
-		return ">"
-	case rtmlast.OpLte:
-		return "<="
-	case rtmlast.OpGte:
-		return ">="
-	case rtmlast.OpAnd:
-		return "&&"
-	case rtmlast.OpOr:
-		return "||"
-	case rtmlast.OpAdd:
-		return "+"
-	case rtmlast.OpSub:
-		return "-"
-	case rtmlast.OpMul:
-		return "*"
-	case rtmlast.OpDiv:
-		return "/"
-	default:
-		return "?"
-	}
+		return true
+	})
+	return refs
 }
 
 func updateExprBindings(c *HTMLComponent, exprID string, newValue any) {
@@ -775,10 +808,7 @@ func replacePropPlaceholders(template string, c *HTMLComponent) string {
 		if len(parts) != 2 {
 			return match
 		}
-		if value, exists := c.Props[parts[1]]; exists {
-			return fmt.Sprintf("%v", value)
-		}
-		return match
+		return renderRawProp(c, parts[1])
 	})
 	propRegex := reProp
 	idx := 0
@@ -787,25 +817,38 @@ func replacePropPlaceholders(template string, c *HTMLComponent) string {
 		if len(parts) != 2 {
 			return match
 		}
-		propName := parts[1]
-		if value, exists := c.Props[propName]; exists {
-			switch v := value.(type) {
-			case Component:
-				placeholder := fmt.Sprintf("prop-%s-%d", propName, idx)
-				idx++
-				c.AddDependency(placeholder, v)
-				return fmt.Sprintf("@include:%s", placeholder)
-			default:
-				return escapeValue(v)
-			}
-		}
-		if DevMode {
-			Log().Warn("component %s missing prop '%s'", c.Name, propName)
-		}
-		return match
+		return renderProp(c, parts[1], &idx)
 	})
 }
 
+// renderProp substitutes @prop:propName. A component prop becomes an include
+// placeholder numbered from *idx; a missing prop is left as written.
+func renderProp(c *HTMLComponent, propName string, idx *int) string {
+	if value, exists := c.Props[propName]; exists {
+		switch v := value.(type) {
+		case Component:
+			placeholder := fmt.Sprintf("prop-%s-%d", propName, *idx)
+			*idx++
+			c.AddDependency(placeholder, v)
+			return fmt.Sprintf("@include:%s", placeholder)
+		default:
+			return escapeValue(v)
+		}
+	}
+	if DevMode {
+		Log().Warn("component %s missing prop '%s'", c.Name, propName)
+	}
+	return "@prop:" + propName
+}
+
+// renderRawProp substitutes @rawprop:propName unescaped.
+func renderRawProp(c *HTMLComponent, propName string) string {
+	if value, exists := c.Props[propName]; exists {
+		return fmt.Sprintf("%v", value)
+	}
+	return "@rawprop:" + propName
+}
+
 func replacePluginPlaceholders(template string) string {
 	varRegex := rePluginVar
 	template = varRegex.ReplaceAllStringFunc(template, func(match string) string {
@@ -986,48 +1029,12 @@ func evaluateCondition(condition string, c *HTMLComponent) (bool, []ConditionDep
 
 	dependencies := extractDependencies(expr)
 
-	lookup := func(name string) (any, bool) {
-		if strings.HasPrefix(name, "store:") {
-			parts := strings.Split(strings.TrimPrefix(name, "store:"), ".")
-			if len(parts) == 3 {
-				store := state.GlobalStoreManager.GetStore(parts[0], parts[1])
-				if store != nil {
-					return store.Get(parts[2]), true
-				}
-			}
-			return nil, false
-		}
-		if strings.HasPrefix(name, "signal:") {
-			sigName := strings.TrimPrefix(name, "signal:")
-			if prop, ok := c.Props[sigName]; ok {
-				if sig, ok := prop.(interface{ Read() any }); ok {
-					return sig.Read(), true
-				}
-			}
-			return nil, false
-		}
-		if strings.HasPrefix(name, "prop:") {
-			propName := strings.TrimPrefix(name, "prop:")
-			if v, ok := c.Props[propName]; ok {
-				return v, true
-			}
-			return nil, false
-		}
-		if v, ok := c.Props[name]; ok {
-			if sig, ok := v.(interface{ Read() any }); ok {
-				return sig.Read(), true
-			}
-			return v, true
-		}
-		return nil, false
-	}
-
-	result, err := rtmleval.Bool(expr, lookup)
+	v, err := rtmlast.Eval(rtmlast.ParseExpr(expr), templateEnv(c, nil, true))
 	if err != nil {
-		Log().Debug("Condition evaluation error: %v", err)
+		reportTemplateError(c, expr, err)
 		return false, dependencies
 	}
-	return result, dependencies
+	return rtmlast.Truthy(v), dependencies
 }
 
 func extractDependencies(expr string) []ConditionDependency {
@@ -1065,28 +1072,8 @@ func updateStoreBindings(c *HTMLComponent, module, storeName, key string, newVal
 
 	placeholder := fmt.Sprintf("@store:%s.%s.%s:w", module, storeName, key)
 
-	// Update value-based inputs and selects
-	inputSelector := fmt.Sprintf(`input[value="%s"], select[value="%s"]`, placeholder, placeholder)
-	inputs := element.Call("querySelectorAll", inputSelector)
-	for i := 0; i < inputs.Length(); i++ {
-		input := inputs.Index(i)
-		input.Set("value", fmt.Sprintf("%v", newValue))
-	}
-
-	// Update checkboxes bound via checked attribute
-	checkedSelector := fmt.Sprintf(`input[checked="%s"]`, placeholder)
-	checks := element.Call("querySelectorAll", checkedSelector)
-	for i := 0; i < checks.Length(); i++ {
-		chk := checks.Index(i)
-		switch v := newValue.(type) {
-		case bool:
-			chk.Set("checked", v)
-		case string:
-			chk.Set("checked", strings.ToLower(v) == "true")
-		default:
-			chk.Set("checked", newValue != nil)
-		}
-	}
+	// Update bound inputs, selects, checkboxes and radios
+	dom.UpdateBoundInputs(element.Value, placeholder, newValue)
 
 	// Update textareas where placeholder is in content
 	textareas := element.Call("querySelectorAll", "textarea")
@@ -1115,28 +1102,8 @@ func updateSignalBindings(c *HTMLComponent, name string, newValue any) {
 
 	placeholder := fmt.Sprintf("@signal:%s:w", name)
 
-	// Update value-based inputs and selects
-	inputSelector := fmt.Sprintf(`input[value="%s"], select[value="%s"]`, placeholder, placeholder)
-	inputs := element.Call("querySelectorAll", inputSelector)
-	for i := 0; i < inputs.Length(); i++ {
-		input := inputs.Index(i)
-		input.Set("value", fmt.Sprintf("%v", newValue))
-	}
-
-	// Update checkboxes
-	checkedSelector := fmt.Sprintf(`input[checked="%s"]`, placeholder)
-	checks := element.Call("querySelectorAll", checkedSelector)
-	for i := 0; i < checks.Length(); i++ {
-		chk := checks.Index(i)
-		switch v := newValue.(type) {
-		case bool:
-			chk.Set("checked", v)
-		case string:
-			chk.Set("checked", strings.ToLower(v) == "true")
-		default:
-			chk.Set("checked", newValue != nil)
-		}
-	}
+	// Update bound inputs, selects, checkboxes and radios
+	dom.UpdateBoundInputs(element.Value, placeholder, newValue)
 
 	// Update textareas with placeholder in content
 	textareas := element.Call("querySelectorAll", "textarea")
@@ -1274,6 +1241,7 @@ func updateConditionBindings(c *HTMLComponent, conditionID string) {
 
 	dom.BindStoreInputsForComponent(c.ID, node)
 	dom.BindSignalInputs(c.ID, node)
+	syncDirectives(c)
 }
 
 func updateConditionsForStoreVariable(c *HTMLComponent, module, storeName, key string) {
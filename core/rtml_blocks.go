@@ -0,0 +1,319 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// rtmlBlock is an open @switch or @await block while expandBlocks walks a
+// template.
+type rtmlBlock struct {
+	kind     string // "switch" or "await"
+	subject  string // switch subject, or the resource name of an await
+	open     bool   // the @if of the block has been written
+	section  string // current await section: "pending", "then" or "catch"
+	alias    string // name given to the current @then or @catch section
+	bad      bool   // the await names no resource; every section stays hidden
+	id       int    // position of the await among the awaits of the template
+	branches []int  // output lines opening each section of the await
+}
+
+// expandBlocks rewrites @switch and @await blocks into the @if chains
+// replaceConditionals renders, so they mount, track their dependencies and
+// swap branches the same way:
+//
+//	@switch:role        @if:(role) == ('admin')
+//	@case:'admin'   =>  ...
+//	...                 @else-if:(role) == ('editor')
+//	@case:'editor'      ...
+//	...                 @else
+//	@default            ...
+//	...                 @endif
+//	@endswitch
+//
+// An @await section becomes a test of the resource status. The names given
+// to @then and @catch are bound as props reading its value and error under
+// a name of the block's own, and the references of their section renamed to
+// it, so they do not reach past the section or replace a prop of the same
+// name, nested awaits of the same resource included.
+func expandBlocks(template string, c *HTMLComponent) string {
+	if !strings.Contains(template, "@switch:") && !strings.Contains(template, "@await:") {
+		return template
+	}
+	var stack []*rtmlBlock
+	awaits := 0
+	lines := strings.Split(template, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		var top *rtmlBlock
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if names := awaitAliases(stack); names != nil && !isAwaitMarker(trimmed) {
+			line = renameSlotRefs(line, names)
+			trimmed = strings.TrimSpace(line)
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "@switch:"):
+			stack = append(stack, &rtmlBlock{kind: "switch", subject: strings.TrimSpace(strings.TrimPrefix(trimmed, "@switch:"))})
+			continue
+		case strings.HasPrefix(trimmed, "@await:"):
+			b := &rtmlBlock{kind: "await", subject: strings.TrimSpace(strings.TrimPrefix(trimmed, "@await:")), open: true, section: "pending", id: awaits}
+			awaits++
+			b.bad = !awaitResource(c, b.subject)
+			b.branches = append(b.branches, len(out))
+			stack = append(stack, b)
+			line = "@if:" + awaitCondition(b)
+		case top == nil:
+		case top.kind == "switch" && strings.HasPrefix(trimmed, "@case:"):
+			value := strings.TrimSpace(strings.TrimPrefix(trimmed, "@case:"))
+			line = openBranch(top, fmt.Sprintf("(%s) == (%s)", top.subject, value))
+		case top.kind == "switch" && trimmed == "@default":
+			line = openBranch(top, "")
+		case top.kind == "switch" && trimmed == "@endswitch":
+			stack = stack[:len(stack)-1]
+			if !top.open {
+				continue
+			}
+			line = "@endif"
+		case top.kind == "switch" && !top.open:
+			// only whitespace belongs before the first case
+			continue
+		case top.kind == "await" && trimmed == "@pending":
+			if top.section == "pending" {
+				continue
+			}
+			top.section, top.alias = "pending", ""
+			top.branches = append(top.branches, len(out))
+			line = "@else-if:" + awaitCondition(top)
+		case top.kind == "await" && strings.HasPrefix(trimmed, "@then:"):
+			top.section = "then"
+			top.alias = bindAwaitAlias(c, top, strings.TrimSpace(strings.TrimPrefix(trimmed, "@then:")))
+			top.branches = append(top.branches, len(out))
+			line = "@else-if:" + awaitCondition(top)
+		case top.kind == "await" && strings.HasPrefix(trimmed, "@catch:"):
+			top.section = "catch"
+			top.alias = bindAwaitAlias(c, top, strings.TrimSpace(strings.TrimPrefix(trimmed, "@catch:")))
+			top.branches = append(top.branches, len(out))
+			line = "@else-if:" + awaitCondition(top)
+		case top.kind == "await" && trimmed == "@endawait":
+			stack = stack[:len(stack)-1]
+			if !top.bad {
+				watchAwait(c, top, out)
+			}
+			line = "@endif"
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// openBranch returns the conditional line starting the next branch of a
+// switch; an empty condition is the @default branch.
+func openBranch(b *rtmlBlock, condition string) string {
+	if !b.open {
+		b.open = true
+		if condition == "" {
+			return "@if:true"
+		}
+		return "@if:" + condition
+	}
+	if condition == "" {
+		return "@else"
+	}
+	return "@else-if:" + condition
+}
+
+// awaitCondition is the condition under which the current section of an
+// await block shows.
+func awaitCondition(b *rtmlBlock) string {
+	if b.bad {
+		return "false"
+	}
+	switch b.section {
+	case "then":
+		return b.subject + ".status == 'ready'"
+	case "catch":
+		return b.subject + ".status == 'error'"
+	}
+	return b.subject + ".status is not 'ready' and " + b.subject + ".status is not 'error'"
+}
+
+// awaitResource reports whether the prop name holds a resource.
+func awaitResource(c *HTMLComponent, name string) bool {
+	if _, ok := c.Props[name].(state.AnyResource); !ok {
+		reportTemplateError(c, name, fmt.Errorf("@await:%s: %s is not a resource", name, name))
+		return false
+	}
+	return true
+}
+
+// watchAwait swaps the section of the await block b when the status of its
+// resource changes. out holds the expanded template up to the end of the
+// block. The section a status change swaps in was rendered before the value
+// or error it shows existed, so it is rendered again first, or the whole
+// component when it is not self-contained.
+func watchAwait(c *HTMLComponent, b *rtmlBlock, out []string) {
+	conditions := make([]string, len(b.branches))
+	sections := make([]string, len(b.branches))
+	for i, start := range b.branches {
+		end := len(out)
+		if i+1 < len(b.branches) {
+			end = b.branches[i+1]
+		}
+		conditions[i] = out[start]
+		sections[i] = strings.Join(out[start+1:end], "\n")
+	}
+	res := c.Props[b.subject].(state.AnyResource)
+	last := res.Status()
+	unsub := state.Effect(func() func() {
+		if status := res.Status(); status != last {
+			last = status
+			state.Untracked(func() bool {
+				if !patchAwait(c, conditions, sections) {
+					dom.UpdateMountedDOM(c.ID, c.RenderFresh())
+				}
+				return true
+			})
+		}
+		return nil
+	})
+	c.unsubscribes.Add(unsub)
+}
+
+// patchAwait renders the sections of the await block mounted with conditions
+// again and swaps in the one its status selects. It reports false when a
+// section needs the whole pipeline, or the block cannot be told apart from
+// another await of the same resource.
+func patchAwait(c *HTMLComponent, conditions, sections []string) bool {
+	for _, section := range sections {
+		if !selfContained(section) {
+			return false
+		}
+	}
+	conditionID := ""
+	for id, content := range c.conditionContents {
+		if !sameConditions(content, conditions) {
+			continue
+		}
+		if conditionID != "" {
+			return false
+		}
+		conditionID = id
+	}
+	if conditionID == "" {
+		return false
+	}
+	branches := c.conditionContents[conditionID].Branches
+	for i, section := range sections {
+		branches[i].Content = c.renderSectionFragment(section)
+	}
+	updateConditionBindings(c, conditionID)
+	return true
+}
+
+// sameConditions reports whether content holds the branches of conditions.
+func sameConditions(content ConditionContent, conditions []string) bool {
+	if len(content.Branches) != len(conditions) {
+		return false
+	}
+	for i, br := range content.Branches {
+		if br.Condition != conditions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// renderSectionFragment runs the substitutions a render applies after the
+// blocks are expanded over one self-contained await section.
+func (c *HTMLComponent) renderSectionFragment(section string) string {
+	for key, value := range c.Props {
+		section = strings.ReplaceAll(section, fmt.Sprintf("{{%s}}", key), escapeValue(value))
+	}
+	section = replaceStorePlaceholders(section, c)
+	section = replaceSignalPlaceholders(section, c)
+	section = replaceExprInClassAttr(section, c)
+	section = replaceExprPlaceholders(section, c)
+	section = replaceInterpolations(section, c)
+	section = replacePropPlaceholders(section, c)
+	section = replacePluginPlaceholders(section)
+	if len(c.hostComponentNames()) > 0 {
+		section = replaceHostPlaceholders(section, c)
+	}
+	section = replaceDirectives(section, c)
+	section = replaceEventHandlers(section)
+	section = replaceConstructors(section)
+	return minifyInline(section)
+}
+
+// bindAwaitAlias binds the value or the error of the resource of b, for the
+// @then or @catch section it is in, as a prop named after the block. It
+// returns that name, or "" when the section names nothing.
+func bindAwaitAlias(c *HTMLComponent, b *rtmlBlock, name string) string {
+	if b.bad || name == "" {
+		return ""
+	}
+	c.Props[awaitProp(b)] = awaitAlias{res: c.Props[b.subject].(state.AnyResource), isErr: b.section == "catch"}
+	return name
+}
+
+// awaitAliases maps the names of the open @then and @catch sections of stack
+// to their props; an inner section shadows an outer one.
+func awaitAliases(stack []*rtmlBlock) map[string]string {
+	var names map[string]string
+	for _, b := range stack {
+		if b.kind != "await" || b.alias == "" {
+			continue
+		}
+		if names == nil {
+			names = make(map[string]string)
+		}
+		names[b.alias] = awaitProp(b)
+	}
+	return names
+}
+
+// awaitProp is the prop the current @then or @catch section of b reads its
+// name from. The position of the block keeps nested awaits of one resource
+// apart.
+func awaitProp(b *rtmlBlock) string {
+	return fmt.Sprintf("_await_%s_%d_%s", b.subject, b.id, b.section)
+}
+
+// isAwaitMarker reports whether line opens, switches or ends an await
+// section; those lines name the resource, not the aliases.
+func isAwaitMarker(line string) bool {
+	return line == "@pending" || line == "@endawait" ||
+		strings.HasPrefix(line, "@then:") || strings.HasPrefix(line, "@catch:") || strings.HasPrefix(line, "@await:")
+}
+
+// awaitAlias is the @then value or @catch error of an @await block. It reads
+// through to the resource on every use, so expressions over it see the
+// current result and track it like a signal.
+type awaitAlias struct {
+	res   state.AnyResource
+	isErr bool
+}
+
+// Read returns the resource value, or its error for a @catch alias.
+func (a awaitAlias) Read() any {
+	if a.isErr {
+		return a.res.Error()
+	}
+	return a.res.ReadValue()
+}
+
+// String renders the alias as its current result, for {{name}}.
+func (a awaitAlias) String() string {
+	if v := a.Read(); v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
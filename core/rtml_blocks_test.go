@@ -0,0 +1,113 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// The @then and @catch names reach their own section only, so a prop of the
+// same name keeps its value outside it, and @then naming the resource itself
+// does not replace it.
+func TestAwaitAliasesFollowStatus(t *testing.T) {
+	results := make(chan error, 2)
+	res := state.NewResource(func(context.Context) (string, error) {
+		if err := <-results; err != nil {
+			return "", err
+		}
+		return "Ada", nil
+	}, state.WithoutImmediateLoad())
+	tpl := "<root>\n<h1>{{title}}</h1>\n@await:user\n@pending\n<p>wait {{title}}</p>\n@then:title\n<p>hi {{title}}</p>\n@catch:user\n<p>{{user}}</p>\n@endawait\n</root>"
+	c := NewHTMLComponent("AwaitAliases", []byte(tpl), map[string]any{"user": res, "title": "keep"})
+	c.Init(nil)
+	render := func() string {
+		t.Helper()
+		html := c.RenderFresh()
+		if !strings.Contains(html, "<h1>keep</h1>") {
+			t.Fatalf("alias leaked out of its section: %s", html)
+		}
+		return html
+	}
+	waitFor := func(status state.ResourceStatus) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); res.Status() != status; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("status = %v, want %v", res.Status(), status)
+			}
+		}
+	}
+
+	res.Load(context.Background())
+	if html := render(); !strings.Contains(html, "wait keep") {
+		t.Fatalf("pending section: %s", html)
+	}
+	results <- nil
+	waitFor(state.ResourceReady)
+	if html := render(); !strings.Contains(html, "hi Ada") {
+		t.Fatalf("ready section: %s", html)
+	}
+	res.Load(context.Background())
+	results <- errors.New("offline")
+	waitFor(state.ResourceError)
+	if html := render(); !strings.Contains(html, "<p>offline</p>") {
+		t.Fatalf("error section: %s", html)
+	}
+	if v, ok := c.Props["title"].(string); !ok || v != "keep" {
+		t.Fatalf("title prop = %#v", c.Props["title"])
+	}
+	if _, ok := c.Props["user"].(state.AnyResource); !ok {
+		t.Fatalf("user prop = %#v", c.Props["user"])
+	}
+}
+
+// A status change swaps the @await section alone: the rest of the component
+// keeps its nodes.
+func TestAwaitSwapsOnlyItsSection(t *testing.T) {
+	if dom.ByID("app").IsNull() {
+		host := dom.CreateElement("div")
+		host.SetAttr("id", "app")
+		dom.Doc().Body().AppendChild(host)
+	}
+	res := state.NewResource[string](nil, state.WithoutImmediateLoad())
+	tpl := "<root>\n<h1>title</h1>\n@await:user\n@pending\n<p>wait</p>\n@then:name\n<p id=\"name\">hi {{name}}</p>\n@endawait\n</root>"
+	c := NewHTMLComponent("AwaitSwap", []byte(tpl), map[string]any{"user": res})
+	c.SetComponent(c)
+	c.Init(nil)
+	dom.UpdateDOM(c.GetID(), c.Render())
+	c.Mount()
+	defer c.Unmount()
+
+	root := dom.ComponentRoot(c.GetID())
+	root.Query("h1").Value.Set("kept", true)
+	res.Mutate("Ada")
+	if html := root.HTML(); !strings.Contains(html, "hi Ada") || strings.Contains(html, "wait") {
+		t.Fatalf("ready section not swapped in: %s", html)
+	}
+	if !root.Query("h1").Value.Get("kept").Truthy() {
+		t.Fatal("a status change re-rendered the whole component")
+	}
+}
+
+// Nested awaits of one resource bind their names under props of their own.
+func TestNestedAwaitsKeepTheirNames(t *testing.T) {
+	nested := state.NewResource[string](nil, state.WithoutImmediateLoad())
+	nested.Mutate("x")
+	tpl := "<root>\n@await:user\n@then:a\n@await:user\n@catch:b\n@then:b\n<i>{{a}}{{b}}</i>\n@endawait\n@endawait\n</root>"
+	n := NewHTMLComponent("AwaitNested", []byte(tpl), map[string]any{"user": nested})
+	n.Init(nil)
+	if html := n.Render(); !strings.Contains(html, "<i>xx</i>") {
+		t.Fatalf("nested sections: %s", html)
+	}
+	for _, prop := range []string{"_await_user_0_then", "_await_user_1_then"} {
+		if _, ok := n.Props[prop].(awaitAlias); !ok {
+			t.Fatalf("no prop %s: %v", prop, n.Props)
+		}
+	}
+}
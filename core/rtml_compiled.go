@@ -0,0 +1,431 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rfwlab/rfw/v2/rtmlast"
+)
+
+// CompiledTemplate is a template renderer generated ahead of time by the rtml
+// build plugin. Render writes the markup and registers the bindings the
+// interpreter would produce for the template whose rtmlast.Fingerprint is
+// Hash.
+type CompiledTemplate struct {
+	// Hash is the fingerprint of the template source.
+	Hash string
+	// File is the template file relative to its package, named in template
+	// errors of components that did not set one with WithTemplateFile.
+	File string
+	// Render writes the template through r.
+	Render func(r *TemplateRenderer)
+}
+
+var compiledTemplates = struct {
+	sync.RWMutex
+	byHash   map[string]*CompiledTemplate
+	disabled bool
+}{byHash: make(map[string]*CompiledTemplate)}
+
+// RegisterCompiledTemplate makes tpl the renderer of every component whose
+// template source matches tpl.Hash. Generated code calls it from init.
+func RegisterCompiledTemplate(tpl CompiledTemplate) {
+	compiledTemplates.Lock()
+	compiledTemplates.byHash[tpl.Hash] = &tpl
+	compiledTemplates.Unlock()
+}
+
+// EnableCompiledTemplates turns compiled rendering on or off. While it is off
+// every component goes through the interpreter, which helps to tell whether a
+// rendering problem comes from generated code. It is on by default.
+func EnableCompiledTemplates(enabled bool) {
+	compiledTemplates.Lock()
+	compiledTemplates.disabled = !enabled
+	compiledTemplates.Unlock()
+}
+
+// compiledTemplateFile returns the file of the compiled template matching
+// source, or "" when there is none. It ignores EnableCompiledTemplates: the
+// name is right whichever path renders the template.
+func compiledTemplateFile(source string) string {
+	compiledTemplates.RLock()
+	defer compiledTemplates.RUnlock()
+	if len(compiledTemplates.byHash) == 0 {
+		return ""
+	}
+	if tpl := compiledTemplates.byHash[rtmlast.Fingerprint(source)]; tpl != nil {
+		return tpl.File
+	}
+	return ""
+}
+
+// compiledTemplate returns the renderer registered for the component's
+// template, or nil when the interpreter has to render it. The lookup hashes the
+// source, so its result is kept until the template changes.
+func (c *HTMLComponent) compiledTemplate() *CompiledTemplate {
+	compiledTemplates.RLock()
+	defer compiledTemplates.RUnlock()
+	if compiledTemplates.disabled || len(compiledTemplates.byHash) == 0 {
+		return nil
+	}
+	if !c.compiledResolved || c.compiledSource != c.Template {
+		c.compiled = compiledTemplates.byHash[rtmlast.Fingerprint(c.Template)]
+		c.compiledSource = c.Template
+		c.compiledResolved = true
+	}
+	return c.compiled
+}
+
+// renderCompiled renders the component through its compiled template. It
+// reports false when there is none or when the template met a value it can
+// only render through the interpreter; the bindings it registered are then
+// released again.
+func (c *HTMLComponent) renderCompiled() (string, bool) {
+	tpl := c.compiledTemplate()
+	if tpl == nil {
+		return "", false
+	}
+	var out strings.Builder
+	r := &TemplateRenderer{c: c, out: &out}
+	c.condSeq = 0
+	c.forSeq = 0
+	tpl.Render(r)
+	if r.fallback {
+		c.unsubscribes.Run()
+		return "", false
+	}
+	// the passes the interpreter finishes with run on the output as well, but
+	// only when it holds something they could match
+	rendered := replaceDirectives(out.String(), c)
+	if strings.Contains(rendered, "@") {
+		rendered = replaceEventHandlers(rendered)
+		rendered = replaceIncludePlaceholders(c, rendered)
+	}
+	if strings.Contains(rendered, "[") {
+		rendered = replaceConstructors(rendered)
+	}
+	if strings.Contains(rendered, "<script") || strings.Contains(rendered, "<style") {
+		rendered = minifyInline(rendered)
+	}
+	return rendered, true
+}
+
+// TemplateRenderer is what compiled templates write through. Every method does
+// what the interpreter does for the directive it stands for, so both render
+// the same markup and register the same bindings.
+type TemplateRenderer struct {
+	c        *HTMLComponent
+	out      *strings.Builder
+	row      *templateRow
+	exprSeq  int
+	propSeq  int
+	fallback bool
+}
+
+// TemplateBranch is one branch of an @if block.
+type TemplateBranch struct {
+	// Condition is the directive as written, "@if:cond" or "@else-if:cond",
+	// and empty for @else.
+	Condition string
+	Render    func(r *TemplateRenderer)
+}
+
+// TemplateLoop is an @for block.
+type TemplateLoop struct {
+	Aliases []string
+	// Collection is a store key (store:module.store.key), a prop name or an
+	// a..b range.
+	Collection string
+	// Body is the loop body as written, used to patch rows in place when a
+	// store collection changes.
+	Body string
+	// Row renders the body for the current item.
+	Row func(r *TemplateRenderer)
+}
+
+type templateRowKind int
+
+const (
+	rowRange templateRowKind = iota
+	rowItem
+	rowEntry
+)
+
+// templateRow is the loop item the body is being rendered for.
+type templateRow struct {
+	kind     templateRowKind
+	alias    string
+	keyAlias string
+	pair     bool
+	index    int
+	key      any
+	value    any
+	include  string
+	loopID   string
+}
+
+// Text writes static markup.
+func (r *TemplateRenderer) Text(s string) { r.out.WriteString(s) }
+
+// RootAttrs writes the attributes the interpreter adds to the <root element.
+func (r *TemplateRenderer) RootAttrs() {
+	fmt.Fprintf(r.out, ` data-component-id="%s"`, r.c.ID)
+}
+
+// Store writes @store:module.store.key.
+func (r *TemplateRenderer) Store(module, storeName, key string) {
+	if value, ok := bindStoreKey(r.c, module, storeName, key); ok {
+		fmt.Fprintf(r.out, `<span data-store="%s.%s.%s">%s</span>`, module, storeName, key, escapeValue(value))
+		return
+	}
+	r.missingStore(module, storeName, key)
+	fmt.Fprintf(r.out, "@store:%s.%s.%s", module, storeName, key)
+}
+
+// StoreInput writes @store:module.store.key:w, which the DOM binds as a
+// two-way input.
+func (r *TemplateRenderer) StoreInput(module, storeName, key string) {
+	if _, ok := bindStoreKey(r.c, module, storeName, key); !ok {
+		r.missingStore(module, storeName, key)
+	}
+	fmt.Fprintf(r.out, "@store:%s.%s.%s:w", module, storeName, key)
+}
+
+// RawStore writes @rawstore:module.store.key.
+func (r *TemplateRenderer) RawStore(module, storeName, key string) {
+	value, ok := bindStoreKey(r.c, module, storeName, key)
+	if !ok {
+		fmt.Fprintf(r.out, "@rawstore:%s.%s.%s", module, storeName, key)
+		return
+	}
+	fmt.Fprintf(r.out, `<span data-store-raw="%s.%s.%s">%v</span>`, module, storeName, key, value)
+}
+
+func (r *TemplateRenderer) missingStore(module, storeName, key string) {
+	if DevMode {
+		Log().Warn("store %s.%s not found for key '%s' in component %s", module, storeName, key, r.c.Name)
+	}
+}
+
+// Signal writes @signal:name.
+func (r *TemplateRenderer) Signal(name string) {
+	if val, ok := bindSignal(r.c, name); ok {
+		fmt.Fprintf(r.out, `<span data-signal="%s">%s</span>`, name, escapeValue(val))
+		return
+	}
+	r.missingSignal(name)
+	r.out.WriteString("@signal:" + name)
+}
+
+// SignalInput writes @signal:name:w.
+func (r *TemplateRenderer) SignalInput(name string) {
+	if _, ok := bindSignal(r.c, name); !ok {
+		r.missingSignal(name)
+	}
+	r.out.WriteString("@signal:" + name + ":w")
+}
+
+func (r *TemplateRenderer) missingSignal(name string) {
+	if DevMode {
+		Log().Warn("signal '%s' not found in component %s", name, r.c.Name)
+	}
+}
+
+// Expr writes @expr:source.
+func (r *TemplateRenderer) Expr(source string) {
+	exprID := fmt.Sprintf("expr-%d", r.exprSeq)
+	r.exprSeq++
+	r.out.WriteString(bindExpr(r.c, exprID, source))
+}
+
+// Prop writes @prop:path. Inside a loop the row's aliases are resolved first.
+func (r *TemplateRenderer) Prop(path string) {
+	if r.row != nil {
+		if s, ok := r.row.substitute(path, false); ok {
+			r.out.WriteString(s)
+			return
+		}
+	}
+	name, rest := splitPropPath(path)
+	r.out.WriteString(renderProp(r.c, name, &r.propSeq) + rest)
+}
+
+// RawProp writes @rawprop:path.
+func (r *TemplateRenderer) RawProp(path string) {
+	if r.row != nil {
+		if s, ok := r.row.substitute(path, true); ok {
+			r.out.WriteString(s)
+			return
+		}
+	}
+	name, rest := splitPropPath(path)
+	r.out.WriteString(renderRawProp(r.c, name) + rest)
+}
+
+// splitPropPath splits a dotted path into the prop name and the text after it,
+// which the component-level substitution leaves alone.
+func splitPropPath(path string) (string, string) {
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+// substitute resolves path against the row's aliases the way the loop
+// expansion does, reporting false when no alias applies.
+func (row *templateRow) substitute(path string, raw bool) (string, bool) {
+	switch row.kind {
+	case rowRange:
+		if raw || !strings.HasPrefix(path, row.alias) {
+			return "", false
+		}
+		return strconv.Itoa(row.index) + path[len(row.alias):], true
+	case rowEntry:
+		if !raw && strings.HasPrefix(path, row.keyAlias) {
+			return escapeValue(row.key) + path[len(row.keyAlias):], true
+		}
+		if !row.pair {
+			return "", false
+		}
+	}
+	switch v := row.value.(type) {
+	case map[string]any:
+		field, ok := strings.CutPrefix(path, row.alias+".")
+		if !ok {
+			return "", false
+		}
+		value, ok := resolveNestedKey(v, field)
+		if !ok {
+			return "", false
+		}
+		if raw {
+			return fmt.Sprintf("%v", value), true
+		}
+		return escapeValue(value), true
+	case Component:
+		if raw || !strings.HasPrefix(path, row.alias) {
+			return "", false
+		}
+		return "@include:" + row.include + path[len(row.alias):], true
+	default:
+		if !strings.HasPrefix(path, row.alias) {
+			return "", false
+		}
+		if raw {
+			if row.kind != rowEntry {
+				return "", false
+			}
+			return fmt.Sprintf("%v", v) + path[len(row.alias):], true
+		}
+		return escapeValue(v) + path[len(row.alias):], true
+	}
+}
+
+// If writes an @if block.
+func (r *TemplateRenderer) If(branches ...TemplateBranch) {
+	conditions := make([]string, len(branches))
+	for i, br := range branches {
+		conditions[i] = br.Condition
+	}
+	conditionID := nextConditionID(r.c, conditions)
+	contents := make([]string, len(branches))
+	for i, br := range branches {
+		contents[i] = r.capture(br.Render)
+	}
+	r.out.WriteString(mountConditional(r.c, conditionID, conditions, contents))
+}
+
+func (r *TemplateRenderer) capture(render func(*TemplateRenderer)) string {
+	out := r.out
+	var sb strings.Builder
+	r.out = &sb
+	render(r)
+	r.out = out
+	return sb.String()
+}
+
+// For writes an @for block.
+func (r *TemplateRenderer) For(loop TemplateLoop) {
+	c := r.c
+	loopID := fmt.Sprintf("for-%s-%d", c.ID, c.forSeq)
+	c.forSeq++
+	outer := r.row
+	defer func() { r.row = outer }()
+
+	if strings.Contains(loop.Collection, "..") {
+		start, end, ok := resolveForRange(c, loop.Collection)
+		if !ok {
+			r.fallback = true
+			return
+		}
+		for i := start; i <= end; i++ {
+			r.row = &templateRow{kind: rowRange, alias: loop.Aliases[0], index: i, key: i}
+			loop.Row(r)
+		}
+		return
+	}
+
+	collection, ok := resolveForCollection(c, loop.Collection, loopID, loop.Aliases, loop.Body)
+	if !ok {
+		r.fallback = true
+		return
+	}
+	switch col := collection.(type) {
+	case nil:
+		r.out.WriteString(forAnchor(loopID))
+	case []any:
+		r.out.WriteString(forAnchor(loopID))
+		alias := loop.Aliases[0]
+		for idx, item := range col {
+			row := &templateRow{kind: rowItem, alias: alias, index: idx, key: idx, value: item, loopID: loopID}
+			if comp, ok := item.(Component); ok {
+				row.include = fmt.Sprintf("for-%s-%d", alias, idx)
+				c.AddDependency(row.include, comp)
+			}
+			r.row = row
+			loop.Row(r)
+		}
+	case map[string]any:
+		r.out.WriteString(forAnchor(loopID))
+		keyAlias := loop.Aliases[0]
+		valAlias := keyAlias
+		pair := len(loop.Aliases) > 1
+		if pair {
+			valAlias = loop.Aliases[1]
+		}
+		keys := make([]string, 0, len(col))
+		for k := range col {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for idx, k := range keys {
+			v := col[k]
+			row := &templateRow{kind: rowEntry, alias: valAlias, keyAlias: keyAlias, pair: pair, index: idx, key: k, value: v, loopID: loopID}
+			if comp, ok := v.(Component); ok && pair {
+				row.include = fmt.Sprintf("for-%s-%d", valAlias, idx)
+				c.AddDependency(row.include, comp)
+			}
+			r.row = row
+			loop.Row(r)
+		}
+	default:
+		r.fallback = true
+	}
+}
+
+// RowMarkers writes the key and loop attributes of a loop row's first element.
+func (r *TemplateRenderer) RowMarkers() {
+	row := r.row
+	if row == nil {
+		return
+	}
+	fmt.Fprintf(r.out, ` data-key="%v"`, row.key)
+	if row.loopID != "" {
+		fmt.Fprintf(r.out, ` data-for="%s"`, row.loopID)
+	}
+}
@@ -0,0 +1,187 @@
+//go:build js && wasm
+
+// Code generated by rtml plugin. DO NOT EDIT.
+
+package core_test
+
+import "github.com/rfwlab/rfw/v2/core"
+
+func init() {
+	// testdata/rtml/bindings.rtml
+	core.RegisterCompiledTemplate(core.CompiledTemplate{Hash: "c7c2c94ce85e28d66972ccbda154c0ca2e12a31830b240f69ae57d86f14833d4", File: "testdata/rtml/bindings.rtml", Render: renderTestdataRtmlBindings})
+	// testdata/rtml/conditions.rtml
+	core.RegisterCompiledTemplate(core.CompiledTemplate{Hash: "e773013b9c5c7d49857cef852ea4fadf6747aecf5d8c91eea9d7d1f7a5e2d029", File: "testdata/rtml/conditions.rtml", Render: renderTestdataRtmlConditions})
+	// testdata/rtml/fallback.rtml
+	core.RegisterCompiledTemplate(core.CompiledTemplate{Hash: "33ff60fc088242c88d4a93529cf1e92dcb58a57a13d111b44469c923721574ef", File: "testdata/rtml/fallback.rtml", Render: renderTestdataRtmlFallback})
+	// testdata/rtml/loops.rtml
+	core.RegisterCompiledTemplate(core.CompiledTemplate{Hash: "4a79e41993b34eb72359c157c508ce6d738af7fa4f2e108d4d868e957bcd3de8", File: "testdata/rtml/loops.rtml", Render: renderTestdataRtmlLoops})
+	// testdata/rtml/unsupported.rtml is interpreted: {{prop}} interpolation is not compiled
+}
+
+func renderTestdataRtmlBindings(r *core.TemplateRenderer) {
+	r.Text("<root")
+	r.RootAttrs()
+	r.Text(" class=\"card\">\n  <h1>")
+	r.Prop("title")
+	r.Text("</h1>\n  <p>")
+	r.Store("aot", "s", "name")
+	r.Text(" and ")
+	r.RawStore("aot", "s", "html")
+	r.Text("</p>\n  <input value=\"")
+	r.StoreInput("aot", "s", "name")
+	r.Text("\">\n  <p>")
+	r.Signal("count")
+	r.Text(" clicks</p>\n  <input value=\"")
+	r.SignalInput("count")
+	r.Text("\">\n  <p>")
+	r.Expr("n * 2")
+	r.Text("</p>\n  <p>(")
+	r.Expr("n + 1")
+	r.Text(") | ")
+	r.Expr("title")
+	r.Text("</p>\n  <p>")
+	r.RawProp("markup")
+	r.Text(" ")
+	r.Prop("missing")
+	r.Text(" ")
+	r.Prop("title.more")
+	r.Text("</p>\n  <button @on:click:increment [btn]>Add</button>\n  <style>\n    .card { color: red; }\n  </style>\n</root>\n\n")
+}
+
+func renderTestdataRtmlConditions(r *core.TemplateRenderer) {
+	r.Text("<root")
+	r.RootAttrs()
+	r.Text(">\n")
+	r.If(
+		core.TemplateBranch{Condition: "@if:store:aot.s.count > 2", Render: func(r *core.TemplateRenderer) {
+			r.Text("    <p>many</p>\n")
+			r.If(
+				core.TemplateBranch{Condition: "@if:signal:count == 1", Render: func(r *core.TemplateRenderer) {
+					r.Text("    <em>one click</em>\n")
+				}},
+				core.TemplateBranch{Render: func(r *core.TemplateRenderer) {
+					r.Text("    <em>")
+					r.Signal("count")
+					r.Text(" clicks</em>\n")
+				}},
+			)
+		}},
+		core.TemplateBranch{Condition: "@else-if:store:aot.s.count == 2", Render: func(r *core.TemplateRenderer) {
+			r.Text("    <p>two</p>\n")
+		}},
+		core.TemplateBranch{Render: func(r *core.TemplateRenderer) {
+			r.Text("    <p>few: ")
+			r.Store("aot", "s", "count")
+			r.Text("</p>\n")
+		}},
+	)
+	r.Text("</root>\n\n")
+}
+
+func renderTestdataRtmlFallback(r *core.TemplateRenderer) {
+	r.Text("<root")
+	r.RootAttrs()
+	r.Text(">\n  <p>")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"x"},
+		Collection: "n",
+		Body:       " <span>@prop:x</span>",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text(" <span")
+			r.RowMarkers()
+			r.Text(">")
+			r.Prop("x")
+			r.Text("</span>")
+		},
+	})
+	r.Text("</p>\n  <p>")
+	r.Store("aot", "s", "name")
+	r.Text("</p>\n</root>\n\n")
+}
+
+func renderTestdataRtmlLoops(r *core.TemplateRenderer) {
+	r.Text("<root")
+	r.RootAttrs()
+	r.Text(">\n  <ul>\n    ")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"todo"},
+		Collection: "store:aot.s.todos",
+		Body:       "\n    <li class=\"todo\">@prop:todo.title by @prop:todo.owner.name @rawprop:todo.title\n      @if:store:aot.s.count > 2\n      <b>busy</b>\n      @endif\n    </li>\n    ",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text("\n    <li")
+			r.RowMarkers()
+			r.Text(" class=\"todo\">")
+			r.Prop("todo.title")
+			r.Text(" by ")
+			r.Prop("todo.owner.name")
+			r.Text(" ")
+			r.RawProp("todo.title")
+			r.Text("\n")
+			r.If(
+				core.TemplateBranch{Condition: "@if:store:aot.s.count > 2", Render: func(r *core.TemplateRenderer) {
+					r.Text("      <b>busy</b>\n")
+				}},
+			)
+			r.Text("    </li>\n    ")
+		},
+	})
+	r.Text("\n  </ul>\n  <dl>\n    ")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"key", "val"},
+		Collection: "store:aot.s.tags",
+		Body:       "\n    <dt>@prop:key</dt><dd>@prop:val @rawprop:val</dd>\n    ",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text("\n    <dt")
+			r.RowMarkers()
+			r.Text(">")
+			r.Prop("key")
+			r.Text("</dt><dd>")
+			r.Prop("val")
+			r.Text(" ")
+			r.RawProp("val")
+			r.Text("</dd>\n    ")
+		},
+	})
+	r.Text("\n  </dl>\n  <ol>\n    ")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"i"},
+		Collection: "1..3",
+		Body:       "\n    <li>item @prop:i of @prop:title</li>\n    ",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text("\n    <li")
+			r.RowMarkers()
+			r.Text(">item ")
+			r.Prop("i")
+			r.Text(" of ")
+			r.Prop("title")
+			r.Text("</li>\n    ")
+		},
+	})
+	r.Text("\n  </ol>\n  <p>")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"word"},
+		Collection: "words",
+		Body:       " <span>@prop:word</span>",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text(" <span")
+			r.RowMarkers()
+			r.Text(">")
+			r.Prop("word")
+			r.Text("</span>")
+		},
+	})
+	r.Text("</p>\n  <section>")
+	r.For(core.TemplateLoop{
+		Aliases:    []string{"child"},
+		Collection: "children",
+		Body:       " <div>@prop:child</div>",
+		Row: func(r *core.TemplateRenderer) {
+			r.Text(" <div")
+			r.RowMarkers()
+			r.Text(">")
+			r.Prop("child")
+			r.Text("</div>")
+		},
+	})
+	r.Text("</section>\n</root>\n\n")
+}
@@ -0,0 +1,89 @@
+//go:build js && wasm
+
+package core_test
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/rfwlab/rfw/v2/core"
+	"github.com/rfwlab/rfw/v2/rtmlast"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+//go:embed testdata/rtml/*.rtml
+var compiledFixtures embed.FS
+
+// component ids and the hashes derived from them differ between two
+// instances of one template
+var reHexID = regexp.MustCompile(`[0-9a-f]{40}`)
+
+func compiledFixtureProps() map[string]any {
+	child := func(text string) core.Component {
+		return core.NewHTMLComponent("AOTChild", []byte("<root><em>"+text+"</em></root>"), nil)
+	}
+	return map[string]any{
+		"title":    "<T>",
+		"markup":   "<b>raw</b>",
+		"n":        3,
+		"count":    state.NewSignal(1),
+		"words":    []any{"a", "<b>"},
+		"children": []core.Component{child("one"), child("two")},
+	}
+}
+
+func TestCompiledTemplatesMatchInterpreter(t *testing.T) {
+	st := state.NewStore("s", state.WithModule("aot"))
+	st.Set("name", "Ada & co")
+	st.Set("html", "<i>h</i>")
+	st.Set("count", 3)
+	st.Set("todos", []any{
+		map[string]any{"title": "<write>", "owner": map[string]any{"name": "Bo"}},
+		map[string]any{"title": "ship", "owner": map[string]any{}},
+	})
+	st.Set("tags", map[string]any{"b": "<two>", "a": 1})
+	defer core.EnableCompiledTemplates(true)
+
+	paths, err := fs.Glob(compiledFixtures, "testdata/rtml/*.rtml")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, p := range paths {
+		src, err := compiledFixtures.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := path.Base(p)
+		t.Run(name, func(t *testing.T) {
+			render := func(compiled bool) (string, *core.HTMLComponent) {
+				core.EnableCompiledTemplates(compiled)
+				c := core.NewHTMLComponent("AOT", src, compiledFixtureProps())
+				c.Init(nil)
+				return reHexID.ReplaceAllString(c.Render(), "ID"), c
+			}
+			want, _ := render(false)
+			got, c := render(true)
+			if compiled := core.UsesCompiledTemplate(c); compiled != (name != "unsupported.rtml") {
+				t.Fatalf("compiled = %v", compiled)
+			}
+			if got != want {
+				t.Fatalf("compiled render differs\n got: %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCompiledTemplateIgnoredWhenSourceChanges(t *testing.T) {
+	src, err := compiledFixtures.ReadFile("testdata/rtml/fallback.rtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := core.NewHTMLComponent("AOTEdited", append(src, ' '), map[string]any{"n": 1})
+	c.Init(nil)
+	if core.UsesCompiledTemplate(c) {
+		t.Fatalf("edited template %s used a stale renderer", rtmlast.Fingerprint(c.Template))
+	}
+}
@@ -52,16 +52,8 @@ func replaceForPlaceholders(template string, c *HTMLComponent) string {
 		c.forSeq++
 
 		if strings.Contains(expr, "..") {
-			rangeParts := strings.Split(expr, "..")
-			if len(rangeParts) != 2 {
-				return match
-			}
-			start, err := resolveNumber(rangeParts[0], c)
-			if err != nil {
-				return match
-			}
-			end, err := resolveNumber(rangeParts[1], c)
-			if err != nil {
+			start, end, ok := resolveForRange(c, expr)
+			if !ok {
 				return match
 			}
 			var result strings.Builder
@@ -73,63 +65,10 @@ func replaceForPlaceholders(template string, c *HTMLComponent) string {
 			return result.String()
 		}
 
-		var collection any
-		if strings.HasPrefix(expr, "store:") {
-			storeParts := strings.Split(strings.TrimPrefix(expr, "store:"), ".")
-			if len(storeParts) == 3 {
-				module, storeName, key := storeParts[0], storeParts[1], storeParts[2]
-				store := state.GlobalStoreManager.GetStore(module, storeName)
-				if store != nil {
-					collection = store.Get(key)
-					// a list that changes should cost its own rows, not a
-					// re-render of the whole component: patch the loop subtree
-					// when the body allows it and fall back otherwise
-					unsubscribe := store.OnChange(key, func(newValue any) {
-						if patchForLoop(c, loopID, aliases, loopContent, newValue) {
-							return
-						}
-						dom.UpdateMountedDOM(c.ID, c.RenderFresh())
-					})
-					c.unsubscribes.Add(unsubscribe)
-				} else {
-					return match
-				}
-			} else {
-				return match
-			}
-		} else if val, ok := c.Props[expr]; ok {
-			collection = val
-		} else {
+		collection, ok := resolveForCollection(c, expr, loopID, aliases, loopContent)
+		if !ok {
 			return match
 		}
-
-		switch col := collection.(type) {
-		case []Component:
-			tmp := make([]any, len(col))
-			for i, v := range col {
-				tmp[i] = v
-			}
-			collection = tmp
-		case []*HTMLComponent:
-			tmp := make([]any, len(col))
-			for i, v := range col {
-				tmp[i] = v
-			}
-			collection = tmp
-		case map[string]Component:
-			tmp := make(map[string]any, len(col))
-			for k, v := range col {
-				tmp[k] = v
-			}
-			collection = tmp
-		case map[string]*HTMLComponent:
-			tmp := make(map[string]any, len(col))
-			for k, v := range col {
-				tmp[k] = v
-			}
-			collection = tmp
-		}
-
 		rows, ok := expandForRows(c, aliases, loopContent, collection, loopID)
 		if !ok {
 			return match
@@ -138,6 +77,84 @@ func replaceForPlaceholders(template string, c *HTMLComponent) string {
 	})
 }
 
+// resolveForRange resolves the bounds of an a..b loop.
+func resolveForRange(c *HTMLComponent, expr string) (start, end int, ok bool) {
+	rangeParts := strings.Split(expr, "..")
+	if len(rangeParts) != 2 {
+		return 0, 0, false
+	}
+	start, err := resolveNumber(rangeParts[0], c)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = resolveNumber(rangeParts[1], c)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// resolveForCollection looks up the collection a loop iterates, subscribing to
+// it when it lives in a store. Component slices and maps are widened to []any
+// and map[string]any. It reports false when expr names nothing.
+func resolveForCollection(c *HTMLComponent, expr, loopID string, aliases []string, loopContent string) (any, bool) {
+	var collection any
+	if strings.HasPrefix(expr, "store:") {
+		storeParts := strings.Split(strings.TrimPrefix(expr, "store:"), ".")
+		if len(storeParts) != 3 {
+			return nil, false
+		}
+		module, storeName, key := storeParts[0], storeParts[1], storeParts[2]
+		store := state.GlobalStoreManager.GetStore(module, storeName)
+		if store == nil {
+			return nil, false
+		}
+		collection = store.Get(key)
+		// a list that changes should cost its own rows, not a re-render of
+		// the whole component: patch the loop subtree when the body allows it
+		// and fall back otherwise
+		unsubscribe := store.OnChange(key, func(newValue any) {
+			if patchForLoop(c, loopID, aliases, loopContent, newValue) {
+				return
+			}
+			dom.UpdateMountedDOM(c.ID, c.RenderFresh())
+		})
+		c.unsubscribes.Add(unsubscribe)
+	} else if val, ok := c.Props[expr]; ok {
+		collection = val
+	} else {
+		return nil, false
+	}
+
+	switch col := collection.(type) {
+	case []Component:
+		tmp := make([]any, len(col))
+		for i, v := range col {
+			tmp[i] = v
+		}
+		collection = tmp
+	case []*HTMLComponent:
+		tmp := make([]any, len(col))
+		for i, v := range col {
+			tmp[i] = v
+		}
+		collection = tmp
+	case map[string]Component:
+		tmp := make(map[string]any, len(col))
+		for k, v := range col {
+			tmp[k] = v
+		}
+		collection = tmp
+	case map[string]*HTMLComponent:
+		tmp := make(map[string]any, len(col))
+		for k, v := range col {
+			tmp[k] = v
+		}
+		collection = tmp
+	}
+	return collection, true
+}
+
 // forAnchor marks where a loop's rows begin. A template element carries no box
 // and no layout, so it sits inside a flex or grid container without disturbing
 // it, and an empty list still leaves the patch somewhere to insert into.
@@ -53,6 +53,7 @@ func patchForLoop(c *HTMLComponent, loopID string, aliases []string, loopContent
 	dom.BindSignalInputs(c.ID, root.Value)
 	dom.BindASTStoreInputs(c.ID, root.Value)
 	dom.BindASTSignalInputs(c.ID, root.Value)
+	syncDirectives(c)
 
 	if dom.TemplateHook != nil {
 		dom.TemplateHook(c.ID, rows)
@@ -63,12 +64,18 @@ func patchForLoop(c *HTMLComponent, loopID string, aliases []string, loopContent
 // incrementalForBody reports whether a loop body is self-contained enough to be
 // patched without a full render.
 func incrementalForBody(body string) bool {
+	return selfContained(body) && singleRootRow(body)
+}
+
+// selfContained reports whether markup renders through the substitutions of a
+// fragment alone: no components, conditionals, loops or slots of its own.
+func selfContained(body string) bool {
 	for _, directive := range []string{"@include:", "@if:", "@for:", "@slot", "rt-is="} {
 		if strings.Contains(body, directive) {
 			return false
 		}
 	}
-	return singleRootRow(body)
+	return true
 }
 
 // renderRowFragment runs the substitutions that normally follow the loop
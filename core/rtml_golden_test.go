@@ -65,6 +65,21 @@ func TestGoldenClassExprDirective(t *testing.T) {
 	expectGolden(t, got, want)
 }
 
+func TestGoldenExprCallsAndPipes(t *testing.T) {
+	RegisterTemplateFunc("goldenCurrency", func(v float64, code string) string {
+		return fmt.Sprintf("%.2f %s", v, code)
+	})
+	tpl := `<root><p>@expr:items[0] + len(items)</p><p>@expr:(price | goldenCurrency "EUR")|x</p><p>{{ price * 2 | goldenCurrency "USD" }}</p></root>`
+	props := map[string]any{"items": []string{"<a>", "b"}, "price": state.NewSignal(1.5)}
+	got, c := renderGolden(t, "GoldenExprCalls", tpl, props)
+	want := fmt.Sprintf(`<root data-component-id="%s"><p><span data-expr="expr-0">&lt;a&gt;2</span></p><p><span data-expr="expr-1">1.50 EUR</span>|x</p><p><span data-expr="interp-0">3.00 USD</span></p></root>
+`, c.ID)
+	expectGolden(t, got, want)
+	if c.exprContents["expr-1"] != `(price | goldenCurrency "EUR")` {
+		t.Fatalf("expr-1 source = %q", c.exprContents["expr-1"])
+	}
+}
+
 func TestGoldenPropDirectives(t *testing.T) {
 	tpl := `<root><p>{{p}}</p><p>@prop:p</p><p>@rawprop:m</p><p>@prop:missing</p></root>`
 	got, c := renderGolden(t, "GoldenProp", tpl, map[string]any{"p": "<u>p</u>", "m": "<u>m</u>"})
@@ -145,6 +160,35 @@ func TestGoldenConditionalDirective(t *testing.T) {
 	expectGolden(t, got, want)
 }
 
+// @switch renders as the @if chain comparing the subject with each case.
+func TestGoldenSwitchDirective(t *testing.T) {
+	tpl := "<root>\n@switch:role\n@case:'admin'\nAdmin\n@case:'editor'\nEditor\n@default\nGuest\n@endswitch\n</root>"
+	got, c := renderGolden(t, "GoldenSwitch", tpl, map[string]any{"role": "editor"})
+	conds := []string{"@if:(role) == ('admin')", "@else-if:(role) == ('editor')", ""}
+	condHash := sha256.Sum256([]byte(strings.Join(conds, "|")))
+	condID := fmt.Sprintf("cond-%x-0", condHash[:20])
+	want := fmt.Sprintf("<root data-component-id=\"%s\">\n<div data-condition=\"%s\">Editor\n</div></root>\n", c.ID, condID)
+	expectGolden(t, got, want)
+}
+
+// @await renders the section matching the resource status, with the @then
+// name bound to its value.
+func TestGoldenAwaitDirective(t *testing.T) {
+	res := state.NewResource[string](nil, state.WithoutImmediateLoad())
+	res.Mutate("<b>Ada</b>")
+	tpl := "<root>\n@await:user\n@pending\nLoading\n@then:name\n<p>{{name}}</p>\n@catch:err\n<p>{{err}}</p>\n@endawait\n</root>"
+	got, c := renderGolden(t, "GoldenAwait", tpl, map[string]any{"user": res})
+	conds := []string{
+		"@if:user.status is not 'ready' and user.status is not 'error'",
+		"@else-if:user.status == 'ready'",
+		"@else-if:user.status == 'error'",
+	}
+	condHash := sha256.Sum256([]byte(strings.Join(conds, "|")))
+	condID := fmt.Sprintf("cond-%x-0", condHash[:20])
+	want := fmt.Sprintf("<root data-component-id=\"%s\">\n<div data-condition=\"%s\"><p>&lt;b&gt;Ada&lt;/b&gt;</p>\n</div></root>\n", c.ID, condID)
+	expectGolden(t, got, want)
+}
+
 func TestGoldenEventDirectives(t *testing.T) {
 	tpl := `<root><button @on:click:save>s</button><button @click.stop:undo>u</button></root>`
 	got, c := renderGolden(t, "GoldenEvents", tpl, nil)
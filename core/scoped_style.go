@@ -0,0 +1,61 @@
+//go:build js && wasm
+
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rfwlab/rfw/v2/dom"
+)
+
+// scopedStyles holds the scope attribute whose style element each component
+// name uses, so its instances share one and a swapped template replaces it.
+var scopedStyles = struct {
+	sync.Mutex
+	byName map[string]string
+}{byName: make(map[string]string)}
+
+// UseScopedStyle applies css, the <style scoped> rules of the component
+// name as rtmlast.ScopeStyle rewrote them for attr. The first component of a
+// scope injects one style element into the document head. A new attr for
+// name, which an edited template brings, replaces the element of the old
+// one. Components call it from Init; a hot reload client calls it with the
+// scope and style of the dev server's template updates.
+func UseScopedStyle(name, attr, css string) {
+	scopedStyles.Lock()
+	defer scopedStyles.Unlock()
+	prev := scopedStyles.byName[name]
+	if prev == attr {
+		return
+	}
+	scopedStyles.byName[name] = attr
+	head := dom.Doc().Head()
+	if prev != "" && !scopeInUse(prev) {
+		if old := head.Query(scopedStyleSelector(prev)); !old.IsNull() {
+			old.Call("remove")
+		}
+	}
+	if attr == "" || !head.Query(scopedStyleSelector(attr)).IsNull() {
+		return
+	}
+	style := dom.CreateElement("style")
+	style.SetAttr("data-rfw-scope", attr)
+	style.SetText(css)
+	head.AppendChild(style)
+}
+
+// scopeInUse reports whether a component name still uses attr. Two
+// components with the same template share it.
+func scopeInUse(attr string) bool {
+	for _, used := range scopedStyles.byName {
+		if used == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func scopedStyleSelector(attr string) string {
+	return fmt.Sprintf(`style[data-rfw-scope="%s"]`, attr)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/rfwlab/rfw/v2/dom"
 	"github.com/rfwlab/rfw/v2/state"
 )
 
@@ -50,6 +51,70 @@ func TestIncludePlaceholderPrefixCollision(t *testing.T) {
 	}
 }
 
+func TestScopedSlotBindsRowInLoop(t *testing.T) {
+	childTpl := []byte("<root><table>@for:row in rows\n<tr>\n@slot:cell row\n<td>@prop:row.name</td>\n@endslot\n</tr>\n@endfor</table></root>")
+	parentTpl := []byte("<root>@slot:table.cell r\n<td><b>@prop:r.name</b></td>\n@endslot@include:table@include:plain</root>")
+	rows := []any{map[string]any{"name": "Ada"}, map[string]any{"name": "Linus"}}
+
+	parent := NewHTMLComponent("ScopedParent", parentTpl, nil)
+	parent.Init(nil)
+	table := NewHTMLComponent("DataTable", childTpl, map[string]any{"rows": rows})
+	plain := NewHTMLComponent("DataTable", childTpl, map[string]any{"rows": rows})
+	parent.AddDependency("table", table)
+	parent.AddDependency("plain", plain)
+
+	html := parent.Render()
+	if strings.Count(html, "<b>") != 2 || !strings.Contains(html, "<b>Ada</b>") || !strings.Contains(html, "<b>Linus</b>") {
+		t.Fatalf("scoped content not bound per row: %s", html)
+	}
+	if !strings.Contains(html, "<td>Ada</td>") {
+		t.Fatalf("fallback not rendered without content: %s", html)
+	}
+	if strings.Contains(html, "cell row") || strings.Contains(html, "@prop:") {
+		t.Fatalf("slot header or references left over: %s", html)
+	}
+	if strings.Count(html, `data-for="for-`+table.ID) != 2 {
+		t.Fatalf("rows not marked for keyed patching: %s", html)
+	}
+}
+
+func TestScopedSlotContentUsesParentScope(t *testing.T) {
+	childTpl := []byte("<root><ul>@for:row in rows\n<li>\n@slot:item row\n@prop:row.name\n@endslot\n</li>\n@endfor</ul></root>")
+	parentTpl := []byte("<root>@slot:list.item r\n<button @on:click:open>@prop:title @prop:r.name</button>\n@endslot@include:list</root>")
+	rows := []any{map[string]any{"name": "Ada"}}
+
+	parent := NewHTMLComponent("ScopeParent", parentTpl, map[string]any{"title": "Open", "r": "shadowed"})
+	parent.Init(nil)
+	opened := 0
+	parent.On("open", func() { opened++ })
+	list := NewHTMLComponent("ScopeList", childTpl, map[string]any{"rows": rows, "title": "child title"})
+	parent.AddDependency("list", list)
+
+	html := parent.Render()
+	if !strings.Contains(html, "Open") || strings.Contains(html, "child title") {
+		t.Fatalf("parent prop not read from the parent: %s", html)
+	}
+	if !strings.Contains(html, "Ada") || strings.Contains(html, "shadowed") {
+		t.Fatalf("exposed value does not shadow the parent prop: %s", html)
+	}
+	alias := slotParentName("item", "open")
+	if !strings.Contains(html, `data-on-click="`+alias+`"`) {
+		t.Fatalf("handler not forwarded: %s", html)
+	}
+	dom.GetComponentHandler(list.ID, alias).Invoke()
+	if opened != 1 {
+		t.Fatalf("parent handler called %d times", opened)
+	}
+}
+
+func TestRenameSlotRefs(t *testing.T) {
+	body := `<p title="{{ r.title }}">@prop:r.name @signal:r @expr:len(r.tags)</p><i>r</i>` + "\n@if:r.done\n@for:tag in r.tags\n@endfor"
+	want := `<p title="{{ row.title }}">@prop:row.name @signal:row @expr:len(row.tags)</p><i>r</i>` + "\n@if:row.done\n@for:tag in row.tags\n@endfor"
+	if got := renameSlotRefs(body, map[string]string{"r": "row"}); got != want {
+		t.Fatalf("renameSlotRefs =\n%s\nwant\n%s", got, want)
+	}
+}
+
 func TestComponentInstancesHaveDistinctIDs(t *testing.T) {
 	first := NewHTMLComponent("Repeated", []byte("<root></root>"), nil)
 	second := NewHTMLComponent("Repeated", []byte("<root></root>"), nil)
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/rtmlast"
+)
+
+// TemplateError is an RTML expression or condition that failed while a
+// component rendered, located in the template source. The runtime reports it
+// through ReportError, so OnError sinks receive it as their err and can
+// unwrap it with errors.As.
+type TemplateError struct {
+	// Component is the name of the component rendering the template.
+	Component string
+	// File is the template file, or "" when the component was not told.
+	File string
+	// Line and Col locate Source in the template, counting from 1. Both are
+	// 0 when the source could not be found, for instance in a loop body the
+	// runtime rewrote before evaluating it.
+	Line, Col int
+	// Source is the offending expression as written in the template.
+	Source string
+	// Err is what went wrong.
+	Err error
+	// Frame shows the template lines around Line, see rtmlast.Frame.
+	Frame string
+}
+
+// Error formats the error as file:line:col, followed by the code frame on the
+// lines after it.
+func (e *TemplateError) Error() string {
+	if e.Frame == "" {
+		return e.header()
+	}
+	return e.header() + "\n\n" + e.Frame
+}
+
+// Unwrap returns the underlying error.
+func (e *TemplateError) Unwrap() error { return e.Err }
+
+// header is the first line of the error.
+func (e *TemplateError) header() string {
+	loc := e.File
+	if loc == "" {
+		loc = e.Component
+	}
+	if e.Line > 0 {
+		loc = fmt.Sprintf("%s:%d:%d", loc, e.Line, e.Col)
+	}
+	return fmt.Sprintf("%s: template expression %q: %v", loc, e.Source, e.Err)
+}
+
+// newTemplateError locates source in the template tpl of component name.
+func newTemplateError(name, file, tpl, source string, err error) *TemplateError {
+	te := &TemplateError{Component: name, File: file, Source: source, Err: err}
+	te.Line, te.Col = locateSource(tpl, source)
+	if te.Line > 0 {
+		te.Frame = rtmlast.Frame(tpl, te.Line, te.Col)
+	}
+	return te
+}
+
+// locateSource finds source in tpl. An occurrence inside an RTML command or
+// interpolation wins over one in plain text, so an expression is not matched
+// against markup that merely reads the same. It returns 0, 0 when tpl does not
+// hold source.
+func locateSource(tpl, source string) (line, col int) {
+	if source == "" {
+		return 0, 0
+	}
+	tokens := rtmlast.NewLexer(tpl).Lex()
+	for i, tok := range tokens[:len(tokens)-1] {
+		if tok.Type != rtmlast.TokenCommand && tok.Type != rtmlast.TokenVarOpen {
+			continue
+		}
+		if j := strings.Index(tpl[tok.Pos:tokens[i+1].Pos], source); j >= 0 {
+			return rtmlast.Position(tpl, tok.Pos+j)
+		}
+	}
+	if i := strings.Index(tpl, source); i >= 0 {
+		return rtmlast.Position(tpl, i)
+	}
+	return 0, 0
+}
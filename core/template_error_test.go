@@ -0,0 +1,33 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplateErrorLocatesSource(t *testing.T) {
+	tpl := "<root>\n  <p>count &gt;</p>\n  @if:count >\n    <p>many</p>\n  @endif\n</root>"
+	cause := errors.New(`unexpected token ""`)
+	te := newTemplateError("Counter", "templates/counter.rtml", tpl, "count >", cause)
+
+	// the text in the <p> reads the same but is not a command
+	if te.Line != 3 || te.Col != 7 {
+		t.Fatalf("located at %d:%d", te.Line, te.Col)
+	}
+	if !errors.Is(te, cause) {
+		t.Fatal("TemplateError does not unwrap to its cause")
+	}
+	header, frame, _ := strings.Cut(te.Error(), "\n\n")
+	if header != `templates/counter.rtml:3:7: template expression "count >": unexpected token ""` {
+		t.Fatalf("header = %q", header)
+	}
+	if !strings.Contains(frame, "> 3 |   @if:count >\n    |       ^") {
+		t.Fatalf("frame =\n%s", frame)
+	}
+
+	missing := newTemplateError("Counter", "", tpl, "total >", cause)
+	if missing.Line != 0 || missing.Frame != "" || !strings.HasPrefix(missing.Error(), "Counter: ") {
+		t.Fatalf("unlocated error = %q", missing.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var templateFuncs = struct {
+	sync.RWMutex
+	byName map[string]any
+}{byName: make(map[string]any)}
+
+// RegisterTemplateFunc makes fn callable from every RTML expression under
+// name, both as name(args) and as the pipe value | name args, which passes
+// value as the first argument:
+//
+//	core.RegisterTemplateFunc("currency", func(v float64, code string) string {
+//		return fmt.Sprintf("%.2f %s", v, code)
+//	})
+//
+//	<p>{{ price | currency "EUR" }}</p>
+//	<p>@expr:(price | currency "EUR")</p>
+//
+// An @expr ends at a bare |, so a pipe in one goes in parentheses.
+//
+// fn is a Go function returning one value, or a value and an error; a
+// non-nil error is reported as a template error. Registering a name again
+// replaces the function. It panics when fn is not such a function.
+func RegisterTemplateFunc(name string, fn any) {
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func {
+		panic(fmt.Sprintf("core: template func %s is a %T, not a function", name, fn))
+	}
+	if ft.NumOut() != 1 && (ft.NumOut() != 2 || ft.Out(1) != reflect.TypeFor[error]()) {
+		panic(fmt.Sprintf("core: template func %s must return one value, or a value and an error", name))
+	}
+	templateFuncs.Lock()
+	templateFuncs.byName[name] = fn
+	templateFuncs.Unlock()
+}
+
+// templateFunc returns the function registered under name.
+func templateFunc(name string) (any, bool) {
+	templateFuncs.RLock()
+	defer templateFuncs.RUnlock()
+	fn, ok := templateFuncs.byName[name]
+	return fn, ok
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterTemplateFunc(t *testing.T) {
+	RegisterTemplateFunc("testShout", strings.ToUpper)
+	fn, ok := templateFunc("testShout")
+	if !ok || fn.(func(string) string)("hi") != "HI" {
+		t.Fatalf("templateFunc(testShout) = %v, %v", fn, ok)
+	}
+	for name, fn := range map[string]any{
+		"testNotFunc":  "upper",
+		"testNoResult": func(string) {},
+		"testTwoVals":  func() (int, int) { return 0, 0 },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterTemplateFunc(%s) did not panic", name)
+				}
+			}()
+			RegisterTemplateFunc(name, fn)
+		}()
+	}
+}
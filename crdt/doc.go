@@ -0,0 +1,399 @@
+// Package crdt implements the conflict-free replicated document behind shared
+// stores: a last-writer-wins map of registers and ordered lists that converge
+// regardless of the order replicas receive operations in.
+package crdt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ID identifies an operation and, for list inserts, the element it creates.
+// IDs are ordered by Lamport counter, then by replica.
+type ID struct {
+	Counter uint64 `json:"c"`
+	Replica string `json:"r"`
+}
+
+// IsZero reports whether id is unset. The zero ID denotes the head of a list.
+func (id ID) IsZero() bool { return id.Counter == 0 && id.Replica == "" }
+
+// Less reports whether id orders before other.
+func (id ID) Less(other ID) bool {
+	if id.Counter != other.Counter {
+		return id.Counter < other.Counter
+	}
+	return id.Replica < other.Replica
+}
+
+// OpKind names an operation type.
+type OpKind string
+
+const (
+	// OpSet writes a map register. A nil value deletes the key.
+	OpSet OpKind = "set"
+	// OpInsert inserts a list element after another element or at the head.
+	OpInsert OpKind = "insert"
+	// OpDelete removes a list element.
+	OpDelete OpKind = "delete"
+)
+
+// Op is one replicated operation. Ops are JSON-encoded on the wire.
+type Op struct {
+	ID     ID     `json:"id"`
+	Kind   OpKind `json:"kind"`
+	Key    string `json:"key"`
+	Value  any    `json:"value,omitempty"`
+	After  ID     `json:"after,omitzero"`
+	Target ID     `json:"target,omitzero"`
+}
+
+type register struct {
+	id    ID
+	value any
+}
+
+type element struct {
+	id      ID
+	value   any
+	deleted bool
+}
+
+// Doc is one replica of a document. Registers and lists live in separate
+// namespaces; use distinct keys for them. Doc is safe for concurrent use.
+type Doc struct {
+	mu        sync.Mutex
+	replica   string
+	clock     uint64
+	registers map[string]register
+	lists     map[string][]element
+	applied   map[ID]struct{}
+	pending   []Op
+}
+
+// NewReplicaID returns a random replica identifier.
+func NewReplicaID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// NewDoc creates an empty replica. An empty replica ID is replaced by a
+// random one.
+func NewDoc(replica string) *Doc {
+	if replica == "" {
+		replica = NewReplicaID()
+	}
+	return &Doc{
+		replica:   replica,
+		registers: make(map[string]register),
+		lists:     make(map[string][]element),
+		applied:   make(map[ID]struct{}),
+	}
+}
+
+// Replica returns the replica ID stamped on local operations.
+func (d *Doc) Replica() string { return d.replica }
+
+func (d *Doc) nextIDLocked() ID {
+	d.clock++
+	return ID{Counter: d.clock, Replica: d.replica}
+}
+
+// Set writes key locally and returns the operation to replicate. Values are
+// normalized through JSON so every replica stores the same representation.
+func (d *Doc) Set(key string, value any) Op {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	op := Op{ID: d.nextIDLocked(), Kind: OpSet, Key: key, Value: normalize(value)}
+	d.applyLocked(op)
+	return op
+}
+
+// Insert inserts value at index of the visible elements of list, clamping
+// index to the list bounds, and returns the operation to replicate.
+func (d *Doc) Insert(list string, index int, value any) Op {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var after ID
+	if visible := d.visibleLocked(list); index > 0 && len(visible) > 0 {
+		after = visible[min(index, len(visible))-1].id
+	}
+	op := Op{ID: d.nextIDLocked(), Kind: OpInsert, Key: list, Value: normalize(value), After: after}
+	d.applyLocked(op)
+	return op
+}
+
+// Append adds value to the end of list.
+func (d *Doc) Append(list string, value any) Op {
+	return d.Insert(list, int(^uint(0)>>1), value)
+}
+
+// Remove deletes the visible element at index of list. It reports false when
+// index is out of range.
+func (d *Doc) Remove(list string, index int) (Op, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	visible := d.visibleLocked(list)
+	if index < 0 || index >= len(visible) {
+		return Op{}, false
+	}
+	op := Op{ID: d.nextIDLocked(), Kind: OpDelete, Key: list, Target: visible[index].id}
+	d.applyLocked(op)
+	return op, true
+}
+
+// Limits on remote operations, so a misbehaving replica cannot exhaust the
+// Lamport clock or the memory of the replicas applying its operations.
+const (
+	// MaxClockSkew is how far ahead of the local clock a remote operation's
+	// counter may be.
+	MaxClockSkew = 1 << 20
+	// MaxPending is how many operations may wait for their list element.
+	MaxPending = 1024
+)
+
+var (
+	// ErrClockSkew reports an operation whose counter is more than
+	// MaxClockSkew ahead of the local clock.
+	ErrClockSkew = errors.New("crdt: operation counter too far ahead")
+	// ErrPendingFull reports an operation that would wait for its list
+	// element while MaxPending operations already do.
+	ErrPendingFull = errors.New("crdt: too many operations waiting for their element")
+	// ErrInvalidOp reports an operation without an ID or with an unknown kind.
+	ErrInvalidOp = errors.New("crdt: invalid operation")
+)
+
+// Apply integrates a remote operation. It reports false for an operation
+// already applied or one TryApply rejects. Operations whose list element has
+// not arrived yet are held back and applied once it does.
+func (d *Doc) Apply(op Op) bool {
+	applied, err := d.TryApply(op)
+	return applied && err == nil
+}
+
+// TryApply integrates a remote operation like Apply, and reports why an
+// operation was rejected: ErrInvalidOp, ErrClockSkew or ErrPendingFull.
+func (d *Doc) TryApply(op Op) (bool, error) {
+	switch op.Kind {
+	case OpSet, OpInsert, OpDelete:
+	default:
+		return false, ErrInvalidOp
+	}
+	if op.ID.Counter == 0 || op.ID.Replica == "" {
+		return false, ErrInvalidOp
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, seen := d.applied[op.ID]; seen {
+		return false, nil
+	}
+	for _, held := range d.pending {
+		if held.ID == op.ID {
+			return false, nil
+		}
+	}
+	if op.ID.Counter > d.clock && op.ID.Counter-d.clock > MaxClockSkew {
+		return false, ErrClockSkew
+	}
+	if !d.applyLocked(op) {
+		if len(d.pending) >= MaxPending {
+			return false, ErrPendingFull
+		}
+		d.pending = append(d.pending, op)
+		d.clock = max(d.clock, op.ID.Counter)
+		return true, nil
+	}
+	d.clock = max(d.clock, op.ID.Counter)
+	for progress := true; progress; {
+		progress = false
+		for i := 0; i < len(d.pending); i++ {
+			if d.applyLocked(d.pending[i]) {
+				d.pending = append(d.pending[:i], d.pending[i+1:]...)
+				progress = true
+				i--
+			}
+		}
+	}
+	return true, nil
+}
+
+// applyLocked integrates op, returning false when it depends on an element
+// that is not present yet. Callers must hold d.mu.
+func (d *Doc) applyLocked(op Op) bool {
+	switch op.Kind {
+	case OpSet:
+		if current, ok := d.registers[op.Key]; !ok || current.id.Less(op.ID) {
+			d.registers[op.Key] = register{id: op.ID, value: op.Value}
+		}
+	case OpInsert:
+		elements := d.lists[op.Key]
+		pos := 0
+		if !op.After.IsZero() {
+			anchor := indexOf(elements, op.After)
+			if anchor < 0 {
+				return false
+			}
+			pos = anchor + 1
+		}
+		// Concurrent inserts after the same anchor order by descending ID;
+		// elements with a greater ID, and their successors, stay in front.
+		for pos < len(elements) && op.ID.Less(elements[pos].id) {
+			pos++
+		}
+		elements = append(elements, element{})
+		copy(elements[pos+1:], elements[pos:])
+		elements[pos] = element{id: op.ID, value: op.Value}
+		d.lists[op.Key] = elements
+	case OpDelete:
+		elements := d.lists[op.Key]
+		target := indexOf(elements, op.Target)
+		if target < 0 {
+			return false
+		}
+		elements[target].deleted = true
+	}
+	d.applied[op.ID] = struct{}{}
+	return true
+}
+
+func indexOf(elements []element, id ID) int {
+	for i, e := range elements {
+		if e.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Doc) visibleLocked(list string) []element {
+	var visible []element
+	for _, e := range d.lists[list] {
+		if !e.deleted {
+			visible = append(visible, e)
+		}
+	}
+	return visible
+}
+
+// Get returns the value of a register, or nil.
+func (d *Doc) Get(key string) any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.registers[key].value
+}
+
+// List returns the visible elements of list in order.
+func (d *Doc) List(list string) []any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.listValuesLocked(list)
+}
+
+func (d *Doc) listValuesLocked(list string) []any {
+	visible := d.visibleLocked(list)
+	values := make([]any, len(visible))
+	for i, e := range visible {
+		values[i] = e.value
+	}
+	return values
+}
+
+// Value returns the current value of key: a list when key names one,
+// otherwise the register value.
+func (d *Doc) Value(key string) any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.lists[key]; ok {
+		return d.listValuesLocked(key)
+	}
+	return d.registers[key].value
+}
+
+// Snapshot returns every register and list. Deleted registers are omitted.
+func (d *Doc) Snapshot() map[string]any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	snap := make(map[string]any, len(d.registers)+len(d.lists))
+	for key, reg := range d.registers {
+		if reg.value != nil {
+			snap[key] = reg.value
+		}
+	}
+	for key := range d.lists {
+		snap[key] = d.listValuesLocked(key)
+	}
+	return snap
+}
+
+// Reset empties the replica and gives it replica as its new ID, or a random
+// one when replica is empty, as when a client rebuilds its copy from the host
+// after the host rejected its edits.
+func (d *Doc) Reset(replica string) {
+	if replica == "" {
+		replica = NewReplicaID()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.replica = replica
+	d.clock = 0
+	d.registers = make(map[string]register)
+	d.lists = make(map[string][]element)
+	d.applied = make(map[ID]struct{})
+	d.pending = nil
+}
+
+// Keys returns the register and list keys in sorted order.
+func (d *Doc) Keys() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := make([]string, 0, len(d.registers)+len(d.lists))
+	for key := range d.registers {
+		keys = append(keys, key)
+	}
+	for key := range d.lists {
+		if _, ok := d.registers[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func normalize(value any) any {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return value
+	}
+	return out
+}
+
+// DecodeOps converts a decoded JSON payload, as received by host components
+// and hostclient handlers, into operations.
+func DecodeOps(raw any) ([]Op, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var ops []Op
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
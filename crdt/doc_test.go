@@ -0,0 +1,131 @@
+package crdt
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestConcurrentInsertsConverge(t *testing.T) {
+	a, b := NewDoc("a"), NewDoc("b")
+	base := a.Append("todos", "milk")
+	b.Apply(base)
+
+	ops := []Op{
+		a.Insert("todos", 1, "eggs"),
+		a.Insert("todos", 0, "bread"),
+		b.Insert("todos", 1, "tea"),
+		b.Append("todos", "jam"),
+	}
+	if op, ok := b.Remove("todos", 0); ok {
+		ops = append(ops, op)
+	}
+
+	replicas := make([]*Doc, 4)
+	for i := range replicas {
+		replicas[i] = NewDoc("")
+		replicas[i].Apply(base)
+		shuffled := append([]Op(nil), ops...)
+		rand.New(rand.NewSource(int64(i))).Shuffle(len(shuffled), func(x, y int) {
+			shuffled[x], shuffled[y] = shuffled[y], shuffled[x]
+		})
+		for _, op := range shuffled {
+			replicas[i].Apply(op)
+		}
+	}
+	want := replicas[0].List("todos")
+	if len(want) != 4 {
+		t.Fatalf("unexpected list: %v", want)
+	}
+	for i, r := range replicas[1:] {
+		if got := r.List("todos"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("replica %d diverged: %v != %v", i+1, got, want)
+		}
+	}
+}
+
+func TestSetIsLastWriterWins(t *testing.T) {
+	a, b := NewDoc("a"), NewDoc("b")
+	first := a.Set("title", "draft")
+	b.Apply(first)
+	fromA := a.Set("title", "from a")
+	fromB := b.Set("title", "from b")
+	a.Apply(fromB)
+	b.Apply(fromA)
+	if a.Get("title") != b.Get("title") {
+		t.Fatalf("registers diverged: %v != %v", a.Get("title"), b.Get("title"))
+	}
+	if a.Get("title") != "from b" {
+		t.Fatalf("expected replica b to win the tie, got %v", a.Get("title"))
+	}
+}
+
+func TestApplyHoldsOperationsUntilDependenciesArrive(t *testing.T) {
+	a, b := NewDoc("a"), NewDoc("b")
+	insert := a.Append("todos", "milk")
+	remove, _ := a.Remove("todos", 0)
+	after := a.Append("todos", "eggs")
+
+	b.Apply(remove)
+	if got := b.List("todos"); len(got) != 0 {
+		t.Fatalf("expected empty list while held, got %v", got)
+	}
+	b.Apply(insert)
+	b.Apply(after)
+	if got := b.List("todos"); !reflect.DeepEqual(got, []any{"eggs"}) {
+		t.Fatalf("unexpected list: %v", got)
+	}
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	a, b := NewDoc("a"), NewDoc("b")
+	op := a.Append("todos", map[string]any{"done": false})
+	if !b.Apply(op) {
+		t.Fatal("first apply should report a change")
+	}
+	if b.Apply(op) {
+		t.Fatal("second apply should be ignored")
+	}
+	if got := b.List("todos"); len(got) != 1 {
+		t.Fatalf("duplicate element applied: %v", got)
+	}
+}
+
+func TestDecodeOpsRoundTrip(t *testing.T) {
+	a := NewDoc("a")
+	op := a.Append("todos", 3)
+	payload := normalize(map[string]any{"ops": []Op{op}}).(map[string]any)
+	ops, err := DecodeOps(payload["ops"])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(ops) != 1 || ops[0].ID != op.ID || ops[0].Value != float64(3) {
+		t.Fatalf("unexpected ops: %+v", ops)
+	}
+	if _, err := DecodeOps("nope"); err == nil {
+		t.Fatal("expected error for malformed payload")
+	}
+}
+
+func TestApplyBoundsRemoteOperations(t *testing.T) {
+	d := NewDoc("local")
+	if _, err := d.TryApply(Op{ID: ID{Counter: ^uint64(0), Replica: "x"}, Kind: OpSet, Key: "k"}); err != ErrClockSkew {
+		t.Fatalf("exhausted counter: %v", err)
+	}
+	if op := d.Set("k", 1); op.ID.Counter != 1 {
+		t.Fatalf("local clock moved to %d", op.ID.Counter)
+	}
+	if _, err := d.TryApply(Op{ID: ID{Counter: 5}, Kind: OpSet, Key: "k"}); err != ErrInvalidOp {
+		t.Fatalf("op without replica: %v", err)
+	}
+	for i := range MaxPending {
+		orphan := Op{ID: ID{Counter: uint64(i + 2), Replica: "x"}, Kind: OpDelete, Key: "l", Target: ID{Counter: 1, Replica: "gone"}}
+		if ok, err := d.TryApply(orphan); !ok || err != nil {
+			t.Fatalf("orphan %d: %v", i, err)
+		}
+	}
+	orphan := Op{ID: ID{Counter: MaxPending + 2, Replica: "x"}, Kind: OpDelete, Key: "l", Target: ID{Counter: 1, Replica: "gone"}}
+	if _, err := d.TryApply(orphan); err != ErrPendingFull {
+		t.Fatalf("pending beyond the limit: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package dom
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// inputModifiers are the dot modifiers following a :w binding, as in
+// value="@signal:age:w.lazy.number".
+type inputModifiers struct {
+	lazy   bool // write on change instead of on every keystroke
+	trim   bool // strip surrounding whitespace
+	number bool // parse as a number when the target has no type of its own
+}
+
+func parseInputModifiers(s string) inputModifiers {
+	var mods inputModifiers
+	for _, m := range strings.Split(strings.TrimPrefix(s, "."), ".") {
+		switch m {
+		case "lazy":
+			mods.lazy = true
+		case "trim":
+			mods.trim = true
+		case "number":
+			mods.number = true
+		}
+	}
+	return mods
+}
+
+// coerceInput converts the text of a form control to t, the type held by
+// the bound signal or store key. A nil or interface t keeps the text, or
+// with the number modifier parses it as a float64.
+func coerceInput(raw string, t reflect.Type, mods inputModifiers) (any, error) {
+	if mods.trim {
+		raw = strings.TrimSpace(raw)
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		if !mods.number {
+			return raw, nil
+		}
+		t = reflect.TypeOf(float64(0))
+	}
+	text := strings.TrimSpace(raw)
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, t.Bits())
+		if err != nil {
+			return nil, numberError(raw, "a whole number", err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, t.Bits())
+		if err != nil {
+			return nil, numberError(raw, "a positive whole number", err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, t.Bits())
+		if err != nil {
+			return nil, numberError(raw, "a number", err)
+		}
+		v.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("cannot bind a form field to %s", t)
+	}
+	return v.Interface(), nil
+}
+
+func numberError(raw, want string, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return fmt.Errorf("%s is out of range", raw)
+	}
+	return fmt.Errorf("%q is not %s", raw, want)
+}
+
+// coerceInputs converts the values of the selected options of a multiple
+// select, or of the checked boxes of a group, to the slice type t. A nil or
+// interface t gives a []string, or a []float64 with the number modifier.
+func coerceInputs(raws []string, t reflect.Type, mods inputModifiers) (any, error) {
+	if t == nil || t.Kind() != reflect.Slice {
+		t = reflect.TypeOf([]string(nil))
+		if mods.number {
+			t = reflect.TypeOf([]float64(nil))
+		}
+	}
+	out := reflect.MakeSlice(t, 0, len(raws))
+	for _, raw := range raws {
+		v, err := coerceInput(raw, t.Elem(), mods)
+		if err != nil {
+			return nil, err
+		}
+		out = reflect.Append(out, reflect.ValueOf(v))
+	}
+	return out.Interface(), nil
+}
+
+// formatBound renders a bound value the way a form control shows it.
+func formatBound(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// boundStrings renders the items of a bound slice, the options a multiple
+// select or a checkbox group shows checked.
+func boundStrings(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	out := make([]string, rv.Len())
+	for i := range out {
+		out[i] = formatBound(rv.Index(i).Interface())
+	}
+	return out
+}
+
+// sameBound reports whether the text of a control already shows v, so an
+// update does not rewrite what is being typed: "1." and " 1" both show 1.
+func sameBound(text string, v any) bool {
+	s := formatBound(v)
+	if text == s || strings.TrimSpace(text) == s {
+		return true
+	}
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		return err == nil && fmt.Sprintf("%v", f) == fmt.Sprintf("%v", reflect.ValueOf(v).Convert(reflect.TypeOf(f)).Interface())
+	}
+	return false
+}
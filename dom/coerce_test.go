@@ -0,0 +1,110 @@
+package dom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInputModifiers(t *testing.T) {
+	if got := parseInputModifiers(".lazy.trim.number"); got != (inputModifiers{lazy: true, trim: true, number: true}) {
+		t.Errorf("all modifiers = %+v", got)
+	}
+	if got := parseInputModifiers(""); got != (inputModifiers{}) {
+		t.Errorf("no modifiers = %+v", got)
+	}
+	if got := parseInputModifiers(".trim.unknown"); got != (inputModifiers{trim: true}) {
+		t.Errorf("unknown modifier = %+v", got)
+	}
+}
+
+func TestCoerceInput(t *testing.T) {
+	type level int
+	cases := []struct {
+		raw  string
+		typ  reflect.Type
+		mods inputModifiers
+		want any
+	}{
+		{"42", reflect.TypeOf(0), inputModifiers{}, 42},
+		{" 42 ", reflect.TypeOf(int64(0)), inputModifiers{}, int64(42)},
+		{"3", reflect.TypeOf(level(0)), inputModifiers{}, level(3)},
+		{"1.5", reflect.TypeOf(float64(0)), inputModifiers{}, 1.5},
+		{"7", reflect.TypeOf(uint8(0)), inputModifiers{}, uint8(7)},
+		{"true", reflect.TypeOf(false), inputModifiers{}, true},
+		{" ada ", reflect.TypeOf(""), inputModifiers{}, " ada "},
+		{" ada ", reflect.TypeOf(""), inputModifiers{trim: true}, "ada"},
+		{"2.5", nil, inputModifiers{}, "2.5"},
+		{"2.5", nil, inputModifiers{number: true}, 2.5},
+		{"2", reflect.TypeOf((*any)(nil)).Elem(), inputModifiers{number: true}, 2.0},
+	}
+	for _, c := range cases {
+		got, err := coerceInput(c.raw, c.typ, c.mods)
+		if err != nil || got != c.want {
+			t.Errorf("coerceInput(%q, %v, %+v) = %#v, %v; want %#v", c.raw, c.typ, c.mods, got, err, c.want)
+		}
+	}
+}
+
+func TestCoerceInputErrors(t *testing.T) {
+	cases := []struct {
+		raw  string
+		typ  reflect.Type
+		mods inputModifiers
+		want string
+	}{
+		{"12a", reflect.TypeOf(0), inputModifiers{}, `"12a" is not a whole number`},
+		{"", reflect.TypeOf(0), inputModifiers{}, `"" is not a whole number`},
+		{"-1", reflect.TypeOf(uint(0)), inputModifiers{}, `"-1" is not a positive whole number`},
+		{"300", reflect.TypeOf(int8(0)), inputModifiers{}, `300 is out of range`},
+		{"x", nil, inputModifiers{number: true}, `"x" is not a number`},
+		{"a", reflect.TypeOf(struct{}{}), inputModifiers{}, `cannot bind a form field to struct {}`},
+	}
+	for _, c := range cases {
+		_, err := coerceInput(c.raw, c.typ, c.mods)
+		if err == nil || err.Error() != c.want {
+			t.Errorf("coerceInput(%q, %v) error = %v, want %s", c.raw, c.typ, err, c.want)
+		}
+	}
+}
+
+func TestCoerceInputs(t *testing.T) {
+	got, err := coerceInputs([]string{"1", "3"}, reflect.TypeOf([]int(nil)), inputModifiers{})
+	if err != nil || !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("[]int = %#v, %v", got, err)
+	}
+	got, err = coerceInputs(nil, nil, inputModifiers{})
+	if err != nil || !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("untyped = %#v, %v", got, err)
+	}
+	got, err = coerceInputs([]string{"a"}, reflect.TypeOf([]any(nil)), inputModifiers{})
+	if err != nil || !reflect.DeepEqual(got, []any{"a"}) {
+		t.Errorf("[]any = %#v, %v", got, err)
+	}
+	if _, err = coerceInputs([]string{"1", "b"}, reflect.TypeOf([]int(nil)), inputModifiers{}); err == nil {
+		t.Error("[]int accepted b")
+	}
+}
+
+func TestSameBound(t *testing.T) {
+	cases := []struct {
+		text string
+		v    any
+		want bool
+	}{
+		{"1.", 1, true},
+		{" 2 ", 2.0, true},
+		{"2.50", 2.5, true},
+		{"ada ", "ada", true},
+		{"3", 4, false},
+		{"", nil, true},
+		{"x", "y", false},
+	}
+	for _, c := range cases {
+		if got := sameBound(c.text, c.v); got != c.want {
+			t.Errorf("sameBound(%q, %#v) = %v", c.text, c.v, got)
+		}
+	}
+	if got := boundStrings([]int{1, 2}); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("boundStrings = %#v", got)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"strings"
 	"sync"
 
-	events "github.com/rfwlab/rfw/v2/events"
 	js "github.com/rfwlab/rfw/v2/js"
 	"github.com/rfwlab/rfw/v2/state"
 )
@@ -48,8 +47,8 @@ func ReleaseInputBindings(componentID string) {
 }
 
 var (
-	reStoreWrite  = regexp.MustCompile(`@store:(\w+)\.(\w+)\.(\w+):w`)
-	reSignalWrite = regexp.MustCompile(`@signal:(\w+):w`)
+	reStoreWrite  = regexp.MustCompile(`@store:(\w+)\.(\w+)\.(\w+):w((?:\.\w+)*)`)
+	reSignalWrite = regexp.MustCompile(`@signal:(\w+):w((?:\.\w+)*)`)
 )
 
 // RegisterSignal associates a signal with a component so inputs can bind to it.
@@ -67,6 +66,9 @@ func RemoveComponentSignals(componentID string) {
 	componentSignalsMu.Lock()
 	delete(componentSignals, componentID)
 	componentSignalsMu.Unlock()
+	fieldErrorsMu.Lock()
+	delete(fieldErrors, componentID)
+	fieldErrorsMu.Unlock()
 }
 
 func getSignal(componentID, name string) any {
@@ -208,35 +210,7 @@ func BindASTStoreInputs(componentID string, element js.Value) {
 		if StoreBindingHook != nil && componentID != "" {
 			StoreBindingHook(componentID, module, storeName, key)
 		}
-		storeValue := store.Get(key)
-		tag := strings.ToLower(input.Get("tagName").String())
-		if tag == "input" {
-			inputType := input.Get("type").String()
-			if inputType == "checkbox" {
-				if b, ok := storeValue.(bool); ok {
-					input.Set("checked", b)
-				}
-				ch, stop := events.Listen("change", input)
-				addInputBindingStop(componentID, stop)
-				go func(in js.Value, st *state.Store, k string) {
-					for range ch {
-						st.Set(k, in.Get("checked").Bool())
-					}
-				}(input, store, key)
-				continue
-			}
-		}
-		if storeValue == nil {
-			storeValue = ""
-		}
-		input.Set("value", fmt.Sprintf("%v", storeValue))
-		ch, stop := events.Listen("input", input)
-		addInputBindingStop(componentID, stop)
-		go func(in js.Value, st *state.Store, k string) {
-			for range ch {
-				st.Set(k, in.Get("value").String())
-			}
-		}(input, store, key)
+		bindControl(componentID, input, isInputType(input, "checkbox"), storeTarget(module, storeName, key, store), inputModifiers{})
 	}
 }
 
@@ -247,45 +221,11 @@ func BindASTSignalInputs(componentID string, element js.Value) {
 	for i := 0; i < inputs.Length(); i++ {
 		input := inputs.Index(i)
 		name := input.Call("getAttribute", "data-bind-signal").String()
-		sig := getSignal(componentID, name)
-		if sig == nil {
+		target, ok := signalTarget(name, getSignal(componentID, name))
+		if !ok {
 			continue
 		}
-		tag := strings.ToLower(input.Get("tagName").String())
-		if tag == "input" {
-			inputType := input.Get("type").String()
-			if inputType == "checkbox" {
-				if s, ok := sig.(interface {
-					Read() any
-					Set(bool)
-				}); ok {
-					if b, ok := s.Read().(bool); ok {
-						input.Set("checked", b)
-					}
-					ch, stop := events.Listen("change", input)
-					addInputBindingStop(componentID, stop)
-					go func(in js.Value, sg interface{ Set(bool) }) {
-						for range ch {
-							sg.Set(in.Get("checked").Bool())
-						}
-					}(input, s)
-					continue
-				}
-			}
-		}
-		if s, ok := sig.(interface {
-			Read() any
-			Set(string)
-		}); ok {
-			input.Set("value", fmt.Sprintf("%v", s.Read()))
-			ch, stop := events.Listen("input", input)
-			addInputBindingStop(componentID, stop)
-			go func(in js.Value, sg interface{ Set(string) }) {
-				for range ch {
-					sg.Set(in.Get("value").String())
-				}
-			}(input, s)
-		}
+		bindControl(componentID, input, isInputType(input, "checkbox"), target, inputModifiers{})
 	}
 }
 
@@ -295,31 +235,11 @@ func BindStoreInputsForComponent(componentID string, element js.Value) {
 	inputs := element.Call("querySelectorAll", "input, select, textarea")
 	for i := 0; i < inputs.Length(); i++ {
 		input := inputs.Index(i)
-
-		valueAttr := ""
-		if input.Call("hasAttribute", "value").Bool() {
-			valueAttr = input.Call("getAttribute", "value").String()
-		}
-		checkedAttr := ""
-		if input.Call("hasAttribute", "checked").Bool() {
-			checkedAttr = input.Call("getAttribute", "checked").String()
-		}
-
-		re := reStoreWrite
-		valueMatch := re.FindStringSubmatch(valueAttr)
-		checkedMatch := re.FindStringSubmatch(checkedAttr)
-
-		var module, storeName, key string
-		var usesChecked bool
-		if len(valueMatch) == 4 {
-			module, storeName, key = valueMatch[1], valueMatch[2], valueMatch[3]
-		} else if len(checkedMatch) == 4 {
-			module, storeName, key = checkedMatch[1], checkedMatch[2], checkedMatch[3]
-			usesChecked = true
-		} else {
+		m, checked := writeBinding(input, reStoreWrite)
+		if m == nil {
 			continue
 		}
-
+		module, storeName, key := m[1], m[2], m[3]
 		store := state.GlobalStoreManager.GetStore(module, storeName)
 		if store == nil {
 			continue
@@ -329,32 +249,7 @@ func BindStoreInputsForComponent(componentID string, element js.Value) {
 			StoreBindingHook(componentID, module, storeName, key)
 		}
 
-		storeValue := store.Get(key)
-
-		if usesChecked {
-			boolVal, _ := storeValue.(bool)
-			input.Set("checked", boolVal)
-			ch, stop := events.Listen("change", input)
-			addInputBindingStop(componentID, stop)
-			go func(in js.Value, st *state.Store, k string) {
-				for range ch {
-					st.Set(k, in.Get("checked").Bool())
-				}
-			}(input, store, key)
-			continue
-		}
-
-		if storeValue == nil {
-			storeValue = ""
-		}
-		input.Set("value", fmt.Sprintf("%v", storeValue))
-		ch, stop := events.Listen("input", input)
-		addInputBindingStop(componentID, stop)
-		go func(in js.Value, st *state.Store, k string) {
-			for range ch {
-				st.Set(k, in.Get("value").String())
-			}
-		}(input, store, key)
+		bindControl(componentID, input, checked, storeTarget(module, storeName, key, store), parseInputModifiers(m[4]))
 	}
 }
 
@@ -368,68 +263,15 @@ func BindSignalInputs(componentID string, element js.Value) {
 	inputs := element.Call("querySelectorAll", "input, select, textarea")
 	for i := 0; i < inputs.Length(); i++ {
 		input := inputs.Index(i)
-
-		valueAttr := ""
-		if input.Call("hasAttribute", "value").Bool() {
-			valueAttr = input.Call("getAttribute", "value").String()
-		}
-		checkedAttr := ""
-		if input.Call("hasAttribute", "checked").Bool() {
-			checkedAttr = input.Call("getAttribute", "checked").String()
-		}
-
-		re := reSignalWrite
-		valueMatch := re.FindStringSubmatch(valueAttr)
-		checkedMatch := re.FindStringSubmatch(checkedAttr)
-
-		var name string
-		var usesChecked bool
-		if len(valueMatch) == 2 {
-			name = valueMatch[1]
-		} else if len(checkedMatch) == 2 {
-			name = checkedMatch[1]
-			usesChecked = true
-		} else {
+		m, checked := writeBinding(input, reSignalWrite)
+		if m == nil {
 			continue
 		}
-
-		sig := getSignal(componentID, name)
-		if sig == nil {
-			continue
-		}
-
-		if usesChecked {
-			if s, ok := sig.(interface {
-				Read() any
-				Set(bool)
-			}); ok {
-				if b, ok := s.Read().(bool); ok {
-					input.Set("checked", b)
-				}
-				ch, stop := events.Listen("change", input)
-				addInputBindingStop(componentID, stop)
-				go func(in js.Value, sg interface{ Set(bool) }) {
-					for range ch {
-						sg.Set(in.Get("checked").Bool())
-					}
-				}(input, s)
-			}
+		target, ok := signalTarget(m[1], getSignal(componentID, m[1]))
+		if !ok {
 			continue
 		}
-
-		if s, ok := sig.(interface {
-			Read() any
-			Set(string)
-		}); ok {
-			input.Set("value", fmt.Sprintf("%v", s.Read()))
-			ch, stop := events.Listen("input", input)
-			addInputBindingStop(componentID, stop)
-			go func(in js.Value, sg interface{ Set(string) }) {
-				for range ch {
-					sg.Set(in.Get("value").String())
-				}
-			}(input, s)
-		}
+		bindControl(componentID, input, checked, target, parseInputModifiers(m[2]))
 	}
 }
 
@@ -2,7 +2,10 @@
 
 package dom
 
-import js "github.com/rfwlab/rfw/v2/js"
+import (
+	js "github.com/rfwlab/rfw/v2/js"
+	"github.com/rfwlab/rfw/v2/sanitize"
+)
 
 // Element wraps a DOM element and provides typed helpers.
 type Element struct{ js.Value }
@@ -59,6 +62,16 @@ func (e Element) SetHTML(html string) {
 	e.Set("innerHTML", html)
 }
 
+// SetSanitizedHTML replaces the element's children with untrusted HTML after
+// filtering it through policy. A nil policy uses sanitize.UserContent.
+func (e Element) SetSanitizedHTML(html string, policy *sanitize.Policy) {
+	if policy == nil {
+		e.SetHTML(sanitize.HTML(html))
+		return
+	}
+	e.SetHTML(policy.Sanitize(html))
+}
+
 // AppendChild appends a child element.
 func (e Element) AppendChild(child Element) {
 	if e.missing() {
@@ -0,0 +1,249 @@
+//go:build js && wasm
+
+package dom
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	events "github.com/rfwlab/rfw/v2/events"
+	js "github.com/rfwlab/rfw/v2/js"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// fieldErrors holds the error signal of each bound field, by component.
+var (
+	fieldErrors   = make(map[string]map[string]*state.Signal[string])
+	fieldErrorsMu sync.RWMutex
+)
+
+// RegisterFieldError makes sig hold the message of the last value of field
+// that could not be converted for its binding, or "" once one could. field
+// is the signal name of an @signal binding, or module.store.key.
+func RegisterFieldError(componentID, field string, sig *state.Signal[string]) {
+	fieldErrorsMu.Lock()
+	if fieldErrors[componentID] == nil {
+		fieldErrors[componentID] = make(map[string]*state.Signal[string])
+	}
+	fieldErrors[componentID][field] = sig
+	fieldErrorsMu.Unlock()
+}
+
+func reportFieldError(componentID, field string, err error) {
+	fieldErrorsMu.RLock()
+	sig := fieldErrors[componentID][field]
+	fieldErrorsMu.RUnlock()
+	if sig == nil {
+		return
+	}
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if sig.Get() != msg {
+		sig.Set(msg)
+	}
+}
+
+// boundTarget is the signal or store key a form control writes.
+type boundTarget struct {
+	field string
+	typ   reflect.Type // nil when the target takes any value
+	read  func() any
+	set   func(any)
+}
+
+// signalTarget binds sig through its Set method, whose parameter gives the
+// type input is converted to. Signals without one, such as computed
+// signals, cannot be bound.
+func signalTarget(name string, sig any) (boundTarget, bool) {
+	r, ok := sig.(interface{ Read() any })
+	if !ok {
+		return boundTarget{}, false
+	}
+	set := reflect.ValueOf(sig).MethodByName("Set")
+	if !set.IsValid() || set.Type().NumIn() != 1 {
+		return boundTarget{}, false
+	}
+	typ := set.Type().In(0)
+	return boundTarget{
+		field: name,
+		typ:   typ,
+		read:  r.Read,
+		set: func(v any) {
+			rv := reflect.ValueOf(v)
+			if !rv.Type().AssignableTo(typ) {
+				if !rv.CanConvert(typ) {
+					return
+				}
+				rv = rv.Convert(typ)
+			}
+			set.Call([]reflect.Value{rv})
+		},
+	}, true
+}
+
+// storeTarget binds a store key. Stores are untyped, so input keeps the type
+// of the current value; a string or nil value takes text, or a number with
+// the number modifier.
+func storeTarget(module, storeName, key string, store *state.Store) boundTarget {
+	var typ reflect.Type
+	if v := store.Get(key); v != nil {
+		if t := reflect.TypeOf(v); t.Kind() != reflect.String {
+			typ = t
+		}
+	}
+	return boundTarget{
+		field: module + "." + storeName + "." + key,
+		typ:   typ,
+		read:  func() any { return store.Get(key) },
+		set:   func(v any) { store.Set(key, v) },
+	}
+}
+
+// writeBinding matches re against the value attribute of a form control,
+// then its checked attribute, reporting which one holds the binding.
+func writeBinding(input js.Value, re *regexp.Regexp) (match []string, checked bool) {
+	for _, attr := range []string{"value", "checked"} {
+		if !input.Call("hasAttribute", attr).Bool() {
+			continue
+		}
+		if m := re.FindStringSubmatch(input.Call("getAttribute", attr).String()); m != nil {
+			return m, attr == "checked"
+		}
+	}
+	return nil, false
+}
+
+// bindControl shows the value of t in input and writes t from it:
+//
+//   - a checkbox bound by checked writes a bool, or adds and removes its
+//     value when t holds a slice;
+//   - a radio bound by checked writes its value when picked;
+//   - a multiple select writes the values of the selected options;
+//   - anything else writes its text, converted to the type of t.
+//
+// A value that cannot be converted is not written; its message goes to the
+// error signal of the field.
+func bindControl(componentID string, input js.Value, checked bool, t boundTarget, mods inputModifiers) {
+	applyBound(input, checked, t.read())
+	event := "input"
+	if checked || mods.lazy || isMultiSelect(input) {
+		event = "change"
+	}
+	ch, stop := events.Listen(event, input)
+	addInputBindingStop(componentID, stop)
+	go func() {
+		for range ch {
+			v, ok, err := readControl(input, checked, t, mods)
+			reportFieldError(componentID, t.field, err)
+			if ok && err == nil {
+				t.set(v)
+			}
+		}
+	}()
+}
+
+// readControl converts what input holds for t. ok is false when there is
+// nothing to write, as for a radio being unchecked.
+func readControl(input js.Value, checked bool, t boundTarget, mods inputModifiers) (v any, ok bool, err error) {
+	own := input.Get("value").String()
+	switch {
+	case checked && isInputType(input, "radio"):
+		if !input.Get("checked").Bool() {
+			return nil, false, nil
+		}
+		v, err = coerceInput(own, t.typ, mods)
+	case checked && t.typ != nil && t.typ.Kind() == reflect.Slice:
+		items := slices.DeleteFunc(boundStrings(t.read()), func(s string) bool { return s == own })
+		if input.Get("checked").Bool() {
+			items = append(items, own)
+		}
+		v, err = coerceInputs(items, t.typ, mods)
+	case checked:
+		on := input.Get("checked").Bool()
+		if t.typ == nil || t.typ.Kind() == reflect.Interface {
+			return on, true, nil
+		}
+		v, err = coerceInput(strconv.FormatBool(on), t.typ, mods)
+	case isMultiSelect(input):
+		var picked []string
+		opts := input.Get("selectedOptions")
+		for i := 0; i < opts.Length(); i++ {
+			picked = append(picked, opts.Index(i).Get("value").String())
+		}
+		v, err = coerceInputs(picked, t.typ, mods)
+	default:
+		v, err = coerceInput(input.Get("value").String(), t.typ, mods)
+	}
+	return v, true, err
+}
+
+// applyBound shows v in input the way readControl reads it back.
+func applyBound(input js.Value, checked bool, v any) {
+	own := input.Get("value").String()
+	switch {
+	case checked && isInputType(input, "radio"):
+		input.Set("checked", formatBound(v) == own)
+	case checked:
+		if items := boundStrings(v); items != nil {
+			input.Set("checked", slices.Contains(items, own))
+			return
+		}
+		input.Set("checked", truthy(v))
+	case isMultiSelect(input):
+		items := boundStrings(v)
+		opts := input.Get("options")
+		for i := 0; i < opts.Length(); i++ {
+			opt := opts.Index(i)
+			opt.Set("selected", slices.Contains(items, opt.Get("value").String()))
+		}
+	default:
+		if !sameBound(own, v) {
+			input.Set("value", formatBound(v))
+		}
+	}
+}
+
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.ToLower(v) == "true"
+	}
+	return v != nil
+}
+
+func isInputType(input js.Value, typ string) bool {
+	return strings.EqualFold(input.Get("tagName").String(), "input") &&
+		strings.EqualFold(input.Get("type").String(), typ)
+}
+
+func isMultiSelect(input js.Value) bool {
+	return strings.EqualFold(input.Get("tagName").String(), "select") && input.Get("multiple").Bool()
+}
+
+// UpdateBoundInputs shows v in the form controls under root whose value or
+// checked attribute holds placeholder, a binding such as @signal:name:w,
+// with or without modifiers.
+func UpdateBoundInputs(root js.Value, placeholder string, v any) {
+	selector := fmt.Sprintf(`[value="%[1]s"], [value^="%[1]s."], [checked="%[1]s"], [checked^="%[1]s."]`, placeholder)
+	nodes := root.Call("querySelectorAll", selector)
+	for i := 0; i < nodes.Length(); i++ {
+		input := nodes.Index(i)
+		switch strings.ToLower(input.Get("tagName").String()) {
+		case "input", "select", "textarea":
+		default:
+			continue
+		}
+		checked := input.Call("hasAttribute", "checked").Bool() &&
+			strings.HasPrefix(input.Call("getAttribute", "checked").String(), placeholder)
+		applyBound(input, checked, v)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+package dom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+func TestReadControlCoercesToSignalType(t *testing.T) {
+	age := state.NewSignal(0)
+	target, ok := signalTarget("age", age)
+	if !ok {
+		t.Fatal("int signal not bindable")
+	}
+	input := CreateElement("input")
+	applyBound(input.Value, false, target.read())
+	if v := input.Get("value").String(); v != "0" {
+		t.Fatalf("shown value = %q", v)
+	}
+	input.Set("value", "12a")
+	if _, _, err := readControl(input.Value, false, target, inputModifiers{}); err == nil {
+		t.Fatal("12a accepted")
+	}
+	input.Set("value", "12")
+	v, ok, err := readControl(input.Value, false, target, inputModifiers{})
+	if !ok || err != nil {
+		t.Fatalf("12: %v", err)
+	}
+	target.set(v)
+	if age.Get() != 12 {
+		t.Fatalf("age = %d", age.Get())
+	}
+}
+
+func TestRadioGroupAndMultipleSelect(t *testing.T) {
+	size := state.NewSignal("m")
+	target, _ := signalTarget("size", size)
+	small, medium := CreateElement("input"), CreateElement("input")
+	for i, el := range []Element{small, medium} {
+		el.Set("type", "radio")
+		el.Set("value", []string{"s", "m"}[i])
+		applyBound(el.Value, true, target.read())
+	}
+	if small.Get("checked").Bool() || !medium.Get("checked").Bool() {
+		t.Fatal("radio group does not show m")
+	}
+	small.Set("checked", true)
+	if v, ok, _ := readControl(small.Value, true, target, inputModifiers{}); !ok || v != "s" {
+		t.Fatalf("picked = %v", v)
+	}
+
+	tags := state.NewSignal([]int{2})
+	target, _ = signalTarget("tags", tags)
+	sel := CreateElement("select")
+	sel.Set("multiple", true)
+	sel.SetHTML(`<option value="1">1</option><option value="2">2</option><option value="3">3</option>`)
+	applyBound(sel.Value, false, target.read())
+	sel.Get("options").Index(0).Set("selected", true)
+	v, _, err := readControl(sel.Value, false, target, inputModifiers{})
+	if err != nil || !reflect.DeepEqual(v, []int{1, 2}) {
+		t.Fatalf("selected = %v, %v", v, err)
+	}
+}
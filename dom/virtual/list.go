@@ -4,11 +4,13 @@
 package virtual
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/rfwlab/rfw/v2/dom"
 	"github.com/rfwlab/rfw/v2/events"
 	js "github.com/rfwlab/rfw/v2/js"
+	"github.com/rfwlab/rfw/v2/state"
 )
 
 // VirtualList renders only the portion of a list that is visible within its container.
@@ -18,6 +20,10 @@ type VirtualList struct {
 	ItemHeight int
 	Render     func(i int) string
 	stopScroll func()
+	stopItems  func()
+
+	nearEnd     func()
+	nearEndRows int
 }
 
 // List is the concise name for VirtualList.
@@ -59,6 +65,48 @@ func (v *VirtualList) update() {
 	offsetBottom := (v.Total - end) * v.ItemHeight
 	html += fmt.Sprintf("<div style='height:%dpx'></div>", offsetBottom)
 	v.Container.SetHTML(html)
+	if v.nearEnd != nil && end >= v.Total-v.nearEndRows {
+		v.nearEnd()
+	}
+}
+
+// SetTotal changes the number of items and re-renders the visible range.
+func (v *VirtualList) SetTotal(total int) {
+	v.Total = total
+	v.update()
+}
+
+// OnNearEnd calls fn whenever the visible range ends within rows items of
+// the end of the list.
+func (v *VirtualList) OnNearEnd(rows int, fn func()) {
+	v.nearEndRows = rows
+	v.nearEnd = fn
+	v.update()
+}
+
+// NewPagedList renders the items of a paged resource and loads the next page
+// when the viewport comes within prefetchRows items of the end. After a page
+// fails to load, scrolling does not retry it; call Refresh or LoadMore on the
+// resource to try again.
+func NewPagedList[T, Cursor any](containerID string, resource *state.PagedResource[T, Cursor], itemHeight, prefetchRows int, render func(i int, item T) string) *VirtualList {
+	v := NewVirtualList(containerID, len(resource.Items()), itemHeight, func(i int) string {
+		if items := resource.Items(); i < len(items) {
+			return render(i, items[i])
+		}
+		return ""
+	})
+	subscription := resource.ItemsSignal().OnChange(func(items []T) { v.SetTotal(len(items)) })
+	v.stopItems = subscription.Stop
+	v.OnNearEnd(prefetchRows, func() { loadNearEnd(resource) })
+	return v
+}
+
+// loadNearEnd loads the next page unless there is none or the last load
+// failed, so a failing page is not requested again on every scroll event.
+func loadNearEnd[T, Cursor any](resource *state.PagedResource[T, Cursor]) {
+	if resource.HasMore() && resource.Status() != state.ResourceError {
+		resource.LoadMore(context.Background())
+	}
 }
 
 // Destroy removes scroll listeners and cleans up resources.
@@ -66,4 +114,7 @@ func (v *VirtualList) Destroy() {
 	if v.stopScroll != nil {
 		v.stopScroll()
 	}
+	if v.stopItems != nil {
+		v.stopItems()
+	}
 }
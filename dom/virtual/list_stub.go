@@ -3,6 +3,8 @@
 // Package virtual provides no-op stubs for non-JS/WASM builds.
 package virtual
 
+import "github.com/rfwlab/rfw/v2/state"
+
 // VirtualList is a placeholder that does nothing on non-JS/WASM platforms.
 type VirtualList struct{}
 
@@ -14,5 +16,16 @@ func NewVirtualList(_ string, _ int, _ int, _ func(int) string) *VirtualList {
 	return &VirtualList{}
 }
 
+// NewPagedList returns an empty VirtualList placeholder.
+func NewPagedList[T, Cursor any](_ string, _ *state.PagedResource[T, Cursor], _, _ int, _ func(int, T) string) *VirtualList {
+	return &VirtualList{}
+}
+
+// SetTotal performs no action in non-JS/WASM builds.
+func (v *VirtualList) SetTotal(int) {}
+
+// OnNearEnd performs no action in non-JS/WASM builds.
+func (v *VirtualList) OnNearEnd(int, func()) {}
+
 // Destroy performs no action in non-JS/WASM builds.
 func (v *VirtualList) Destroy() {}
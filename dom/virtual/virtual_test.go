@@ -3,10 +3,15 @@
 package virtual
 
 import (
+	"context"
+	"errors"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/rfwlab/rfw/v2/dom"
+	"github.com/rfwlab/rfw/v2/state"
 )
 
 func TestVirtualListRendersVisibleItems(t *testing.T) {
@@ -35,3 +40,70 @@ func TestVirtualListMissingContainer(t *testing.T) {
 	}
 	v.Destroy()
 }
+
+func TestPagedListLoadsNextPageNearEnd(t *testing.T) {
+	c := dom.CreateElement("div")
+	c.Set("id", "plist")
+	c.Get("style").Set("height", "60px")
+	c.Get("style").Set("overflow", "auto")
+	dom.Doc().Body().AppendChild(c)
+	defer c.Call("remove")
+
+	resource := state.NewPagedResource(0, func(_ context.Context, page int) (state.Page[int, int], error) {
+		return state.Page[int, int]{Items: []int{page * 2, page*2 + 1}, Next: page + 1, HasMore: page < 2}, nil
+	})
+	defer resource.Close()
+	v := NewPagedList("plist", resource, 20, 1, func(_ int, item int) string {
+		return "<div class='it'>row</div>"
+	})
+	defer v.Destroy()
+
+	deadline := time.Now().Add(time.Second)
+	for resource.HasMore() {
+		if time.Now().After(deadline) {
+			t.Fatalf("pages not loaded near end: %v", resource.Items())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v.Total != 6 {
+		t.Fatalf("expected 6 items, got %d", v.Total)
+	}
+}
+
+func TestPagedListSkipsFailedPageUntilRetry(t *testing.T) {
+	var calls atomic.Int32
+	resource := state.NewPagedResource(0, func(_ context.Context, page int) (state.Page[int, int], error) {
+		if calls.Add(1) == 1 {
+			return state.Page[int, int]{}, errors.New("offline")
+		}
+		return state.Page[int, int]{Items: []int{page}, Next: page + 1, HasMore: true}, nil
+	})
+	defer resource.Close()
+	wait := func(status state.ResourceStatus) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for resource.Status() != status {
+			if time.Now().After(deadline) {
+				t.Fatalf("status %q, want %q", resource.Status(), status)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait(state.ResourceError)
+
+	for range 3 {
+		loadNearEnd(resource)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if calls.Load() != 1 || resource.Status() != state.ResourceError {
+		t.Fatalf("failed page reloaded on scroll: calls=%d status=%q", calls.Load(), resource.Status())
+	}
+
+	resource.LoadMore(context.Background())
+	wait(state.ResourceReady)
+	loadNearEnd(resource)
+	wait(state.ResourceReady)
+	if calls.Load() != 3 || len(resource.Items()) != 2 {
+		t.Fatalf("retry did not resume loading: calls=%d items=%v", calls.Load(), resource.Items())
+	}
+}
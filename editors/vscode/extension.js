@@ -0,0 +1,34 @@
+// Starts `rfw lsp` for .rtml files: diagnostics, completion, hover and
+// go-to-definition come from the language server built into the rfw CLI.
+const vscode = require("vscode");
+const { LanguageClient } = require("vscode-languageclient/node");
+
+let client;
+
+function activate(context) {
+  const config = vscode.workspace.getConfiguration("rtml");
+  if (!config.get("server.enabled", true)) {
+    return;
+  }
+  const command = config.get("server.path", "rfw");
+  client = new LanguageClient(
+    "rtml",
+    "RTML language server",
+    { command, args: ["lsp"] },
+    {
+      documentSelector: [{ scheme: "file", language: "rtml" }],
+      synchronize: {
+        // Go sources declare the stores, props and handlers templates use
+        fileEvents: vscode.workspace.createFileSystemWatcher("**/*.go"),
+      },
+    },
+  );
+  context.subscriptions.push(client);
+  return client.start();
+}
+
+function deactivate() {
+  return client ? client.stop() : undefined;
+}
+
+module.exports = { activate, deactivate };
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"sync"
 )
 
@@ -33,6 +34,7 @@ func WithActionAuthorizer[Request any](authorize ActionAuthorizer[Request]) Acti
 
 type registeredAction interface {
 	dispatch(context.Context, *Session, map[string]any) (any, *ActionError)
+	requestType() reflect.Type
 }
 
 type typedAction[Request, Response any] struct {
@@ -66,6 +68,10 @@ func (a typedAction[Request, Response]) dispatch(ctx context.Context, session *S
 	return response, nil
 }
 
+func (a typedAction[Request, Response]) requestType() reflect.Type {
+	return reflect.TypeFor[Request]()
+}
+
 var actionRegistry = struct {
 	sync.RWMutex
 	actions map[string]registeredAction
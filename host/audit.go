@@ -0,0 +1,292 @@
+package host
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit record kinds and outcomes.
+const (
+	AuditKindAction    = "action"
+	AuditKindComponent = "component"
+
+	AuditOutcomeOK       = "ok"
+	AuditOutcomeError    = "error"
+	AuditOutcomeRejected = "rejected"
+)
+
+// AuditRedacted replaces payload values marked `audit:"redact"`.
+const AuditRedacted = "[REDACTED]"
+
+// AuditRecord describes one inbound SSC message and its result.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Session   string    `json:"session"`
+	Principal string    `json:"principal,omitempty"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Payload   any       `json:"payload,omitempty"`
+	Outcome   string    `json:"outcome"`
+	ErrorCode string    `json:"errorCode,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for every dispatched action and inbound
+// host component message. Audit is called synchronously from the connection
+// loop, so sinks doing slow I/O should buffer.
+type AuditSink interface {
+	Audit(AuditRecord) error
+}
+
+// WithAuditSink records SSC traffic handled by the endpoint to sink.
+func WithAuditSink(sink AuditSink) MuxOption {
+	return func(runtime *WSRuntime) { runtime.audit = sink }
+}
+
+// AuditMessage records msg and its result when an audit sink is configured.
+// A nil actionErr records success; forbidden, rate-limited, out-of-sequence
+// and CSRF-rejected messages are recorded as rejected, the latter without a
+// session. The payload is redacted according to the `audit`
+// struct tags of the action request type or the component's redaction keys.
+func (runtime *WSRuntime) AuditMessage(session *Session, msg Inbound, actionErr *ActionError) {
+	if runtime == nil || runtime.audit == nil {
+		return
+	}
+	record := AuditRecord{Time: time.Now().UTC(), Outcome: AuditOutcomeOK}
+	if session != nil {
+		record.Session = session.ID()
+		record.Principal = session.Principal()
+	}
+	if msg.Action != "" {
+		record.Kind = AuditKindAction
+		record.Name = msg.Action
+		record.Payload = redactActionPayload(msg.Action, msg.Payload)
+	} else {
+		record.Kind = AuditKindComponent
+		record.Name = msg.Component
+		if hc, ok := Get(msg.Component); ok {
+			record.Payload = hc.redactPayload(msg.Payload)
+		} else {
+			record.Payload = copyPayload(msg.Payload)
+		}
+	}
+	if actionErr != nil {
+		record.ErrorCode = actionErr.Code
+		record.Outcome = AuditOutcomeError
+		switch actionErr.Code {
+		case "forbidden", "rate_limited", "csrf_rejected", "sequence_gap":
+			record.Outcome = AuditOutcomeRejected
+		}
+	}
+	if err := runtime.audit.Audit(record); err != nil {
+		logger.Warn("audit sink failed", "kind", record.Kind, "name", record.Name, "err", err)
+	}
+}
+
+func redactActionPayload(name string, payload map[string]any) any {
+	actionRegistry.RLock()
+	action := actionRegistry.actions[name]
+	actionRegistry.RUnlock()
+	if action == nil {
+		return copyPayload(payload)
+	}
+	return redactByType(action.requestType(), payload)
+}
+
+// redactByType applies `audit:"redact"` and `audit:"-"` tags of the struct
+// type t to payload. Keys are matched to fields the way encoding/json decodes
+// them: by JSON name, case-insensitively, with the fields of embedded structs
+// promoted. Nested structs, and slices and maps of them, are redacted
+// recursively; keys without a matching field are kept.
+func redactByType(t reflect.Type, payload map[string]any) map[string]any {
+	if payload == nil {
+		return nil
+	}
+	out := copyPayload(payload)
+	fields := auditFields(t)
+	if fields == nil {
+		return out
+	}
+	for key, value := range out {
+		field, ok := matchAuditField(fields, key)
+		if !ok {
+			continue
+		}
+		switch field.tag {
+		case "-":
+			delete(out, key)
+		case "redact":
+			out[key] = AuditRedacted
+		default:
+			out[key] = redactValue(field.typ, value)
+		}
+	}
+	return out
+}
+
+// redactValue redacts value, decoded from JSON into a field of type t.
+func redactValue(t reflect.Type, value any) any {
+	t = derefType(t)
+	if t == nil {
+		return value
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			return redactByType(t, value)
+		case reflect.Map:
+			out := make(map[string]any, len(value))
+			for key, item := range value {
+				out[key] = redactValue(t.Elem(), item)
+			}
+			return out
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			out := make([]any, len(value))
+			for i, item := range value {
+				out[i] = redactValue(t.Elem(), item)
+			}
+			return out
+		}
+	}
+	return value
+}
+
+type auditField struct {
+	name  string
+	tag   string
+	typ   reflect.Type
+	depth int
+}
+
+// auditFields lists the JSON fields of the struct type t, shallowest first,
+// including those promoted from embedded structs. An embedded struct's audit
+// tag applies to the fields it promotes that have none of their own.
+func auditFields(t reflect.Type) []auditField {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []auditField
+	var walk func(t reflect.Type, tag string, depth int)
+	walk = func(t reflect.Type, tag string, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldTag := field.Tag.Get("audit")
+			if fieldTag == "" {
+				fieldTag = tag
+			}
+			if embedded := derefType(field.Type); field.Anonymous && embedded.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				if depth < 8 {
+					walk(embedded, fieldTag, depth+1)
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if key := jsonFieldName(field); key != "" {
+				fields = append(fields, auditField{name: key, tag: fieldTag, typ: field.Type, depth: depth})
+			}
+		}
+	}
+	walk(t, "", 0)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].depth < fields[j].depth })
+	return fields
+}
+
+// matchAuditField finds the field encoding/json decodes key into: an exact
+// name match first, then a case-insensitive one.
+func matchAuditField(fields []auditField, key string) (auditField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return auditField{}, false
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func copyPayload(payload map[string]any) map[string]any {
+	if payload == nil {
+		return nil
+	}
+	out := make(map[string]any, len(payload))
+	for key, value := range payload {
+		out[key] = value
+	}
+	return out
+}
+
+// JSONLinesAuditSink writes one JSON object per record.
+type JSONLinesAuditSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewJSONLinesAuditSink writes audit records to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{w: w}
+}
+
+// OpenJSONLinesAuditFile appends audit records to the file at path, creating
+// it with owner-only permissions when missing.
+func OpenJSONLinesAuditFile(path string) (*JSONLinesAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesAuditSink{w: file, closer: file}, nil
+}
+
+// Audit writes record as a single line.
+func (s *JSONLinesAuditSink) Audit(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// Close closes the underlying file when the sink owns one.
+func (s *JSONLinesAuditSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
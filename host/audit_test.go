@@ -0,0 +1,256 @@
+//go:build !js
+
+package host
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type recordingAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *recordingAuditSink) Audit(record AuditRecord) error {
+	s.mu.Lock()
+	s.records = append(s.records, record)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *recordingAuditSink) snapshot() []AuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditRecord(nil), s.records...)
+}
+
+func TestWSAuditRecordsActionsWithRedaction(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password" audit:"redact"`
+	}
+	type request struct {
+		Login credentials `json:"login"`
+		Token string      `json:"token" audit:"-"`
+		Note  string      `json:"note"`
+	}
+	const action = "test.ws.audit"
+	if err := RegisterAction(action, func(_ context.Context, _ *Session, _ request) (string, error) {
+		return "", NewActionError("locked", "account locked")
+	}); err != nil {
+		t.Fatalf("register action: %v", err)
+	}
+	sink := &recordingAuditSink{}
+	socket, closeSocket := openProtocolSocket(t,
+		WithAuditSink(sink),
+		WithSSCSessionInitializer(func(_ *http.Request, session *Session) error {
+			session.SetPrincipal("alice")
+			return nil
+		}),
+	)
+	defer closeSocket()
+
+	sendProtocolMessage(t, socket, Inbound{
+		Action:   action,
+		ID:       "audit",
+		Sequence: 1,
+		Payload: map[string]any{
+			"login": map[string]any{"user": "alice", "password": "hunter2"},
+			"token": "secret",
+			"note":  "hello",
+		},
+	})
+	response := receiveProtocolMessage(t, socket)
+	records := sink.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("expected one audit record, got %#v", records)
+	}
+	record := records[0]
+	if record.Session != response.Session || record.Principal != "alice" ||
+		record.Kind != AuditKindAction || record.Name != action ||
+		record.Outcome != AuditOutcomeError || record.ErrorCode != "locked" || record.Time.IsZero() {
+		t.Fatalf("unexpected audit record: %#v", record)
+	}
+	payload := record.Payload.(map[string]any)
+	login := payload["login"].(map[string]any)
+	if login["password"] != AuditRedacted || login["user"] != "alice" {
+		t.Fatalf("nested field not redacted: %#v", payload)
+	}
+	if _, ok := payload["token"]; ok || payload["note"] != "hello" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestWSAuditRecordsRejectedComponentMessages(t *testing.T) {
+	const name = "AuditedHost"
+	Register(NewHostComponent(name, func(map[string]any) any { return nil }).WithAuditRedaction("PIN"))
+	sink := &recordingAuditSink{}
+	socket, closeSocket := openProtocolSocket(t,
+		WithAuditSink(sink),
+		WithSSCAuthorizer(func(_ context.Context, _ *Session, message Inbound) error {
+			if message.Payload["pin"] == "0000" {
+				return errors.New("denied")
+			}
+			return nil
+		}),
+	)
+	defer closeSocket()
+
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 1, Payload: map[string]any{"pin": "1234", "Pin": "5678"}})
+	receiveProtocolMessage(t, socket)
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 2, Payload: map[string]any{"pin": "0000"}})
+	receiveProtocolMessage(t, socket)
+
+	records := sink.snapshot()
+	if len(records) != 2 {
+		t.Fatalf("expected two audit records, got %#v", records)
+	}
+	accepted := records[0].Payload.(map[string]any)
+	if records[0].Kind != AuditKindComponent || records[0].Outcome != AuditOutcomeOK ||
+		accepted["pin"] != AuditRedacted || accepted["Pin"] != AuditRedacted {
+		t.Fatalf("unexpected accepted record: %#v", records[0])
+	}
+	if records[1].Outcome != AuditOutcomeRejected || records[1].ErrorCode != "forbidden" {
+		t.Fatalf("unexpected rejected record: %#v", records[1])
+	}
+}
+
+func TestWSAuditRecordsProtocolRejections(t *testing.T) {
+	const name = "AuditedSequence"
+	Register(NewHostComponent(name, func(map[string]any) any { return nil }))
+	sink := &recordingAuditSink{}
+	forged, closeForged := openProtocolSocket(t, WithAuditSink(sink), WithCSRF())
+	defer closeForged()
+	sendProtocolMessage(t, forged, Inbound{Component: name, Sequence: 1, CSRFToken: "forged"})
+	if out := receiveProtocolMessage(t, forged); out.Error == nil || out.Error.Code != "csrf_rejected" {
+		t.Fatalf("forged token accepted: %#v", out)
+	}
+
+	socket, closeSocket := openProtocolSocket(t, WithAuditSink(sink))
+	defer closeSocket()
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 1})
+	receiveProtocolMessage(t, socket)
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 3})
+	if out := receiveProtocolMessage(t, socket); out.Error == nil || out.Error.Code != "sequence_gap" {
+		t.Fatalf("sequence gap accepted: %#v", out)
+	}
+
+	records := sink.snapshot()
+	if len(records) != 3 {
+		t.Fatalf("expected three audit records, got %#v", records)
+	}
+	if records[0].Session != "" || records[0].Outcome != AuditOutcomeRejected || records[0].ErrorCode != "csrf_rejected" {
+		t.Fatalf("unexpected csrf record: %#v", records[0])
+	}
+	if records[2].Session == "" || records[2].Outcome != AuditOutcomeRejected || records[2].ErrorCode != "sequence_gap" {
+		t.Fatalf("unexpected sequence gap record: %#v", records[2])
+	}
+}
+
+func TestJSONLinesAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenJSONLinesAuditFile(path)
+	if err != nil {
+		t.Fatalf("open audit file: %v", err)
+	}
+	for _, name := range []string{"first", "second"} {
+		if err := sink.Audit(AuditRecord{Kind: AuditKindAction, Name: name, Outcome: AuditOutcomeOK}); err != nil {
+			t.Fatalf("audit: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer closeTestResource(t, file)
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decode line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, record.Name)
+	}
+	if len(names) != 2 || names[0] != "first" || names[1] != "second" {
+		t.Fatalf("unexpected records: %v", names)
+	}
+}
+
+func TestRedactByTypeMatchesDecoding(t *testing.T) {
+	type secret struct {
+		Value string `json:"value" audit:"redact"`
+	}
+	type Credentials struct {
+		Password string `json:"password" audit:"redact"`
+	}
+	type request struct {
+		Credentials
+		Secrets []secret          `json:"secrets"`
+		ByName  map[string]secret `json:"byName"`
+		Note    string            `json:"note"`
+	}
+	var decoded request
+	raw := `{"PASSWORD":"hunter2","Secrets":[{"VALUE":"a"}],"byname":{"x":{"value":"b"}},"note":"hi"}`
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil || decoded.Password != "hunter2" {
+		t.Fatalf("decode: %v %#v", err, decoded)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	out := redactByType(reflect.TypeOf(request{}), payload)
+	if out["PASSWORD"] != AuditRedacted || out["note"] != "hi" {
+		t.Fatalf("case-insensitive key not redacted: %#v", out)
+	}
+	if item := out["Secrets"].([]any)[0].(map[string]any); item["VALUE"] != AuditRedacted {
+		t.Fatalf("slice item not redacted: %#v", out["Secrets"])
+	}
+	if item := out["byname"].(map[string]any)["x"].(map[string]any); item["value"] != AuditRedacted {
+		t.Fatalf("map item not redacted: %#v", out["byname"])
+	}
+	if payload["PASSWORD"] != "hunter2" {
+		t.Fatalf("payload modified: %#v", payload)
+	}
+}
+
+func TestWSAuditRecordsTypedComponentErrors(t *testing.T) {
+	type input struct {
+		Count int `json:"count"`
+	}
+	type output struct {
+		Count int `json:"count"`
+	}
+	const name = "AuditedTypedHost"
+	Register(NewTypedComponent(name, func(_ *Session, in input) (output, error) {
+		return output{Count: in.Count}, nil
+	}).HostComponent)
+	sink := &recordingAuditSink{}
+	socket, closeSocket := openProtocolSocket(t, WithAuditSink(sink))
+	defer closeSocket()
+
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 1, Payload: map[string]any{"count": 1}})
+	receiveProtocolMessage(t, socket)
+	sendProtocolMessage(t, socket, Inbound{Component: name, Sequence: 2, Payload: map[string]any{"count": "x"}})
+	receiveProtocolMessage(t, socket)
+
+	records := sink.snapshot()
+	if len(records) != 2 || records[0].Outcome != AuditOutcomeOK {
+		t.Fatalf("unexpected audit records: %#v", records)
+	}
+	if records[1].Outcome != AuditOutcomeError || records[1].ErrorCode != "invalid_request" {
+		t.Fatalf("invalid payload audited as %#v", records[1])
+	}
+}
@@ -0,0 +1,178 @@
+package host
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// CSRFCookieName is the HttpOnly cookie holding the per-session CSRF token.
+	CSRFCookieName = "rfw_csrf"
+	// CSRFHeader carries the CSRF token on HTTP requests such as rpc calls.
+	CSRFHeader = "X-RFW-CSRF"
+	// CSRFMetaName is the meta tag name the served index.html exposes the
+	// token under so the client can read it.
+	CSRFMetaName = "rfw-csrf-token"
+)
+
+// DefaultContentSecurityPolicy is the policy sent by WithCSP when no policy
+// is given. {nonce} is replaced with the per-request nonce that is also added
+// to every script tag of index.html. 'wasm-unsafe-eval' allows compiling the
+// application module without allowing eval; inline event handlers are not
+// needed because RTML @on: bindings are attached with addEventListener.
+// style-src keeps 'unsafe-inline' for style attributes rendered by templates.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}' 'wasm-unsafe-eval'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self' ws: wss:; " +
+	"object-src 'none'; base-uri 'self'; frame-ancestors 'none'"
+
+var scriptTagRe = regexp.MustCompile(`(?i)<script\b`)
+
+// WithCSRF issues a per-session CSRF token with the served index.html and
+// requires it on the first SSC message of every WebSocket connection. Wrap
+// rpc and other state-changing HTTP handlers with RequireCSRF.
+func WithCSRF() MuxOption {
+	return func(runtime *WSRuntime) { runtime.csrf = true }
+}
+
+// WithCSP sends policy as the Content-Security-Policy of index.html and adds
+// a fresh nonce to its script tags. Occurrences of {nonce} in policy are
+// replaced with that nonce. An empty policy uses DefaultContentSecurityPolicy.
+func WithCSP(policy string) MuxOption {
+	if policy == "" {
+		policy = DefaultContentSecurityPolicy
+	}
+	return func(runtime *WSRuntime) { runtime.csp = policy }
+}
+
+// CheckCSRF reports whether token matches the CSRF cookie of r. It always
+// succeeds when the endpoint was not configured with WithCSRF.
+func (runtime *WSRuntime) CheckCSRF(r *http.Request, token string) bool {
+	if runtime == nil || !runtime.csrf {
+		return true
+	}
+	return validCSRF(r, token)
+}
+
+// RequireCSRF rejects state-changing requests whose CSRFHeader does not match
+// the CSRF cookie issued with index.html. GET, HEAD and OPTIONS pass through.
+func RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !validCSRF(r, r.Header.Get(CSRFHeader)) {
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validCSRF(r *http.Request, token string) bool {
+	if r == nil || token == "" {
+		return false
+	}
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) == 1
+}
+
+// csrfToken returns the request's CSRF cookie value, issuing a new cookie
+// when none is present.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && len(cookie.Value) >= 32 {
+		return cookie.Value
+	}
+	token := randomToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+func randomToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// ServeIndex serves the index.html at path, injecting the CSRF meta tag and
+// script nonces when the runtime enables them. Servers mounting their own
+// page fallback, such as ssc, use it so WithCSRF and WithCSP apply.
+func (runtime *WSRuntime) ServeIndex(w http.ResponseWriter, r *http.Request, path string) {
+	if runtime == nil || (!runtime.csrf && runtime.csp == "") {
+		http.ServeFile(w, r, path)
+		return
+	}
+	page, err := os.ReadFile(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	header := w.Header()
+	if runtime.csrf {
+		meta := `<meta name="` + CSRFMetaName + `" content="` + html.EscapeString(csrfToken(w, r)) + `">`
+		page = injectHead(page, meta)
+	}
+	if runtime.csp != "" {
+		nonce := randomToken()
+		page = scriptTagRe.ReplaceAll(page, []byte(`<script nonce="`+nonce+`"`))
+		header.Set("Content-Security-Policy", strings.ReplaceAll(runtime.csp, "{nonce}", nonce))
+	}
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(page)
+}
+
+// injectHead inserts fragment right after the opening head tag, or at the
+// start of the document when there is none.
+func injectHead(page []byte, fragment string) []byte {
+	lower := bytes.ToLower(page)
+	for offset := 0; ; {
+		index := bytes.Index(lower[offset:], []byte("<head"))
+		if index < 0 {
+			break
+		}
+		index += offset
+		offset = index + len("<head")
+		if offset < len(page) && page[offset] != '>' && !isHTMLSpace(page[offset]) {
+			continue
+		}
+		if end := bytes.IndexByte(page[index:], '>'); end >= 0 {
+			at := index + end + 1
+			out := make([]byte, 0, len(page)+len(fragment))
+			out = append(out, page[:at]...)
+			out = append(out, fragment...)
+			return append(out, page[at:]...)
+		}
+		break
+	}
+	return append([]byte(fragment), page...)
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
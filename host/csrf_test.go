@@ -0,0 +1,166 @@
+//go:build !js
+
+package host
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+const csrfTestIndex = `<!doctype html><html><head><title>x</title></head><body>` +
+	`<script src="/wasm_exec.js"></script><script>go.run()</script></body></html>`
+
+func fetchIndex(t *testing.T, server *httptest.Server) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("get index: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	closeTestResource(t, resp.Body)
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	return resp, string(body)
+}
+
+func writeTestIndex(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte(csrfTestIndex), 0o600); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	return root
+}
+
+func TestIndexEmbedsCSRFTokenAndCookie(t *testing.T) {
+	server := httptest.NewServer(NewMux(writeTestIndex(t), WithCSRF()))
+	defer server.Close()
+	resp, body := fetchIndex(t, server)
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == CSRFCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("missing strict csrf cookie: %#v", resp.Cookies())
+	}
+	meta := `<head><meta name="rfw-csrf-token" content="` + cookie.Value + `">`
+	if !strings.Contains(body, meta) {
+		t.Fatalf("token not embedded in head: %s", body)
+	}
+}
+
+func TestIndexCSPNoncesScripts(t *testing.T) {
+	server := httptest.NewServer(NewMux(writeTestIndex(t), WithCSP("")))
+	defer server.Close()
+	resp, body := fetchIndex(t, server)
+	policy := resp.Header.Get("Content-Security-Policy")
+	start := strings.Index(policy, "'nonce-")
+	if start < 0 || !strings.Contains(policy, "'wasm-unsafe-eval'") {
+		t.Fatalf("unexpected policy: %q", policy)
+	}
+	nonce := policy[start+len("'nonce-"):]
+	nonce = nonce[:strings.IndexByte(nonce, '\'')]
+	if strings.Count(body, `<script nonce="`+nonce+`"`) != 2 {
+		t.Fatalf("scripts not nonced with %q: %s", nonce, body)
+	}
+	_, again := fetchIndex(t, server)
+	if strings.Contains(again, nonce) {
+		t.Fatal("nonce reused across requests")
+	}
+}
+
+func TestWSRequiresCSRFOnFirstMessage(t *testing.T) {
+	type request struct{}
+	const action = "test.ws.csrf"
+	if err := RegisterAction(action, func(_ context.Context, _ *Session, _ request) (string, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("register action: %v", err)
+	}
+	server := httptest.NewServer(NewMux(writeTestIndex(t), WithCSRF()))
+	defer server.Close()
+	resp, _ := fetchIndex(t, server)
+	token := ""
+	for _, c := range resp.Cookies() {
+		if c.Name == CSRFCookieName {
+			token = c.Value
+		}
+	}
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	dial := func() *websocket.Conn {
+		config, err := websocket.NewConfig(wsURL, server.URL)
+		if err != nil {
+			t.Fatalf("websocket config: %v", err)
+		}
+		config.Header.Set("Cookie", CSRFCookieName+"="+token)
+		socket, err := websocket.DialConfig(config)
+		if err != nil {
+			t.Fatalf("dial websocket: %v", err)
+		}
+		return socket
+	}
+
+	rejected := dial()
+	defer closeTestResource(t, rejected)
+	sendProtocolMessage(t, rejected, Inbound{Action: action, ID: "bad", Sequence: 1, CSRFToken: "forged"})
+	if out := receiveProtocolMessage(t, rejected); out.Error == nil || out.Error.Code != "csrf_rejected" {
+		t.Fatalf("forged token accepted: %#v", out)
+	}
+
+	accepted := dial()
+	defer closeTestResource(t, accepted)
+	sendProtocolMessage(t, accepted, Inbound{Action: action, ID: "good", Sequence: 1, CSRFToken: token})
+	if out := receiveProtocolMessage(t, accepted); out.Error != nil || out.Payload != "ok" {
+		t.Fatalf("valid token rejected: %#v", out)
+	}
+}
+
+func TestRequireCSRF(t *testing.T) {
+	handler := RequireCSRF(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	post := func(cookie, header string) int {
+		r := httptest.NewRequest(http.MethodPost, "/rpc", nil)
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: cookie})
+		}
+		if header != "" {
+			r.Header.Set(CSRFHeader, header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := post("secret", ""); code != http.StatusForbidden {
+		t.Fatalf("missing header allowed: %d", code)
+	}
+	if code := post("secret", "other"); code != http.StatusForbidden {
+		t.Fatalf("mismatched header allowed: %d", code)
+	}
+	if code := post("secret", "secret"); code != http.StatusNoContent {
+		t.Fatalf("valid token rejected: %d", code)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rpc", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("safe method rejected: %d", w.Code)
+	}
+}
+
+func TestInjectHeadSkipsHeaderElement(t *testing.T) {
+	got := string(injectHead([]byte(`<header>x</header>`), "<meta>"))
+	if got != "<meta><header>x</header>" {
+		t.Fatalf("unexpected injection: %s", got)
+	}
+}
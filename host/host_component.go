@@ -1,6 +1,8 @@
 package host
 
 import (
+	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/rfwlab/rfw/v2/state"
@@ -20,6 +22,8 @@ type HostComponent struct {
 	handler        Handler
 	sessionHandler HandlerWithSession
 	initSnapshot   func(*Session, map[string]any) *InitSnapshot
+	auditRedact    map[string]bool
+	auditType      reflect.Type
 }
 
 // ServerComponent is the concise name for HostComponent.
@@ -52,6 +56,34 @@ func (hc *HostComponent) WithInitSnapshot(fn func(*Session, map[string]any) *Ini
 	return hc
 }
 
+// WithAuditRedaction replaces the given payload keys with AuditRedacted in
+// audit records for this component. Keys match case-insensitively, as
+// encoding/json matches field names.
+func (hc *HostComponent) WithAuditRedaction(keys ...string) *HostComponent {
+	if hc.auditRedact == nil {
+		hc.auditRedact = make(map[string]bool, len(keys))
+	}
+	for _, key := range keys {
+		hc.auditRedact[strings.ToLower(key)] = true
+	}
+	return hc
+}
+
+func (hc *HostComponent) redactPayload(payload map[string]any) map[string]any {
+	var out map[string]any
+	if hc.auditType != nil {
+		out = redactByType(hc.auditType, payload)
+	} else {
+		out = copyPayload(payload)
+	}
+	for key := range out {
+		if hc.auditRedact[strings.ToLower(key)] {
+			out[key] = AuditRedacted
+		}
+	}
+	return out
+}
+
 // Name returns the registered component name.
 func (hc *HostComponent) Name() string { return hc.name }
 
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"html"
 	"strings"
+
+	"github.com/rfwlab/rfw/v2/sanitize"
 )
 
 const hostVarAttr = "data-host-var"
@@ -77,6 +79,21 @@ func Raw(html string) string {
 	return html
 }
 
+// Sanitized filters an untrusted HTML fragment through policy so it can be
+// embedded in InitSnapshot.HTML. A nil policy uses sanitize.UserContent.
+func Sanitized(fragment string, policy *sanitize.Policy) string {
+	if policy == nil {
+		return sanitize.HTML(fragment)
+	}
+	return policy.Sanitize(fragment)
+}
+
+// SanitizedTag builds a host variable element whose value is untrusted markup
+// filtered through policy. A nil policy uses sanitize.UserContent.
+func SanitizedTag(tag, name string, value any, policy *sanitize.Policy) string {
+	return hostVarTag(tag, name, Sanitized(fmt.Sprintf("%v", value), policy), false)
+}
+
 // Join concatenates rendered host fragments.
 func Join(parts ...string) string {
 	var b strings.Builder
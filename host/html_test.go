@@ -3,6 +3,8 @@ package host
 import (
 	"strings"
 	"testing"
+
+	"github.com/rfwlab/rfw/v2/sanitize"
 )
 
 func TestSpan(t *testing.T) {
@@ -109,3 +111,15 @@ func TestTagRejectsInvalidName(t *testing.T) {
 		t.Fatalf("invalid tag name was accepted: %s", got)
 	}
 }
+
+// Sanitized filters untrusted markup instead of passing it through like Raw.
+func TestSanitized(t *testing.T) {
+	got := Sanitized(`<b>ok</b><script>alert(1)</script>`, nil)
+	if got != "<b>ok</b>" {
+		t.Fatalf("unexpected sanitized output: %q", got)
+	}
+	got = SanitizedTag("div", "bio", `<em>hi</em><img src=x onerror=alert(1)>`, sanitize.Strict())
+	if !strings.Contains(got, `data-host-var="bio"`) || !strings.Contains(got, "><em>hi</em></div>") {
+		t.Fatalf("unexpected sanitized tag: %s", got)
+	}
+}
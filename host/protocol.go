@@ -11,6 +11,7 @@ type Inbound struct {
 	Sequence    uint64         `json:"sequence,omitempty"`
 	Ack         uint64         `json:"ack,omitempty"`
 	ResumeToken string         `json:"resumeToken,omitempty"`
+	CSRFToken   string         `json:"csrf,omitempty"`
 }
 
 // Outbound is a host-to-client SSC protocol message.
@@ -79,7 +79,7 @@ func NewMux(root string, opts ...MuxOption) *http.ServeMux {
 		// returning HTML for CSS, JS, image, etc. requests.
 		accept := r.Header.Get("Accept")
 		if strings.Contains(accept, "text/html") || r.URL.Path == "/" || r.URL.Path == "" {
-			http.ServeFile(w, r, filepath.Join(root, "index.html"))
+			runtime.ServeIndex(w, r, filepath.Join(root, "index.html"))
 			return
 		}
 		http.NotFound(w, r)
@@ -2,6 +2,8 @@ package host
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
 	"sync"
@@ -16,6 +18,8 @@ import (
 type Session struct {
 	id          string
 	resumeToken string
+	principal   string
+	fingerprint []byte
 	stores      *state.StoreManager
 
 	ctxMu sync.RWMutex
@@ -61,8 +65,47 @@ func newSession(id string, options ...sessionOptions) *Session {
 // ID returns the session ID.
 func (s *Session) ID() string { return s.id }
 
-// ResumeToken returns the opaque token used to resume this session.
-func (s *Session) ResumeToken() string { return s.resumeToken }
+// ResumeToken returns the opaque token used to resume this session. The token
+// changes on every successful resume.
+func (s *Session) ResumeToken() string {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+	return s.resumeToken
+}
+
+// Principal returns the identity recorded by SetPrincipal.
+func (s *Session) Principal() string {
+	s.ctxMu.RLock()
+	defer s.ctxMu.RUnlock()
+	return s.principal
+}
+
+// SetPrincipal records the authenticated identity that owns the session.
+// Call it from a SessionInitializer; resume is rejected when the principal
+// initialized from the resuming request differs.
+func (s *Session) SetPrincipal(principal string) {
+	s.ctxMu.Lock()
+	s.principal = principal
+	s.ctxMu.Unlock()
+}
+
+// connected reports whether a connection is attached to the session, rather
+// than the session waiting to be resumed or being released.
+func (s *Session) connected() bool {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+	return s.attached && !s.released
+}
+
+func (s *Session) setFingerprint(fingerprint string) {
+	sum := sha256.Sum256([]byte(fingerprint))
+	s.fingerprint = sum[:]
+}
+
+func (s *Session) matchesFingerprint(fingerprint string) bool {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return subtle.ConstantTimeCompare(s.fingerprint, sum[:]) == 1
+}
 
 // StoreManager returns the session-local store registry.
 func (s *Session) StoreManager() *state.StoreManager { return s.stores }
@@ -161,23 +204,36 @@ func SuspendSession(session *Session, ttl time.Duration) {
 	session.outboundMu.Unlock()
 }
 
-// ResumeSession attaches a disconnected session by opaque token.
-// The new socket must call ReplaySession or BindSessionConnection before sends.
-func ResumeSession(token string) (*Session, bool) {
+// sessionForToken looks up a retained session without attaching it.
+func sessionForToken(token string) *Session {
 	if token == "" {
-		return nil, false
+		return nil
 	}
 	sessionMu.RLock()
-	session := sessionByToken[token]
-	sessionMu.RUnlock()
+	defer sessionMu.RUnlock()
+	return sessionByToken[token]
+}
+
+// ResumeSession attaches a disconnected session by opaque token and rotates
+// the token, so a presented token is accepted at most once.
+// The new socket must call ReplaySession or BindSessionConnection before sends.
+func ResumeSession(token string) (*Session, bool) {
+	session := sessionForToken(token)
 	if session == nil {
 		return nil, false
 	}
 	session.deliveryMu.Lock()
 	defer session.deliveryMu.Unlock()
-	if session.released || session.attached || (!session.expires.IsZero() && time.Now().After(session.expires)) {
+	if session.released || session.attached || session.resumeToken != token ||
+		(!session.expires.IsZero() && time.Now().After(session.expires)) {
 		return nil, false
 	}
+	rotated := generateSessionID() + generateSessionID()
+	sessionMu.Lock()
+	delete(sessionByToken, token)
+	sessionByToken[rotated] = session
+	session.resumeToken = rotated
+	sessionMu.Unlock()
 	session.attached = true
 	session.resumePending = true
 	session.expires = time.Time{}
@@ -2,6 +2,8 @@ package host
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -101,3 +103,133 @@ func TestExpiredTimerDoesNotReleaseResumedSession(t *testing.T) {
 		t.Fatal("stale expiry released the resumed session")
 	}
 }
+
+func TestResumeRotatesToken(t *testing.T) {
+	session := AllocateResumableSession(4)
+	defer ReleaseSession(session)
+	token := session.ResumeToken()
+	SuspendSession(session, time.Second)
+	if _, ok := ResumeSession(token); !ok {
+		t.Fatal("session did not resume")
+	}
+	rotated := session.ResumeToken()
+	if rotated == "" || rotated == token {
+		t.Fatalf("token was not rotated: %q", rotated)
+	}
+	if out := session.PrepareOutbound(Outbound{Payload: "x"}); out.ResumeToken != rotated {
+		t.Fatalf("outbound carries stale token: %q", out.ResumeToken)
+	}
+	SuspendSession(session, time.Second)
+	if _, ok := ResumeSession(token); ok {
+		t.Fatal("spent token resumed the session")
+	}
+	if _, ok := ResumeSession(rotated); !ok {
+		t.Fatal("rotated token did not resume the session")
+	}
+}
+
+func TestResumeBoundToFingerprint(t *testing.T) {
+	runtime := NewWSRuntime(
+		WithSSCLimits(SSCLimits{ResumeTTL: time.Second}),
+		WithSSCResumeFingerprint(func(r *http.Request) string { return r.UserAgent() }),
+	)
+	request := func(agent string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("User-Agent", agent)
+		return r
+	}
+	session, err := runtime.NewSession(request("alpha"))
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	defer ReleaseSession(session)
+	SuspendSession(session, time.Second)
+	token := session.ResumeToken()
+
+	other, resumed, err := runtime.OpenSession(request("beta"), token)
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	ReleaseSession(other)
+	if resumed || other == session {
+		t.Fatal("token resumed from a different fingerprint")
+	}
+	same, resumed, err := runtime.OpenSession(request("alpha"), token)
+	if err != nil || !resumed || same != session {
+		t.Fatalf("matching fingerprint did not resume: resumed=%v err=%v", resumed, err)
+	}
+}
+
+func TestResumeBoundToPrincipal(t *testing.T) {
+	runtime := NewWSRuntime(
+		WithSSCLimits(SSCLimits{ResumeTTL: time.Second}),
+		WithSSCSessionInitializer(func(r *http.Request, session *Session) error {
+			session.SetPrincipal(r.Header.Get("X-User"))
+			return nil
+		}),
+	)
+	request := func(user string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("X-User", user)
+		return r
+	}
+	session, err := runtime.NewSession(request("alice"))
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	defer ReleaseSession(session)
+	if session.Principal() != "alice" {
+		t.Fatalf("principal = %q", session.Principal())
+	}
+	SuspendSession(session, time.Second)
+	token := session.ResumeToken()
+
+	other, resumed, err := runtime.OpenSession(request("mallory"), token)
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	ReleaseSession(other)
+	if resumed || other.Principal() != "mallory" {
+		t.Fatal("token resumed for a different principal")
+	}
+	if _, resumed, _ := runtime.OpenSession(request("alice"), token); !resumed {
+		t.Fatal("owning principal could not resume")
+	}
+}
+
+// On resume the initializer runs on a probe that is neither the retained
+// session nor registered, so it leaves the retained session as it was.
+func TestResumeProbesInitializerOnDetachedSession(t *testing.T) {
+	var initialized []*Session
+	runtime := NewWSRuntime(
+		WithSSCLimits(SSCLimits{ResumeTTL: time.Second}),
+		WithSSCSessionInitializer(func(_ *http.Request, session *Session) error {
+			initialized = append(initialized, session)
+			session.SetPrincipal("alice")
+			session.ContextSet("run", len(initialized))
+			return nil
+		}),
+	)
+	request := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	session, err := runtime.NewSession(request)
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	defer ReleaseSession(session)
+	SuspendSession(session, time.Second)
+
+	resumed, ok, err := runtime.OpenSession(request, session.ResumeToken())
+	if err != nil || !ok || resumed != session {
+		t.Fatalf("open session = %v, %v, %v", resumed, ok, err)
+	}
+	if len(initialized) != 2 {
+		t.Fatalf("initializer ran %d times, want 2", len(initialized))
+	}
+	probe := initialized[1]
+	if probe == session || probe.ID() != "" {
+		t.Fatalf("initializer probed with %q, want a detached session", probe.ID())
+	}
+	if run, _ := session.ContextGet("run"); run != 1 {
+		t.Fatalf("probe reached the retained session: run = %v", run)
+	}
+}
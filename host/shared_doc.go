@@ -0,0 +1,204 @@
+package host
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/rfwlab/rfw/v2/crdt"
+)
+
+// SharedDocChannel returns the host component name a shared document is
+// exchanged on.
+func SharedDocChannel(name string) string { return "rfw:doc:" + name }
+
+// SharedDoc is the authoritative replica of a collaborative document. Clients
+// opened with hostclient.OpenSharedDoc send their operations here; the host
+// applies each one once, appends it to the document log and broadcasts it to
+// every subscribed client. A client that reconnects, resumed or not, asks for
+// the log entries after the last one it saw and resends the edits the host has
+// not confirmed, so offline edits merge instead of overwriting.
+type SharedDoc struct {
+	name string
+	doc  *crdt.Doc
+
+	// mu serializes log appends with their broadcast, so clients receive log
+	// ranges in order.
+	mu  sync.Mutex
+	log []crdt.Op
+	// owners binds each client replica to the session that first sent its
+	// operations, so no other client can write under its IDs.
+	owners map[string]replicaOwner
+}
+
+// replicaOwner is the session, and its principal, a client replica belongs
+// to.
+type replicaOwner struct {
+	session   string
+	principal string
+}
+
+// hostReplica is the replica ID of the host's own edits.
+const hostReplica = "host"
+
+// NewSharedDoc creates a shared document and registers its host component.
+// Use WithSSCAuthorizer to restrict who may edit SharedDocChannel(name).
+func NewSharedDoc(name string) *SharedDoc {
+	sd := &SharedDoc{name: name, doc: crdt.NewDoc(hostReplica), owners: make(map[string]replicaOwner)}
+	Register(NewHostComponentWithSession(SharedDocChannel(name), sd.handle))
+	return sd
+}
+
+// Name returns the document name.
+func (sd *SharedDoc) Name() string { return sd.name }
+
+// Snapshot returns the current registers and lists.
+func (sd *SharedDoc) Snapshot() map[string]any { return sd.doc.Snapshot() }
+
+// Get returns a register value.
+func (sd *SharedDoc) Get(key string) any { return sd.doc.Get(key) }
+
+// List returns the visible elements of a list.
+func (sd *SharedDoc) List(key string) []any { return sd.doc.List(key) }
+
+// Set writes a register from the host.
+func (sd *SharedDoc) Set(key string, value any) {
+	sd.publishLocal(func() []crdt.Op { return []crdt.Op{sd.doc.Set(key, value)} })
+}
+
+// Insert inserts a list element from the host.
+func (sd *SharedDoc) Insert(list string, index int, value any) {
+	sd.publishLocal(func() []crdt.Op { return []crdt.Op{sd.doc.Insert(list, index, value)} })
+}
+
+// Append appends a list element from the host.
+func (sd *SharedDoc) Append(list string, value any) {
+	sd.publishLocal(func() []crdt.Op { return []crdt.Op{sd.doc.Append(list, value)} })
+}
+
+// Remove deletes a list element from the host.
+func (sd *SharedDoc) Remove(list string, index int) {
+	sd.publishLocal(func() []crdt.Op {
+		if op, ok := sd.doc.Remove(list, index); ok {
+			return []crdt.Op{op}
+		}
+		return nil
+	})
+}
+
+func (sd *SharedDoc) publishLocal(edit func() []crdt.Op) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	ops := edit()
+	if len(ops) == 0 {
+		return
+	}
+	from := len(sd.log)
+	sd.log = append(sd.log, ops...)
+	Broadcast(SharedDocChannel(sd.name), sd.rangePayload(from, ops))
+}
+
+// Apply integrates operations received from a client and broadcasts the ones
+// that were new. It returns the log length after applying them. Apply trusts
+// the replica IDs of ops; client messages are checked against their session
+// first.
+func (sd *SharedDoc) Apply(ops []crdt.Op) int {
+	seq, _ := sd.apply(nil, ops)
+	return seq
+}
+
+// apply integrates ops sent by session, or by the host itself when session is
+// nil. It stops at the first operation the session may not send or the
+// document rejects, keeping the ones before it.
+func (sd *SharedDoc) apply(session *Session, ops []crdt.Op) (int, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	from := len(sd.log)
+	var err error
+	for _, op := range ops {
+		if session != nil {
+			if err = sd.claimLocked(session, op.ID.Replica); err != nil {
+				break
+			}
+		}
+		var applied bool
+		if applied, err = sd.doc.TryApply(op); err != nil {
+			break
+		}
+		if applied {
+			sd.log = append(sd.log, op)
+		}
+	}
+	if len(sd.log) > from {
+		Broadcast(SharedDocChannel(sd.name), sd.rangePayload(from, sd.log[from:]))
+	}
+	return len(sd.log), err
+}
+
+var errReplicaOwned = errors.New("replica belongs to another session")
+
+// claimLocked checks that session may write as replica, binding a new
+// replica to it. A replica stays with its session, or with its principal,
+// while that session is connected: a client reconnecting without resuming
+// takes its replica over, even while the old session waits to be resumed.
+// Callers must hold sd.mu.
+func (sd *SharedDoc) claimLocked(session *Session, replica string) error {
+	if replica == hostReplica {
+		return errReplicaOwned
+	}
+	principal := session.Principal()
+	owner, ok := sd.owners[replica]
+	switch {
+	case !ok, owner.session == session.ID():
+	case owner.principal != "" && owner.principal == principal:
+	case owner.principal == "":
+		if previous, live := SessionByID(owner.session); live && previous.connected() {
+			return errReplicaOwned
+		}
+	default:
+		return errReplicaOwned
+	}
+	sd.owners[replica] = replicaOwner{session: session.ID(), principal: principal}
+	return nil
+}
+
+// rangePayload describes log entries [from, from+len(ops)). Callers must hold
+// sd.mu.
+func (sd *SharedDoc) rangePayload(from int, ops []crdt.Op) map[string]any {
+	return map[string]any{
+		"from": from,
+		"seq":  from + len(ops),
+		"ops":  append([]crdt.Op(nil), ops...),
+	}
+}
+
+// handle answers the client protocol: "init" with the current log length,
+// "ops" by applying them, and "since" with the log entries after it.
+func (sd *SharedDoc) handle(session *Session, payload map[string]any) any {
+	if payload["init"] == true {
+		sd.mu.Lock()
+		seq := len(sd.log)
+		sd.mu.Unlock()
+		return map[string]any{"hello": true, "seq": seq}
+	}
+	if raw, ok := payload["ops"]; ok {
+		ops, err := crdt.DecodeOps(raw)
+		if err != nil {
+			return NewActionError("invalid_ops", "shared document operations are malformed")
+		}
+		if session == nil {
+			return NewActionError("invalid_ops", "shared document operations need a session")
+		}
+		if _, err := sd.apply(session, ops); err != nil {
+			logger.Warn("shared document operations rejected", "doc", sd.name, "session", session.ID(), "err", err)
+			return NewActionError("invalid_ops", "shared document operations were rejected")
+		}
+	}
+	since, ok := payload["since"].(float64)
+	if !ok {
+		return nil
+	}
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	from := min(max(int(since), 0), len(sd.log))
+	return sd.rangePayload(from, sd.log[from:])
+}
@@ -0,0 +1,141 @@
+package host
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rfwlab/rfw/v2/crdt"
+)
+
+func wirePayload(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func TestSharedDocProtocol(t *testing.T) {
+	sd := NewSharedDoc("shared-doc-protocol")
+	hello := wirePayload(t, sd.handle(nil, map[string]any{"init": true}))
+	if hello["hello"] != true || hello["seq"] != float64(0) {
+		t.Fatalf("unexpected hello: %v", hello)
+	}
+
+	session := AllocateSession()
+	defer ReleaseSession(session)
+	client := crdt.NewDoc("client")
+	op := client.Append("todos", "milk")
+	if reply := sd.handle(session, wirePayload(t, map[string]any{"ops": []crdt.Op{op}})); reply != nil {
+		t.Fatalf("ops without since should not reply, got %v", reply)
+	}
+	// A replayed edit, e.g. resent after reconnecting, is applied once.
+	sd.handle(session, wirePayload(t, map[string]any{"ops": []crdt.Op{op}}))
+	sd.Append("todos", "eggs")
+
+	reply := wirePayload(t, sd.handle(nil, map[string]any{"since": float64(1)}))
+	if reply["from"] != float64(1) || reply["seq"] != float64(2) {
+		t.Fatalf("unexpected range: %v", reply)
+	}
+	ops, err := crdt.DecodeOps(reply["ops"])
+	if err != nil || len(ops) != 1 || ops[0].Value != "eggs" {
+		t.Fatalf("unexpected ops: %+v (%v)", ops, err)
+	}
+	if got := sd.List("todos"); len(got) != 2 || got[0] != "milk" {
+		t.Fatalf("unexpected list: %v", got)
+	}
+}
+
+func TestSharedDocRejectsMalformedOps(t *testing.T) {
+	sd := NewSharedDoc("shared-doc-malformed")
+	reply := sd.handle(newSession("malformed"), map[string]any{"ops": "nope"})
+	if err, ok := reply.(*ActionError); !ok || err.Code != "invalid_ops" {
+		t.Fatalf("unexpected reply: %#v", reply)
+	}
+}
+
+func TestSharedDocRejectsHostileClient(t *testing.T) {
+	sd := NewSharedDoc("shared-doc-hostile")
+	owner, hostile := AllocateSession(), AllocateSession()
+	defer ReleaseSession(owner)
+	defer ReleaseSession(hostile)
+	rejected := func(session *Session, ops ...crdt.Op) bool {
+		t.Helper()
+		reply := sd.handle(session, wirePayload(t, map[string]any{"ops": ops}))
+		err, ok := reply.(*ActionError)
+		return ok && err.Code == "invalid_ops"
+	}
+
+	client := crdt.NewDoc("victim")
+	if rejected(owner, client.Append("todos", "milk")) {
+		t.Fatal("owner's edit rejected")
+	}
+	forged := crdt.Op{ID: crdt.ID{Counter: 2, Replica: "victim"}, Kind: crdt.OpSet, Key: "title", Value: "forged"}
+	if !rejected(hostile, forged) {
+		t.Fatal("edit under another session's replica accepted")
+	}
+	if !rejected(hostile, crdt.Op{ID: crdt.ID{Counter: 1, Replica: hostReplica}, Kind: crdt.OpSet, Key: "title", Value: "x"}) {
+		t.Fatal("edit under the host replica accepted")
+	}
+	if !rejected(hostile, crdt.Op{ID: crdt.ID{Counter: 1 << 50, Replica: "mallory"}, Kind: crdt.OpSet, Key: "title", Value: "x"}) {
+		t.Fatal("edit with a counter far ahead of the clock accepted")
+	}
+	orphans := make([]crdt.Op, crdt.MaxPending+1)
+	for i := range orphans {
+		orphans[i] = crdt.Op{ID: crdt.ID{Counter: uint64(i + 1), Replica: "mallory"}, Kind: crdt.OpDelete, Key: "todos",
+			Target: crdt.ID{Counter: 1, Replica: "nowhere"}}
+	}
+	if !rejected(hostile, orphans...) {
+		t.Fatal("unbounded operations waiting for their element accepted")
+	}
+	if got := sd.Get("title"); got != nil {
+		t.Fatalf("title = %v", got)
+	}
+
+	// the host's own edits still carry sane counters
+	sd.Set("title", "host")
+	op := client.Set("title", "victim")
+	if rejected(owner, op) || sd.Get("title") != "host" {
+		t.Fatalf("title = %v", sd.Get("title"))
+	}
+
+	// a replica whose session is gone moves to the client's new session
+	ReleaseSession(owner)
+	reconnected := AllocateSession()
+	defer ReleaseSession(reconnected)
+	if rejected(reconnected, client.Append("todos", "eggs")) {
+		t.Fatal("replica not handed over after its session ended")
+	}
+}
+
+func TestSharedDocReconnectWithoutResume(t *testing.T) {
+	sd := NewSharedDoc("shared-doc-reconnect")
+	first := AllocateResumableSession(16)
+	defer ReleaseSession(first)
+	client := crdt.NewDoc("laptop")
+	if reply := sd.handle(first, wirePayload(t, map[string]any{"ops": []crdt.Op{client.Set("title", "draft")}})); reply != nil {
+		t.Fatalf("reply = %v", reply)
+	}
+
+	// the connection drops and the client comes back in a new session, while
+	// the old one is still retained for resuming
+	SuspendSession(first, time.Minute)
+	if _, live := SessionByID(first.ID()); !live {
+		t.Fatal("suspended session not retained")
+	}
+	second := AllocateSession()
+	defer ReleaseSession(second)
+	reply := sd.handle(second, wirePayload(t, map[string]any{"ops": []crdt.Op{client.Set("title", "offline edit")}, "since": 0}))
+	if err, ok := reply.(*ActionError); ok {
+		t.Fatalf("offline edits rejected: %v", err)
+	}
+	if got := sd.Get("title"); got != "offline edit" {
+		t.Fatalf("title = %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package host
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/rfwlab/rfw/v2/internal/jsonfields"
+)
+
+// TypedHandler handles a strictly decoded payload and returns the host
+// variables to push to the client.
+type TypedHandler[In, Out any] func(*Session, In) (Out, error)
+
+// TypedComponent is a HostComponent whose payloads and host variables are Go
+// structs. In is decoded with unknown fields rejected, and Out is encoded to
+// host variables named after its JSON field names, which is how `{h:name}`
+// placeholders address them.
+type TypedComponent[In, Out any] struct {
+	*HostComponent
+	state func(*Session) (Out, error)
+}
+
+// NewTypedComponent builds a typed host component. Register it with
+// Register(component.HostComponent). A handler error is sent to the client
+// as an ActionError; return one from NewActionError to control its code.
+func NewTypedComponent[In, Out any](name string, handler TypedHandler[In, Out]) *TypedComponent[In, Out] {
+	tc := &TypedComponent[In, Out]{}
+	tc.HostComponent = &HostComponent{
+		name:      name,
+		auditType: reflect.TypeFor[In](),
+		sessionHandler: func(session *Session, payload map[string]any) any {
+			if payload != nil && payload["init"] == true {
+				return tc.initialVars(session)
+			}
+			var in In
+			if err := decodeActionPayload(payload, &in); err != nil {
+				return &ActionError{Code: "invalid_request", Message: err.Error()}
+			}
+			if handler == nil {
+				return NewActionError("not_implemented", "component handler is not configured")
+			}
+			out, err := handler(session, in)
+			if err != nil {
+				return publicActionError(err, "component_failed", "component failed")
+			}
+			vars, err := EncodeHostVars(out)
+			if err != nil {
+				return NewActionError("component_failed", "component failed")
+			}
+			return vars
+		},
+	}
+	return tc
+}
+
+// WithState registers the source of the component's current host variables.
+// It answers the client's init message with the values and resync requests
+// with an InitSnapshot built by SnapshotOf.
+func (tc *TypedComponent[In, Out]) WithState(fn func(*Session) (Out, error)) *TypedComponent[In, Out] {
+	tc.state = fn
+	tc.initSnapshot = func(session *Session, _ map[string]any) *InitSnapshot {
+		out, err := fn(session)
+		if err != nil {
+			return nil
+		}
+		snapshot, err := SnapshotOf(out)
+		if err != nil {
+			return nil
+		}
+		return &snapshot
+	}
+	return tc
+}
+
+func (tc *TypedComponent[In, Out]) initialVars(session *Session) any {
+	if tc.state == nil {
+		return nil
+	}
+	out, err := tc.state(session)
+	if err != nil {
+		return publicActionError(err, "component_failed", "component failed")
+	}
+	vars, err := EncodeHostVars(out)
+	if err != nil {
+		return NewActionError("component_failed", "component failed")
+	}
+	return vars
+}
+
+// EncodeHostVars converts a struct into host variables keyed by JSON field
+// name.
+func EncodeHostVars(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var vars map[string]any
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("host: %T does not encode to an object", value)
+	}
+	return vars, nil
+}
+
+// SnapshotOf renders every host variable of value as an escaped span, in
+// field order, and lists them in Vars.
+func SnapshotOf[Out any](value Out) (InitSnapshot, error) {
+	vars, err := EncodeHostVars(value)
+	if err != nil {
+		return InitSnapshot{}, err
+	}
+	names := hostVarNames(reflect.TypeFor[Out]())
+	parts := make([]string, 0, len(names))
+	listed := make([]string, 0, len(names))
+	for _, name := range names {
+		v, ok := vars[name]
+		if !ok {
+			continue
+		}
+		parts = append(parts, Span(name, v))
+		listed = append(listed, name)
+	}
+	return InitSnapshot{HTML: Join(parts...), Vars: listed}, nil
+}
+
+// hostVarNames returns the JSON names of t's fields in the order
+// encoding/json writes them, promoted fields of embedded structs included.
+func hostVarNames(t reflect.Type) []string {
+	return jsonfields.Names(jsonfields.Of(t))
+}
@@ -0,0 +1,72 @@
+package host
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type typedCounterIn struct {
+	Increment bool `json:"increment"`
+}
+
+type typedCounterOut struct {
+	Count int    `json:"count"`
+	Label string `json:"label"`
+}
+
+func TestTypedComponentDecodesAndEncodes(t *testing.T) {
+	count := 0
+	tc := NewTypedComponent("TypedCounter", func(_ *Session, in typedCounterIn) (typedCounterOut, error) {
+		if in.Increment {
+			count++
+		}
+		return typedCounterOut{Count: count, Label: "<b>n</b>"}, nil
+	})
+	session := newSession("typed")
+	resp := tc.HandleWithSession(session, map[string]any{"increment": true})
+	vars, ok := resp.(map[string]any)
+	if !ok || vars["count"] != float64(1) || vars["label"] != "<b>n</b>" {
+		t.Fatalf("unexpected host vars: %#v", resp)
+	}
+	resp = tc.HandleWithSession(session, map[string]any{"increment": "yes"})
+	if err, ok := resp.(*ActionError); !ok || err.Code != "invalid_request" {
+		t.Fatalf("wrong type accepted: %#v", resp)
+	}
+	resp = tc.HandleWithSession(session, map[string]any{"increment": true, "admin": true})
+	if err, ok := resp.(*ActionError); !ok || err.Code != "invalid_request" {
+		t.Fatalf("unknown field accepted: %#v", resp)
+	}
+}
+
+func TestTypedComponentErrors(t *testing.T) {
+	tc := NewTypedComponent("TypedFailing", func(_ *Session, _ typedCounterIn) (typedCounterOut, error) {
+		return typedCounterOut{}, errors.New("database down")
+	})
+	resp := tc.HandleWithSession(newSession("typed-err"), map[string]any{})
+	if err, ok := resp.(*ActionError); !ok || err.Code != "component_failed" {
+		t.Fatalf("internal error leaked or missing: %#v", resp)
+	}
+}
+
+func TestTypedComponentStateAnswersInitAndResync(t *testing.T) {
+	tc := NewTypedComponent[typedCounterIn, typedCounterOut]("TypedState", nil).
+		WithState(func(*Session) (typedCounterOut, error) {
+			return typedCounterOut{Count: 7, Label: "<i>x</i>"}, nil
+		})
+	session := newSession("typed-state")
+	vars, ok := tc.HandleWithSession(session, map[string]any{"init": true}).(map[string]any)
+	if !ok || vars["count"] != float64(7) {
+		t.Fatalf("init did not return state: %#v", vars)
+	}
+	snap, ok := tc.HandleWithSession(session, map[string]any{"resync": true}).(*InitSnapshot)
+	if !ok {
+		t.Fatal("resync did not return a snapshot")
+	}
+	if len(snap.Vars) != 2 || snap.Vars[0] != "count" || snap.Vars[1] != "label" {
+		t.Fatalf("unexpected snapshot vars: %v", snap.Vars)
+	}
+	if !strings.Contains(snap.HTML, `data-host-var="count"`) || !strings.Contains(snap.HTML, "&lt;i&gt;x&lt;/i&gt;") {
+		t.Fatalf("snapshot not rendered with escaping helpers: %s", snap.HTML)
+	}
+}
@@ -77,6 +77,12 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 			continue
 		}
 		if session == nil {
+			if !runtime.CheckCSRF(ws.Request(), msg.CSRFToken) {
+				csrfErr := NewActionError("csrf_rejected", "invalid csrf token")
+				runtime.AuditMessage(nil, msg, csrfErr)
+				SendOutbound(ws, Outbound{Error: csrfErr})
+				return
+			}
 			var resumed bool
 			var err error
 			session, resumed, err = runtime.OpenSession(ws.Request(), msg.ResumeToken)
@@ -99,18 +105,22 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 			if errors.Is(err, ErrDuplicateMessage) {
 				continue
 			}
+			gapErr := NewActionError("sequence_gap", "client message sequence gap")
+			runtime.AuditMessage(session, msg, gapErr)
 			SendSessionOutbound(ws, session, Outbound{
 				ID:     msg.ID,
 				Action: msg.Action,
-				Error:  NewActionError("sequence_gap", "client message sequence gap"),
+				Error:  gapErr,
 			})
 			continue
 		}
 		if !session.AllowMessage(runtime.MessagesPerMinute()) {
+			rateErr := NewActionError("rate_limited", "message rate limit exceeded")
+			runtime.AuditMessage(session, msg, rateErr)
 			SendSessionOutbound(ws, session, Outbound{
 				ID:     msg.ID,
 				Action: msg.Action,
-				Error:  NewActionError("rate_limited", "message rate limit exceeded"),
+				Error:  rateErr,
 			})
 			continue
 		}
@@ -118,16 +128,19 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 		authorizeErr := runtime.Authorize(authorizeCtx, session, msg)
 		cancelAuthorize()
 		if authorizeErr != nil {
+			forbidden := NewActionError("forbidden", "message forbidden")
+			runtime.AuditMessage(session, msg, forbidden)
 			SendSessionOutbound(ws, session, Outbound{
 				Component: msg.Component,
 				Action:    msg.Action,
 				ID:        msg.ID,
-				Error:     NewActionError("forbidden", "message forbidden"),
+				Error:     forbidden,
 			})
 			continue
 		}
 		if msg.Action != "" {
 			payload, actionErr := runtime.DispatchAction(context.Background(), session, msg)
+			runtime.AuditMessage(session, msg, actionErr)
 			SendSessionOutbound(ws, session, Outbound{
 				Action:  msg.Action,
 				ID:      msg.ID,
@@ -147,6 +160,8 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 			}
 			connMu.Unlock()
 			resp := hc.HandleWithSession(session, msg.Payload)
+			respErr, _ := resp.(*ActionError)
+			runtime.AuditMessage(session, msg, respErr)
 			if resp != nil {
 				switch v := resp.(type) {
 				case *InitSnapshot:
@@ -157,6 +172,9 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 				case InitSnapshot:
 					SendSessionOutbound(ws, session, Outbound{Component: msg.Component, ID: msg.ID, Payload: map[string]any{"initSnapshot": v}})
 					continue
+				case *ActionError:
+					SendSessionOutbound(ws, session, Outbound{Component: msg.Component, ID: msg.ID, Error: v})
+					continue
 				default:
 					SendSessionOutbound(ws, session, Outbound{Component: msg.Component, ID: msg.ID, Payload: resp})
 					continue
@@ -170,6 +188,11 @@ func wsHandler(ws *websocket.Conn, runtime *WSRuntime) {
 				continue
 			}
 		}
+		if msg.Component != "" {
+			if _, ok := Get(msg.Component); !ok {
+				runtime.AuditMessage(session, msg, NewActionError("component_not_found", "component not found"))
+			}
+		}
 		SendSessionOutbound(ws, session, Outbound{Control: "ack"})
 	}
 }
@@ -250,7 +273,11 @@ func ReplaySession(ws *websocket.Conn, session *Session, acknowledged uint64) {
 		}))
 		return
 	}
+	// Replayed messages carry the token that was current when they were first
+	// sent; rewrite it so the client keeps the token rotated by the resume.
+	token := session.ResumeToken()
 	for _, message := range messages {
+		message.ResumeToken = token
 		sendOutboundUnlocked(ws, message)
 	}
 }
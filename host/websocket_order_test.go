@@ -274,7 +274,7 @@ func TestManagedSessionRejectsAllPriorConnections(t *testing.T) {
 	}
 	BindSessionConnection(secondServer, resumed)
 	SuspendSession(resumed, time.Second)
-	resumed, ok = ResumeSession(token)
+	resumed, ok = ResumeSession(resumed.ResumeToken())
 	if !ok {
 		t.Fatal("second resume failed")
 	}
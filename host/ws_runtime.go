@@ -37,8 +37,18 @@ func DefaultSSCLimits() SSCLimits {
 type MessageAuthorizer func(context.Context, *Session, Inbound) error
 
 // SessionInitializer copies authenticated request state into a new session.
+// When a client presents a resume token it also runs on a probe session that
+// is discarded afterwards, so the principal it sets for the new request can be
+// compared with the retained session's. It must therefore only read the
+// request and write the session it is handed: a side effect elsewhere, such as
+// counting logins, happens once more for every resume attempt.
 type SessionInitializer func(*http.Request, *Session) error
 
+// ResumeFingerprint derives a binding value from the upgrade request, such as
+// a cookie identity or user agent. A resume token is only accepted from a
+// request producing the same fingerprint as the one that created the session.
+type ResumeFingerprint func(*http.Request) string
+
 // MuxOption configures the WebSocket endpoint created by NewMux.
 type MuxOption func(*WSRuntime)
 
@@ -48,6 +58,10 @@ type WSRuntime struct {
 	origins     []string
 	authorize   MessageAuthorizer
 	initialize  SessionInitializer
+	fingerprint ResumeFingerprint
+	csrf        bool
+	csp         string
+	audit       AuditSink
 	limits      SSCLimits
 	connections atomic.Int64
 }
@@ -83,6 +97,11 @@ func WithSSCSessionInitializer(initialize SessionInitializer) MuxOption {
 	return func(runtime *WSRuntime) { runtime.initialize = initialize }
 }
 
+// WithSSCResumeFingerprint binds resume tokens to fingerprint(request).
+func WithSSCResumeFingerprint(fingerprint ResumeFingerprint) MuxOption {
+	return func(runtime *WSRuntime) { runtime.fingerprint = fingerprint }
+}
+
 // WithSSCLimits overrides non-zero SSC resource limits.
 func WithSSCLimits(limits SSCLimits) MuxOption {
 	return func(runtime *WSRuntime) {
@@ -192,6 +211,9 @@ func (runtime *WSRuntime) NewSession(request *http.Request) (*Session, error) {
 	if err != nil {
 		return nil, err
 	}
+	if runtime != nil && runtime.fingerprint != nil {
+		session.setFingerprint(runtime.fingerprint(request))
+	}
 	if runtime != nil && runtime.initialize != nil {
 		if err := runtime.initialize(request, session); err != nil {
 			ReleaseSession(session)
@@ -201,15 +223,46 @@ func (runtime *WSRuntime) NewSession(request *http.Request) (*Session, error) {
 	return session, nil
 }
 
-// OpenSession resumes a retained session before allocating a new one.
+// OpenSession resumes a retained session before allocating a new one. A token
+// presented from a request with a different fingerprint or principal is
+// rejected and a fresh session is allocated instead.
 func (runtime *WSRuntime) OpenSession(request *http.Request, resumeToken string) (*Session, bool, error) {
-	if resumed, ok := ResumeSession(resumeToken); ok {
-		return resumed, true, nil
+	if runtime.canResume(request, resumeToken) {
+		if resumed, ok := ResumeSession(resumeToken); ok {
+			return resumed, true, nil
+		}
 	}
 	session, err := runtime.NewSession(request)
 	return session, false, err
 }
 
+// canResume checks the retained session's bindings against request. The
+// session initializer runs on a detached probe session, which is never
+// registered, so the principal it establishes for request can be compared
+// with the retained one.
+func (runtime *WSRuntime) canResume(request *http.Request, resumeToken string) bool {
+	session := sessionForToken(resumeToken)
+	if session == nil {
+		return false
+	}
+	if runtime == nil {
+		return true
+	}
+	if runtime.fingerprint != nil && !session.matchesFingerprint(runtime.fingerprint(request)) {
+		return false
+	}
+	if runtime.initialize != nil {
+		probe := newSession("")
+		if err := runtime.initialize(request, probe); err != nil {
+			return false
+		}
+		if probe.Principal() != session.Principal() {
+			return false
+		}
+	}
+	return true
+}
+
 // Authorize validates a decoded message.
 func (runtime *WSRuntime) Authorize(ctx context.Context, session *Session, message Inbound) error {
 	if runtime == nil || runtime.authorize == nil {
@@ -23,3 +23,29 @@ var connectionState = state.NewSignal(ConnectionDisconnected)
 func ConnectionStateSignal() *state.Signal[ConnectionState] {
 	return connectionState
 }
+
+// RefetchOnReconnect refetches every observed query of client when the SSC
+// connection becomes connected again after being lost. The initial connect
+// does not refetch, and a connection already open or desynced when it is
+// called counts as established. The returned function stops watching.
+func RefetchOnReconnect(client *state.QueryClient) (stop func()) {
+	var seen, dropped bool
+	switch connectionState.Get() {
+	case ConnectionConnected:
+		seen = true
+	case ConnectionDesynced:
+		seen, dropped = true, true
+	}
+	subscription := connectionState.OnChange(func(current ConnectionState) {
+		switch current {
+		case ConnectionConnected:
+			if dropped {
+				client.Refetch("*")
+			}
+			seen, dropped = true, false
+		case ConnectionDisconnected, ConnectionDesynced:
+			dropped = seen
+		}
+	})
+	return subscription.Stop
+}
@@ -0,0 +1,51 @@
+package hostclient
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+func TestRefetchOnReconnect(t *testing.T) {
+	previous := connectionState.Get()
+	t.Cleanup(func() { connectionState.Set(previous) })
+	connectionState.Set(ConnectionDisconnected)
+
+	client := state.NewQueryClient(state.WithStaleTime(time.Hour))
+	var calls atomic.Int32
+	query := state.NewQuery(client, "reconnect", func(context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	})
+	defer query.Close()
+	stop := RefetchOnReconnect(client)
+	defer func() { stop() }()
+
+	wait := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for calls.Load() != want || query.Fetching() {
+			if time.Now().After(deadline) {
+				t.Fatalf("fetch calls = %d, want %d", calls.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait(1)
+	connectionState.Set(ConnectionConnected)
+	wait(1)
+	connectionState.Set(ConnectionDisconnected)
+	connectionState.Set(ConnectionConnecting)
+	connectionState.Set(ConnectionConnected)
+	wait(2)
+
+	// a watcher started on an open connection refetches on its first
+	// reconnect too
+	stop()
+	stop = RefetchOnReconnect(client)
+	connectionState.Set(ConnectionDisconnected)
+	connectionState.Set(ConnectionConnected)
+	wait(3)
+}
@@ -18,6 +18,7 @@ import (
 
 	dom "github.com/rfwlab/rfw/v2/dom"
 	js "github.com/rfwlab/rfw/v2/js"
+	"github.com/rfwlab/rfw/v2/rpc"
 	"nhooyr.io/websocket"
 	"nhooyr.io/websocket/wsjson"
 )
@@ -71,6 +72,7 @@ type wireMessage struct {
 	Sequence    uint64 `json:"sequence"`
 	Ack         uint64 `json:"ack,omitempty"`
 	ResumeToken string `json:"resumeToken,omitempty"`
+	CSRFToken   string `json:"csrf,omitempty"`
 }
 
 type messageWriter func(context.Context, *websocket.Conn, wireMessage) error
@@ -128,10 +130,29 @@ func init() {
 		}
 	})
 	hydrateCB = fnres.NewCircuitBreaker(3, 15*time.Second)
+	if token := csrfMetaToken(); token != "" {
+		rpc.SetCSRFToken(token)
+		rpc.SetCSRFOrigin(js.Location().Get("origin").String())
+	}
 	sendCache = fncaching.NewInMemory[string](
 		fncaching.WithMaxEntries[string](256),
 		fncaching.WithTTL[string](5*time.Second),
 	)
+	sharedDocTransport.send = Send
+	sharedDocTransport.register = RegisterHandler
+}
+
+// csrfMetaToken reads the CSRF token host.WithCSRF embeds in index.html.
+func csrfMetaToken() string {
+	doc := js.Document()
+	if !doc.Truthy() {
+		return ""
+	}
+	meta := doc.Call("querySelector", `meta[name="rfw-csrf-token"]`)
+	if !meta.Truthy() {
+		return ""
+	}
+	return meta.Call("getAttribute", "content").String()
 }
 
 func connect() {
@@ -378,6 +399,9 @@ func readLoop(ctx context.Context, c *websocket.Conn) error {
 			if msg.Session != "" {
 				payload["_session"] = msg.Session
 			}
+			if msg.Error != nil {
+				payload["_error"] = msg.Error.Code
+			}
 			h(payload)
 			continue
 		}
@@ -557,6 +581,7 @@ func sendMessageUnlockedWithWriter(c *websocket.Conn, msg message, writer messag
 		Sequence:    msg.sequence,
 		Ack:         ack,
 		ResumeToken: token,
+		CSRFToken:   rpc.CSRFToken(),
 	}
 	ctx := context.Background()
 	_ = writer(ctx, c, outbound)
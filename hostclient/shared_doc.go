@@ -0,0 +1,191 @@
+package hostclient
+
+import (
+	"sync"
+
+	"github.com/rfwlab/rfw/v2/crdt"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// sharedDocTransport is wired to Send and RegisterHandler in wasm builds.
+// Elsewhere shared documents work offline only.
+var sharedDocTransport = struct {
+	send     func(name string, payload any)
+	register func(name string, handler func(map[string]any))
+}{
+	send:     func(string, any) {},
+	register: func(string, func(map[string]any)) {},
+}
+
+// SharedDoc is a client replica of a host.SharedDoc. Edits apply locally at
+// once and are sent to the host. Edits made while disconnected stay pending
+// and are resent after reconnecting, and edits from other clients merge in as
+// they arrive. The document is mirrored into a state.Store, so templates bind
+// to it like any store: lists are []any values usable in @for loops.
+type SharedDoc struct {
+	name    string
+	channel string
+	doc     *crdt.Doc
+	store   *state.Store
+	send    func(string, any)
+
+	mu          sync.Mutex
+	seq         int
+	unconfirmed []crdt.Op
+	pending     *state.Signal[int]
+}
+
+// OpenSharedDoc joins the shared document name and mirrors it into a store
+// created with opts, named after the document.
+func OpenSharedDoc(name string, opts ...state.StoreOption) *SharedDoc {
+	return openSharedDoc(name, sharedDocTransport.send, sharedDocTransport.register, opts...)
+}
+
+func openSharedDoc(name string, send func(string, any), register func(string, func(map[string]any)), opts ...state.StoreOption) *SharedDoc {
+	sd := &SharedDoc{
+		name:    name,
+		channel: "rfw:doc:" + name,
+		doc:     crdt.NewDoc(""),
+		store:   state.NewStore(name, opts...),
+		send:    send,
+		pending: state.NewSignal(0),
+	}
+	register(sd.channel, sd.receive)
+	return sd
+}
+
+// Name returns the document name.
+func (sd *SharedDoc) Name() string { return sd.name }
+
+// Store returns the reactive mirror of the document. Write through the
+// SharedDoc methods; writes made directly to the store are not shared.
+func (sd *SharedDoc) Store() *state.Store { return sd.store }
+
+// Get returns a register value.
+func (sd *SharedDoc) Get(key string) any { return sd.doc.Get(key) }
+
+// List returns the visible elements of a list.
+func (sd *SharedDoc) List(key string) []any { return sd.doc.List(key) }
+
+// Set writes a register.
+func (sd *SharedDoc) Set(key string, value any) { sd.local(sd.doc.Set(key, value)) }
+
+// Delete removes a register.
+func (sd *SharedDoc) Delete(key string) { sd.local(sd.doc.Set(key, nil)) }
+
+// Insert inserts value at index of list.
+func (sd *SharedDoc) Insert(list string, index int, value any) {
+	sd.local(sd.doc.Insert(list, index, value))
+}
+
+// Append adds value to the end of list.
+func (sd *SharedDoc) Append(list string, value any) { sd.local(sd.doc.Append(list, value)) }
+
+// Remove deletes the element at index of list.
+func (sd *SharedDoc) Remove(list string, index int) {
+	if op, ok := sd.doc.Remove(list, index); ok {
+		sd.local(op)
+	}
+}
+
+// Pending returns the number of local edits the host has not confirmed.
+func (sd *SharedDoc) Pending() int { return sd.pending.Get() }
+
+// PendingSignal returns the reactive count of unconfirmed local edits.
+func (sd *SharedDoc) PendingSignal() *state.Signal[int] { return sd.pending }
+
+func (sd *SharedDoc) local(op crdt.Op) {
+	sd.mu.Lock()
+	sd.unconfirmed = append(sd.unconfirmed, op)
+	count := len(sd.unconfirmed)
+	sd.mu.Unlock()
+	sd.pending.Set(count)
+	sd.mirror([]string{op.Key})
+	sd.send(sd.channel, map[string]any{"ops": []crdt.Op{op}})
+}
+
+// receive handles host messages: "hello" after (re)connecting, log ranges
+// carrying operations, and the rejection of operations it sent.
+func (sd *SharedDoc) receive(payload map[string]any) {
+	if payload["_error"] == "invalid_ops" {
+		sd.resync()
+		return
+	}
+	if payload["hello"] == true {
+		sd.mu.Lock()
+		request := map[string]any{"since": sd.seq}
+		if len(sd.unconfirmed) > 0 {
+			request["ops"] = append([]crdt.Op(nil), sd.unconfirmed...)
+		}
+		sd.mu.Unlock()
+		sd.send(sd.channel, request)
+		return
+	}
+	ops, err := crdt.DecodeOps(payload["ops"])
+	if err != nil {
+		return
+	}
+	from, _ := payload["from"].(float64)
+	seq, _ := payload["seq"].(float64)
+
+	var keys []string
+	for _, op := range ops {
+		if sd.doc.Apply(op) {
+			keys = append(keys, op.Key)
+		}
+	}
+	sd.mu.Lock()
+	confirmed := make(map[crdt.ID]struct{}, len(ops))
+	for _, op := range ops {
+		confirmed[op.ID] = struct{}{}
+	}
+	remaining := sd.unconfirmed[:0]
+	for _, op := range sd.unconfirmed {
+		if _, ok := confirmed[op.ID]; !ok {
+			remaining = append(remaining, op)
+		}
+	}
+	sd.unconfirmed = remaining
+	count := len(sd.unconfirmed)
+	gap := int(from) > sd.seq
+	if !gap && int(seq) > sd.seq {
+		sd.seq = int(seq)
+	}
+	since := sd.seq
+	sd.mu.Unlock()
+
+	sd.pending.Set(count)
+	sd.mirror(keys)
+	if gap {
+		sd.send(sd.channel, map[string]any{"since": since})
+	}
+}
+
+// resync drops the local edits after the host rejected them and rebuilds the
+// replica from the host's whole log under a new replica ID, so the document
+// converges with the host again instead of keeping edits nobody else sees.
+func (sd *SharedDoc) resync() {
+	keys := sd.doc.Keys()
+	sd.doc.Reset("")
+	sd.mu.Lock()
+	sd.seq = 0
+	sd.unconfirmed = nil
+	sd.mu.Unlock()
+	sd.pending.Set(0)
+	sd.mirror(keys)
+	sd.send(sd.channel, map[string]any{"since": 0})
+}
+
+// mirror copies the current values of keys into the store in one
+// transaction.
+func (sd *SharedDoc) mirror(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	_ = sd.store.Transaction(func(tx *state.Tx) error {
+		for _, key := range keys {
+			tx.Set(key, sd.doc.Value(key))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,175 @@
+package hostclient
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rfwlab/rfw/v2/crdt"
+	"github.com/rfwlab/rfw/v2/state"
+)
+
+// docRelay stands in for the SSC connection and the host replica: it records
+// the log, answers "since" requests and broadcasts new ranges to connected
+// clients.
+type docRelay struct {
+	t       *testing.T
+	log     []crdt.Op
+	host    *crdt.Doc
+	clients map[*SharedDoc]bool
+	// rejects lists the clients whose operations the host refuses, as it
+	// does for a replica another session owns
+	rejects map[*SharedDoc]bool
+}
+
+func newDocRelay(t *testing.T) *docRelay {
+	return &docRelay{t: t, host: crdt.NewDoc("host"), clients: map[*SharedDoc]bool{}, rejects: map[*SharedDoc]bool{}}
+}
+
+func (r *docRelay) roundTrip(v any) map[string]any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("marshal: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		r.t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func (r *docRelay) open(name string) *SharedDoc {
+	var sd *SharedDoc
+	sd = openSharedDoc(name, func(_ string, payload any) { r.receive(sd, payload) }, func(string, func(map[string]any)) {}, state.WithModule("docs-"+name))
+	return sd
+}
+
+func (r *docRelay) connect(sd *SharedDoc) {
+	r.clients[sd] = true
+	sd.receive(r.roundTrip(map[string]any{"hello": true, "seq": len(r.log)}))
+}
+
+func (r *docRelay) receive(from *SharedDoc, raw any) {
+	if !r.clients[from] {
+		return
+	}
+	payload := r.roundTrip(raw)
+	ops, err := crdt.DecodeOps(payload["ops"])
+	if err != nil {
+		r.t.Fatalf("decode: %v", err)
+	}
+	if len(ops) > 0 && r.rejects[from] {
+		from.receive(map[string]any{"_error": "invalid_ops"})
+		return
+	}
+	start := len(r.log)
+	for _, op := range ops {
+		if r.host.Apply(op) {
+			r.log = append(r.log, op)
+		}
+	}
+	if len(r.log) > start {
+		for client, online := range r.clients {
+			if online {
+				client.receive(r.roundTrip(map[string]any{"from": start, "seq": len(r.log), "ops": r.log[start:]}))
+			}
+		}
+	}
+	if since, ok := payload["since"].(float64); ok {
+		from.receive(r.roundTrip(map[string]any{"from": int(since), "seq": len(r.log), "ops": r.log[int(since):]}))
+	}
+}
+
+func TestSharedDocSyncsClients(t *testing.T) {
+	relay := newDocRelay(t)
+	a, b := relay.open("sync"), relay.open("sync")
+	relay.connect(a)
+	relay.connect(b)
+
+	a.Append("todos", "milk")
+	b.Append("todos", "eggs")
+	a.Set("title", "Groceries")
+
+	want := []any{"milk", "eggs"}
+	if got := b.List("todos"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected list on b: %v", got)
+	}
+	if got := a.Store().Get("todos"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("store not mirrored: %v", got)
+	}
+	if b.Store().Get("title") != "Groceries" {
+		t.Fatalf("unexpected title: %v", b.Store().Get("title"))
+	}
+	if a.Pending() != 0 || b.Pending() != 0 {
+		t.Fatalf("edits left unconfirmed: %d, %d", a.Pending(), b.Pending())
+	}
+}
+
+func TestSharedDocReplaysOfflineEdits(t *testing.T) {
+	relay := newDocRelay(t)
+	a, b := relay.open("offline"), relay.open("offline")
+	relay.connect(a)
+	relay.connect(b)
+	a.Append("todos", "milk")
+
+	relay.clients[b] = false
+	b.Append("todos", "offline")
+	b.Remove("todos", 0)
+	a.Append("todos", "online")
+	if b.Pending() != 2 {
+		t.Fatalf("expected 2 pending edits, got %d", b.Pending())
+	}
+
+	relay.connect(b)
+	// Concurrent appends order by replica ID, so only the contents are fixed.
+	got := a.List("todos")
+	if len(got) != 2 || !contains(got, "online") || !contains(got, "offline") {
+		t.Fatalf("unexpected list on a: %v", got)
+	}
+	if mirrored := b.Store().Get("todos"); !reflect.DeepEqual(mirrored, got) {
+		t.Fatalf("replicas diverged: %v != %v", mirrored, got)
+	}
+	if b.Pending() != 0 {
+		t.Fatalf("edits left unconfirmed: %d", b.Pending())
+	}
+}
+
+func TestSharedDocResyncsAfterRejection(t *testing.T) {
+	relay := newDocRelay(t)
+	a, b := relay.open("rejected"), relay.open("rejected")
+	relay.connect(a)
+	relay.connect(b)
+	a.Append("todos", "milk")
+
+	relay.clients[b] = false
+	b.Set("title", "offline")
+	b.Append("todos", "offline")
+	relay.rejects[b] = true
+	relay.connect(b)
+	relay.rejects[b] = false
+
+	if b.Pending() != 0 {
+		t.Fatalf("rejected edits left pending: %d", b.Pending())
+	}
+	if got := b.List("todos"); !reflect.DeepEqual(got, []any{"milk"}) {
+		t.Fatalf("replica not rebuilt from the host: %v", got)
+	}
+	if b.Get("title") != nil || b.Store().Get("title") != nil {
+		t.Fatalf("rejected title kept: %v", b.Store().Get("title"))
+	}
+
+	// edits after the resync reach the other clients again
+	b.Append("todos", "eggs")
+	if got := a.List("todos"); !reflect.DeepEqual(got, []any{"milk", "eggs"}) {
+		t.Fatalf("unexpected list on a: %v", got)
+	}
+}
+
+func contains(values []any, want any) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
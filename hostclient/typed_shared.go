@@ -0,0 +1,43 @@
+package hostclient
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/rfwlab/rfw/v2/internal/jsonfields"
+)
+
+var hostVarPlaceholder = regexp.MustCompile(`\{h:(\w+)\}`)
+
+// HostVarNames returns the host variable names a typed host component with
+// output type Out pushes: the JSON names encoding/json gives its fields,
+// promoted fields of embedded structs included.
+func HostVarNames[Out any]() []string {
+	return jsonfields.Names(jsonfields.Of(reflect.TypeFor[Out]()))
+}
+
+// CheckHostVars reports `{h:name}` placeholders in template that are not
+// host variables of Out. It runs natively, so a test calling it on a
+// component's .rtml file fails the build when Out and the template drift.
+func CheckHostVars[Out any](template string) error {
+	known := make(map[string]bool)
+	for _, name := range HostVarNames[Out]() {
+		known[name] = true
+	}
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, match := range hostVarPlaceholder.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if known[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("hostclient: %s has no host variables %s", reflect.TypeFor[Out](), strings.Join(unknown, ", "))
+	}
+	return nil
+}
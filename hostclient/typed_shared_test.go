@@ -0,0 +1,37 @@
+package hostclient
+
+import (
+	"strings"
+	"testing"
+)
+
+type typedBase struct {
+	ID   int `json:"id"`
+	Name string
+}
+
+type typedVars struct {
+	*typedBase
+	Count   int    `json:"count"`
+	Label   string `json:"label,omitempty"`
+	Ignored string `json:"-"`
+	Plain   bool
+	private int
+}
+
+func TestHostVarNames(t *testing.T) {
+	got := strings.Join(HostVarNames[typedVars](), ",")
+	if got != "id,Name,count,label,Plain" {
+		t.Fatalf("unexpected names: %s", got)
+	}
+}
+
+func TestCheckHostVars(t *testing.T) {
+	if err := CheckHostVars[typedVars](`<p>{h:count} {h:label} {h:Plain}</p>`); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+	err := CheckHostVars[typedVars](`<p>{h:cuont} {h:count} {h:cuont} {h:Ignored}</p>`)
+	if err == nil || !strings.Contains(err.Error(), "cuont, Ignored") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package jsonfields lists the object keys encoding/json encodes the fields of
+// a struct to, following its rules for tags and embedded structs. The host
+// and the client read it from reflection, rfw check from source.
+package jsonfields
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Field is one declared field of a struct.
+type Field struct {
+	// Name is the Go name of the field, the type name for an embedded one.
+	Name string
+	// Tag is the value of the field's json tag.
+	Tag      string
+	Exported bool
+	Embedded bool
+	// Struct holds the fields of an embedded struct, or pointer to struct,
+	// type, and is empty but not nil when that struct embeds itself, as
+	// encoding/json does not expand it twice. It is nil for every other
+	// field.
+	Struct []Field
+}
+
+// Names returns the keys encoding/json gives fields, in the order it encodes
+// them. The fields of an untagged embedded struct are promoted; of several
+// fields sharing a key, the least nested one wins, or the only tagged one of
+// the least nested, and the key is dropped when that leaves a tie.
+func Names(fields []Field) []string {
+	type candidate struct {
+		name   string
+		index  []int
+		tagged bool
+	}
+	var all []candidate
+	type level struct {
+		fields []Field
+		index  []int
+	}
+	next := []level{{fields: fields}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		for _, l := range current {
+			for i, f := range l.fields {
+				if f.Embedded {
+					if !f.Exported && f.Struct == nil {
+						continue
+					}
+				} else if !f.Exported {
+					continue
+				}
+				if f.Tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(f.Tag, ",")
+				index := append(slices.Clip(l.index), i)
+				if name != "" || !f.Embedded || f.Struct == nil {
+					tagged := name != ""
+					if name == "" {
+						name = f.Name
+					}
+					all = append(all, candidate{name: name, index: index, tagged: tagged})
+					continue
+				}
+				next = append(next, level{fields: f.Struct, index: index})
+			}
+		}
+	}
+
+	byName := make(map[string][]candidate)
+	for _, c := range all {
+		byName[c.name] = append(byName[c.name], c)
+	}
+	var kept []candidate
+	for _, group := range byName {
+		depth := len(group[0].index)
+		var shallow []candidate
+		for _, c := range group {
+			switch {
+			case len(c.index) < depth:
+				depth = len(c.index)
+				shallow = []candidate{c}
+			case len(c.index) == depth:
+				shallow = append(shallow, c)
+			}
+		}
+		if len(shallow) > 1 {
+			var tagged []candidate
+			for _, c := range shallow {
+				if c.tagged {
+					tagged = append(tagged, c)
+				}
+			}
+			shallow = tagged
+		}
+		if len(shallow) == 1 {
+			kept = append(kept, shallow[0])
+		}
+	}
+	slices.SortFunc(kept, func(a, b candidate) int { return slices.Compare(a.index, b.index) })
+	names := make([]string, len(kept))
+	for i, c := range kept {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Of describes the fields of the struct t, or of the struct t points to. It
+// returns nil for other types.
+func Of(t reflect.Type) []Field {
+	return of(t, nil)
+}
+
+func of(t reflect.Type, outer []reflect.Type) []Field {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if slices.Contains(outer, t) {
+		return []Field{}
+	}
+	outer = append(outer, t)
+	fields := make([]Field, t.NumField())
+	for i := range fields {
+		sf := t.Field(i)
+		fields[i] = Field{
+			Name:     sf.Name,
+			Tag:      sf.Tag.Get("json"),
+			Exported: sf.IsExported(),
+			Embedded: sf.Anonymous,
+		}
+		if sf.Anonymous {
+			fields[i].Struct = of(sf.Type, outer)
+		}
+	}
+	return fields
+}
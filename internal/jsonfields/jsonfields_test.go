@@ -0,0 +1,96 @@
+package jsonfields
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type Base struct {
+	ID   int `json:"id"`
+	Name string
+}
+
+type Audit struct {
+	Name    string `json:"name"`
+	Created string
+}
+
+type inner struct {
+	Hidden int
+}
+
+type Tagged struct {
+	Value int
+}
+
+type Out struct {
+	Base
+	*Audit
+	inner
+	Tagged `json:"tagged"`
+	Count  int `json:"count"`
+	secret int
+	Skip   int `json:"-"`
+}
+
+type Tie struct {
+	A
+	B
+}
+
+type A struct{ X int }
+type B struct{ X int }
+
+type Node struct {
+	*Node
+	Label string
+}
+
+// encodedKeys returns the keys of v's JSON object in encoding order.
+func encodedKeys(t *testing.T, v any) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	var keys []string
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+func TestNamesFollowEncoding(t *testing.T) {
+	tests := []struct {
+		value any
+		want  []string
+	}{
+		{Out{Audit: &Audit{}}, []string{"id", "Name", "name", "Created", "Hidden", "tagged", "count"}},
+		{Tie{}, nil},
+		{Node{Node: &Node{}}, []string{"Label"}},
+	}
+	for _, tt := range tests {
+		got := Names(Of(reflect.TypeOf(tt.value)))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%T: Names = %v, want %v", tt.value, got, tt.want)
+		}
+		if encoded := encodedKeys(t, tt.value); !slices.Equal(got, encoded) {
+			t.Errorf("%T: Names = %v, encoding/json writes %v", tt.value, got, encoded)
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Request is a JSON RPC request.
@@ -79,6 +82,42 @@ func (s *Server) Handle(ctx context.Context, reqBytes []byte) ([]byte, error) {
 	return json.Marshal(res)
 }
 
+// CSRFHeader is the request header Call uses to send the CSRF token. It
+// matches the header checked by host.RequireCSRF.
+const CSRFHeader = "X-RFW-CSRF"
+
+var csrfToken, csrfOrigin atomic.Value
+
+// SetCSRFToken sets the token Call sends to the page's own origin. The wasm
+// host client sets it from the page's rfw-csrf-token meta tag.
+func SetCSRFToken(token string) { csrfToken.Store(token) }
+
+// SetCSRFOrigin sets the origin of the page, such as "https://app.example".
+// Call sends the CSRF token to relative endpoints and to absolute ones on
+// this origin only, so the token never reaches a third party. The wasm host
+// client sets it from window.location.
+func SetCSRFOrigin(origin string) { csrfOrigin.Store(origin) }
+
+// sameOrigin reports whether endpoint is relative or on the origin set with
+// SetCSRFOrigin.
+func sameOrigin(endpoint *url.URL) bool {
+	if endpoint.Scheme == "" && endpoint.Host == "" {
+		return true
+	}
+	origin, _ := csrfOrigin.Load().(string)
+	page, err := url.Parse(origin)
+	if origin == "" || err != nil {
+		return false
+	}
+	return strings.EqualFold(endpoint.Scheme, page.Scheme) && strings.EqualFold(endpoint.Host, page.Host)
+}
+
+// CSRFToken returns the token set with SetCSRFToken.
+func CSRFToken() string {
+	token, _ := csrfToken.Load().(string)
+	return token
+}
+
 // Call invokes an RPC method over HTTP.
 func Call(ctx context.Context, endpoint, method string, params any, out any) error {
 	req := Request{ID: "1", Method: method}
@@ -100,6 +139,9 @@ func Call(ctx context.Context, endpoint, method string, params any, out any) err
 		return err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	if token := CSRFToken(); token != "" && sameOrigin(httpReq.URL) {
+		httpReq.Header.Set(CSRFHeader, token)
+	}
 
 	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
@@ -91,6 +91,44 @@ func TestCall_HTTPRoundTrip(t *testing.T) {
 	}
 }
 
+func TestCall_SendsCSRFToken(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(CSRFHeader)
+		_, _ = w.Write([]byte(`{"id":"1","result":null}`))
+	}))
+	defer srv.Close()
+	SetCSRFToken("token-1")
+	SetCSRFOrigin(srv.URL)
+	defer SetCSRFToken("")
+	defer SetCSRFOrigin("")
+	if err := Call(context.Background(), srv.URL+"/rpc", "noop", nil, nil); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if got != "token-1" {
+		t.Fatalf("expected csrf header, got %q", got)
+	}
+}
+
+func TestCall_KeepsCSRFTokenFromOtherOrigins(t *testing.T) {
+	got := "unset"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(CSRFHeader)
+		_, _ = w.Write([]byte(`{"id":"1","result":null}`))
+	}))
+	defer srv.Close()
+	SetCSRFToken("token-1")
+	SetCSRFOrigin("https://app.example")
+	defer SetCSRFToken("")
+	defer SetCSRFOrigin("")
+	if err := Call(context.Background(), srv.URL, "noop", nil, nil); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if got != "" {
+		t.Fatalf("csrf token sent to another origin: %q", got)
+	}
+}
+
 func mustJSON(v any) []byte {
 	b, _ := json.Marshal(v)
 	return b
@@ -2,10 +2,11 @@
 //
 // Experimental: this package is meant to eventually replace the regex
 // renderer in core, but today production rendering goes through core's
-// pipeline and only ParseExpr is consumed there. The AST renderer
-// (RenderNodes) follows the same escape-by-default policy as core, yet its
-// output and this API may change or be removed without notice; do not depend
-// on it outside the framework.
+// pipeline and only ParseExpr and Eval are consumed there; the rtml build
+// plugin parses templates with Parse to compile them ahead of time. The AST
+// renderer (RenderNodes) follows the same escape-by-default policy as core,
+// yet its output and this API may change or be removed without notice; do
+// not depend on it outside the framework.
 package rtmlast
 
 // Node is the root of the AST.
@@ -22,6 +23,8 @@ func (IfNode) node()      {}
 func (ForNode) node()     {}
 func (SlotNode) node()    {}
 func (IncludeNode) node() {}
+func (SwitchNode) node()  {}
+func (AwaitNode) node()   {}
 
 // TextNode is literal text.
 type TextNode struct {
@@ -30,7 +33,8 @@ type TextNode struct {
 
 // ExprNode is a standalone reactive expression @expr:expression.
 type ExprNode struct {
-	Expr Expr
+	Expr   Expr
+	Source string // text after "@expr:", up to the next tag or line end
 }
 
 // VarNode is a reactive interpolation {expr}.
@@ -72,6 +76,7 @@ type CommandNode struct {
 // IfNode is a conditional block.
 type IfNode struct {
 	Cond   Expr
+	Source string // condition as written after "@if:"
 	Then   []Node
 	ElseIf []ElseIfBranch
 	Else   []Node
@@ -79,21 +84,52 @@ type IfNode struct {
 
 // ElseIfBranch is an @else-if branch.
 type ElseIfBranch struct {
-	Cond Expr
-	Body []Node
+	Cond   Expr
+	Source string // condition as written after "@else-if:"
+	Body   []Node
 }
 
 // ForNode is a list loop.
 type ForNode struct {
-	Alias    string // e.g. "item"
-	KeyAlias string // e.g. "key" in @for:key,val in obj
-	Expr     Expr   // collection expression
-	Body     []Node
+	Alias      string // e.g. "item"
+	KeyAlias   string // e.g. "key" in @for:key,val in obj
+	Expr       Expr   // collection expression
+	Source     string // header as written after "@for:"
+	Body       []Node
+	BodySource string // body text between the header and @endfor
+}
+
+// SwitchNode is an @switch block. The first case whose value equals the
+// subject renders, or Default when none does.
+type SwitchNode struct {
+	Subject Expr
+	Source  string // subject as written after "@switch:"
+	Cases   []CaseBranch
+	Default []Node
+}
+
+// CaseBranch is an @case branch.
+type CaseBranch struct {
+	Value  Expr
+	Source string // value as written after "@case:"
+	Body   []Node
+}
+
+// AwaitNode is an @await block over a state.Resource: Pending renders while
+// it has no result, Then once it is ready and Catch when loading failed.
+type AwaitNode struct {
+	Resource   string // resource name after "@await:"
+	Pending    []Node
+	ValueAlias string // name after "@then:", bound to the value in Then
+	Then       []Node
+	ErrorAlias string // name after "@catch:", bound to the error in Catch
+	Catch      []Node
 }
 
 // SlotNode is a named/placeholder slot.
 type SlotNode struct {
 	Name     string
+	Params   []string // values a scoped slot exposes, or names bound to them
 	Fallback []Node
 }
 
@@ -116,15 +152,17 @@ func (UnaryExpr) expr()   {}
 func (CallExpr) expr()    {}
 func (FieldExpr) expr()   {}
 func (TernaryExpr) expr() {}
+func (IndexExpr) expr()   {}
+func (BadExpr) expr()     {}
 
 // IdentExpr is a variable reference by name.
 type IdentExpr struct {
 	Name string
 }
 
-// LiteralExpr is a string, number, or bool literal.
+// LiteralExpr is a string, number, bool or nil literal.
 type LiteralExpr struct {
-	Value any // string, float64, bool
+	Value any // string, int, float64, bool or nil
 }
 
 // BinaryExpr is lhs op rhs.
@@ -184,7 +222,8 @@ const (
 	UnaryNeg
 )
 
-// CallExpr is function(args).
+// CallExpr is function(args). A pipe x | function args calls function with x
+// before args.
 type CallExpr struct {
 	Fn   string
 	Args []Expr
@@ -202,3 +241,15 @@ type TernaryExpr struct {
 	Then Expr
 	Else Expr
 }
+
+// IndexExpr is obj[index], on a slice, array, string or map.
+type IndexExpr struct {
+	Obj   Expr
+	Index Expr
+}
+
+// BadExpr is an expression that does not parse. Evaluating it fails with Err.
+type BadExpr struct {
+	Source string
+	Err    error
+}
@@ -0,0 +1,559 @@
+package rtmlast
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Env is what Eval resolves names and calls against.
+type Env struct {
+	// Lookup returns the value of a variable. A dotted reference such as
+	// user.name is looked up whole first, then field by field from user.
+	Lookup func(name string) (any, bool)
+	// Func returns the Go function a call names. It may take any arguments
+	// and must return one value, or a value and an error.
+	Func func(name string) (any, bool)
+	// BareWords makes a name Lookup does not know evaluate to itself, so
+	// @if:status == active compares against the string "active".
+	BareWords bool
+}
+
+// reader is a signal: variables, fields, elements and results holding one
+// evaluate to its current value.
+type reader interface{ Read() any }
+
+func read(v any) any {
+	if r, ok := v.(reader); ok {
+		return r.Read()
+	}
+	return v
+}
+
+var errDivZero = errors.New("division by zero")
+
+// Eval evaluates e against env. Reading an unknown variable gives nil (or the
+// name, with BareWords); a bad field, index or call is an error.
+func Eval(e Expr, env Env) (any, error) {
+	switch x := e.(type) {
+	case nil:
+		return nil, nil
+	case LiteralExpr:
+		return x.Value, nil
+	case BadExpr:
+		return nil, x.Err
+	case IdentExpr:
+		return env.ident(x.Name), nil
+	case FieldExpr:
+		if path, ok := dottedName(x); ok {
+			if v, ok := env.lookup(path); ok {
+				return read(v), nil
+			}
+			if root := path[:strings.IndexByte(path, '.')]; env.BareWords {
+				if _, ok := env.lookup(root); !ok {
+					return path, nil
+				}
+			}
+		}
+		obj, err := Eval(x.Obj, env)
+		if err != nil {
+			return nil, err
+		}
+		return field(obj, x.Field)
+	case IndexExpr:
+		obj, err := Eval(x.Obj, env)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := Eval(x.Index, env)
+		if err != nil {
+			return nil, err
+		}
+		return index(obj, idx)
+	case CallExpr:
+		args := make([]any, len(x.Args))
+		for i, a := range x.Args {
+			v, err := Eval(a, env)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		return env.call(x.Fn, args)
+	case UnaryExpr:
+		v, err := Eval(x.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		if x.Op == UnaryNeg {
+			n, ok := toNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot negate %s", describe(v))
+			}
+			if n.isInt {
+				return -n.i, nil
+			}
+			return -n.f, nil
+		}
+		return !Truthy(v), nil
+	case BinaryExpr:
+		return evalBinary(x, env)
+	case TernaryExpr:
+		cond, err := Eval(x.Cond, env)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(cond) {
+			return Eval(x.Then, env)
+		}
+		return Eval(x.Else, env)
+	}
+	return nil, fmt.Errorf("cannot evaluate %T", e)
+}
+
+func (env Env) lookup(name string) (any, bool) {
+	if env.Lookup == nil {
+		return nil, false
+	}
+	return env.Lookup(name)
+}
+
+func (env Env) ident(name string) any {
+	if v, ok := env.lookup(name); ok {
+		return read(v)
+	}
+	if env.BareWords {
+		return name
+	}
+	return nil
+}
+
+// dottedName returns a.b.c for a field chain on a plain name.
+func dottedName(f FieldExpr) (string, bool) {
+	switch obj := f.Obj.(type) {
+	case IdentExpr:
+		return obj.Name + "." + f.Field, true
+	case FieldExpr:
+		path, ok := dottedName(obj)
+		return path + "." + f.Field, ok
+	}
+	return "", false
+}
+
+func evalBinary(b BinaryExpr, env Env) (any, error) {
+	lhs, err := Eval(b.LHS, env)
+	if err != nil {
+		return nil, err
+	}
+	switch b.Op {
+	case OpAnd, OpOr:
+		if Truthy(lhs) == (b.Op == OpOr) {
+			return b.Op == OpOr, nil
+		}
+		rhs, err := Eval(b.RHS, env)
+		if err != nil {
+			return nil, err
+		}
+		return Truthy(rhs), nil
+	}
+	rhs, err := Eval(b.RHS, env)
+	if err != nil {
+		return nil, err
+	}
+	switch b.Op {
+	case OpEq:
+		return equal(lhs, rhs), nil
+	case OpNeq:
+		return !equal(lhs, rhs), nil
+	case OpLt, OpLte, OpGt, OpGte:
+		return compare(lhs, rhs, b.Op), nil
+	case OpAdd, OpSub, OpMul, OpDiv:
+		return arith(lhs, rhs, b.Op)
+	}
+	return nil, fmt.Errorf("unknown operator %d", b.Op)
+}
+
+// Truthy reports whether v counts as true in a condition: false, nil, zero
+// numbers and "", "false" and "0" do not.
+func Truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != "" && x != "false" && x != "0"
+	}
+	if n, ok := toNumber(v); ok {
+		return n.f != 0
+	}
+	return true
+}
+
+// number is a numeric operand. Integers stay integers through + - and *.
+type number struct {
+	i     int
+	f     float64
+	isInt bool
+}
+
+func toNumber(v any) (number, bool) {
+	switch x := v.(type) {
+	case int:
+		return number{i: x, f: float64(x), isInt: true}, true
+	case float64:
+		return number{f: x}, true
+	case string:
+		if i, err := strconv.Atoi(x); err == nil {
+			return number{i: i, f: float64(i), isInt: true}, true
+		}
+		if f, err := strconv.ParseFloat(x, 64); err == nil {
+			return number{f: f}, true
+		}
+		return number{}, false
+	case nil, bool:
+		return number{}, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{i: int(rv.Int()), f: float64(rv.Int()), isInt: true}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{i: int(rv.Uint()), f: float64(rv.Uint()), isInt: true}, true
+	case reflect.Float32, reflect.Float64:
+		return number{f: rv.Float()}, true
+	}
+	return number{}, false
+}
+
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// equal compares same-typed values directly, numbers by value and anything
+// else by its printed form.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return x == y
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return x == y
+		}
+	}
+	if m, ok := toNumber(a); ok {
+		if n, ok := toNumber(b); ok {
+			return m.f == n.f
+		}
+	}
+	return toString(a) == toString(b)
+}
+
+// compare orders numbers, and strings that are not numbers; anything else
+// compares false.
+func compare(a, b any, op BinOp) bool {
+	var c int
+	m, mok := toNumber(a)
+	n, nok := toNumber(b)
+	switch {
+	case mok && nok:
+		switch {
+		case m.f < n.f:
+			c = -1
+		case m.f > n.f:
+			c = 1
+		}
+	default:
+		x, xok := a.(string)
+		y, yok := b.(string)
+		if !xok || !yok {
+			return false
+		}
+		c = strings.Compare(x, y)
+	}
+	switch op {
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	}
+	return c >= 0
+}
+
+func arith(a, b any, op BinOp) (any, error) {
+	m, mok := toNumber(a)
+	n, nok := toNumber(b)
+	if !mok || !nok {
+		if op == OpAdd {
+			return toString(a) + toString(b), nil
+		}
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", opSymbol(op), describe(a), describe(b))
+	}
+	if op == OpDiv {
+		if n.f == 0 {
+			return nil, errDivZero
+		}
+		return m.f / n.f, nil
+	}
+	if m.isInt && n.isInt {
+		switch op {
+		case OpAdd:
+			return m.i + n.i, nil
+		case OpSub:
+			return m.i - n.i, nil
+		}
+		return m.i * n.i, nil
+	}
+	switch op {
+	case OpAdd:
+		return m.f + n.f, nil
+	case OpSub:
+		return m.f - n.f, nil
+	}
+	return m.f * n.f, nil
+}
+
+func opSymbol(op BinOp) string {
+	return map[BinOp]string{OpAdd: "+", OpSub: "-", OpMul: "*", OpDiv: "/"}[op]
+}
+
+// describe names v's type for an error message.
+func describe(v any) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// field reads name from a map with string keys, or the field or
+// zero-argument method name of a struct. Templates may use the Go name or
+// its lower-case spelling: user.name reads User.Name.
+func field(obj any, name string) (any, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() == reflect.Map {
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot read field %s of %T", name, obj)
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return read(v.Interface()), nil
+	}
+	for _, n := range []string{name, exported(name)} {
+		if m := rv.MethodByName(n); m.IsValid() {
+			return callValue(n, m, nil)
+		}
+		s := rv
+		for s.Kind() == reflect.Pointer {
+			if s.IsNil() {
+				return nil, nil
+			}
+			s = s.Elem()
+		}
+		if s.Kind() != reflect.Struct {
+			break
+		}
+		if sf, ok := s.Type().FieldByName(n); ok && sf.IsExported() {
+			return read(s.FieldByIndex(sf.Index).Interface()), nil
+		}
+	}
+	return nil, fmt.Errorf("%T has no field %s", obj, name)
+}
+
+func exported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// index reads element idx of a slice, array or string, or key idx of a map.
+func index(obj, idx any) (any, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	if s, ok := obj.(string); ok {
+		runes := []rune(s)
+		i, err := position(idx, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[i]), nil
+	}
+	rv := reflect.ValueOf(obj)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, err := position(idx, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		return read(rv.Index(i).Interface()), nil
+	case reflect.Map:
+		key, err := convert(idx, rv.Type().Key())
+		if err != nil {
+			return nil, err
+		}
+		v := rv.MapIndex(key)
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return read(v.Interface()), nil
+	}
+	return nil, fmt.Errorf("cannot index %T", obj)
+}
+
+func position(idx any, n int) (int, error) {
+	i, ok := toNumber(idx)
+	if !ok || !i.isInt {
+		return 0, fmt.Errorf("index %v is not an integer", idx)
+	}
+	if i.i < 0 || i.i >= n {
+		return 0, fmt.Errorf("index %d out of range with length %d", i.i, n)
+	}
+	return i.i, nil
+}
+
+// convert turns v into a value of type t, converting between numeric kinds
+// but not between numbers and strings.
+func convert(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %s", t)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	if isNumericKind(rv.Kind()) && isNumericKind(t.Kind()) ||
+		rv.Kind() == reflect.String && t.Kind() == reflect.String {
+		return rv.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", describe(v), t)
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
+
+// builtins are the functions every template can call.
+var builtins = map[string]any{
+	"len": length,
+}
+
+func length(v any) (int, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if s, ok := v.(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return rv.Len(), nil
+	}
+	return 0, fmt.Errorf("%T has no length", v)
+}
+
+func (env Env) call(name string, args []any) (any, error) {
+	var fn any
+	var ok bool
+	if env.Func != nil {
+		fn, ok = env.Func(name)
+	}
+	if !ok {
+		fn, ok = builtins[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s is a %T, not a function", name, fn)
+	}
+	ft := fv.Type()
+	if n := ft.NumIn(); len(args) != n && !(ft.IsVariadic() && len(args) >= n-1) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, n, len(args))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		t := ft.In(min(i, ft.NumIn()-1))
+		if ft.IsVariadic() && i >= ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		v, err := convert(a, t)
+		if err != nil {
+			return nil, fmt.Errorf("%s argument %d: %w", name, i+1, err)
+		}
+		in[i] = v
+	}
+	return callValue(name, fv, in)
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// callValue calls fn, which returns one value, or a value and an error.
+func callValue(name string, fn reflect.Value, in []reflect.Value) (any, error) {
+	ft := fn.Type()
+	switch {
+	case ft.NumOut() == 1:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	default:
+		return nil, fmt.Errorf("%s must return one value, or a value and an error", name)
+	}
+	if len(in) != ft.NumIn() && !ft.IsVariadic() {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, ft.NumIn(), len(in))
+	}
+	out := fn.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, fmt.Errorf("%s: %w", name, out[1].Interface().(error))
+	}
+	return read(out[0].Interface()), nil
+}
+
+// Inspect walks e depth first, calling f for each expression. Returning
+// false from f skips the expression's children.
+func Inspect(e Expr, f func(Expr) bool) {
+	if e == nil || !f(e) {
+		return
+	}
+	switch x := e.(type) {
+	case BinaryExpr:
+		Inspect(x.LHS, f)
+		Inspect(x.RHS, f)
+	case UnaryExpr:
+		Inspect(x.Expr, f)
+	case FieldExpr:
+		Inspect(x.Obj, f)
+	case IndexExpr:
+		Inspect(x.Obj, f)
+		Inspect(x.Index, f)
+	case CallExpr:
+		for _, a := range x.Args {
+			Inspect(a, f)
+		}
+	case TernaryExpr:
+		Inspect(x.Cond, f)
+		Inspect(x.Then, f)
+		Inspect(x.Else, f)
+	}
+}
@@ -0,0 +1,153 @@
+package rtmlast
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testSignal struct{ v any }
+
+func (s testSignal) Read() any { return s.v }
+
+type testUser struct {
+	Name  string
+	Roles []string
+}
+
+func (u testUser) Initials() string { return u.Name[:1] }
+
+func testEnv() Env {
+	vars := map[string]any{
+		"count": 3,
+		"price": 2.5,
+		"items": []string{"a", "b", "c"},
+		"m":     map[string]any{"k": "v", "n": testSignal{7}},
+		"sig":   testSignal{[]int{10, 20}},
+		"user":  &testUser{Name: "Ada", Roles: []string{"admin"}},
+		"word":  "héllo",
+	}
+	funcs := map[string]any{
+		"currency": func(v float64, code string) string { return fmt.Sprintf("%.2f %s", v, code) },
+		"upper":    strings.ToUpper,
+		"join":     func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"fail":     func() (string, error) { return "", errors.New("boom") },
+	}
+	return Env{
+		Lookup: func(name string) (any, bool) { v, ok := vars[name]; return v, ok },
+		Func:   func(name string) (any, bool) { f, ok := funcs[name]; return f, ok },
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"count + 1", 4},
+		{"count / 2", 1.5},
+		{"price * 2", 5.0},
+		{"-count", -3},
+		{"'a' + count", "a3"},
+		{"items[1]", "b"},
+		{"items[count - 1]", "c"},
+		{`m["k"]`, "v"},
+		{"m.k", "v"},
+		{"m.n + 1", 8},
+		{"m.missing", nil},
+		{"sig[1]", 20},
+		{"word[1]", "é"},
+		{"len(items)", 3},
+		{"len(word)", 5},
+		{"len(sig)", 2},
+		{"user.name", "Ada"},
+		{"user.Roles[0]", "admin"},
+		{"user.initials", "A"},
+		{"price | currency 'EUR'", "2.50 EUR"},
+		{"price | currency 'EUR' | upper", "2.50 EUR"},
+		{"currency(price * 2, 'USD')", "5.00 USD"},
+		{"join('-', 'a', 'b')", "a-b"},
+		{"join('-')", ""},
+		{"count > 2 and len(items) == 3", true},
+		{"count is not 3 or items[0] is 'a'", true},
+		{"count > 5 then 'many' else 'few'", "few"},
+		{"unknown", nil},
+		{"nil == unknown", true},
+		{"'b' < 'c'", true},
+	}
+	env := testEnv()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Eval(ParseExpr(tt.expr), env)
+			if err != nil {
+				t.Fatalf("eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %#v, want %#v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct{ expr, want string }{
+		{"items[3]", "index 3 out of range with length 3"},
+		{"items['x']", "index x is not an integer"},
+		{"count.size", "int has no field size"},
+		{"user.age", "*rtmlast.testUser has no field age"},
+		{"nope(1)", "unknown function nope"},
+		{"currency('x', 'EUR')", "currency argument 1: cannot use string as float64"},
+		{"upper()", "upper takes 1 arguments, got 0"},
+		{"fail()", "fail: boom"},
+		{"count / 0", "division by zero"},
+		{"items - 1", "cannot apply - to []string and int"},
+		{"len(count)", "len: int has no length"},
+		{"count >", "unexpected end of expression"},
+		{"false or fail()", "fail: boom"},
+	}
+	env := testEnv()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Eval(ParseExpr(tt.expr), env)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("Eval(%q) error = %v, want %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+	// and and or stop at the first operand that decides them
+	if v, err := Eval(ParseExpr("true or fail()"), env); err != nil || v != true {
+		t.Fatalf("true or fail() = %v, %v", v, err)
+	}
+}
+
+func TestEvalBareWords(t *testing.T) {
+	env := testEnv()
+	env.BareWords = true
+	for expr, want := range map[string]any{
+		"status":              "status",
+		"status == active":    false,
+		"active == active":    true,
+		"user.name":           "Ada",
+		"config.theme":        "config.theme",
+		"count == 3 and open": true,
+	} {
+		got, err := Eval(ParseExpr(expr), env)
+		if err != nil || got != want {
+			t.Errorf("Eval(%q) = %#v, %v, want %#v", expr, got, err, want)
+		}
+	}
+}
+
+func TestInspectVisitsEveryName(t *testing.T) {
+	var names []string
+	Inspect(ParseExpr("(a[b] + f(c, d.e) | g h) ? i : -j"), func(e Expr) bool {
+		if id, ok := e.(IdentExpr); ok {
+			names = append(names, id.Name)
+		}
+		return true
+	})
+	if got := strings.Join(names, " "); got != "a b c d h i j" {
+		t.Fatalf("visited %q", got)
+	}
+}
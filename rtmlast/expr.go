@@ -0,0 +1,516 @@
+package rtmlast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The expression grammar, loosest binding first:
+//
+//	pipe     = ternary { "|" name { unary } }
+//	ternary  = or [ "then" or "else" ternary | "?" or ":" ternary ]
+//	or       = and { ("||" | "or") and }
+//	and      = equality { ("&&" | "and") equality }
+//	equality = relation { ("==" | "!=" | "is" | "is not") relation }
+//	relation = sum { ("<" | "<=" | ">" | ">=") sum }
+//	sum      = product { ("+" | "-") product }
+//	product  = unary { ("*" | "/") unary }
+//	unary    = ("!" | "not" | "-") unary | postfix
+//	postfix  = primary { "." name | "[" pipe "]" | "(" [ pipe { "," pipe } ] ")" }
+//	primary  = string | number | "true" | "false" | "nil" | name | "(" pipe ")"
+//
+// A name may carry a store:, signal: or prop: prefix, whose dotted path then
+// belongs to the name: store:app.cart.total is one IdentExpr.
+
+// ParseExpr parses a reactive RTML expression. An expression that does not
+// parse comes back as a BadExpr carrying the error, so the failure surfaces
+// when the expression is evaluated.
+func ParseExpr(s string) Expr {
+	src := strings.TrimSpace(s)
+	if src == "" {
+		return LiteralExpr{Value: ""}
+	}
+	toks, err := lexExpr(src)
+	if err != nil {
+		return BadExpr{Source: src, Err: err}
+	}
+	p := &exprParser{toks: toks}
+	x, err := p.pipe()
+	if err == nil && p.tok().kind != xEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return BadExpr{Source: src, Err: err}
+	}
+	return x
+}
+
+// ExprEnd returns how much of s, the text after "@expr:", the expression
+// takes: it ends at a tag, a line break, a brace or another "@", and at a ")"
+// or a single "|" outside brackets and quotes. It returns len(s) when nothing in s
+// ends it.
+func ExprEnd(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '\n' || ch == '\r':
+			return i
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '<' || ch == '@' || ch == '{' || ch == '}':
+			return i
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ch == '|' && depth == 0:
+			if i+1 < len(s) && s[i+1] == '|' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// RenameIdents returns the expression src with the names it reads replaced
+// as names maps them. Fields, store paths and quoted text are left alone, so
+// renaming row to r turns "row.name + store:app.row.x" into
+// "r.name + store:app.row.x".
+func RenameIdents(src string, names map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		ch := src[i]
+		j := i + 1
+		switch {
+		case ch == '\'' || ch == '"':
+			for j < len(src) && src[j] != ch {
+				j++
+			}
+			if j < len(src) {
+				j++
+			}
+		case isDigit(ch):
+			for j < len(src) && (isNamePart(src[j]) || src[j] == '.') {
+				j++
+			}
+		case isNameStart(ch):
+			for j < len(src) && isNamePart(src[j]) {
+				j++
+			}
+			member := i > 0 && src[i-1] == '.' || strings.HasSuffix(src[:i], "store:")
+			if to, ok := names[src[i:j]]; ok && !member {
+				b.WriteString(to)
+				i = j
+				continue
+			}
+		}
+		b.WriteString(src[i:j])
+		i = j
+	}
+	return b.String()
+}
+
+type exprTokenKind int
+
+const (
+	xEOF exprTokenKind = iota
+	xName
+	xString
+	xNumber
+	xPunct
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	val  string
+}
+
+// punctuation, longest first so "<=" is not read as "<"
+var exprPuncts = []string{"||", "&&", "==", "!=", "<=", ">=", "(", ")", "[", "]", ".", ",", "?", ":", "|", "!", "<", ">", "+", "-", "*", "/"}
+
+func lexExpr(s string) ([]exprToken, error) {
+	var toks []exprToken
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '"' || ch == '\'':
+			val, n, err := lexString(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, exprToken{xString, val})
+			i += n
+		case isDigit(ch):
+			j := i
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			if j+1 < len(s) && s[j] == '.' && isDigit(s[j+1]) {
+				j++
+				for j < len(s) && isDigit(s[j]) {
+					j++
+				}
+			}
+			toks = append(toks, exprToken{xNumber, s[i:j]})
+			i = j
+		case isNameStart(ch):
+			j := i
+			for j < len(s) && isNamePart(s[j]) {
+				j++
+			}
+			// a qualified reference keeps its dotted path
+			switch s[i:j] {
+			case "store", "signal", "prop":
+				if j+1 < len(s) && s[j] == ':' && isNameStart(s[j+1]) {
+					j++
+					for j < len(s) && (isNamePart(s[j]) || s[j] == '.') {
+						j++
+					}
+				}
+			}
+			toks = append(toks, exprToken{xName, s[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, p := range exprPuncts {
+				if strings.HasPrefix(s[i:], p) {
+					toks = append(toks, exprToken{xPunct, p})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				if ch == '=' {
+					return nil, fmt.Errorf("unexpected %q, compare with == or is", "=")
+				}
+				return nil, fmt.Errorf("unexpected %q", s[i:i+1])
+			}
+		}
+	}
+	return append(toks, exprToken{kind: xEOF}), nil
+}
+
+// lexString reads the quoted string s starts with and returns its value and
+// length.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == quote:
+			return b.String(), i + 1, nil
+		case ch == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+func isDigit(ch byte) bool     { return '0' <= ch && ch <= '9' }
+func isNameStart(ch byte) bool { return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' }
+func isNamePart(ch byte) bool  { return isNameStart(ch) || isDigit(ch) }
+
+// keywords cannot name a value
+var exprKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "is": true, "then": true, "else": true,
+}
+
+type exprParser struct {
+	toks []exprToken
+	pos  int
+}
+
+func (p *exprParser) tok() exprToken { return p.toks[p.pos] }
+
+func (p *exprParser) next() { p.pos++ }
+
+// is reports whether the current token is the punctuation or keyword val.
+func (p *exprParser) is(val string) bool {
+	t := p.tok()
+	return (t.kind == xPunct || t.kind == xName) && t.val == val
+}
+
+// accept consumes the punctuation or keyword val if it comes next.
+func (p *exprParser) accept(val string) bool {
+	if p.is(val) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(val string) error {
+	if !p.accept(val) {
+		return fmt.Errorf("expected %q, found %s", val, p.describe())
+	}
+	return nil
+}
+
+func (p *exprParser) describe() string {
+	t := p.tok()
+	switch t.kind {
+	case xEOF:
+		return "end of expression"
+	case xString:
+		return strconv.Quote(t.val)
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+func (p *exprParser) unexpected() error {
+	return fmt.Errorf("unexpected %s", p.describe())
+}
+
+func (p *exprParser) pipe() (Expr, error) {
+	x, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		t := p.tok()
+		if t.kind != xName || exprKeywords[t.val] || strings.Contains(t.val, ":") {
+			return nil, fmt.Errorf("expected a function name after |, found %s", p.describe())
+		}
+		p.next()
+		call := CallExpr{Fn: t.val, Args: []Expr{x}}
+		for p.startsOperand() {
+			arg, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+		}
+		x = call
+	}
+	return x, nil
+}
+
+// startsOperand reports whether the current token can begin a pipe argument.
+func (p *exprParser) startsOperand() bool {
+	t := p.tok()
+	switch t.kind {
+	case xString, xNumber:
+		return true
+	case xName:
+		return !exprKeywords[t.val] || t.val == "not"
+	case xPunct:
+		return t.val == "(" || t.val == "!" || t.val == "-"
+	}
+	return false
+}
+
+func (p *exprParser) ternary() (Expr, error) {
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	var elseTok string
+	switch {
+	case p.accept("then"):
+		elseTok = "else"
+	case p.accept("?"):
+		elseTok = ":"
+	default:
+		return cond, nil
+	}
+	then, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(elseTok); err != nil {
+		return nil, err
+	}
+	els, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return TernaryExpr{Cond: cond, Then: then, Else: els}, nil
+}
+
+// binary parses a left-associative chain of operand joined by the operators
+// ops maps from their spelling.
+func (p *exprParser) binary(operand func() (Expr, error), ops map[string]BinOp) (Expr, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.tok()
+		op, ok := ops[t.val]
+		if !ok || t.kind != xPunct && t.kind != xName {
+			return x, nil
+		}
+		p.next()
+		if op == OpEq && t.val == "is" && p.accept("not") {
+			op = OpNeq
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = BinaryExpr{Op: op, LHS: x, RHS: y}
+	}
+}
+
+func (p *exprParser) or() (Expr, error) {
+	return p.binary(p.and, map[string]BinOp{"||": OpOr, "or": OpOr})
+}
+
+func (p *exprParser) and() (Expr, error) {
+	return p.binary(p.equality, map[string]BinOp{"&&": OpAnd, "and": OpAnd})
+}
+
+func (p *exprParser) equality() (Expr, error) {
+	return p.binary(p.relation, map[string]BinOp{"==": OpEq, "!=": OpNeq, "is": OpEq})
+}
+
+func (p *exprParser) relation() (Expr, error) {
+	return p.binary(p.sum, map[string]BinOp{"<": OpLt, "<=": OpLte, ">": OpGt, ">=": OpGte})
+}
+
+func (p *exprParser) sum() (Expr, error) {
+	return p.binary(p.product, map[string]BinOp{"+": OpAdd, "-": OpSub})
+}
+
+func (p *exprParser) product() (Expr, error) {
+	return p.binary(p.unary, map[string]BinOp{"*": OpMul, "/": OpDiv})
+}
+
+func (p *exprParser) unary() (Expr, error) {
+	var op UnaryOp
+	switch {
+	case p.accept("!"), p.accept("not"):
+		op = UnaryNot
+	case p.accept("-"):
+		op = UnaryNeg
+	default:
+		return p.postfix()
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return UnaryExpr{Op: op, Expr: x}, nil
+}
+
+func (p *exprParser) postfix() (Expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.tok()
+			if t.kind != xName || strings.Contains(t.val, ":") {
+				return nil, fmt.Errorf("expected a field name after ., found %s", p.describe())
+			}
+			p.next()
+			x = FieldExpr{Obj: x, Field: t.val}
+		case p.accept("["):
+			index, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = IndexExpr{Obj: x, Index: index}
+		case p.is("("):
+			fn, ok := x.(IdentExpr)
+			if !ok || strings.Contains(fn.Name, ":") {
+				return nil, fmt.Errorf("only a function name can be called")
+			}
+			p.next()
+			call := CallExpr{Fn: fn.Name}
+			for !p.accept(")") {
+				if len(call.Args) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.pipe()
+				if err != nil {
+					return nil, err
+				}
+				call.Args = append(call.Args, arg)
+			}
+			x = call
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *exprParser) primary() (Expr, error) {
+	t := p.tok()
+	switch t.kind {
+	case xString:
+		p.next()
+		return LiteralExpr{Value: t.val}, nil
+	case xNumber:
+		p.next()
+		if n, err := strconv.Atoi(t.val); err == nil {
+			return LiteralExpr{Value: n}, nil
+		}
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, err
+		}
+		return LiteralExpr{Value: f}, nil
+	case xName:
+		if exprKeywords[t.val] {
+			return nil, p.unexpected()
+		}
+		p.next()
+		switch t.val {
+		case "true":
+			return LiteralExpr{Value: true}, nil
+		case "false":
+			return LiteralExpr{Value: false}, nil
+		case "nil":
+			return LiteralExpr{Value: nil}, nil
+		}
+		return IdentExpr{Name: t.val}, nil
+	case xPunct:
+		if p.accept("(") {
+			x, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,14 @@
+package rtmlast
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fingerprint identifies a template by its source. Ahead-of-time compiled
+// renderers are registered under the fingerprint of the source they were
+// generated from, so an edited template never runs stale code.
+func Fingerprint(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
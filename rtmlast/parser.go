@@ -1,7 +1,6 @@
 package rtmlast
 
 import (
-	"fmt"
 	"strings"
 	"unicode"
 )
@@ -26,6 +25,12 @@ const (
 type Token struct {
 	Type  TokenType
 	Value string
+	// Pos is the byte offset of the token in the input. A command starts at
+	// its "@"; the end of input is len(input).
+	Pos int
+	// Line and Col locate Pos, both counting from 1. Col counts bytes, like
+	// go/token.
+	Line, Col int
 }
 
 // Lexer tokenizes an RTML template.
@@ -43,15 +48,20 @@ func NewLexer(input string) *Lexer {
 func (l *Lexer) Lex() []Token {
 	var tokens []Token
 	var text strings.Builder
+	textPos := 0
+	flushText := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, Token{Type: TokenText, Value: text.String(), Pos: textPos})
+			text.Reset()
+		}
+	}
 
 	for l.pos < len(l.input) {
 		ch := l.input[l.pos]
 
 		if ch == '{' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '{' {
-			if text.Len() > 0 {
-				tokens = append(tokens, Token{Type: TokenText, Value: text.String()})
-				text.Reset()
-			}
+			flushText()
+			open := l.pos
 			l.pos += 2
 			var expr strings.Builder
 			for l.pos < len(l.input) {
@@ -62,37 +72,60 @@ func (l *Lexer) Lex() []Token {
 				expr.WriteByte(l.input[l.pos])
 				l.pos++
 			}
-			tokens = append(tokens, Token{Type: TokenVarOpen, Value: strings.TrimSpace(expr.String())})
-			tokens = append(tokens, Token{Type: TokenVarClose, Value: "}}"})
+			tokens = append(tokens, Token{Type: TokenVarOpen, Value: strings.TrimSpace(expr.String()), Pos: open})
+			tokens = append(tokens, Token{Type: TokenVarClose, Value: "}}", Pos: l.pos})
 
 		} else if ch == '@' && isCommandPrefix(l.input[l.pos+1:]) {
-			if text.Len() > 0 {
-				tokens = append(tokens, Token{Type: TokenText, Value: text.String()})
-				text.Reset()
-			}
+			flushText()
+			at := l.pos
 			l.pos++
 			start := l.pos
 			for l.pos < len(l.input) && !isNewline(l.input[l.pos]) && l.input[l.pos] != '<' {
 				l.pos++
 			}
-			cmdText := strings.TrimSpace(l.input[start:l.pos])
-			tokens = append(tokens, Token{Type: TokenCommand, Value: cmdText})
+			raw := l.input[start:l.pos]
+			cmdText := strings.TrimSpace(raw)
+			tokens = append(tokens, Token{Type: TokenCommand, Value: cmdText, Pos: at})
+			// whitespace between a command and the next tag stays text, so the
+			// tokens still cover the whole input
+			if trailing := raw[len(strings.TrimRightFunc(raw, unicode.IsSpace)):]; trailing != "" {
+				textPos = l.pos - len(trailing)
+				text.WriteString(trailing)
+			}
 
 		} else {
+			if text.Len() == 0 {
+				textPos = l.pos
+			}
 			text.WriteByte(ch)
 			l.pos++
 		}
 	}
 
-	if text.Len() > 0 {
-		tokens = append(tokens, Token{Type: TokenText, Value: text.String()})
-	}
-	tokens = append(tokens, Token{Type: TokenEOF})
+	flushText()
+	tokens = append(tokens, Token{Type: TokenEOF, Pos: len(l.input)})
+	l.locate(tokens)
 	return tokens
 }
 
+// locate sets Line and Col from Pos. Tokens come in input order, so one scan
+// of the input serves them all.
+func (l *Lexer) locate(tokens []Token) {
+	line, lineStart, off := 1, 0, 0
+	for i := range tokens {
+		for ; off < tokens[i].Pos; off++ {
+			if l.input[off] == '\n' {
+				line++
+				lineStart = off + 1
+			}
+		}
+		tokens[i].Line = line
+		tokens[i].Col = tokens[i].Pos - lineStart + 1
+	}
+}
+
 func isCommandPrefix(s string) bool {
-	prefixes := []string{"if:", "else-if:", "else", "endif", "for:", "endfor", "include:", "slot:", "endslot", "on:", "store:", "signal:", "prop:", "h:", "plugin:", "expr:"}
+	prefixes := []string{"if:", "else-if:", "else", "endif", "for:", "endfor", "include:", "slot:", "endslot", "on:", "store:", "signal:", "prop:", "h:", "plugin:", "expr:", "switch:", "case:", "default", "endswitch", "await:", "pending", "then:", "catch:", "endawait"}
 	for _, p := range prefixes {
 		if len(s) > len(p) && s[:len(p)] == p {
 			return true
@@ -112,11 +145,12 @@ func isNewline(ch byte) bool {
 func Parse(template string) ([]Node, error) {
 	lex := NewLexer(template)
 	tokens := lex.Lex()
-	p := &parser{tokens: tokens, pos: 0}
+	p := &parser{src: template, tokens: tokens, pos: 0}
 	return p.parseNodes()
 }
 
 type parser struct {
+	src    string
 	tokens []Token
 	pos    int
 }
@@ -187,6 +221,8 @@ func (p *parser) parseCommand(cmdText string) (Node, bool, error) {
 			return nil, false, nil
 		case "endslot":
 			return nil, false, nil
+		case "default", "endswitch", "pending", "endawait":
+			return nil, false, nil
 		}
 		return CommandNode{Kind: cmdText, Value: ""}, true, nil
 	}
@@ -201,6 +237,10 @@ func (p *parser) parseCommand(cmdText string) (Node, bool, error) {
 	case "for":
 		node, err := p.parseFor(rest)
 		return node, true, err
+	case "switch":
+		return p.parseSwitch(rest), true, nil
+	case "await":
+		return p.parseAwait(rest), true, nil
 	case "include":
 		return IncludeNode{Name: rest, Props: nil}, true, nil
 	case "slot":
@@ -214,7 +254,7 @@ func (p *parser) parseCommand(cmdText string) (Node, bool, error) {
 	case "plugin":
 		return CommandNode{Kind: "plugin", Value: rest}, true, nil
 	case "expr":
-		return ExprNode{Expr: ParseExpr(rest)}, true, nil
+		return ExprNode{Expr: ParseExpr(rest), Source: rest}, true, nil
 	default:
 		return CommandNode{Kind: kind, Value: rest}, true, nil
 	}
@@ -222,13 +262,13 @@ func (p *parser) parseCommand(cmdText string) (Node, bool, error) {
 
 func (p *parser) parseIf(condStr string) (Node, error) {
 	thenNodes, _ := p.parseUntilCommands("else-if", "else", "endif")
-	node := IfNode{Cond: ParseExpr(condStr), Then: thenNodes}
+	node := IfNode{Cond: ParseExpr(condStr), Source: condStr, Then: thenNodes}
 
 	for p.peek().Type == TokenCommand && strings.HasPrefix(p.peek().Value, "else-if:") {
 		cmdText := p.next().Value
 		elseCond := strings.TrimPrefix(cmdText, "else-if:")
 		body, _ := p.parseUntilCommands("else-if", "else", "endif")
-		node.ElseIf = append(node.ElseIf, ElseIfBranch{Cond: ParseExpr(elseCond), Body: body})
+		node.ElseIf = append(node.ElseIf, ElseIfBranch{Cond: ParseExpr(elseCond), Source: elseCond, Body: body})
 	}
 
 	if p.peek().Type == TokenCommand && p.peek().Value == "else" {
@@ -244,7 +284,9 @@ func (p *parser) parseIf(condStr string) (Node, error) {
 }
 
 func (p *parser) parseFor(detail string) (Node, error) {
+	bodyStart := p.peek().Pos
 	body, _ := p.parseUntilCommands("endfor")
+	bodySource := p.src[bodyStart:p.peek().Pos]
 	if p.peek().Type == TokenCommand && p.peek().Value == "endfor" {
 		p.next()
 	}
@@ -260,15 +302,102 @@ func (p *parser) parseFor(detail string) (Node, error) {
 		keyAlias = strings.TrimSpace(alias[commaIdx+1:])
 		alias = strings.TrimSpace(alias[:commaIdx])
 	}
-	return ForNode{Alias: alias, KeyAlias: keyAlias, Expr: ParseExpr(exprStr), Body: body}, nil
+	return ForNode{Alias: alias, KeyAlias: keyAlias, Expr: ParseExpr(exprStr), Source: detail, Body: body, BodySource: bodySource}, nil
+}
+
+func (p *parser) parseSwitch(subject string) Node {
+	node := SwitchNode{Subject: ParseExpr(subject), Source: subject}
+	// only whitespace belongs before the first case
+	p.parseUntilCommands("case", "default", "endswitch")
+	for {
+		t := p.peek()
+		if t.Type != TokenCommand {
+			return node
+		}
+		switch {
+		case strings.HasPrefix(t.Value, "case:"):
+			p.next()
+			value := strings.TrimPrefix(t.Value, "case:")
+			body, _ := p.parseUntilCommands("case", "default", "endswitch")
+			node.Cases = append(node.Cases, CaseBranch{Value: ParseExpr(value), Source: value, Body: body})
+		case t.Value == "default":
+			p.next()
+			node.Default, _ = p.parseUntilCommands("case", "default", "endswitch")
+		case t.Value == "endswitch":
+			p.next()
+			return node
+		default:
+			return node
+		}
+	}
+}
+
+func (p *parser) parseAwait(resource string) Node {
+	node := AwaitNode{Resource: strings.TrimSpace(resource)}
+	sections := []string{"pending", "then", "catch", "endawait"}
+	// content before any section is pending content, as after @pending
+	node.Pending, _ = p.parseUntilCommands(sections...)
+	for {
+		t := p.peek()
+		if t.Type != TokenCommand {
+			return node
+		}
+		switch {
+		case t.Value == "pending":
+			p.next()
+			body, _ := p.parseUntilCommands(sections...)
+			node.Pending = append(node.Pending, body...)
+		case strings.HasPrefix(t.Value, "then:"):
+			p.next()
+			node.ValueAlias = strings.TrimSpace(strings.TrimPrefix(t.Value, "then:"))
+			node.Then, _ = p.parseUntilCommands(sections...)
+		case strings.HasPrefix(t.Value, "catch:"):
+			p.next()
+			node.ErrorAlias = strings.TrimSpace(strings.TrimPrefix(t.Value, "catch:"))
+			node.Catch, _ = p.parseUntilCommands(sections...)
+		case t.Value == "endawait":
+			p.next()
+			return node
+		default:
+			return node
+		}
+	}
 }
 
 func (p *parser) parseSlot(name string) (Node, bool, error) {
+	var params []string
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		if params = SlotParams(name[i:]); params != nil {
+			name = name[:i]
+		}
+	}
 	body, _ := p.parseUntilCommands("endslot")
 	if p.peek().Type == TokenCommand && p.peek().Value == "endslot" {
 		p.next()
 	}
-	return SlotNode{Name: name, Fallback: body}, true, nil
+	return SlotNode{Name: name, Params: params, Fallback: body}, true, nil
+}
+
+// SlotParams returns the names a scoped slot header lists after the slot
+// name, given the rest of its line: " row, index" gives [row index]. It
+// returns nil when the rest holds anything but names, as inline fallback
+// content does.
+func SlotParams(rest string) []string {
+	fields := strings.FieldsFunc(rest, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' || r == '\r' })
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, f := range fields {
+		if !isNameStart(f[0]) {
+			return nil
+		}
+		for i := 1; i < len(f); i++ {
+			if !isNamePart(f[i]) {
+				return nil
+			}
+		}
+	}
+	return fields
 }
 
 func (p *parser) parseUntilCommands(commands ...string) ([]Node, error) {
@@ -308,284 +437,3 @@ func (p *parser) parseUntilCommands(commands ...string) ([]Node, error) {
 	}
 	return nodes, nil
 }
-
-// ParseExpr parses a reactive RTML expression.
-func ParseExpr(s string) Expr {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return LiteralExpr{Value: ""}
-	}
-	// Ternary: cond then X else Y (preferred) or cond ? X : Y (legacy)
-	if idx, ok := findTernaryThen(s); ok {
-		condStr := strings.TrimSpace(s[:idx])
-		rest := s[idx+5:] // len(" then") = 5
-		elseIdx, ok2 := findElse(rest)
-		if ok2 {
-			thenStr := strings.TrimSpace(rest[:elseIdx])
-			elseStr := strings.TrimSpace(rest[elseIdx+5:]) // len(" else") = 5
-			return TernaryExpr{Cond: ParseExpr(condStr), Then: ParseExpr(thenStr), Else: ParseExpr(elseStr)}
-		}
-	}
-	if idx, ok := findTernarySymbol(s); ok {
-		condStr := strings.TrimSpace(s[:idx])
-		rest := s[idx+1:]
-		colonIdx := findTernaryColon(rest)
-		if colonIdx >= 0 {
-			thenStr := strings.TrimSpace(rest[:colonIdx])
-			elseStr := strings.TrimSpace(rest[colonIdx+1:])
-			return TernaryExpr{Cond: ParseExpr(condStr), Then: ParseExpr(thenStr), Else: ParseExpr(elseStr)}
-		}
-	}
-	if result, ok := tryParseBinary(s); ok {
-		return result
-	}
-	if strings.HasPrefix(s, "!") || strings.HasPrefix(s, "not ") {
-		var inner string
-		if strings.HasPrefix(s, "not ") {
-			inner = strings.TrimSpace(s[4:])
-		} else {
-			inner = strings.TrimSpace(s[1:])
-		}
-		return UnaryExpr{Op: UnaryNot, Expr: ParseExpr(inner)}
-	}
-	if strings.HasPrefix(s, "-") && len(s) > 1 {
-		return UnaryExpr{Op: UnaryNeg, Expr: ParseExpr(s[1:])}
-	}
-	if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
-		return LiteralExpr{Value: s[1 : len(s)-1]}
-	}
-	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2 {
-		return LiteralExpr{Value: s[1 : len(s)-1]}
-	}
-	if n, ok := tryParseNumber(s); ok {
-		return LiteralExpr{Value: n}
-	}
-	if strings.HasPrefix(s, "store:") || strings.HasPrefix(s, "signal:") || strings.HasPrefix(s, "prop:") {
-		return IdentExpr{Name: s}
-	}
-	if strings.Contains(s, ".") && !strings.Contains(s, "(") {
-		parts := strings.SplitN(s, ".", 2)
-		if isIdent(parts[0]) && isIdent(parts[1]) {
-			return FieldExpr{Obj: ParseExpr(parts[0]), Field: parts[1]}
-		}
-	}
-	if strings.Contains(s, "(") {
-		parenIdx := strings.Index(s, "(")
-		fnName := s[:parenIdx]
-		argsStr := s[parenIdx+1 : len(s)-1]
-		var args []Expr
-		if argsStr != "" {
-			for _, a := range strings.Split(argsStr, ",") {
-				args = append(args, ParseExpr(strings.TrimSpace(a)))
-			}
-		}
-		return CallExpr{Fn: fnName, Args: args}
-	}
-	return IdentExpr{Name: s}
-}
-
-// findTernaryThen finds " then " outside strings and parens.
-func findTernaryThen(s string) (int, bool) {
-	depth := 0
-	inStr := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' || ch == '\'' {
-			inStr = !inStr
-		}
-		if inStr {
-			continue
-		}
-		if ch == '(' {
-			depth++
-		}
-		if ch == ')' {
-			depth--
-		}
-		if depth > 0 {
-			continue
-		}
-		if i+5 <= len(s) && s[i:i+5] == " then" && (i+5 >= len(s) || s[i+5] == ' ') {
-			return i, true
-		}
-	}
-	return -1, false
-}
-
-// findElse finds " else " outside strings and parens.
-func findElse(s string) (int, bool) {
-	depth := 0
-	inStr := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' || ch == '\'' {
-			inStr = !inStr
-		}
-		if inStr {
-			continue
-		}
-		if ch == '(' {
-			depth++
-		}
-		if ch == ')' {
-			depth--
-		}
-		if depth > 0 {
-			continue
-		}
-		if i+5 <= len(s) && s[i:i+5] == " else" && (i+5 >= len(s) || s[i+5] == ' ') {
-			return i, true
-		}
-	}
-	return -1, false
-}
-
-// findTernarySymbol finds "?" outside strings and parens.
-func findTernarySymbol(s string) (int, bool) {
-	depth := 0
-	inStr := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' || ch == '\'' {
-			inStr = !inStr
-		}
-		if inStr {
-			continue
-		}
-		if ch == '(' {
-			depth++
-		}
-		if ch == ')' {
-			depth--
-		}
-		if depth > 0 {
-			continue
-		}
-		if ch == '?' {
-			return i, true
-		}
-	}
-	return -1, false
-}
-
-// findTernaryColon finds ":" outside strings and parens, used for legacy ? : ternary.
-func findTernaryColon(s string) int {
-	depth := 0
-	inStr := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' || ch == '\'' {
-			inStr = !inStr
-		}
-		if inStr {
-			continue
-		}
-		if ch == '(' {
-			depth++
-		}
-		if ch == ')' {
-			depth--
-		}
-		if depth > 0 {
-			continue
-		}
-		if ch == ':' {
-			return i
-		}
-	}
-	return -1
-}
-
-func isIdent(s string) bool {
-	if s == "" {
-		return false
-	}
-	for _, ch := range s {
-		if !unicode.IsLetter(ch) && ch != '_' && !unicode.IsDigit(ch) {
-			return false
-		}
-	}
-	return true
-}
-
-func tryParseNumber(s string) (any, bool) {
-	var f float64
-	if _, err := fmt.Sscanf(s, "%f", &f); err == nil {
-		if f == float64(int(f)) {
-			return int(f), true
-		}
-		return f, true
-	}
-	return nil, false
-}
-
-func tryParseBinary(s string) (Expr, bool) {
-	type opInfo struct {
-		op   BinOp
-		syms []string
-	}
-	ops := []opInfo{
-		{OpOr, []string{" or ", "||"}},
-		{OpAnd, []string{" and ", "&&"}},
-		{OpEq, []string{" is ", "=="}},
-		{OpNeq, []string{" is not ", "!="}},
-		{OpLte, []string{"<="}},
-		{OpGte, []string{">="}},
-		{OpLt, []string{"<"}},
-		{OpGt, []string{">"}},
-		{OpAdd, []string{"+"}},
-		{OpSub, []string{"-"}},
-		{OpMul, []string{"*"}},
-		{OpDiv, []string{"/"}},
-	}
-	for _, o := range ops {
-		for _, sym := range o.syms {
-			idx := findBinaryOp(s, sym)
-			if idx >= 0 {
-				lhs := strings.TrimSpace(s[:idx])
-				rhs := strings.TrimSpace(s[idx+len(sym):])
-				if lhs != "" && rhs != "" {
-					return BinaryExpr{Op: o.op, LHS: ParseExpr(lhs), RHS: ParseExpr(rhs)}, true
-				}
-			}
-		}
-	}
-	return nil, false
-}
-
-func findBinaryOp(s, op string) int {
-	depth := 0
-	inStr := false
-	inVar := false
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' || ch == '\'' {
-			inStr = !inStr
-		}
-		if inStr {
-			continue
-		}
-		if ch == '{' {
-			inVar = true
-		}
-		if ch == '}' {
-			inVar = false
-		}
-		if inVar {
-			continue
-		}
-		if ch == '(' {
-			depth++
-		}
-		if ch == ')' {
-			depth--
-		}
-		if depth > 0 {
-			continue
-		}
-		if i+len(op) <= len(s) && s[i:i+len(op)] == op {
-			return i
-		}
-	}
-	return -1
-}
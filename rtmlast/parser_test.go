@@ -1,6 +1,7 @@
 package rtmlast
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -83,6 +84,80 @@ func TestParseForLoop(t *testing.T) {
 	}
 }
 
+func TestParseForLoopSource(t *testing.T) {
+	input := "@for:item in items  \n<li>@prop:item</li>\n@endfor"
+	nodes, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := nodes[0].(ForNode)
+	if fn.Source != "item in items" {
+		t.Fatalf("Source = %q", fn.Source)
+	}
+	if fn.BodySource != "  \n<li>@prop:item</li>\n" {
+		t.Fatalf("BodySource = %q", fn.BodySource)
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	input := "<p>@store:a.b.c </p>"
+	tokens := NewLexer(input).Lex()
+	var text strings.Builder
+	for _, tok := range tokens {
+		switch tok.Type {
+		case TokenText:
+			if input[tok.Pos:tok.Pos+len(tok.Value)] != tok.Value {
+				t.Fatalf("text %q not at %d", tok.Value, tok.Pos)
+			}
+			text.WriteString(tok.Value)
+		case TokenCommand:
+			if tok.Pos != 3 || input[tok.Pos] != '@' {
+				t.Fatalf("command at %d", tok.Pos)
+			}
+			text.WriteString("@" + tok.Value)
+		case TokenEOF:
+			if tok.Pos != len(input) {
+				t.Fatalf("EOF at %d", tok.Pos)
+			}
+		}
+	}
+	if text.String() != input {
+		t.Fatalf("tokens cover %q", text.String())
+	}
+}
+
+func TestLexLineCol(t *testing.T) {
+	input := "<root>\n  <p>{{ name }}</p>\n\t@if:count > 1\n</root>"
+	tokens := NewLexer(input).Lex()
+	for _, tok := range tokens {
+		if line, col := Position(input, tok.Pos); tok.Line != line || tok.Col != col {
+			t.Fatalf("token %q at %d:%d, want %d:%d", tok.Value, tok.Line, tok.Col, line, col)
+		}
+		switch {
+		case tok.Type == TokenVarOpen && (tok.Line != 2 || tok.Col != 6):
+			t.Fatalf("interpolation at %d:%d", tok.Line, tok.Col)
+		case tok.Type == TokenCommand && (tok.Line != 3 || tok.Col != 2):
+			t.Fatalf("command at %d:%d", tok.Line, tok.Col)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	src := "<root>\n  <p>\n\t@if:count >\n  </p>\n</root>\n<!-- end -->"
+	want := "  1 | <root>\n" +
+		"  2 |   <p>\n" +
+		"> 3 | \t@if:count >\n" +
+		"    | \t          ^\n" +
+		"  4 |   </p>\n" +
+		"  5 | </root>"
+	if got := Frame(src, 3, 12); got != want {
+		t.Fatalf("Frame =\n%s\nwant\n%s", got, want)
+	}
+	if got := Frame(src, 9, 1); got != "" {
+		t.Fatalf("Frame past the end = %q", got)
+	}
+}
+
 func TestParseInclude(t *testing.T) {
 	input := "@include:MyComponent"
 	nodes, err := Parse(input)
@@ -186,6 +261,20 @@ func TestParseSlot(t *testing.T) {
 	}
 }
 
+func TestParseScopedSlot(t *testing.T) {
+	nodes, err := Parse("@slot:cell row, index\n<td>@prop:row.name</td>\n@endslot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn := nodes[0].(SlotNode)
+	if sn.Name != "cell" || strings.Join(sn.Params, " ") != "row index" {
+		t.Fatalf("slot = %q %v", sn.Name, sn.Params)
+	}
+	if got := SlotParams(" fallback@endslot"); got != nil {
+		t.Fatalf("inline fallback read as params %v", got)
+	}
+}
+
 func TestParseStoreIdent(t *testing.T) {
 	expr := ParseExpr("store:app.user.name")
 	ident, ok := expr.(IdentExpr)
@@ -218,3 +307,120 @@ func TestParseComplexTemplate(t *testing.T) {
 		t.Fatal("expected nodes")
 	}
 }
+
+func TestParseIndexExpr(t *testing.T) {
+	expr := ParseExpr(`m["k"][0]`)
+	outer, ok := expr.(IndexExpr)
+	if !ok {
+		t.Fatalf("expected IndexExpr, got %T", expr)
+	}
+	inner, ok := outer.Obj.(IndexExpr)
+	if !ok {
+		t.Fatalf("expected nested IndexExpr, got %T", outer.Obj)
+	}
+	if lit, _ := inner.Index.(LiteralExpr); lit.Value != "k" {
+		t.Fatalf("expected key \"k\", got %#v", inner.Index)
+	}
+}
+
+func TestParsePipe(t *testing.T) {
+	expr := ParseExpr(`price * qty | currency "EUR" | upper`)
+	outer, ok := expr.(CallExpr)
+	if !ok || outer.Fn != "upper" || len(outer.Args) != 1 {
+		t.Fatalf("expected upper(...), got %#v", expr)
+	}
+	inner, ok := outer.Args[0].(CallExpr)
+	if !ok || inner.Fn != "currency" || len(inner.Args) != 2 {
+		t.Fatalf("expected currency(x, \"EUR\"), got %#v", outer.Args[0])
+	}
+	if _, ok := inner.Args[0].(BinaryExpr); !ok {
+		t.Fatalf("the piped value should be the whole product, got %T", inner.Args[0])
+	}
+}
+
+func TestParseBadExpr(t *testing.T) {
+	for _, src := range []string{"count >", "a = 1", "f(1", "x | 3", "'open", "a b"} {
+		bad, ok := ParseExpr(src).(BadExpr)
+		if !ok {
+			t.Errorf("%q: expected BadExpr, got %T", src, ParseExpr(src))
+			continue
+		}
+		if bad.Source != src || bad.Err == nil {
+			t.Errorf("%q: BadExpr = %#v", src, bad)
+		}
+	}
+}
+
+func TestExprEnd(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"count + 1</span>", "count + 1"},
+		{"(total(items) | currency 'EUR'))", "(total(items) | currency 'EUR')"},
+		{"a || b | c", "a || b "},
+		{"'<@)' + x\nnext", "'<@)' + x"},
+		{"m[k] @prop:x", "m[k] "},
+		{"len(items) {{ title }}", "len(items) "},
+		{"done", "done"},
+	}
+	for _, tt := range tests {
+		if got := tt.in[:ExprEnd(tt.in)]; got != tt.want {
+			t.Errorf("ExprEnd(%q) keeps %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenameIdents(t *testing.T) {
+	names := map[string]string{"r": "row", "i": "index"}
+	tests := []struct{ in, want string }{
+		{"r.name + 1", "row.name + 1"},
+		{"item.r == 'r' and prop:i > 0", "item.r == 'r' and prop:index > 0"},
+		{"store:r.i.r + len(r)", "store:r.i.r + len(row)"},
+		{"ri + 2.5", "ri + 2.5"},
+	}
+	for _, tt := range tests {
+		if got := RenameIdents(tt.in, names); got != tt.want {
+			t.Errorf("RenameIdents(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSwitch(t *testing.T) {
+	input := "@switch:user.role\n@case:'admin'\nA\n@case:'editor'\nE\n@default\nD\n@endswitch\nafter"
+	nodes, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sn, ok := nodes[0].(SwitchNode)
+	if !ok {
+		t.Fatalf("expected SwitchNode, got %T", nodes[0])
+	}
+	if sn.Source != "user.role" {
+		t.Fatalf("expected subject 'user.role', got %q", sn.Source)
+	}
+	if len(sn.Cases) != 2 || sn.Cases[0].Source != "'admin'" || sn.Cases[1].Source != "'editor'" {
+		t.Fatalf("unexpected cases: %#v", sn.Cases)
+	}
+	if len(sn.Default) == 0 {
+		t.Fatal("expected default branch")
+	}
+	if tn, ok := nodes[len(nodes)-1].(TextNode); !ok || !strings.Contains(tn.Text, "after") {
+		t.Fatalf("expected text after @endswitch, got %#v", nodes[len(nodes)-1])
+	}
+}
+
+func TestParseAwait(t *testing.T) {
+	input := "@await:profile\n@pending\nloading\n@then:p\n{{p.name}}\n@catch:err\n{{err}}\n@endawait"
+	nodes, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	an, ok := nodes[0].(AwaitNode)
+	if !ok {
+		t.Fatalf("expected AwaitNode, got %T", nodes[0])
+	}
+	if an.Resource != "profile" || an.ValueAlias != "p" || an.ErrorAlias != "err" {
+		t.Fatalf("unexpected await: %#v", an)
+	}
+	if len(an.Pending) == 0 || len(an.Then) == 0 || len(an.Catch) == 0 {
+		t.Fatalf("expected every section, got %#v", an)
+	}
+}
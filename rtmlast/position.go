@@ -0,0 +1,66 @@
+package rtmlast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// frameContext is the number of lines a code frame shows around the line it
+// points at.
+const frameContext = 2
+
+// Position returns the line and column of the byte offset off in src, both
+// counting from 1 as Token does. An offset past the end is clamped to it.
+func Position(src string, off int) (line, col int) {
+	if off > len(src) {
+		off = len(src)
+	}
+	if off < 0 {
+		off = 0
+	}
+	line = 1 + strings.Count(src[:off], "\n")
+	return line, off - (strings.LastIndexByte(src[:off], '\n') + 1) + 1
+}
+
+// Frame renders the lines of src around line, numbered, with the line marked
+// and a caret under col:
+//
+//	  3 |   <p>
+//	> 4 |     @if:count >
+//	    |         ^
+//	  5 |   </p>
+//
+// It returns "" when line is not in src.
+func Frame(src string, line, col int) string {
+	lines := strings.Split(src, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first := max(line-frameContext, 1)
+	last := min(line+frameContext, len(lines))
+	width := len(fmt.Sprint(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		text := strings.TrimRight(lines[n-1], "\r")
+		mark := " "
+		if n == line {
+			mark = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", mark, width, n, text)
+		if n != line || col < 1 {
+			continue
+		}
+		// pad with the line's own tabs so the caret lines up however wide
+		// the reader renders them
+		var pad strings.Builder
+		for _, r := range text[:min(col-1, len(text))] {
+			if r == '\t' {
+				pad.WriteByte('\t')
+			} else {
+				pad.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(&b, "  %*s | %s^\n", width, "", pad.String())
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
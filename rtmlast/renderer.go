@@ -5,7 +5,6 @@ package rtmlast
 import (
 	"fmt"
 	"html"
-	"strconv"
 	"strings"
 
 	"github.com/rfwlab/rfw/v2/state"
@@ -52,6 +51,10 @@ func renderNode(n Node, ctx *RenderContext) string {
 		return renderIf(v, ctx)
 	case ForNode:
 		return renderFor(v, ctx)
+	case SwitchNode:
+		return renderSwitch(v, ctx)
+	case AwaitNode:
+		return renderAwait(v, ctx)
 	case IncludeNode:
 		return renderInclude(v, ctx)
 	case SlotNode:
@@ -89,6 +92,44 @@ func renderIf(v IfNode, ctx *RenderContext) string {
 	return ""
 }
 
+func renderSwitch(v SwitchNode, ctx *RenderContext) string {
+	subject := evalExpr(v.Subject, ctx)
+	for _, c := range v.Cases {
+		if equal(subject, evalExpr(c.Value, ctx)) {
+			return RenderNodes(c.Body, ctx)
+		}
+	}
+	return RenderNodes(v.Default, ctx)
+}
+
+func renderAwait(v AwaitNode, ctx *RenderContext) string {
+	res, ok := ctx.Props[v.Resource].(state.AnyResource)
+	if !ok {
+		return ""
+	}
+	switch res.Status() {
+	case state.ResourceReady:
+		return RenderNodes(v.Then, withProp(ctx, v.ValueAlias, res.ReadValue()))
+	case state.ResourceError:
+		return RenderNodes(v.Catch, withProp(ctx, v.ErrorAlias, res.Error()))
+	}
+	return RenderNodes(v.Pending, ctx)
+}
+
+// withProp returns ctx with the prop name set to value, leaving ctx as it is.
+func withProp(ctx *RenderContext, name string, value any) *RenderContext {
+	if name == "" {
+		return ctx
+	}
+	child := *ctx
+	child.Props = make(map[string]any, len(ctx.Props)+1)
+	for k, v := range ctx.Props {
+		child.Props[k] = v
+	}
+	child.Props[name] = value
+	return &child
+}
+
 func renderFor(v ForNode, ctx *RenderContext) string {
 	collection := evalExpr(v.Expr, ctx)
 	var items []any
@@ -206,169 +247,31 @@ func renderEventCmd(val string) string {
 	return fmt.Sprintf(`data-on-%s`, val)
 }
 
+// evalExpr evaluates e against the props and stores of ctx. The AST renderer
+// has nowhere to report errors, so a failing expression renders as nil.
 func evalExpr(e Expr, ctx *RenderContext) any {
-	switch v := e.(type) {
-	case IdentExpr:
-		return lookupIdent(v.Name, ctx)
-	case LiteralExpr:
-		return v.Value
-	case BinaryExpr:
-		return evalBinary(v, ctx)
-	case UnaryExpr:
-		return evalUnary(v, ctx)
-	case FieldExpr:
-		obj := evalExpr(v.Obj, ctx)
-		if m, ok := obj.(map[string]any); ok {
-			return m[v.Field]
-		}
-		return nil
-	case CallExpr:
-		return nil
-	case TernaryExpr:
-		if evalBool(v.Cond, ctx) {
-			return evalExpr(v.Then, ctx)
-		}
-		return evalExpr(v.Else, ctx)
-	default:
+	v, err := Eval(e, Env{Lookup: func(name string) (any, bool) { return lookupIdent(name, ctx) }})
+	if err != nil {
 		return nil
 	}
+	return v
 }
 
 func evalBool(e Expr, ctx *RenderContext) bool {
-	val := evalExpr(e, ctx)
-	switch v := val.(type) {
-	case bool:
-		return v
-	case string:
-		return v != ""
-	case int:
-		return v != 0
-	case float64:
-		return v != 0
-	case nil:
-		return false
-	default:
-		return true
-	}
-}
-
-func evalBinary(b BinaryExpr, ctx *RenderContext) any {
-	lhs := evalExpr(b.LHS, ctx)
-	rhs := evalExpr(b.RHS, ctx)
-	switch b.Op {
-	case OpEq:
-		return fmt.Sprintf("%v", lhs) == fmt.Sprintf("%v", rhs)
-	case OpNeq:
-		return fmt.Sprintf("%v", lhs) != fmt.Sprintf("%v", rhs)
-	case OpAnd:
-		return toBool(lhs) && toBool(rhs)
-	case OpOr:
-		return toBool(lhs) || toBool(rhs)
-	case OpLt, OpGt, OpLte, OpGte:
-		return compareValues(lhs, rhs, b.Op)
-	case OpAdd:
-		return toFloat(lhs) + toFloat(rhs)
-	case OpSub:
-		return toFloat(lhs) - toFloat(rhs)
-	case OpMul:
-		return toFloat(lhs) * toFloat(rhs)
-	case OpDiv:
-		r := toFloat(rhs)
-		if r == 0 {
-			return 0.0
-		}
-		return toFloat(lhs) / r
-	default:
-		return nil
-	}
+	return Truthy(evalExpr(e, ctx))
 }
 
-func evalUnary(u UnaryExpr, ctx *RenderContext) any {
-	val := evalExpr(u.Expr, ctx)
-	switch u.Op {
-	case UnaryNot:
-		return !toBool(val)
-	case UnaryNeg:
-		return -toFloat(val)
-	default:
-		return val
-	}
-}
-
-func lookupIdent(name string, ctx *RenderContext) any {
+func lookupIdent(name string, ctx *RenderContext) (any, bool) {
 	if strings.HasPrefix(name, "store:") {
 		parts := strings.Split(strings.TrimPrefix(name, "store:"), ".")
 		if len(parts) == 3 && ctx.StoreMgr != nil {
 			store := ctx.StoreMgr.GetStore(parts[0], parts[1])
 			if store != nil {
-				return store.Get(parts[2])
+				return store.Get(parts[2]), true
 			}
 		}
-		return nil
-	}
-	if strings.HasPrefix(name, "signal:") {
-		sigName := strings.TrimPrefix(name, "signal:")
-		if prop, ok := ctx.Props[sigName]; ok {
-			if sig, ok := prop.(interface{ Read() any }); ok {
-				return sig.Read()
-			}
-			return prop
-		}
-		return nil
-	}
-	if v, ok := ctx.Props[name]; ok {
-		if sig, ok := v.(interface{ Read() any }); ok {
-			return sig.Read()
-		}
-		return v
-	}
-	return nil
-}
-
-func toBool(v any) bool {
-	switch val := v.(type) {
-	case bool:
-		return val
-	case string:
-		return val != ""
-	case int:
-		return val != 0
-	case float64:
-		return val != 0
-	default:
-		return v != nil
-	}
-}
-
-func toFloat(v any) float64 {
-	switch val := v.(type) {
-	case int:
-		return float64(val)
-	case float64:
-		return val
-	case string:
-		f, err := strconv.ParseFloat(val, 64)
-		if err != nil {
-			return 0
-		}
-		return f
-	default:
-		return 0
-	}
-}
-
-func compareValues(lhs, rhs any, op BinOp) bool {
-	l, r := toFloat(lhs), toFloat(rhs)
-	switch op {
-	case OpLt:
-		return l < r
-	case OpGt:
-		return l > r
-	case OpLte:
-		return l <= r
-	case OpGte:
-		return l >= r
-	default:
-		return false
+		return nil, false
 	}
+	v, ok := ctx.Props[strings.TrimPrefix(name, "signal:")]
+	return v, ok
 }
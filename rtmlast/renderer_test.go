@@ -47,3 +47,39 @@ func TestRenderNodesEscapesSignals(t *testing.T) {
 		t.Fatalf("signal not escaped: %s", out)
 	}
 }
+
+func TestRenderSwitch(t *testing.T) {
+	nodes, err := Parse("@switch:role\n@case:'admin'\nA\n@case:'editor'\nE\n@default\nD\n@endswitch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for role, want := range map[string]string{"admin": "A", "editor": "E", "guest": "D"} {
+		out := strings.TrimSpace(RenderNodes(nodes, &RenderContext{Props: map[string]any{"role": role}}))
+		if out != want {
+			t.Errorf("role %s rendered %q, want %q", role, out, want)
+		}
+	}
+}
+
+func TestRenderAwait(t *testing.T) {
+	nodes, err := Parse("@await:res\n@pending\nwait\n@then:v\n{{v}}\n@catch:e\n{{e}}\n@endawait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(res state.AnyResource) string {
+		return strings.TrimSpace(RenderNodes(nodes, &RenderContext{Props: map[string]any{"res": res}}))
+	}
+
+	pending := state.NewResource[string](nil, state.WithoutImmediateLoad())
+	if out := render(pending); out != "wait" {
+		t.Fatalf("pending rendered %q", out)
+	}
+	pending.Mutate("<b>ok</b>")
+	if out := render(pending); !strings.Contains(out, "&lt;b&gt;ok&lt;/b&gt;") || strings.Contains(out, "wait") {
+		t.Fatalf("ready rendered %q", out)
+	}
+	failed := state.NewResource[string](nil)
+	if out := render(failed); !strings.Contains(out, "state: nil resource loader") {
+		t.Fatalf("error rendered %q", out)
+	}
+}
@@ -0,0 +1,274 @@
+package rtmlast
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reScopedStyle = regexp.MustCompile(`(?is)<style\b[^>]*\bscoped\b[^>]*>(.*?)</style>[ \t]*\n?`)
+	reOpenTag     = regexp.MustCompile(`^<([a-zA-Z][\w-]*)`)
+	reCSSComment  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// ScopeStyle moves the <style scoped> blocks out of a template, so their
+// rules reach the elements of that template only. Every element of the
+// returned template carries the attribute attr, named after the source, and
+// every selector of css requires it:
+//
+//	<style scoped>.title { color: red }</style>    .title[data-s-1a2b3c4d] {color: red}
+//	<h1 class="title">Hi</h1>                      <h1 data-s-1a2b3c4d class="title">Hi</h1>
+//
+// A template without a scoped style is returned as it is, with no css and
+// no attr. The build plugin and the runtime both call it, so a compiled
+// renderer matches the template the runtime holds.
+func ScopeStyle(src string) (template, css, attr string) {
+	blocks := reScopedStyle.FindAllStringSubmatch(src, -1)
+	if blocks == nil {
+		return src, "", ""
+	}
+	attr = "data-s-" + Fingerprint(src)[:8]
+	var rules strings.Builder
+	for _, m := range blocks {
+		rules.WriteString(m[1])
+		rules.WriteByte('\n')
+	}
+	return scopeTags(reScopedStyle.ReplaceAllString(src, ""), attr), scopeCSS(rules.String(), attr), attr
+}
+
+// exprCommands are the commands whose argument, running to the end of their
+// line, is an expression that may hold a "<".
+var exprCommands = []string{"@if:", "@else-if:", "@for:", "@switch:", "@case:"}
+
+// scopeTags adds attr to every opening tag of src. Attribute values,
+// interpolations, the lines of commands taking an expression and script
+// bodies are skipped, since a "<" there is not a tag.
+func scopeTags(src, attr string) string {
+	var b strings.Builder
+	lineStart := true
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		if skip := skipExpression(rest, lineStart); skip > 0 {
+			b.WriteString(rest[:skip])
+			i += skip
+			lineStart = false
+			continue
+		}
+		ch := src[i]
+		switch {
+		case ch == '\n':
+			lineStart = true
+		case ch != ' ' && ch != '\t' && ch != '\r':
+			lineStart = false
+		}
+		var loc []int
+		if ch == '<' {
+			loc = reOpenTag.FindStringSubmatchIndex(rest)
+		}
+		if loc == nil {
+			b.WriteByte(ch)
+			i++
+			continue
+		}
+		name := rest[loc[2]:loc[3]]
+		b.WriteString(rest[:loc[1]])
+		i += loc[1]
+		if i < len(src) && !strings.ContainsRune(" \t\r\n/>", rune(src[i])) {
+			continue
+		}
+		b.WriteString(" " + attr)
+		end := i + tagEnd(src[i:])
+		b.WriteString(src[i:end])
+		i = end
+		if strings.EqualFold(name, "script") {
+			body := strings.Index(strings.ToLower(src[i:]), "</script")
+			if body < 0 {
+				body = len(src) - i
+			}
+			b.WriteString(src[i : i+body])
+			i += body
+		}
+	}
+	return b.String()
+}
+
+// tagEnd returns where the attributes at the start of s end, just past the
+// '>' closing their tag, or len(s). Quoted values and interpolations, such as
+// class="@expr:a<b ? 'x' : 'y'", are skipped whole.
+func tagEnd(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i:], "}}")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 1
+		case ch == '>':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// skipExpression returns the length of the interpolation, or of the
+// expression command when s starts a line, at the start of s, or 0.
+func skipExpression(s string, lineStart bool) int {
+	if strings.HasPrefix(s, "{{") {
+		if end := strings.Index(s, "}}"); end >= 0 {
+			return end + 2
+		}
+		return len(s)
+	}
+	if !lineStart {
+		return 0
+	}
+	for _, cmd := range exprCommands {
+		if strings.HasPrefix(s, cmd) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+	return 0
+}
+
+// scopeCSS makes every rule of css require attr on the element it styles.
+// The rules inside @media, @supports, @container and @layer are scoped too;
+// other at-rules, such as @keyframes and @font-face, are kept as they are.
+func scopeCSS(css, attr string) string {
+	css = reCSSComment.ReplaceAllString(css, "")
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			if rest := strings.TrimSpace(css); rest != "" {
+				b.WriteString(rest + "\n")
+			}
+			break
+		}
+		prelude := css[:open]
+		// statements such as @import end in ';' before the next rule
+		if semi := strings.LastIndexByte(prelude, ';'); semi >= 0 {
+			b.WriteString(strings.TrimSpace(prelude[:semi+1]) + "\n")
+			prelude = prelude[semi+1:]
+		}
+		prelude = strings.TrimSpace(prelude)
+		end := matchBrace(css, open)
+		body := css[open+1 : end]
+		switch {
+		case hasAtRule(prelude, "@media", "@supports", "@container", "@layer"):
+			b.WriteString(prelude + " {\n" + scopeCSS(body, attr) + "}\n")
+		case strings.HasPrefix(prelude, "@"):
+			b.WriteString(prelude + " {" + body + "}\n")
+		default:
+			b.WriteString(scopeSelectors(prelude, attr) + " {" + strings.TrimSpace(body) + "}\n")
+		}
+		if end == len(css) {
+			break
+		}
+		css = css[end+1:]
+	}
+	return b.String()
+}
+
+func hasAtRule(prelude string, names ...string) bool {
+	for _, name := range names {
+		if prelude == name || strings.HasPrefix(prelude, name+" ") || strings.HasPrefix(prelude, name+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBrace returns the index of the '}' closing the '{' at open, or
+// len(s) when it is never closed.
+func matchBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// scopeSelectors scopes each selector of a comma separated list.
+func scopeSelectors(list, attr string) string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				depth--
+			}
+			if list[i] != ',' || depth > 0 {
+				continue
+			}
+		}
+		if sel := strings.TrimSpace(list[start:i]); sel != "" {
+			out = append(out, scopeSelector(sel, attr))
+		}
+		start = i + 1
+	}
+	return strings.Join(out, ", ")
+}
+
+// scopeSelector adds [attr] to the last compound of sel, the part naming the
+// element the rule styles, before its pseudo-classes and pseudo-elements:
+// ".list li:hover" becomes ".list li[attr]:hover".
+func scopeSelector(sel, attr string) string {
+	depth, compound := 0, 0
+	for i := 0; i < len(sel); i++ {
+		switch ch := sel[i]; {
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case depth == 0 && strings.IndexByte(" \t\n>+~", ch) >= 0:
+			compound = i + 1
+		}
+	}
+	at := len(sel)
+	depth = 0
+	for i := compound; i < len(sel); i++ {
+		switch ch := sel[i]; {
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == ':' && depth == 0:
+			at = i
+		}
+		if at < len(sel) {
+			break
+		}
+	}
+	return sel[:at] + "[" + attr + "]" + sel[at:]
+}
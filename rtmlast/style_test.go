@@ -0,0 +1,66 @@
+package rtmlast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeStyle(t *testing.T) {
+	src := "<root>\n<style scoped>\n.title { color: red }\n</style>\n<h1 class=\"title\">Hi</h1><br/><script>if (a<b) {}</script></root>"
+	tpl, css, attr := ScopeStyle(src)
+	if !strings.HasPrefix(attr, "data-s-") || len(attr) != len("data-s-")+8 {
+		t.Fatalf("attr = %q", attr)
+	}
+	want := "<root " + attr + ">\n<h1 " + attr + ` class="title">Hi</h1><br ` + attr + "/><script " + attr + ">if (a<b) {}</script></root>"
+	if tpl != want {
+		t.Fatalf("template =\n%s\nwant\n%s", tpl, want)
+	}
+	if css != ".title["+attr+"] {color: red}\n" {
+		t.Fatalf("css = %q", css)
+	}
+	if again, _, again2 := ScopeStyle(src); again != tpl || again2 != attr {
+		t.Fatal("ScopeStyle is not deterministic")
+	}
+	if same, css, attr := ScopeStyle("<root><style>p{}</style></root>"); same != "<root><style>p{}</style></root>" || css != "" || attr != "" {
+		t.Fatalf("unscoped template changed: %q %q %q", same, css, attr)
+	}
+}
+
+// A "<" in an expression is not a tag.
+func TestScopeStyleSkipsExpressions(t *testing.T) {
+	src := "<root>\n@if:count<limit\n<p title=\"{{ n<max }}\">{{ a<b }}</p>\n  @else-if:count<max\n<i>x</i>\n@endif\n</root>\n<style scoped>p { margin: 0 }</style>\n"
+	tpl, _, attr := ScopeStyle(src)
+	want := "<root " + attr + ">\n@if:count<limit\n<p " + attr + " title=\"{{ n<max }}\">{{ a<b }}</p>\n  @else-if:count<max\n<i " + attr + ">x</i>\n@endif\n</root>\n"
+	if tpl != want {
+		t.Fatalf("template =\n%s\nwant\n%s", tpl, want)
+	}
+}
+
+func TestScopeStyleSkipsAttributeValues(t *testing.T) {
+	src := "<root>\n<div class=\"@expr:a<b ? 'x' : 'y'\" title='c<d'>x</div>\n</root>\n<style scoped>div { margin: 0 }</style>\n"
+	tpl, _, attr := ScopeStyle(src)
+	want := "<root " + attr + ">\n<div " + attr + " class=\"@expr:a<b ? 'x' : 'y'\" title='c<d'>x</div>\n</root>\n"
+	if tpl != want {
+		t.Fatalf("template =\n%s\nwant\n%s", tpl, want)
+	}
+}
+
+func TestScopeCSS(t *testing.T) {
+	css := `/* note */
+@import url("base.css");
+.list li:hover, a::before , ul > li + li { margin: 0 }
+input[type="text"]:not(.wide) { width: 1px }
+@media (max-width: 600px) { .title { font-size: 1em } }
+@keyframes spin { from { rotate: 0 } to { rotate: 1turn } }`
+	want := `@import url("base.css");
+.list li[s]:hover, a[s]::before, ul > li + li[s] {margin: 0}
+input[type="text"][s]:not(.wide) {width: 1px}
+@media (max-width: 600px) {
+.title[s] {font-size: 1em}
+}
+@keyframes spin { from { rotate: 0 } to { rotate: 1turn } }
+`
+	if got := scopeCSS(css, "s"); got != want {
+		t.Fatalf("scopeCSS =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+// Package sanitize filters untrusted HTML through an allowlist policy so it
+// can be injected into the DOM, either on the host through init snapshots or
+// on the client before Element.SetHTML.
+package sanitize
+
+import (
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Policy describes which elements, attributes and URL schemes survive
+// sanitization. Everything not explicitly allowed is removed. A Policy is
+// safe for concurrent use once it is no longer being configured.
+type Policy struct {
+	elements    map[string]map[string]bool
+	globalAttrs map[string]bool
+	schemes     map[string]bool
+	nofollow    bool
+}
+
+// NewPolicy returns an empty policy that strips every tag and keeps only
+// escaped text.
+func NewPolicy() *Policy {
+	return &Policy{
+		elements:    make(map[string]map[string]bool),
+		globalAttrs: make(map[string]bool),
+		schemes:     make(map[string]bool),
+	}
+}
+
+// AllowElements permits the given tags without attributes.
+func (p *Policy) AllowElements(tags ...string) *Policy {
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if _, ok := p.elements[tag]; !ok {
+			p.elements[tag] = make(map[string]bool)
+		}
+	}
+	return p
+}
+
+// AllowAttrs permits attrs on tag, allowing the tag itself if needed.
+func (p *Policy) AllowAttrs(tag string, attrs ...string) *Policy {
+	p.AllowElements(tag)
+	set := p.elements[strings.ToLower(tag)]
+	for _, attr := range attrs {
+		set[strings.ToLower(attr)] = true
+	}
+	return p
+}
+
+// AllowGlobalAttrs permits attrs on every allowed element.
+func (p *Policy) AllowGlobalAttrs(attrs ...string) *Policy {
+	for _, attr := range attrs {
+		p.globalAttrs[strings.ToLower(attr)] = true
+	}
+	return p
+}
+
+// AllowURLSchemes permits absolute URLs with the given schemes in URL
+// attributes such as href and src. Relative URLs are always allowed.
+func (p *Policy) AllowURLSchemes(schemes ...string) *Policy {
+	for _, scheme := range schemes {
+		p.schemes[strings.ToLower(scheme)] = true
+	}
+	return p
+}
+
+// RequireNoFollow adds rel="nofollow noopener noreferrer" to every link,
+// replacing any rel value from the input.
+func (p *Policy) RequireNoFollow() *Policy {
+	p.nofollow = true
+	return p
+}
+
+// Strict allows inline text formatting only: no links, images or attributes.
+func Strict() *Policy {
+	return NewPolicy().AllowElements(
+		"b", "strong", "i", "em", "u", "s", "code", "br", "p", "span",
+		"sub", "sup", "small", "mark",
+	)
+}
+
+// UserContent allows the markup produced by rendering user-written Markdown:
+// headings, lists, quotes, code blocks, tables, links and images over http,
+// https and mailto. Links are forced to rel="nofollow noopener noreferrer".
+func UserContent() *Policy {
+	return Strict().
+		AllowElements(
+			"h1", "h2", "h3", "h4", "h5", "h6", "hr", "blockquote", "pre",
+			"ul", "ol", "li", "dl", "dt", "dd", "del", "ins", "kbd",
+			"table", "thead", "tbody", "tfoot", "tr", "caption",
+		).
+		AllowAttrs("a", "href", "title").
+		AllowAttrs("img", "src", "alt", "title", "width", "height").
+		AllowAttrs("th", "align", "colspan", "rowspan").
+		AllowAttrs("td", "align", "colspan", "rowspan").
+		AllowAttrs("ol", "start").
+		AllowAttrs("code", "class").
+		AllowAttrs("h1", "id").
+		AllowAttrs("h2", "id").
+		AllowAttrs("h3", "id").
+		AllowAttrs("h4", "id").
+		AllowAttrs("h5", "id").
+		AllowAttrs("h6", "id").
+		AllowURLSchemes("http", "https", "mailto").
+		RequireNoFollow()
+}
+
+// HTML sanitizes s with the UserContent policy.
+func HTML(s string) string {
+	return userContent.Sanitize(s)
+}
+
+var userContent = UserContent()
+
+// dropContent lists elements whose children are removed along with the tag
+// when not allowed, since their text is not meant to be displayed.
+var dropContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "textarea": true,
+	"title": true, "svg": true, "math": true, "select": true, "noembed": true,
+	"noframes": true, "xmp": true, "plaintext": true,
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true,
+	"formaction": true, "poster": true, "background": true, "longdesc": true,
+}
+
+// Sanitize returns s with every disallowed element, attribute and URL
+// removed. Text is re-escaped and unclosed allowed elements are closed.
+func (p *Policy) Sanitize(s string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		tag := strings.ToLower(tok.Data)
+		if skip > 0 {
+			switch {
+			case tt == xhtml.StartTagToken && dropContent[tag]:
+				skip++
+			case tt == xhtml.EndTagToken && dropContent[tag]:
+				skip--
+			}
+			continue
+		}
+		switch tt {
+		case xhtml.TextToken:
+			b.WriteString(html.EscapeString(tok.Data))
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if _, ok := p.elements[tag]; !ok {
+				if tt == xhtml.StartTagToken && dropContent[tag] && !voidElements[tag] {
+					skip = 1
+				}
+				continue
+			}
+			p.writeStart(&b, tag, tok.Attr)
+			if tt == xhtml.StartTagToken && !voidElements[tag] {
+				open = append(open, tag)
+			}
+		case xhtml.EndTagToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tag {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+func (p *Policy) writeStart(b *strings.Builder, tag string, attrs []xhtml.Attribute) {
+	allowed := p.elements[tag]
+	b.WriteString("<" + tag)
+	seen := make(map[string]bool, len(attrs))
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || seen[key] || !allowed[key] && !p.globalAttrs[key] {
+			continue
+		}
+		if tag == "a" && key == "rel" && p.nofollow {
+			continue
+		}
+		if urlAttrs[key] && !p.allowedURL(attr.Val) {
+			continue
+		}
+		seen[key] = true
+		b.WriteString(" " + key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	if tag == "a" && p.nofollow {
+		b.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	b.WriteString(">")
+}
+
+// allowedURL reports whether raw is relative or uses an allowed scheme.
+// Whitespace and control characters are stripped first because browsers
+// ignore them when resolving schemes such as "java\tscript:".
+func (p *Policy) allowedURL(raw string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+	end := strings.IndexAny(cleaned, "/?#")
+	if end < 0 {
+		end = len(cleaned)
+	}
+	colon := strings.IndexByte(cleaned[:end], ':')
+	if colon < 0 {
+		return true
+	}
+	return p.schemes[strings.ToLower(cleaned[:colon])]
+}
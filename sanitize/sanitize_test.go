@@ -0,0 +1,72 @@
+package sanitize
+
+import "testing"
+
+func TestUserContentKeepsFormatting(t *testing.T) {
+	in := `<h2 id="x">Title</h2><p>Some <strong>bold</strong> and <a href="https://example.com">link</a></p>`
+	want := `<h2 id="x">Title</h2><p>Some <strong>bold</strong> and <a href="https://example.com" rel="nofollow noopener noreferrer">link</a></p>`
+	if got := HTML(in); got != want {
+		t.Fatalf("unexpected output:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestDropsScriptsAndHandlers(t *testing.T) {
+	cases := map[string]string{
+		`<script>alert(1)</script>ok`:              "ok",
+		`<img src=x onerror=alert(1)>`:             `<img src="x">`,
+		`<p onclick="steal()">hi</p>`:              "<p>hi</p>",
+		`<style>body{}</style><b>x</b>`:            "<b>x</b>",
+		`<iframe src="https://evil"></iframe>safe`: "safe",
+		`<!-- c --><div>text</div>`:                "text",
+	}
+	for in, want := range cases {
+		if got := HTML(in); got != want {
+			t.Errorf("HTML(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRejectsUnsafeURLSchemes(t *testing.T) {
+	cases := []string{
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="java&#x09;script:alert(1)">x</a>`,
+		`<a href=" JaVaScRiPt:alert(1)">x</a>`,
+		`<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`,
+	}
+	want := `<a rel="nofollow noopener noreferrer">x</a>`
+	for _, in := range cases {
+		if got := HTML(in); got != want {
+			t.Errorf("HTML(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := HTML(`<a href="/docs?x=1">x</a>`); got != `<a href="/docs?x=1" rel="nofollow noopener noreferrer">x</a>` {
+		t.Fatalf("relative URL dropped: %s", got)
+	}
+}
+
+func TestEscapesTextAndClosesTags(t *testing.T) {
+	got := Strict().Sanitize(`<em>a &lt;b&gt; "q"<b>bold`)
+	want := `<em>a &lt;b&gt; &#34;q&#34;<b>bold</b></em>`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := Strict().Sanitize(`</b>x</em>`); got != "x" {
+		t.Fatalf("stray end tags kept: %q", got)
+	}
+}
+
+func TestStrictRemovesLinksAndAttributes(t *testing.T) {
+	got := Strict().Sanitize(`<a href="https://example.com">x</a><span class="c">y</span>`)
+	if got != "x<span>y</span>" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestCustomPolicy(t *testing.T) {
+	p := NewPolicy().AllowAttrs("a", "href").AllowGlobalAttrs("title").AllowURLSchemes("https")
+	got := p.Sanitize(`<a href="https://x" title="t" rel="me">x</a><a href="http://x">y</a>`)
+	want := `<a href="https://x" title="t">x</a><a>y</a>`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -108,7 +108,7 @@ func (s *SSCServer) buildMux() *http.ServeMux {
 			http.NotFound(w, r)
 			return
 		}
-		http.ServeFile(w, r, filepath.Join(root, "index.html"))
+		runtime.ServeIndex(w, r, filepath.Join(root, "index.html"))
 	})
 	return mux
 }
@@ -170,6 +170,12 @@ func wsHandler(ws *websocket.Conn, runtime *host.WSRuntime) {
 			break
 		}
 		if session == nil {
+			if !runtime.CheckCSRF(ws.Request(), msg.CSRFToken) {
+				csrfErr := host.NewActionError("csrf_rejected", "invalid csrf token")
+				runtime.AuditMessage(nil, msg, csrfErr)
+				host.SendOutbound(ws, host.Outbound{Error: csrfErr})
+				return
+			}
 			var resumed bool
 			var err error
 			session, resumed, err = runtime.OpenSession(ws.Request(), msg.ResumeToken)
@@ -192,18 +198,22 @@ func wsHandler(ws *websocket.Conn, runtime *host.WSRuntime) {
 			if errors.Is(err, host.ErrDuplicateMessage) {
 				continue
 			}
+			gapErr := host.NewActionError("sequence_gap", "client message sequence gap")
+			runtime.AuditMessage(session, msg, gapErr)
 			host.SendSessionOutbound(ws, session, host.Outbound{
 				Action: msg.Action,
 				ID:     msg.ID,
-				Error:  host.NewActionError("sequence_gap", "client message sequence gap"),
+				Error:  gapErr,
 			})
 			continue
 		}
 		if !session.AllowMessage(runtime.MessagesPerMinute()) {
+			rateErr := host.NewActionError("rate_limited", "message rate limit exceeded")
+			runtime.AuditMessage(session, msg, rateErr)
 			host.SendSessionOutbound(ws, session, host.Outbound{
 				Action: msg.Action,
 				ID:     msg.ID,
-				Error:  host.NewActionError("rate_limited", "message rate limit exceeded"),
+				Error:  rateErr,
 			})
 			continue
 		}
@@ -211,16 +221,19 @@ func wsHandler(ws *websocket.Conn, runtime *host.WSRuntime) {
 		authorizeErr := runtime.Authorize(authorizeCtx, session, msg)
 		cancelAuthorize()
 		if authorizeErr != nil {
+			forbidden := host.NewActionError("forbidden", "message forbidden")
+			runtime.AuditMessage(session, msg, forbidden)
 			host.SendSessionOutbound(ws, session, host.Outbound{
 				Component: msg.Component,
 				Action:    msg.Action,
 				ID:        msg.ID,
-				Error:     host.NewActionError("forbidden", "message forbidden"),
+				Error:     forbidden,
 			})
 			continue
 		}
 		if msg.Action != "" {
 			payload, actionErr := runtime.DispatchAction(context.Background(), session, msg)
+			runtime.AuditMessage(session, msg, actionErr)
 			host.SendSessionOutbound(ws, session, host.Outbound{
 				Action:  msg.Action,
 				ID:      msg.ID,
@@ -240,8 +253,12 @@ func wsHandler(ws *websocket.Conn, runtime *host.WSRuntime) {
 			subscribed = append(subscribed, name)
 		}
 
-		if hc, ok := host.Get(name); ok {
+		if hc, ok := host.Get(name); !ok {
+			runtime.AuditMessage(session, msg, host.NewActionError("component_not_found", "component not found"))
+		} else {
 			resp := hc.HandleWithSession(session, msg.Payload)
+			respErr, _ := resp.(*host.ActionError)
+			runtime.AuditMessage(session, msg, respErr)
 			if resp != nil {
 				switch v := resp.(type) {
 				case *host.InitSnapshot:
@@ -252,6 +269,9 @@ func wsHandler(ws *websocket.Conn, runtime *host.WSRuntime) {
 				case host.InitSnapshot:
 					host.SendSessionOutbound(ws, session, host.Outbound{Component: name, ID: msg.ID, Payload: map[string]any{"initSnapshot": v}})
 					continue
+				case *host.ActionError:
+					host.SendSessionOutbound(ws, session, host.Outbound{Component: name, ID: msg.ID, Error: v})
+					continue
 				default:
 					host.SendSessionOutbound(ws, session, host.Outbound{Component: name, ID: msg.ID, Payload: resp})
 					continue
@@ -5,11 +5,13 @@ package ssc
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -214,3 +216,130 @@ func TestSSCServerResumesAtSessionLimit(t *testing.T) {
 		host.ReleaseSession(session)
 	}
 }
+
+// With host.WithCSRF the SSC page carries the token its WebSocket requires.
+func TestSSCServerCSRF(t *testing.T) {
+	const action = "test.ssc.csrf"
+	if err := host.RegisterAction(action, func(_ context.Context, _ *host.Session, _ struct{}) (string, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("register action: %v", err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte("<html><head></head><body></body></html>"), 0o600); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	server := httptest.NewServer(NewSSCServer(":0", root, host.WithCSRF(), host.WithCSP("")).Mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("index request failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if closeErr := resp.Body.Close(); err != nil || closeErr != nil {
+		t.Fatalf("read index: %v %v", err, closeErr)
+	}
+	token := ""
+	for _, c := range resp.Cookies() {
+		if c.Name == host.CSRFCookieName {
+			token = c.Value
+		}
+	}
+	if token == "" || !strings.Contains(string(body), `content="`+token+`"`) {
+		t.Fatalf("index carries no csrf token: cookies=%v body=%s", resp.Cookies(), body)
+	}
+	if resp.Header.Get("Content-Security-Policy") == "" {
+		t.Fatal("index served without a Content-Security-Policy")
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	exchange := func(csrf string) host.Outbound {
+		config, err := websocket.NewConfig(wsURL, server.URL)
+		if err != nil {
+			t.Fatalf("websocket config: %v", err)
+		}
+		config.Header.Set("Cookie", host.CSRFCookieName+"="+token)
+		socket, err := websocket.DialConfig(config)
+		if err != nil {
+			t.Fatalf("dial websocket: %v", err)
+		}
+		defer closeTestResource(t, socket)
+		if err := websocket.JSON.Send(socket, host.Inbound{Action: action, ID: "1", Sequence: 1, CSRFToken: csrf}); err != nil {
+			t.Fatalf("send message: %v", err)
+		}
+		var out host.Outbound
+		if err := websocket.JSON.Receive(socket, &out); err != nil {
+			t.Fatalf("receive message: %v", err)
+		}
+		if out.Session != "" {
+			if session, ok := host.SessionByID(out.Session); ok {
+				host.ReleaseSession(session)
+			}
+		}
+		return out
+	}
+	if out := exchange("forged"); out.Error == nil || out.Error.Code != "csrf_rejected" {
+		t.Fatalf("forged token accepted: %#v", out)
+	}
+	if out := exchange(token); out.Error != nil || out.Payload != "ok" {
+		t.Fatalf("page token rejected: %#v", out)
+	}
+}
+
+type recordingAuditSink struct {
+	mu      sync.Mutex
+	records []host.AuditRecord
+}
+
+func (s *recordingAuditSink) Audit(record host.AuditRecord) error {
+	s.mu.Lock()
+	s.records = append(s.records, record)
+	s.mu.Unlock()
+	return nil
+}
+
+// Component messages are audited with the outcome the client receives.
+func TestSSCServerAuditsComponentOutcomes(t *testing.T) {
+	type input struct {
+		Count int `json:"count"`
+	}
+	const name = "SSCAuditedTyped"
+	host.Register(host.NewTypedComponent(name, func(_ *host.Session, in input) (input, error) {
+		return in, nil
+	}).HostComponent)
+	sink := &recordingAuditSink{}
+	server := httptest.NewServer(NewSSCServer(":0", t.TempDir(), host.WithAuditSink(sink)).Mux)
+	defer server.Close()
+	socket, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", "", server.URL)
+	if err != nil {
+		t.Fatalf("dial websocket: %v", err)
+	}
+	defer closeTestResource(t, socket)
+	exchange := func(msg host.Inbound) host.Outbound {
+		if err := websocket.JSON.Send(socket, msg); err != nil {
+			t.Fatalf("send message: %v", err)
+		}
+		var out host.Outbound
+		if err := websocket.JSON.Receive(socket, &out); err != nil {
+			t.Fatalf("receive message: %v", err)
+		}
+		return out
+	}
+	first := exchange(host.Inbound{Component: name, Sequence: 1, Payload: map[string]any{"count": 1}})
+	exchange(host.Inbound{Component: name, Sequence: 2, Payload: map[string]any{"count": "x"}})
+	exchange(host.Inbound{Component: "SSCMissing", Sequence: 3})
+	if session, ok := host.SessionByID(first.Session); ok {
+		defer host.ReleaseSession(session)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	var codes []string
+	for _, record := range sink.records {
+		codes = append(codes, record.Outcome+":"+record.ErrorCode)
+	}
+	if want := []string{"ok:", "error:invalid_request", "error:component_not_found"}; strings.Join(codes, ",") != strings.Join(want, ",") {
+		t.Fatalf("audited %v, want %v", codes, want)
+	}
+}
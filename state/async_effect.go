@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type asyncRunKey struct{}
+
+// asyncRun coordinates the tracking phase of one AsyncEffect run: the
+// triggering goroutine waits until the run reaches Await or returns, so
+// signals read up to that point are tracked as if the effect ran
+// synchronously.
+type asyncRun struct {
+	once    sync.Once
+	tracked chan struct{}
+	resumed chan struct{}
+}
+
+func (r *asyncRun) endTracking() {
+	r.once.Do(func() {
+		close(r.tracked)
+		<-r.resumed
+	})
+}
+
+// AsyncEffect runs fn whenever the signals it reads change, like Effect,
+// for work that waits on the host or the network. Signals read before the
+// first Await are dependencies; reads after it are not tracked. When a
+// dependency changes, the context of the previous run is cancelled before the
+// next run starts, and stopping the effect cancels the current run.
+//
+// The code before the first Await runs while the goroutine that triggered
+// the effect waits, so it must not block. Code after Await runs in the
+// background; like any goroutine calling Get, it should not read signals
+// while other effects are running, or those effects may track them.
+//
+// Errors and panics are passed to WithEffectErrorHandler, or logged through
+// the store logger.
+func AsyncEffect(fn func(ctx context.Context) error, opts ...EffectOption) func() {
+	var config effectConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	parent := config.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	e := &effect{priority: config.priority}
+	e.run = func() func() {
+		ctx, cancel := context.WithCancel(parent)
+		run := &asyncRun{tracked: make(chan struct{}), resumed: make(chan struct{})}
+		ctx = context.WithValue(ctx, asyncRunKey{}, run)
+		go func() {
+			err := runAsyncEffect(ctx, fn)
+			run.endTracking()
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+			if config.onError != nil {
+				config.onError(err)
+				return
+			}
+			logger.Debug("[rfw] async effect: %v", err)
+		}()
+		<-run.tracked
+		currentEffect.CompareAndSwap(e, nil)
+		close(run.resumed)
+		return cancel
+	}
+	return startEffect(e).stop
+}
+
+func runAsyncEffect(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("state: async effect panic: %v", recovered)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Await marks the end of an AsyncEffect's dependency tracking and runs work
+// with the run's context. It returns the context error when the run was
+// cancelled meanwhile, so a superseded run can stop before writing stale
+// results. Outside an AsyncEffect it simply calls work.
+func Await[T any](ctx context.Context, work func(context.Context) (T, error)) (T, error) {
+	if run, ok := ctx.Value(asyncRunKey{}).(*asyncRun); ok {
+		run.endTracking()
+	}
+	value, err := work(ctx)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return value, err
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncEffectTracksReadsBeforeAwait(t *testing.T) {
+	query := NewSignal("a")
+	later := NewSignal(0)
+	results := make(chan string, 4)
+	release := make(chan struct{})
+	stop := AsyncEffect(func(ctx context.Context) error {
+		q := query.Get()
+		value, err := Await(ctx, func(ctx context.Context) (string, error) {
+			select {
+			case <-release:
+				return q, nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		})
+		if err != nil {
+			results <- "cancelled " + q
+			return err
+		}
+		_ = later.Get()
+		results <- value
+		return nil
+	})
+	defer stop()
+
+	query.Set("b")
+	if got := <-results; got != "cancelled a" {
+		t.Fatalf("first run = %q, want it cancelled", got)
+	}
+	close(release)
+	if got := <-results; got != "b" {
+		t.Fatalf("second run = %q", got)
+	}
+	if query.SubCount() != 1 || later.SubCount() != 0 {
+		t.Fatalf("subscriptions: query %d, later %d", query.SubCount(), later.SubCount())
+	}
+}
+
+func TestAsyncEffectReportsErrors(t *testing.T) {
+	trigger := NewSignal(0)
+	errs := make(chan error, 4)
+	stop := AsyncEffect(func(ctx context.Context) error {
+		if trigger.Get() == 1 {
+			panic("bad")
+		}
+		return errors.New("failed")
+	}, WithEffectErrorHandler(func(err error) { errs <- err }))
+	defer stop()
+
+	if err := <-errs; err.Error() != "failed" {
+		t.Fatalf("error = %v", err)
+	}
+	trigger.Set(1)
+	if err := <-errs; err.Error() != "state: async effect panic: bad" {
+		t.Fatalf("panic error = %v", err)
+	}
+}
+
+func TestAsyncEffectStopCancelsRun(t *testing.T) {
+	var mu sync.Mutex
+	var reported error
+	done := make(chan error, 1)
+	stop := AsyncEffect(func(ctx context.Context) error {
+		_, err := Await(ctx, func(ctx context.Context) (struct{}, error) {
+			<-ctx.Done()
+			return struct{}{}, ctx.Err()
+		})
+		done <- err
+		return err
+	}, WithEffectErrorHandler(func(err error) {
+		mu.Lock()
+		reported = err
+		mu.Unlock()
+	}))
+	stop()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("run error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stop did not cancel the run")
+	}
+	time.Sleep(time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if reported != nil {
+		t.Fatalf("cancelled run reported %v", reported)
+	}
+}
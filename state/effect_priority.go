@@ -0,0 +1,99 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// EffectPriority decides when an effect re-runs after a dependency changes.
+// The first run of an effect is always synchronous.
+type EffectPriority int
+
+const (
+	// PrioritySync re-runs the effect on the goroutine calling Set, or when
+	// the enclosing Batch completes. It is the default.
+	PrioritySync EffectPriority = iota
+	// PriorityMicrotask re-runs the effect in a microtask, after the current
+	// event handler returns.
+	PriorityMicrotask
+	// PriorityAnimationFrame re-runs the effect before the next paint.
+	PriorityAnimationFrame
+	// PriorityIdle re-runs the effect when the browser is idle.
+	PriorityIdle
+)
+
+const priorityLevels = int(PriorityIdle) + 1
+
+type effectConfig struct {
+	priority EffectPriority
+	ctx      context.Context
+	onError  func(error)
+}
+
+// EffectOption configures an Effect or AsyncEffect.
+type EffectOption func(*effectConfig)
+
+// WithPriority schedules re-runs at priority. Re-runs requested several times
+// before the scheduled flush run once.
+func WithPriority(priority EffectPriority) EffectOption {
+	return func(config *effectConfig) { config.priority = priority }
+}
+
+// WithEffectContext derives the contexts of AsyncEffect runs from ctx, so
+// cancelling ctx cancels the running work.
+func WithEffectContext(ctx context.Context) EffectOption {
+	return func(config *effectConfig) { config.ctx = ctx }
+}
+
+// WithEffectErrorHandler receives the errors and recovered panics of
+// AsyncEffect runs. Runs cancelled by a newer run or by stopping the effect
+// are not reported.
+func WithEffectErrorHandler(fn func(error)) EffectOption {
+	return func(config *effectConfig) { config.onError = fn }
+}
+
+var priorityQueues struct {
+	sync.Mutex
+	pending   [priorityLevels]map[*effect]struct{}
+	requested [priorityLevels]bool
+}
+
+// enqueueEffect queues e for its priority's next flush, requesting that
+// flush from the platform when none is pending.
+func enqueueEffect(e *effect) {
+	level := int(e.priority)
+	priorityQueues.Lock()
+	if priorityQueues.pending[level] == nil {
+		priorityQueues.pending[level] = make(map[*effect]struct{})
+	}
+	priorityQueues.pending[level][e] = struct{}{}
+	request := !priorityQueues.requested[level]
+	priorityQueues.requested[level] = true
+	priorityQueues.Unlock()
+	if request {
+		requestEffectFlush(e.priority, func() { flushPriority(level) })
+	}
+}
+
+func flushPriority(level int) {
+	priorityQueues.Lock()
+	pending := make([]*effect, 0, len(priorityQueues.pending[level]))
+	for e := range priorityQueues.pending[level] {
+		pending = append(pending, e)
+	}
+	clear(priorityQueues.pending[level])
+	priorityQueues.requested[level] = false
+	priorityQueues.Unlock()
+	for _, e := range pending {
+		e.runEffect()
+	}
+}
+
+// FlushEffects runs every effect queued at a non-synchronous priority now,
+// most urgent first. Tests and server-side rendering use it instead of
+// waiting for the scheduler.
+func FlushEffects() {
+	for level := int(PriorityMicrotask); level < priorityLevels; level++ {
+		flushPriority(level)
+	}
+}
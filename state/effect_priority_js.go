@@ -0,0 +1,28 @@
+//go:build js && wasm
+
+package state
+
+import "github.com/rfwlab/rfw/v2/js"
+
+// requestEffectFlush schedules flush with the browser primitive matching
+// priority. requestIdleCallback falls back to a timeout where missing.
+func requestEffectFlush(priority EffectPriority, flush func()) {
+	var cb js.Func
+	cb = js.SafeFuncOf(func(js.Value, []js.Value) any {
+		cb.Release()
+		flush()
+		return nil
+	})
+	switch priority {
+	case PriorityAnimationFrame:
+		js.Call("requestAnimationFrame", cb)
+	case PriorityIdle:
+		if idle := js.Get("requestIdleCallback"); idle.Type() == js.TypeFunction {
+			js.Call("requestIdleCallback", cb)
+			return
+		}
+		js.Call("setTimeout", cb, 1)
+	default:
+		js.Call("queueMicrotask", cb)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !js || !wasm
+
+package state
+
+import "time"
+
+// Outside the browser there is no event loop: deferred effects run on a
+// timer goroutine roughly matching the browser's ordering. FlushEffects runs
+// them deterministically.
+var effectFlushDelays = [priorityLevels]time.Duration{
+	PriorityMicrotask:      0,
+	PriorityAnimationFrame: 16 * time.Millisecond,
+	PriorityIdle:           50 * time.Millisecond,
+}
+
+func requestEffectFlush(priority EffectPriority, flush func()) {
+	time.AfterFunc(effectFlushDelays[priority], flush)
+}
@@ -0,0 +1,287 @@
+package state
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"weak"
+)
+
+// NodeKind identifies the type of a reactive graph node.
+type NodeKind string
+
+const (
+	// NodeSignal is a Signal.
+	NodeSignal NodeKind = "signal"
+	// NodeMemo is the value signal of a Memo.
+	NodeMemo NodeKind = "memo"
+	// NodeEffect is an Effect, including the one computing a Memo.
+	NodeEffect NodeKind = "effect"
+	// NodeListener is a Signal.OnChange subscription.
+	NodeListener NodeKind = "listener"
+	// NodeStoreListener is a Store.OnChange listener.
+	NodeStoreListener NodeKind = "store-listener"
+)
+
+// GraphNode describes a live reactive node recorded while graph debugging
+// was enabled.
+type GraphNode struct {
+	ID    uint64
+	Kind  NodeKind
+	Label string
+	// Owner is the debug owner active at creation, normally a component ID.
+	Owner string
+	// Stack lists the creating call frames outside the state package.
+	Stack string
+	// Subscribers counts the effects and listeners of a signal.
+	Subscribers int
+}
+
+// GraphEdge points from a signal to a node that depends on it, or from a
+// memo's effect to the signal it writes.
+type GraphEdge struct {
+	From uint64
+	To   uint64
+}
+
+// Graph is a snapshot of the live reactive graph.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// Owned returns the nodes created under owner, optionally limited to kinds.
+func (g Graph) Owned(owner string, kinds ...NodeKind) []GraphNode {
+	var out []GraphNode
+	for _, node := range g.Nodes {
+		if node.Owner != owner {
+			continue
+		}
+		if len(kinds) > 0 && !containsKind(kinds, node.Kind) {
+			continue
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+func containsKind(kinds []NodeKind, kind NodeKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type graphEntry struct {
+	node   GraphNode
+	source uint64
+	writes uint64
+	// inspect reports whether the node is still reachable and, for signals,
+	// the IDs of dependent effects and the number of OnChange listeners.
+	inspect func() (alive bool, effects []uint64, listeners int)
+}
+
+var graphDebug struct {
+	enabled atomic.Bool
+	owner   atomic.Pointer[string]
+
+	mu      sync.Mutex
+	nextID  uint64
+	entries map[uint64]*graphEntry
+}
+
+// EnableGraphDebug starts or stops recording reactive nodes for DebugGraph.
+// Only nodes created while it is enabled are recorded, and recording costs a
+// stack capture per node, so enable it in development builds and tests.
+func EnableGraphDebug(enabled bool) {
+	graphDebug.mu.Lock()
+	if enabled && graphDebug.entries == nil {
+		graphDebug.entries = make(map[uint64]*graphEntry)
+	}
+	if !enabled {
+		graphDebug.entries = nil
+	}
+	graphDebug.mu.Unlock()
+	graphDebug.enabled.Store(enabled)
+}
+
+// GraphDebugEnabled reports whether reactive nodes are being recorded.
+func GraphDebugEnabled() bool { return graphDebug.enabled.Load() }
+
+// SetDebugOwner attributes nodes created until restore is called to owner.
+// Components set their ID while rendering, mounting and registering effects.
+func SetDebugOwner(owner string) (restore func()) {
+	if !graphDebug.enabled.Load() {
+		return func() {}
+	}
+	previous := graphDebug.owner.Swap(&owner)
+	return func() { graphDebug.owner.Store(previous) }
+}
+
+func registerGraphNode(kind NodeKind, label string, source uint64, inspect func() (bool, []uint64, int)) uint64 {
+	if !graphDebug.enabled.Load() {
+		return 0
+	}
+	owner := ""
+	if p := graphDebug.owner.Load(); p != nil {
+		owner = *p
+	}
+	stack := creationStack()
+	graphDebug.mu.Lock()
+	defer graphDebug.mu.Unlock()
+	if graphDebug.entries == nil {
+		return 0
+	}
+	graphDebug.nextID++
+	id := graphDebug.nextID
+	graphDebug.entries[id] = &graphEntry{
+		node:    GraphNode{ID: id, Kind: kind, Label: label, Owner: owner, Stack: stack},
+		source:  source,
+		inspect: inspect,
+	}
+	return id
+}
+
+func unregisterGraphNode(id uint64) {
+	if id == 0 {
+		return
+	}
+	graphDebug.mu.Lock()
+	delete(graphDebug.entries, id)
+	graphDebug.mu.Unlock()
+}
+
+// linkMemo labels the value signal and computing effect of a memo and adds
+// the edge from the effect to the signal.
+func linkMemo(signal, effect uint64, label string) {
+	graphDebug.mu.Lock()
+	if entry := graphDebug.entries[signal]; entry != nil {
+		entry.node.Kind = NodeMemo
+		entry.node.Label = label
+	}
+	if entry := graphDebug.entries[effect]; entry != nil {
+		entry.node.Label = label
+		entry.writes = signal
+	}
+	graphDebug.mu.Unlock()
+}
+
+func creationStack() string {
+	pcs := make([]uintptr, 24)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		internal := strings.Contains(frame.Function, "rfw/v2/state.") && !strings.HasSuffix(frame.File, "_test.go")
+		if !internal && frame.Function != "runtime.goexit" {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// DebugGraph returns the live nodes recorded since EnableGraphDebug and the
+// edges between them, ordered by creation. Signals that have been garbage
+// collected are dropped.
+func DebugGraph() Graph {
+	graphDebug.mu.Lock()
+	entries := make([]*graphEntry, 0, len(graphDebug.entries))
+	for _, entry := range graphDebug.entries {
+		entries = append(entries, entry)
+	}
+	graphDebug.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].node.ID < entries[j].node.ID })
+
+	var graph Graph
+	var dead []uint64
+	for _, entry := range entries {
+		node := entry.node
+		if entry.inspect != nil {
+			alive, effects, listeners := entry.inspect()
+			if !alive {
+				dead = append(dead, node.ID)
+				continue
+			}
+			for _, id := range effects {
+				graph.Edges = append(graph.Edges, GraphEdge{From: node.ID, To: id})
+			}
+			node.Subscribers = len(effects) + listeners
+		}
+		if entry.source != 0 {
+			graph.Edges = append(graph.Edges, GraphEdge{From: entry.source, To: node.ID})
+		}
+		if entry.writes != 0 {
+			graph.Edges = append(graph.Edges, GraphEdge{From: node.ID, To: entry.writes})
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	if len(dead) > 0 {
+		graphDebug.mu.Lock()
+		for _, id := range dead {
+			delete(graphDebug.entries, id)
+		}
+		graphDebug.mu.Unlock()
+	}
+	return graph
+}
+
+func registerSignal[T any](s *Signal[T]) {
+	if !graphDebug.enabled.Load() {
+		return
+	}
+	ref := weak.Make(s)
+	s.debugID = registerGraphNode(NodeSignal, "Signal["+reflect.TypeFor[T]().String()+"]", 0, func() (bool, []uint64, int) {
+		s := ref.Value()
+		if s == nil {
+			return false, nil, 0
+		}
+		var effects []uint64
+		s.mu.Lock()
+		for eff := range s.subs {
+			if eff.debugID != 0 {
+				effects = append(effects, eff.debugID)
+			}
+		}
+		s.mu.Unlock()
+		sort.Slice(effects, func(i, j int) bool { return effects[i] < effects[j] })
+		listeners := 0
+		s.onChangeMu.Lock()
+		for _, fn := range s.onChange {
+			if fn != nil {
+				listeners++
+			}
+		}
+		s.onChangeMu.Unlock()
+		return true, effects, listeners
+	})
+}
+
+func registerEffect(e *effect) {
+	if !graphDebug.enabled.Load() {
+		return
+	}
+	ref := weak.Make(e)
+	e.debugID = registerGraphNode(NodeEffect, "Effect", 0, func() (bool, []uint64, int) {
+		return ref.Value() != nil, nil, 0
+	})
+}
+
+func registerStoreListener(s *Store, key string) uint64 {
+	if !graphDebug.enabled.Load() {
+		return 0
+	}
+	ref := weak.Make(s)
+	return registerGraphNode(NodeStoreListener, "store "+s.module+"/"+s.name+"."+key, 0, func() (bool, []uint64, int) {
+		return ref.Value() != nil, nil, 0
+	})
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func enableGraphDebug(t *testing.T) {
+	t.Helper()
+	EnableGraphDebug(true)
+	t.Cleanup(func() { EnableGraphDebug(false) })
+}
+
+func TestDebugGraphReportsNodesAndEdges(t *testing.T) {
+	enableGraphDebug(t)
+	restore := SetDebugOwner("counter")
+	count := NewSignal(1)
+	double := Memo(func() int { return count.Get() * 2 })
+	stop := Effect(func() func() {
+		_ = double.Get()
+		return nil
+	})
+	sub := count.OnChange(func(int) {})
+	restore()
+	outside := NewSignal("free")
+
+	graph := DebugGraph()
+	owned := graph.Owned("counter")
+	kinds := map[NodeKind]int{}
+	for _, node := range owned {
+		kinds[node.Kind]++
+		if !strings.Contains(node.Stack, "TestDebugGraphReportsNodesAndEdges") {
+			t.Fatalf("%s %s has no creation stack: %q", node.Kind, node.Label, node.Stack)
+		}
+	}
+	if kinds[NodeSignal] != 1 || kinds[NodeMemo] != 1 || kinds[NodeEffect] != 2 || kinds[NodeListener] != 1 {
+		t.Fatalf("unexpected owned nodes %v", kinds)
+	}
+	var signalNode, memoNode GraphNode
+	for _, node := range owned {
+		switch node.Kind {
+		case NodeSignal:
+			signalNode = node
+		case NodeMemo:
+			memoNode = node
+		}
+	}
+	if signalNode.Label != "Signal[int]" || signalNode.Subscribers != 2 || memoNode.Subscribers != 1 {
+		t.Fatalf("unexpected subscriber counts: signal %+v memo %+v", signalNode, memoNode)
+	}
+	edges := map[uint64]int{}
+	for _, edge := range graph.Edges {
+		edges[edge.From]++
+	}
+	if edges[signalNode.ID] != 2 || edges[memoNode.ID] != 1 {
+		t.Fatalf("unexpected edges %+v", graph.Edges)
+	}
+	if nodes := graph.Owned(""); len(nodes) != 1 || nodes[0].Label != "Signal[string]" {
+		t.Fatalf("unowned nodes = %+v", nodes)
+	}
+
+	stop()
+	double.Stop()
+	sub.Stop()
+	if live := DebugGraph().Owned("counter", NodeEffect, NodeListener); len(live) != 0 {
+		t.Fatalf("stopped nodes still live: %+v", live)
+	}
+	_ = outside.Get()
+}
+
+func TestDebugGraphTracksStoreListeners(t *testing.T) {
+	enableGraphDebug(t)
+	store := NewStore("graph", WithModule("debug"))
+	restore := SetDebugOwner("panel")
+	unsubscribe := store.OnChange("open", func(any) {})
+	restore()
+	live := DebugGraph().Owned("panel", NodeStoreListener)
+	if len(live) != 1 || live[0].Label != "store debug/graph.open" {
+		t.Fatalf("store listener not recorded: %+v", live)
+	}
+	unsubscribe()
+	if live := DebugGraph().Owned("panel"); len(live) != 0 {
+		t.Fatalf("unsubscribed listener still live: %+v", live)
+	}
+}
+
+func TestGraphDebugDisabledRecordsNothing(t *testing.T) {
+	stop := Effect(func() func() { return nil })
+	defer stop()
+	if nodes := DebugGraph().Nodes; len(nodes) != 0 {
+		t.Fatalf("recorded nodes while disabled: %+v", nodes)
+	}
+}
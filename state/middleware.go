@@ -0,0 +1,107 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStoreFrozen is returned by the Freeze middleware for writes to frozen
+// keys.
+var ErrStoreFrozen = errors.New("state: store key is frozen")
+
+// WriteOrigin identifies the operation that produced a store write.
+type WriteOrigin string
+
+const (
+	// OriginSet is a Set call or a TypedStore update.
+	OriginSet WriteOrigin = "set"
+	// OriginTransaction is a write committed by Transaction.
+	OriginTransaction WriteOrigin = "transaction"
+	// OriginUndo is a write reverting a history entry.
+	OriginUndo WriteOrigin = "undo"
+	// OriginRedo is a write reapplying an undone history entry.
+	OriginRedo WriteOrigin = "redo"
+	// OriginRemote is a write received through tab sync.
+	OriginRemote WriteOrigin = "remote"
+)
+
+// StoreWrite is a write offered to the middleware chain before it lands.
+// Middleware may replace Value before calling next.
+type StoreWrite struct {
+	Module   string
+	Store    string
+	Key      string
+	Previous any
+	Value    any
+	Origin   WriteOrigin
+}
+
+// WriteHandler accepts a write by returning nil or rejects it with an error.
+type WriteHandler func(*StoreWrite) error
+
+// StoreMiddleware wraps the next handler of a store's write chain. The first
+// middleware sees a write first. A rejection by any of them, or of any write
+// in a transaction, keeps the whole commit from landing.
+type StoreMiddleware func(next WriteHandler) WriteHandler
+
+func chainMiddleware(middleware []StoreMiddleware) WriteHandler {
+	if len(middleware) == 0 {
+		return nil
+	}
+	handler := WriteHandler(func(*StoreWrite) error { return nil })
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// Use appends middleware to the write chain of a running store.
+func (s *Store) Use(middleware ...StoreMiddleware) {
+	s.mu.Lock()
+	s.middleware = append(s.middleware, middleware...)
+	s.handler = chainMiddleware(s.middleware)
+	s.mu.Unlock()
+}
+
+// Validate rejects writes to key for which check returns an error.
+func Validate(key string, check func(value any) error) StoreMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(w *StoreWrite) error {
+			if w.Key == key {
+				if err := check(w.Value); err != nil {
+					return fmt.Errorf("state: invalid %s: %w", key, err)
+				}
+			}
+			return next(w)
+		}
+	}
+}
+
+// Freeze rejects writes to keys with ErrStoreFrozen, or to every key when
+// none are given. Writes received from other tabs are frozen as well.
+func Freeze(keys ...string) StoreMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(w *StoreWrite) error {
+			if len(keys) == 0 || contains(keys, w.Key) {
+				return fmt.Errorf("%w: %s/%s.%s", ErrStoreFrozen, w.Module, w.Store, w.Key)
+			}
+			return next(w)
+		}
+	}
+}
+
+// Logging reports every write and whether the rest of the chain accepted it.
+// WithDevTools installs it first in the chain.
+func Logging(l Logger) StoreMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(w *StoreWrite) error {
+			err := next(w)
+			if err != nil {
+				l.Debug("[rfw] store %s/%s %s %s: %v -> %v rejected: %v", w.Module, w.Store, w.Origin, w.Key, w.Previous, w.Value, err)
+				return err
+			}
+			l.Debug("[rfw] store %s/%s %s %s: %v -> %v", w.Module, w.Store, w.Origin, w.Key, w.Previous, w.Value)
+			return nil
+		}
+	}
+}
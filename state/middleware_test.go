@@ -0,0 +1,89 @@
+package state
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareRejectsWholeTransaction(t *testing.T) {
+	captureLogger(t)
+	s := NewStoreManager().NewStore("validated", WithMiddleware(Validate("age", func(v any) error {
+		if age, ok := v.(int); !ok || age < 0 {
+			return errors.New("must be a non-negative int")
+		}
+		return nil
+	})))
+	err := s.Transaction(func(tx *Tx) error {
+		tx.Set("name", "Ada")
+		tx.Set("age", -1)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid age") {
+		t.Fatalf("error = %v", err)
+	}
+	if s.Get("name") != nil {
+		t.Fatal("accepted write of a rejected transaction landed")
+	}
+	s.Set("age", -2)
+	if s.Get("age") != nil {
+		t.Fatal("rejected Set landed")
+	}
+}
+
+func TestMiddlewareOrderAndTransform(t *testing.T) {
+	var order []string
+	trace := func(name string) StoreMiddleware {
+		return func(next WriteHandler) WriteHandler {
+			return func(w *StoreWrite) error {
+				order = append(order, name)
+				return next(w)
+			}
+		}
+	}
+	upper := func(next WriteHandler) WriteHandler {
+		return func(w *StoreWrite) error {
+			if s, ok := w.Value.(string); ok {
+				w.Value = strings.ToUpper(s)
+			}
+			return next(w)
+		}
+	}
+	s := NewStoreManager().NewStore("ordered", WithMiddleware(trace("a"), upper))
+	s.Use(trace("b"))
+	s.Set("name", "ada")
+	if s.Get("name") != "ADA" || strings.Join(order, ",") != "a,b" {
+		t.Fatalf("value %v, order %v", s.Get("name"), order)
+	}
+}
+
+func TestFreezeBlocksUndo(t *testing.T) {
+	captureLogger(t)
+	s := NewStoreManager().NewStore("frozen", WithHistory(5))
+	s.Set("plan", "free")
+	s.Use(Freeze("plan"))
+	s.Set("plan", "pro")
+	s.Undo()
+	if s.Get("plan") != "free" {
+		t.Fatalf("frozen key changed to %v", s.Get("plan"))
+	}
+	err := s.Transaction(func(tx *Tx) error {
+		tx.Set("plan", "pro")
+		return nil
+	})
+	if !errors.Is(err, ErrStoreFrozen) {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestLoggingReportsRejections(t *testing.T) {
+	captureLogger(t)
+	log := &recordingLogger{}
+	s := NewStoreManager().NewStore("logged", WithMiddleware(Logging(log), Freeze("locked")))
+	s.Set("open", true)
+	s.Set("locked", true)
+	got := strings.Join(log.lines, "\n")
+	if !strings.Contains(got, "set open: <nil> -> true") || !strings.Contains(got, "set locked: <nil> -> true rejected") {
+		t.Fatalf("unexpected log:\n%s", got)
+	}
+}
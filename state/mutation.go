@@ -0,0 +1,356 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrMutationDropped is returned by a MutationDrop mutation started while
+// another run is pending.
+var ErrMutationDropped = errors.New("state: mutation dropped")
+
+// MutationStatus describes the latest run of a Mutation.
+type MutationStatus string
+
+const (
+	// MutationIdle indicates that the mutation has not run since creation or
+	// Reset.
+	MutationIdle MutationStatus = "idle"
+	// MutationPending indicates that a run is in progress.
+	MutationPending MutationStatus = "pending"
+	// MutationSuccess indicates that the latest run succeeded.
+	MutationSuccess MutationStatus = "success"
+	// MutationError indicates that the latest run failed.
+	MutationError MutationStatus = "error"
+)
+
+// MutationPolicy decides what happens when a mutation starts while another
+// run is pending.
+type MutationPolicy int
+
+const (
+	// MutationQueue runs mutations one after another in call order.
+	MutationQueue MutationPolicy = iota
+	// MutationDrop rejects the new run with ErrMutationDropped.
+	MutationDrop
+	// MutationReplace cancels the pending run, rolls back its optimistic
+	// updates and starts the new one.
+	MutationReplace
+)
+
+// MutationScope records the optimistic cache updates of one run so they can
+// be rolled back, and the resource keys to invalidate once it settles.
+type MutationScope struct {
+	mu         sync.Mutex
+	saved      []savedResourceData
+	rolledBack bool
+	invalidate []string
+}
+
+type savedResourceData struct {
+	key     string
+	value   any
+	present bool
+}
+
+// Set replaces the cached value for key as SetResourceData does, remembering
+// the previous value for rollback.
+func (s *MutationScope) Set(key string, value any) {
+	s.mu.Lock()
+	if s.rolledBack {
+		s.mu.Unlock()
+		return
+	}
+	s.saveLocked(key)
+	s.mu.Unlock()
+	SetResourceData(key, value)
+}
+
+func (s *MutationScope) saveLocked(key string) {
+	for _, saved := range s.saved {
+		if saved.key == key {
+			return
+		}
+	}
+	resourceShared.Lock()
+	entry, present := resourceShared.cache[key]
+	resourceShared.Unlock()
+	s.saved = append(s.saved, savedResourceData{key: key, value: entry.value, present: present})
+}
+
+// Patch optimistically updates the cached value of type T for key, passing
+// the zero value when nothing is cached.
+func Patch[T any](scope *MutationScope, key string, update func(T) T) {
+	current, _ := ResourceData[T](key)
+	scope.Set(key, update(current))
+}
+
+// Invalidate reloads resources matching patterns after the run settles,
+// whether it succeeded or failed.
+func (s *MutationScope) Invalidate(patterns ...string) {
+	s.mu.Lock()
+	s.invalidate = append(s.invalidate, patterns...)
+	s.mu.Unlock()
+}
+
+// Rollback restores every value changed through the scope. Keys that were
+// not cached before are cleared and their open resources reloaded. Rollback
+// runs automatically when a run fails and is idempotent.
+func (s *MutationScope) Rollback() {
+	s.mu.Lock()
+	if s.rolledBack {
+		s.mu.Unlock()
+		return
+	}
+	s.rolledBack = true
+	saved := s.saved
+	s.mu.Unlock()
+	for i := len(saved) - 1; i >= 0; i-- {
+		if saved[i].present {
+			SetResourceData(saved[i].key, saved[i].value)
+			continue
+		}
+		InvalidateResources(saved[i].key)
+	}
+}
+
+// Mutation runs an asynchronous write with optimistic updates of the keyed
+// resource cache, rollback on failure and invalidation of related keys.
+type Mutation[Args, Result any] struct {
+	run    func(context.Context, Args) (Result, error)
+	policy MutationPolicy
+
+	onMutate   func(*MutationScope, Args) error
+	onSuccess  func(*MutationScope, Args, Result)
+	onError    func(*MutationScope, Args, error)
+	onSettled  func(*MutationScope, Args, Result, error)
+	invalidate []string
+
+	mu         sync.Mutex
+	pending    bool
+	queue      []*queuedMutation
+	current    *mutationRun
+	generation uint64
+
+	status *Signal[MutationStatus]
+	err    *Signal[error]
+	data   *Signal[Result]
+}
+
+type mutationRun struct {
+	cancel context.CancelFunc
+	scope  *MutationScope
+}
+
+type queuedMutation struct {
+	run  *mutationRun
+	turn chan struct{}
+}
+
+// NewMutation creates a mutation that calls run. Configure it with the On*
+// hooks, WithPolicy and Invalidates before the first run.
+func NewMutation[Args, Result any](run func(context.Context, Args) (Result, error)) *Mutation[Args, Result] {
+	return &Mutation[Args, Result]{
+		run:    run,
+		status: NewSignal(MutationIdle),
+		err:    NewSignal[error](nil),
+		data:   NewSignal(*new(Result)),
+	}
+}
+
+// OnMutate runs before the write to apply optimistic updates through the
+// scope. Returning an error aborts the run.
+func (m *Mutation[Args, Result]) OnMutate(fn func(*MutationScope, Args) error) *Mutation[Args, Result] {
+	m.onMutate = fn
+	return m
+}
+
+// OnSuccess runs after a successful write.
+func (m *Mutation[Args, Result]) OnSuccess(fn func(*MutationScope, Args, Result)) *Mutation[Args, Result] {
+	m.onSuccess = fn
+	return m
+}
+
+// OnError runs after a failed write, once the scope has been rolled back.
+func (m *Mutation[Args, Result]) OnError(fn func(*MutationScope, Args, error)) *Mutation[Args, Result] {
+	m.onError = fn
+	return m
+}
+
+// OnSettled runs after OnSuccess or OnError.
+func (m *Mutation[Args, Result]) OnSettled(fn func(*MutationScope, Args, Result, error)) *Mutation[Args, Result] {
+	m.onSettled = fn
+	return m
+}
+
+// WithPolicy sets the concurrency policy. The default is MutationQueue.
+func (m *Mutation[Args, Result]) WithPolicy(policy MutationPolicy) *Mutation[Args, Result] {
+	m.policy = policy
+	return m
+}
+
+// Invalidates reloads resources matching patterns after every run.
+func (m *Mutation[Args, Result]) Invalidates(patterns ...string) *Mutation[Args, Result] {
+	m.invalidate = append(m.invalidate, patterns...)
+	return m
+}
+
+// Mutate starts a run in the background, for use from event handlers.
+func (m *Mutation[Args, Result]) Mutate(args Args) {
+	go func() { _, _ = m.Run(context.Background(), args) }()
+}
+
+// Run performs the mutation and waits for it to settle.
+func (m *Mutation[Args, Result]) Run(ctx context.Context, args Args) (Result, error) {
+	var zero Result
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := &mutationRun{cancel: cancel, scope: &MutationScope{}}
+	if err := m.acquire(runCtx, run); err != nil {
+		return zero, err
+	}
+	defer m.release(run)
+
+	m.mu.Lock()
+	m.generation++
+	generation := m.generation
+	m.mu.Unlock()
+	Batch(func() {
+		m.err.Set(nil)
+		m.status.Set(MutationPending)
+	})
+
+	scope := run.scope
+	var result Result
+	var err error
+	if m.onMutate != nil {
+		err = m.onMutate(scope, args)
+	}
+	if err == nil {
+		result, err = runResourceLoader(runCtx, func(ctx context.Context) (Result, error) {
+			return m.run(ctx, args)
+		})
+	}
+	if err != nil {
+		scope.Rollback()
+		if m.onError != nil {
+			m.onError(scope, args, err)
+		}
+	} else if m.onSuccess != nil {
+		m.onSuccess(scope, args, result)
+	}
+	if m.onSettled != nil {
+		m.onSettled(scope, args, result, err)
+	}
+	for _, pattern := range append(append([]string(nil), m.invalidate...), scope.invalidate...) {
+		InvalidateResources(pattern)
+	}
+
+	m.mu.Lock()
+	latest := generation == m.generation
+	m.mu.Unlock()
+	if latest {
+		Batch(func() {
+			if err != nil {
+				m.err.Set(err)
+				m.status.Set(MutationError)
+				return
+			}
+			m.data.Set(result)
+			m.status.Set(MutationSuccess)
+		})
+	}
+	return result, err
+}
+
+// acquire admits run according to the policy.
+func (m *Mutation[Args, Result]) acquire(ctx context.Context, run *mutationRun) error {
+	m.mu.Lock()
+	if !m.pending {
+		m.pending = true
+		m.current = run
+		m.mu.Unlock()
+		return nil
+	}
+	switch m.policy {
+	case MutationDrop:
+		m.mu.Unlock()
+		return ErrMutationDropped
+	case MutationReplace:
+		previous := m.current
+		m.current = run
+		m.mu.Unlock()
+		previous.cancel()
+		previous.scope.Rollback()
+		return nil
+	}
+	queued := &queuedMutation{run: run, turn: make(chan struct{})}
+	m.queue = append(m.queue, queued)
+	m.mu.Unlock()
+	select {
+	case <-queued.turn:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, waiting := range m.queue {
+			if waiting == queued {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// Admitted concurrently with cancellation: pass the turn on.
+		m.current = nil
+		m.handOffLocked()
+		return ctx.Err()
+	}
+}
+
+// release ends run's turn, admitting the next queued run.
+func (m *Mutation[Args, Result]) release(run *mutationRun) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != run {
+		// Replaced: the replacing run owns the turn.
+		return
+	}
+	m.current = nil
+	m.handOffLocked()
+}
+
+// handOffLocked admits the next queued run or marks the mutation idle.
+// Callers must hold m.mu.
+func (m *Mutation[Args, Result]) handOffLocked() {
+	if len(m.queue) == 0 {
+		m.pending = false
+		return
+	}
+	next := m.queue[0]
+	m.queue = m.queue[1:]
+	m.current = next.run
+	close(next.turn)
+}
+
+// Status returns the status of the latest run.
+func (m *Mutation[Args, Result]) Status() MutationStatus { return m.status.Get() }
+
+// Error returns the error of the latest run.
+func (m *Mutation[Args, Result]) Error() error { return m.err.Get() }
+
+// Data returns the result of the latest successful run.
+func (m *Mutation[Args, Result]) Data() Result { return m.data.Get() }
+
+// Pending reports whether a run is in progress.
+func (m *Mutation[Args, Result]) Pending() bool { return m.Status() == MutationPending }
+
+// Reset returns the status to idle and clears the error.
+func (m *Mutation[Args, Result]) Reset() {
+	Batch(func() {
+		m.err.Set(nil)
+		m.status.Set(MutationIdle)
+	})
+}
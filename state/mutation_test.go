@@ -0,0 +1,128 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMutationOptimisticUpdateRollsBack(t *testing.T) {
+	const key = "mutation-todos"
+	ClearResourceCache(key)
+	t.Cleanup(func() { ClearResourceCache(key) })
+	todos := NewResource(func(context.Context) ([]string, error) { return []string{"a"}, nil }, WithResourceKey(key))
+	defer todos.Close()
+	waitResourceStatus(t, todos, ResourceReady)
+
+	release := make(chan struct{})
+	var failed atomic.Bool
+	add := NewMutation(func(_ context.Context, title string) (string, error) {
+		<-release
+		return "", errors.New("rejected")
+	}).OnMutate(func(scope *MutationScope, title string) error {
+		Patch(scope, key, func(list []string) []string { return append(append([]string(nil), list...), title) })
+		return nil
+	}).OnError(func(*MutationScope, string, error) { failed.Store(true) })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := add.Run(context.Background(), "b")
+		done <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for len(todos.Value()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("optimistic update not applied: %v", todos.Value())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if err := <-done; err == nil || !failed.Load() {
+		t.Fatalf("expected failure, got %v", err)
+	}
+	if value := todos.Value(); len(value) != 1 || value[0] != "a" {
+		t.Fatalf("not rolled back: %v", value)
+	}
+	if add.Status() != MutationError || add.Error() == nil {
+		t.Fatalf("unexpected status %q", add.Status())
+	}
+}
+
+func TestMutationInvalidatesOnSettle(t *testing.T) {
+	const key = "mutation-count/1"
+	ClearResourceCache(key)
+	t.Cleanup(func() { ClearResourceCache(key) })
+	var loads atomic.Int32
+	count := NewResource(func(context.Context) (int32, error) { return loads.Add(1), nil }, WithResourceKey(key))
+	defer count.Close()
+	waitResourceStatus(t, count, ResourceReady)
+
+	inc := NewMutation(func(context.Context, int) (int, error) { return 1, nil }).Invalidates("mutation-count/*")
+	if result, err := inc.Run(context.Background(), 1); err != nil || result != 1 {
+		t.Fatalf("Run() = %d, %v", result, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for count.Value() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("resource not reloaded: %d", count.Value())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if inc.Status() != MutationSuccess || inc.Data() != 1 {
+		t.Fatalf("unexpected status %q, data %d", inc.Status(), inc.Data())
+	}
+}
+
+func TestMutationPolicies(t *testing.T) {
+	start := make(chan struct{})
+	release := make(chan struct{})
+	blocking := func(ctx context.Context, n int) (int, error) {
+		start <- struct{}{}
+		select {
+		case <-release:
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	drop := NewMutation(blocking).WithPolicy(MutationDrop)
+	first := make(chan error, 1)
+	go func() { _, err := drop.Run(context.Background(), 1); first <- err }()
+	<-start
+	if _, err := drop.Run(context.Background(), 2); !errors.Is(err, ErrMutationDropped) {
+		t.Fatalf("expected drop, got %v", err)
+	}
+	release <- struct{}{}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+
+	replace := NewMutation(blocking).WithPolicy(MutationReplace)
+	go func() { _, err := replace.Run(context.Background(), 1); first <- err }()
+	<-start
+	second := make(chan int, 1)
+	go func() { n, _ := replace.Run(context.Background(), 2); second <- n }()
+	<-start
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("replaced run not cancelled: %v", err)
+	}
+	release <- struct{}{}
+	if n := <-second; n != 2 || replace.Data() != 2 {
+		t.Fatalf("replacement result %d, data %d", n, replace.Data())
+	}
+
+	queue := NewMutation(blocking)
+	results := make(chan int, 2)
+	go func() { n, _ := queue.Run(context.Background(), 1); results <- n }()
+	<-start
+	go func() { n, _ := queue.Run(context.Background(), 2); results <- n }()
+	release <- struct{}{}
+	<-start
+	release <- struct{}{}
+	if a, b := <-results, <-results; a != 1 || b != 2 {
+		t.Fatalf("queued runs out of order: %d, %d", a, b)
+	}
+}
@@ -0,0 +1,288 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Page is one page returned by a PageLoader: its items, the cursor of the
+// following page and whether that page exists.
+type Page[T, Cursor any] struct {
+	Items   []T
+	Next    Cursor
+	HasMore bool
+}
+
+// PageLoader loads the page at cursor.
+type PageLoader[T, Cursor any] func(ctx context.Context, cursor Cursor) (Page[T, Cursor], error)
+
+// PageStatus describes one requested page.
+type PageStatus struct {
+	Status ResourceStatus
+	Items  int
+	Err    error
+}
+
+type pagedCacheEntry[T, Cursor any] struct {
+	items  []T
+	counts []int
+	next   Cursor
+	more   bool
+}
+
+// PagedResource accumulates cursor-paginated items in reactive signals. It
+// accepts the Resource options: WithResourceKey caches the loaded pages and
+// shares in-flight page loads between resources with the same key,
+// WithResourceTTL expires that cache, and WithoutImmediateLoad defers the
+// first page until LoadMore.
+type PagedResource[T, Cursor any] struct {
+	mu         sync.Mutex
+	loader     PageLoader[T, Cursor]
+	first      Cursor
+	key        string
+	ttl        time.Duration
+	loaded     []T
+	counts     []int
+	next       Cursor
+	more       bool
+	loading    bool
+	generation uint64
+	cancel     context.CancelFunc
+	closed     bool
+
+	items   *Signal[[]T]
+	hasMore *Signal[bool]
+	status  *Signal[ResourceStatus]
+	err     *Signal[error]
+	pages   *Signal[[]PageStatus]
+}
+
+// NewPagedResource creates a paged resource starting at first. Unless a
+// keyed cache entry exists or WithoutImmediateLoad is given, it loads the
+// first page immediately.
+func NewPagedResource[T, Cursor any](first Cursor, loader PageLoader[T, Cursor], opts ...ResourceOption) *PagedResource[T, Cursor] {
+	config := resourceConfig{immediate: true}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	r := &PagedResource[T, Cursor]{
+		loader:  loader,
+		first:   first,
+		key:     config.key,
+		ttl:     config.ttl,
+		next:    first,
+		more:    true,
+		items:   NewSignal[[]T](nil),
+		hasMore: NewSignal(true),
+		status:  NewSignal(ResourceIdle),
+		err:     NewSignal[error](nil),
+		pages:   NewSignal[[]PageStatus](nil),
+	}
+	registerKeyedResource(r.key, r)
+	if cached, ok := loadResourceCache[pagedCacheEntry[T, Cursor]](r.key); ok {
+		r.loaded, r.counts, r.next, r.more = cached.items, cached.counts, cached.next, cached.more
+		Batch(func() {
+			r.items.Set(append([]T(nil), r.loaded...))
+			r.hasMore.Set(r.more)
+			r.status.Set(ResourceReady)
+			r.pages.Set(r.statusesLocked(nil))
+		})
+		return r
+	}
+	if config.immediate {
+		r.LoadMore(context.Background())
+	}
+	return r
+}
+
+// statusesLocked lists the loaded pages followed by pending, if non-nil.
+// Callers must hold r.mu or own r exclusively.
+func (r *PagedResource[T, Cursor]) statusesLocked(pending *PageStatus) []PageStatus {
+	statuses := make([]PageStatus, 0, len(r.counts)+1)
+	for _, count := range r.counts {
+		statuses = append(statuses, PageStatus{Status: ResourceReady, Items: count})
+	}
+	if pending != nil {
+		statuses = append(statuses, *pending)
+	}
+	return statuses
+}
+
+func (r *PagedResource[T, Cursor]) flightKey(index int) string {
+	if r.key == "" {
+		return ""
+	}
+	return r.key + "#page" + strconv.Itoa(index)
+}
+
+// LoadMore loads the next page. It does nothing while a page is loading or
+// when there are no more pages; after a failure it retries the failed page.
+func (r *PagedResource[T, Cursor]) LoadMore(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r.mu.Lock()
+	if r.closed || r.loading || !r.more {
+		r.mu.Unlock()
+		return
+	}
+	r.loading = true
+	generation := r.generation
+	index := len(r.counts)
+	cursor := r.next
+	loader := r.loader
+	loadCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	statuses := r.statusesLocked(&PageStatus{Status: ResourceLoading})
+	flightKey := r.flightKey(index)
+	r.mu.Unlock()
+
+	Batch(func() {
+		r.err.Set(nil)
+		r.status.Set(ResourceLoading)
+		r.pages.Set(statuses)
+	})
+	if loader == nil {
+		r.finishPage(generation, index, Page[T, Cursor]{}, errors.New("state: nil page loader"))
+		return
+	}
+	flight := acquireResourceFlight(loadCtx, flightKey, func(ctx context.Context) (Page[T, Cursor], error) {
+		return loader(ctx, cursor)
+	})
+	go func() {
+		page, err := waitResourceFlight[Page[T, Cursor]](loadCtx, flight)
+		r.finishPage(generation, index, page, err)
+	}()
+}
+
+func (r *PagedResource[T, Cursor]) finishPage(generation uint64, index int, page Page[T, Cursor], err error) {
+	r.mu.Lock()
+	if r.closed || generation != r.generation || index != len(r.counts) {
+		r.mu.Unlock()
+		return
+	}
+	r.loading = false
+	r.cancel = nil
+	if err != nil {
+		statuses := r.statusesLocked(&PageStatus{Status: ResourceError, Err: err})
+		r.mu.Unlock()
+		Batch(func() {
+			r.err.Set(err)
+			r.status.Set(ResourceError)
+			r.pages.Set(statuses)
+		})
+		return
+	}
+	r.loaded = append(r.loaded, page.Items...)
+	r.counts = append(r.counts, len(page.Items))
+	r.next, r.more = page.Next, page.HasMore
+	items := append([]T(nil), r.loaded...)
+	statuses := r.statusesLocked(nil)
+	more := r.more
+	if r.key != "" {
+		entry := resourceCacheEntry{value: pagedCacheEntry[T, Cursor]{
+			items:  items,
+			counts: append([]int(nil), r.counts...),
+			next:   r.next,
+			more:   more,
+		}}
+		if r.ttl > 0 {
+			entry.expires = time.Now().Add(r.ttl)
+		}
+		resourceShared.Lock()
+		resourceShared.cache[r.key] = entry
+		resourceShared.Unlock()
+	}
+	r.mu.Unlock()
+
+	Batch(func() {
+		r.items.Set(items)
+		r.hasMore.Set(more)
+		r.err.Set(nil)
+		r.status.Set(ResourceReady)
+		r.pages.Set(statuses)
+	})
+}
+
+// Refresh discards the loaded pages and the keyed cache and loads the first
+// page again.
+func (r *PagedResource[T, Cursor]) Refresh(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.generation++
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.loaded, r.counts = nil, nil
+	r.next, r.more = r.first, true
+	r.loading = false
+	key := r.key
+	r.mu.Unlock()
+	ClearResourceCache(key)
+	Batch(func() {
+		r.items.Set(nil)
+		r.hasMore.Set(true)
+		r.pages.Set(nil)
+	})
+	r.LoadMore(ctx)
+}
+
+// applyShared ignores SetResourceData: page caches are only replaced by
+// loading.
+func (r *PagedResource[T, Cursor]) applyShared(any) {}
+
+func (r *PagedResource[T, Cursor]) reloadShared() { r.Refresh(context.Background()) }
+
+// Items returns every item loaded so far.
+func (r *PagedResource[T, Cursor]) Items() []T { return r.items.Get() }
+
+// ItemsSignal returns the reactive item list.
+func (r *PagedResource[T, Cursor]) ItemsSignal() *Signal[[]T] { return r.items }
+
+// HasMore reports whether another page can be loaded.
+func (r *PagedResource[T, Cursor]) HasMore() bool { return r.hasMore.Get() }
+
+// Status returns the status of the most recent page request.
+func (r *PagedResource[T, Cursor]) Status() ResourceStatus { return r.status.Get() }
+
+// Error returns the error of the most recent page request.
+func (r *PagedResource[T, Cursor]) Error() error { return r.err.Get() }
+
+// Loading reports whether a page is loading.
+func (r *PagedResource[T, Cursor]) Loading() bool { return r.Status() == ResourceLoading }
+
+// Pages returns the status of every requested page in order.
+func (r *PagedResource[T, Cursor]) Pages() []PageStatus { return r.pages.Get() }
+
+// Close cancels pending work and prevents future loads.
+func (r *PagedResource[T, Cursor]) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.generation++
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.mu.Unlock()
+	unregisterKeyedResource(r.key, r)
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func intPages(calls *atomic.Int32, release chan struct{}) PageLoader[int, int] {
+	return func(_ context.Context, cursor int) (Page[int, int], error) {
+		calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		return Page[int, int]{Items: []int{cursor * 10, cursor*10 + 1}, Next: cursor + 1, HasMore: cursor < 2}, nil
+	}
+}
+
+func waitPaged[T, C any](t *testing.T, r *PagedResource[T, C], status ResourceStatus) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for r.Status() != status {
+		if time.Now().After(deadline) {
+			t.Fatalf("paged status = %q, want %q", r.Status(), status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPagedResourceLoadsUntilExhausted(t *testing.T) {
+	var calls atomic.Int32
+	r := NewPagedResource(0, intPages(&calls, nil))
+	defer r.Close()
+	for want := 2; want <= 6; want += 2 {
+		deadline := time.Now().Add(time.Second)
+		for len(r.Items()) != want || r.Loading() {
+			if time.Now().After(deadline) {
+				t.Fatalf("items = %v, want %d", r.Items(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		r.LoadMore(context.Background())
+	}
+	items := r.Items()
+	if len(items) != 6 || items[5] != 21 || calls.Load() != 3 {
+		t.Fatalf("items = %v, calls = %d", items, calls.Load())
+	}
+	if pages := r.Pages(); len(pages) != 3 || pages[2].Status != ResourceReady || pages[2].Items != 2 {
+		t.Fatalf("unexpected page statuses %+v", pages)
+	}
+	if r.HasMore() || calls.Load() != 3 {
+		t.Fatal("loaded past the last page")
+	}
+}
+
+func TestPagedResourceDeduplicatesLoadMore(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	r := NewPagedResource(0, intPages(&calls, release), WithoutImmediateLoad())
+	defer r.Close()
+	r.LoadMore(context.Background())
+	r.LoadMore(context.Background())
+	close(release)
+	waitPaged(t, r, ResourceReady)
+	if calls.Load() != 1 || len(r.Items()) != 2 {
+		t.Fatalf("calls = %d, items = %v", calls.Load(), r.Items())
+	}
+}
+
+func TestPagedResourceRetriesFailedPage(t *testing.T) {
+	fail := true
+	r := NewPagedResource(0, func(_ context.Context, cursor int) (Page[string, int], error) {
+		if cursor == 1 && fail {
+			fail = false
+			return Page[string, int]{}, errors.New("boom")
+		}
+		return Page[string, int]{Items: []string{"x"}, Next: cursor + 1, HasMore: true}, nil
+	})
+	defer r.Close()
+	waitPaged(t, r, ResourceReady)
+	r.LoadMore(context.Background())
+	waitPaged(t, r, ResourceError)
+	if pages := r.Pages(); len(pages) != 2 || pages[1].Status != ResourceError {
+		t.Fatalf("unexpected page statuses %+v", pages)
+	}
+	r.LoadMore(context.Background())
+	waitPaged(t, r, ResourceReady)
+	if len(r.Items()) != 2 {
+		t.Fatalf("failed page not retried: %v", r.Items())
+	}
+}
+
+func TestKeyedPagedResourceUsesCache(t *testing.T) {
+	const key = "paged-cache"
+	ClearResourceCache(key)
+	t.Cleanup(func() { ClearResourceCache(key) })
+	var calls atomic.Int32
+	first := NewPagedResource(0, intPages(&calls, nil), WithResourceKey(key))
+	defer first.Close()
+	waitPaged(t, first, ResourceReady)
+
+	second := NewPagedResource(0, intPages(&calls, nil), WithResourceKey(key))
+	defer second.Close()
+	if second.Status() != ResourceReady || len(second.Items()) != 2 || calls.Load() != 1 {
+		t.Fatalf("cache not used: status %q, items %v, calls %d", second.Status(), second.Items(), calls.Load())
+	}
+	second.Refresh(context.Background())
+	waitPaged(t, second, ResourceReady)
+	if calls.Load() != 2 {
+		t.Fatalf("refresh did not reload: calls %d", calls.Load())
+	}
+}
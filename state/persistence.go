@@ -0,0 +1,261 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PersistenceBackend stores serialized store state. Load reports the data
+// saved under key, or nil when there is none, by calling done; synchronous
+// backends call it before returning and asynchronous ones (IndexedDB) call it
+// later, in which case the loaded values are applied like ordinary updates.
+// Until then the store saves nothing, and keys set meanwhile are not
+// overwritten by the loaded values.
+type PersistenceBackend interface {
+	Load(key string, done func(data []byte, err error))
+	Save(key string, data []byte) error
+}
+
+// Migration upgrades persisted state from one schema version to the next.
+type Migration func(state map[string]any) (map[string]any, error)
+
+// PersistOption configures store persistence.
+type PersistOption func(*persistence)
+
+// WithSchemaVersion sets the schema version written with the persisted state.
+// Data saved by an older version is upgraded with the migrations registered
+// by WithMigration; data that cannot be upgraded is discarded.
+func WithSchemaVersion(version int) PersistOption {
+	return func(p *persistence) { p.version = version }
+}
+
+// WithMigration registers fn to upgrade persisted state from version from to
+// from+1. State saved before schema versions were introduced is version 0.
+func WithMigration(from int, fn Migration) PersistOption {
+	return func(p *persistence) { p.migrations[from] = fn }
+}
+
+// WithPersistKeys persists only the listed keys.
+func WithPersistKeys(keys ...string) PersistOption {
+	return func(p *persistence) {
+		p.allow = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			p.allow[key] = true
+		}
+	}
+}
+
+// WithoutPersistKeys excludes the listed keys from persistence.
+func WithoutPersistKeys(keys ...string) PersistOption {
+	return func(p *persistence) {
+		for _, key := range keys {
+			p.deny[key] = true
+		}
+	}
+}
+
+// WithPersistBackend replaces the default localStorage backend.
+func WithPersistBackend(backend PersistenceBackend) PersistOption {
+	return func(p *persistence) { p.backend = backend }
+}
+
+// WithPersistDebounce coalesces writes made within d into a single save.
+func WithPersistDebounce(d time.Duration) PersistOption {
+	return func(p *persistence) { p.debounce = d }
+}
+
+type persistence struct {
+	backend    PersistenceBackend
+	version    int
+	migrations map[int]Migration
+	allow      map[string]bool
+	deny       map[string]bool
+	debounce   time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending map[string]any
+
+	// loaded and written are guarded by the store's mu. Nothing is saved
+	// until the backend reports the persisted state, and written collects
+	// the keys set meanwhile so restoring it does not overwrite them.
+	loaded  bool
+	written map[string]bool
+}
+
+func newPersistence(opts []PersistOption) *persistence {
+	p := &persistence{
+		migrations: make(map[int]Migration),
+		deny:       make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.backend == nil {
+		p.backend = LocalStorageBackend()
+	}
+	return p
+}
+
+// persistedEnvelope is the stored format. State written before schema
+// versions existed is a bare object and is read as version 0.
+type persistedEnvelope struct {
+	Schema int            `json:"schema"`
+	State  map[string]any `json:"state"`
+}
+
+func (p *persistence) keep(key string) bool {
+	if p.deny[key] {
+		return false
+	}
+	return p.allow == nil || p.allow[key]
+}
+
+func (p *persistence) filter(state map[string]any) map[string]any {
+	out := make(map[string]any, len(state))
+	for k, v := range state {
+		if p.keep(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// decode parses data and migrates it to the current schema version. It
+// reports whether a migration ran so the upgraded state can be written back.
+func (p *persistence) decode(data []byte) (map[string]any, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, fmt.Errorf("decode: %w", err)
+	}
+	version := 0
+	var state map[string]any
+	if _, ok := raw["schema"]; ok && len(raw) == 2 && raw["state"] != nil {
+		var envelope persistedEnvelope
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, false, fmt.Errorf("decode: %w", err)
+		}
+		version, state = envelope.Schema, envelope.State
+	} else if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("decode: %w", err)
+	}
+	if version > p.version {
+		return nil, false, fmt.Errorf("schema version %d is newer than %d", version, p.version)
+	}
+	migrated := version != p.version
+	for ; version < p.version; version++ {
+		migrate := p.migrations[version]
+		if migrate == nil {
+			return nil, false, fmt.Errorf("no migration from schema version %d", version)
+		}
+		next, err := migrate(state)
+		if err != nil {
+			return nil, false, fmt.Errorf("migrate from schema version %d: %w", version, err)
+		}
+		state = next
+	}
+	return p.filter(state), migrated, nil
+}
+
+// load restores persisted state into s once the backend reports it, then
+// saves the state when keys were set meanwhile or the data was migrated.
+func (p *persistence) load(s *Store) {
+	p.backend.Load(s.storageKey(), func(data []byte, err error) {
+		var state map[string]any
+		migrated := false
+		if err != nil {
+			logger.Debug("[rfw] store %s/%s: load persisted state: %v", s.module, s.name, err)
+		} else if data != nil {
+			state, migrated, err = p.decode(data)
+			if err != nil {
+				logger.Debug("[rfw] store %s/%s: discarding persisted state: %v", s.module, s.name, err)
+				state, migrated = nil, false
+			}
+		}
+		if s.restore(state) || migrated {
+			p.write(s, s.Snapshot())
+		}
+	})
+}
+
+// holdLocked records keys set before the persisted state was loaded. Callers
+// must hold the store's mu.
+func (p *persistence) holdLocked(keys []string) {
+	if p.written == nil {
+		p.written = make(map[string]bool, len(keys))
+	}
+	for _, key := range keys {
+		p.written[key] = true
+	}
+}
+
+// save writes state now or, when debounced, after the quiet period.
+func (p *persistence) save(s *Store, state map[string]any) {
+	if p.debounce <= 0 {
+		p.write(s, state)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = state
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.timer = time.AfterFunc(p.debounce, func() { p.flush(s) })
+}
+
+// flush writes a pending debounced save, if any.
+func (p *persistence) flush(s *Store) {
+	p.mu.Lock()
+	state := p.pending
+	p.pending = nil
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.mu.Unlock()
+	if state != nil {
+		p.write(s, state)
+	}
+}
+
+func (p *persistence) write(s *Store, state map[string]any) {
+	data, err := json.Marshal(persistedEnvelope{Schema: p.version, State: p.filter(state)})
+	if err != nil {
+		logger.Debug("[rfw] store %s/%s: serialize persisted state: %v", s.module, s.name, err)
+		return
+	}
+	if err := p.backend.Save(s.storageKey(), data); err != nil {
+		logger.Debug("[rfw] store %s/%s: save persisted state: %v", s.module, s.name, err)
+	}
+}
+
+// MemoryBackend is an in-process PersistenceBackend for native tests and
+// server-side stores.
+type MemoryBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// NewMemoryBackend creates an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{data: make(map[string][]byte)}
+}
+
+// Load reports the data saved under key.
+func (b *MemoryBackend) Load(key string, done func([]byte, error)) {
+	b.mu.Lock()
+	data := b.data[key]
+	b.mu.Unlock()
+	done(data, nil)
+}
+
+// Save stores a copy of data under key.
+func (b *MemoryBackend) Save(key string, data []byte) error {
+	b.mu.Lock()
+	b.data[key] = append([]byte(nil), data...)
+	b.mu.Unlock()
+	return nil
+}
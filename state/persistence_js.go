@@ -3,37 +3,195 @@
 package state
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 
 	js "github.com/rfwlab/rfw/v2/js"
 )
 
-// loadPersistedState retrieves persisted state from localStorage.
-func loadPersistedState(key string) map[string]any {
-	ls := js.LocalStorage()
-	if !ls.Truthy() {
-		return nil
+// LocalStorageBackend persists to window.localStorage.
+func LocalStorageBackend() PersistenceBackend { return webStorageBackend{name: "localStorage"} }
+
+// SessionStorageBackend persists to window.sessionStorage.
+func SessionStorageBackend() PersistenceBackend { return webStorageBackend{name: "sessionStorage"} }
+
+type webStorageBackend struct{ name string }
+
+func (b webStorageBackend) Load(key string, done func([]byte, error)) {
+	var item js.Value
+	err := callJS(func() {
+		if storage := js.Get(b.name); storage.Truthy() {
+			item = storage.Call("getItem", key)
+		}
+	})
+	if err != nil || item.Type() != js.TypeString {
+		done(nil, err)
+		return
 	}
-	item := ls.Call("getItem", key)
-	if item.Type() != js.TypeString {
-		return nil
+	done([]byte(item.String()), nil)
+}
+
+// Save reports quota and security errors thrown by setItem.
+func (b webStorageBackend) Save(key string, data []byte) error {
+	return callJS(func() {
+		if storage := js.Get(b.name); storage.Truthy() {
+			storage.Call("setItem", key, string(data))
+		}
+	})
+}
+
+// callJS converts a JavaScript exception thrown by fn into an error.
+func callJS(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+const indexedDBObjectStore = "stores"
+
+// IndexedDBBackend persists to the named IndexedDB database. Loads complete
+// asynchronously, and save failures are reported through the store logger.
+func IndexedDBBackend(database string) PersistenceBackend {
+	return &indexedDBBackend{name: database}
+}
+
+type indexedDBBackend struct {
+	name    string
+	mu      sync.Mutex
+	db      js.Value
+	err     error
+	opening bool
+	waiting []func(js.Value, error)
+}
+
+func (b *indexedDBBackend) Load(key string, done func([]byte, error)) {
+	b.withDB(func(db js.Value, err error) {
+		if err != nil {
+			done(nil, err)
+			return
+		}
+		var req js.Value
+		if err := callJS(func() {
+			req = db.Call("transaction", indexedDBObjectStore, "readonly").
+				Call("objectStore", indexedDBObjectStore).Call("get", key)
+		}); err != nil {
+			done(nil, err)
+			return
+		}
+		idbRequest(req, func(result js.Value) {
+			if result.Type() != js.TypeString {
+				done(nil, nil)
+				return
+			}
+			done([]byte(result.String()), nil)
+		}, func(err error) { done(nil, err) })
+	})
+}
+
+func (b *indexedDBBackend) Save(key string, data []byte) error {
+	value := string(data)
+	b.withDB(func(db js.Value, err error) {
+		if err == nil {
+			var req js.Value
+			err = callJS(func() {
+				req = db.Call("transaction", indexedDBObjectStore, "readwrite").
+					Call("objectStore", indexedDBObjectStore).Call("put", value, key)
+			})
+			if err == nil {
+				idbRequest(req, func(js.Value) {}, func(err error) {
+					logger.Debug("[rfw] indexeddb %s: save %s: %v", b.name, key, err)
+				})
+				return
+			}
+		}
+		logger.Debug("[rfw] indexeddb %s: save %s: %v", b.name, key, err)
+	})
+	return nil
+}
+
+// withDB runs fn once the database is open, opening it on first use.
+func (b *indexedDBBackend) withDB(fn func(js.Value, error)) {
+	b.mu.Lock()
+	if b.err != nil || b.db.Truthy() {
+		db, err := b.db, b.err
+		b.mu.Unlock()
+		fn(db, err)
+		return
 	}
-	var state map[string]any
-	if err := json.Unmarshal([]byte(item.String()), &state); err != nil {
-		return nil
+	b.waiting = append(b.waiting, fn)
+	if b.opening {
+		b.mu.Unlock()
+		return
 	}
-	return state
-}
+	b.opening = true
+	b.mu.Unlock()
 
-// saveState persists the store state in localStorage.
-func saveState(key string, state map[string]any) {
-	ls := js.LocalStorage()
-	if !ls.Truthy() {
+	factory := js.Get("indexedDB")
+	if !factory.Truthy() {
+		b.opened(js.Undefined(), errors.New("indexedDB is not available"))
 		return
 	}
-	data, err := json.Marshal(state)
-	if err != nil {
+	var req js.Value
+	if err := callJS(func() { req = factory.Call("open", b.name, 1) }); err != nil {
+		b.opened(js.Undefined(), err)
 		return
 	}
-	ls.Call("setItem", key, string(data))
+	upgrade := js.FuncOf(func(js.Value, []js.Value) any {
+		req.Get("result").Call("createObjectStore", indexedDBObjectStore)
+		return nil
+	})
+	req.Set("onupgradeneeded", upgrade)
+	idbRequest(req, func(db js.Value) {
+		upgrade.Release()
+		b.opened(db, nil)
+	}, func(err error) {
+		upgrade.Release()
+		b.opened(js.Undefined(), err)
+	})
+}
+
+func (b *indexedDBBackend) opened(db js.Value, err error) {
+	b.mu.Lock()
+	b.db, b.err = db, err
+	waiting := b.waiting
+	b.waiting = nil
+	b.mu.Unlock()
+	for _, fn := range waiting {
+		fn(db, err)
+	}
+}
+
+// idbRequest calls onSuccess with the request result or onError with its
+// error, releasing both callbacks after the first fires.
+func idbRequest(req js.Value, onSuccess func(js.Value), onError func(error)) {
+	var success, failure js.Func
+	release := func() {
+		success.Release()
+		failure.Release()
+	}
+	success = js.FuncOf(func(js.Value, []js.Value) any {
+		release()
+		onSuccess(req.Get("result"))
+		return nil
+	})
+	failure = js.FuncOf(func(js.Value, []js.Value) any {
+		release()
+		if e := req.Get("error"); e.Truthy() {
+			onError(fmt.Errorf("indexeddb: %s", e.Get("message").String()))
+		} else {
+			onError(errors.New("indexeddb: request failed"))
+		}
+		return nil
+	})
+	req.Set("onsuccess", success)
+	req.Set("onerror", failure)
 }
@@ -0,0 +1,151 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Debug(format string, args ...any) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func captureLogger(t *testing.T) *recordingLogger {
+	t.Helper()
+	rec := &recordingLogger{}
+	previous := logger
+	SetLogger(rec)
+	t.Cleanup(func() { SetLogger(previous) })
+	return rec
+}
+
+func persisted(t *testing.T, backend *MemoryBackend, key string) persistedEnvelope {
+	t.Helper()
+	var envelope persistedEnvelope
+	backend.Load(key, func(data []byte, err error) {
+		if err != nil || data == nil {
+			t.Fatalf("nothing persisted under %s: %v", key, err)
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	})
+	return envelope
+}
+
+func TestPersistenceMigratesLegacyState(t *testing.T) {
+	backend := NewMemoryBackend()
+	if err := backend.Save("default:migrate", []byte(`{"count":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStoreManager().NewStore("migrate", WithPersistence(
+		WithPersistBackend(backend),
+		WithSchemaVersion(2),
+		WithMigration(0, func(m map[string]any) (map[string]any, error) {
+			return map[string]any{"total": m["count"]}, nil
+		}),
+		WithMigration(1, func(m map[string]any) (map[string]any, error) {
+			m["label"] = "clicks"
+			return m, nil
+		}),
+	))
+	if s.Get("total") != float64(3) || s.Get("label") != "clicks" || s.Get("count") != nil {
+		t.Fatalf("unexpected migrated state %v", s.Snapshot())
+	}
+	if envelope := persisted(t, backend, "default:migrate"); envelope.Schema != 2 || envelope.State["total"] != float64(3) {
+		t.Fatalf("migrated state not written back: %+v", envelope)
+	}
+}
+
+func TestPersistenceDiscardsUnmigratableState(t *testing.T) {
+	rec := captureLogger(t)
+	backend := NewMemoryBackend()
+	if err := backend.Save("default:stale", []byte(`{"schema":1,"state":{"a":1}}`)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStoreManager().NewStore("stale", WithPersistence(WithPersistBackend(backend), WithSchemaVersion(3)))
+	if len(s.Snapshot()) != 0 {
+		t.Fatalf("stale state loaded: %v", s.Snapshot())
+	}
+	if len(rec.lines) != 1 || !strings.Contains(rec.lines[0], "no migration from schema version 1") {
+		t.Fatalf("unexpected log %v", rec.lines)
+	}
+}
+
+func TestPersistenceKeyFiltersAndDebounce(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := NewStoreManager().NewStore("filtered", WithPersistence(
+		WithPersistBackend(backend),
+		WithPersistKeys("a", "b"),
+		WithoutPersistKeys("b"),
+		WithPersistDebounce(time.Hour),
+	))
+	s.Set("a", 1)
+	s.Set("b", 2)
+	s.Set("c", 3)
+	backend.Load("default:filtered", func(data []byte, _ error) {
+		if data != nil {
+			t.Fatalf("debounced write happened early: %s", data)
+		}
+	})
+	s.FlushPersistence()
+	state := persisted(t, backend, "default:filtered").State
+	if len(state) != 1 || state["a"] != float64(1) {
+		t.Fatalf("unexpected persisted keys %v", state)
+	}
+}
+
+func TestPersistenceReportsSerializationErrors(t *testing.T) {
+	rec := captureLogger(t)
+	s := NewStoreManager().NewStore("bad", WithPersistence(WithPersistBackend(NewMemoryBackend())))
+	s.Set("fn", func() {})
+	if len(rec.lines) != 1 || !strings.Contains(rec.lines[0], "serialize persisted state") {
+		t.Fatalf("unexpected log %v", rec.lines)
+	}
+}
+
+// deferredBackend holds Load until release is called, like IndexedDB.
+type deferredBackend struct {
+	*MemoryBackend
+	loads []func()
+}
+
+func (b *deferredBackend) Load(key string, done func([]byte, error)) {
+	b.loads = append(b.loads, func() { b.MemoryBackend.Load(key, done) })
+}
+
+func (b *deferredBackend) release() {
+	for _, load := range b.loads {
+		load()
+	}
+	b.loads = nil
+}
+
+func TestPersistenceKeepsWritesMadeWhileLoading(t *testing.T) {
+	backend := &deferredBackend{MemoryBackend: NewMemoryBackend()}
+	if err := backend.Save("default:async", []byte(`{"schema":0,"state":{"a":1,"b":1}}`)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStoreManager().NewStore("async", WithPersistence(WithPersistBackend(backend)))
+	s.Set("a", 2)
+	if state := persisted(t, backend.MemoryBackend, "default:async").State; state["a"] != float64(1) || state["b"] != float64(1) {
+		t.Fatalf("saved before the load finished: %v", state)
+	}
+	backend.release()
+	if s.Get("a") != 2 || s.Get("b") != float64(1) {
+		t.Fatalf("restored a=%v b=%v, want 2 and 1", s.Get("a"), s.Get("b"))
+	}
+	if state := persisted(t, backend.MemoryBackend, "default:async").State; state["a"] != float64(2) || state["b"] != float64(1) {
+		t.Fatalf("persisted %v after load", state)
+	}
+}
@@ -2,5 +2,19 @@
 
 package state
 
-func loadPersistedState(string) map[string]any { return nil }
-func saveState(string, map[string]any)         {}
+// LocalStorageBackend persists to window.localStorage. Outside the browser it
+// stores nothing; use NewMemoryBackend in native tests.
+func LocalStorageBackend() PersistenceBackend { return noopBackend{} }
+
+// SessionStorageBackend persists to window.sessionStorage. Outside the
+// browser it stores nothing.
+func SessionStorageBackend() PersistenceBackend { return noopBackend{} }
+
+// IndexedDBBackend persists to the named IndexedDB database. Outside the
+// browser it stores nothing.
+func IndexedDBBackend(string) PersistenceBackend { return noopBackend{} }
+
+type noopBackend struct{}
+
+func (noopBackend) Load(_ string, done func([]byte, error)) { done(nil, nil) }
+func (noopBackend) Save(string, []byte) error               { return nil }
@@ -0,0 +1,401 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueryGCTime = 5 * time.Minute
+	defaultQueryRetry  = 3
+	maxQueryRetryDelay = 30 * time.Second
+)
+
+type queryConfig struct {
+	staleTime  time.Duration
+	gcTime     time.Duration
+	retry      int
+	retryDelay func(attempt int) time.Duration
+}
+
+// QueryOption configures a QueryClient's defaults or a single Query.
+type QueryOption func(*queryConfig)
+
+// WithStaleTime keeps fetched data fresh for d; stale data is still served
+// but refetched in the background on mount, focus and reconnect. The default
+// of zero treats data as stale as soon as it arrives.
+func WithStaleTime(d time.Duration) QueryOption {
+	return func(config *queryConfig) { config.staleTime = d }
+}
+
+// WithGCTime removes a query's cached data d after its last Query closes.
+// The default is five minutes.
+func WithGCTime(d time.Duration) QueryOption {
+	return func(config *queryConfig) { config.gcTime = d }
+}
+
+// WithRetry retries a failed fetch up to attempts times. The default is 3.
+func WithRetry(attempts int) QueryOption {
+	return func(config *queryConfig) { config.retry = attempts }
+}
+
+// WithRetryDelay sets the wait before retry attempt n (starting at 0). The
+// default doubles from one second up to thirty.
+func WithRetryDelay(delay func(attempt int) time.Duration) QueryOption {
+	return func(config *queryConfig) { config.retryDelay = delay }
+}
+
+func defaultRetryDelay(attempt int) time.Duration {
+	if attempt >= 5 {
+		return maxQueryRetryDelay
+	}
+	return min(time.Second<<attempt, maxQueryRetryDelay)
+}
+
+// QueryClient adds stale-while-revalidate, retries, garbage collection of
+// unused keys and pattern invalidation on top of the keyed resource cache.
+// Queries store their data there, so ResourceData, SetResourceData,
+// InvalidateResources and Mutation updates reach them like keyed resources.
+type QueryClient struct {
+	mu      sync.Mutex
+	config  queryConfig
+	entries map[string]*queryEntry
+}
+
+// NewQueryClient creates a client whose options are the defaults of its
+// queries.
+func NewQueryClient(opts ...QueryOption) *QueryClient {
+	config := queryConfig{
+		gcTime:     defaultQueryGCTime,
+		retry:      defaultQueryRetry,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &QueryClient{config: config, entries: make(map[string]*queryEntry)}
+}
+
+// queryEntry is the state of one key, shared by every Query observing it and
+// registered as a keyed resource while the client holds it. Fields other than
+// the signals are guarded by the client lock.
+type queryEntry struct {
+	client     *QueryClient
+	key        string
+	config     queryConfig
+	fetch      func(context.Context) (any, error)
+	hasData    bool
+	updated    time.Time
+	invalid    bool
+	observers  int
+	gcTimer    *time.Timer
+	cancel     context.CancelFunc
+	generation uint64
+	inFlight   bool
+
+	value    *Signal[any]
+	status   *Signal[ResourceStatus]
+	err      *Signal[error]
+	fetching *Signal[bool]
+}
+
+func (e *queryEntry) staleLocked(now time.Time) bool {
+	return !e.hasData || e.invalid || now.Sub(e.updated) >= e.config.staleTime
+}
+
+// Query observes a cached key. Queries with the same key share data, status
+// and in-flight fetches.
+type Query[T any] struct {
+	client *QueryClient
+	entry  *queryEntry
+	once   sync.Once
+}
+
+// NewQuery observes key, fetching it when there is no data or the cached data
+// is stale. Cached data is served immediately while a stale key revalidates.
+func NewQuery[T any](client *QueryClient, key string, fetch func(context.Context) (T, error), opts ...QueryOption) *Query[T] {
+	config := client.config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	cached, hasCached := loadResourceCache[any](key)
+	client.mu.Lock()
+	entry := client.entries[key]
+	if entry == nil {
+		entry = &queryEntry{
+			client:   client,
+			key:      key,
+			value:    NewSignal[any](nil),
+			status:   NewSignal(ResourceIdle),
+			err:      NewSignal[error](nil),
+			fetching: NewSignal(false),
+		}
+		if hasCached {
+			// data cached by a resource or SetResourceData is served while
+			// the query revalidates it
+			entry.hasData = true
+			entry.value.Set(cached)
+			entry.status.Set(ResourceReady)
+		}
+		client.entries[key] = entry
+		registerKeyedResource(key, entry)
+	}
+	entry.config = config
+	entry.fetch = func(ctx context.Context) (any, error) { return fetch(ctx) }
+	entry.observers++
+	if entry.gcTimer != nil {
+		entry.gcTimer.Stop()
+		entry.gcTimer = nil
+	}
+	stale := entry.staleLocked(time.Now())
+	client.mu.Unlock()
+	if stale {
+		client.fetchEntry(entry, false)
+	}
+	return &Query[T]{client: client, entry: entry}
+}
+
+// fetchEntry starts a fetch for entry. An in-flight fetch is joined unless
+// force is set, in which case it is cancelled and replaced.
+func (c *QueryClient) fetchEntry(entry *queryEntry, force bool) {
+	c.mu.Lock()
+	if entry.inFlight && !force {
+		c.mu.Unlock()
+		return
+	}
+	if entry.cancel != nil {
+		entry.cancel()
+	}
+	entry.generation++
+	generation := entry.generation
+	ctx, cancel := context.WithCancel(context.Background())
+	entry.cancel = cancel
+	entry.inFlight = true
+	fetch, config, hasData := entry.fetch, entry.config, entry.hasData
+	c.mu.Unlock()
+
+	Batch(func() {
+		entry.fetching.Set(true)
+		if !hasData {
+			entry.status.Set(ResourceLoading)
+		}
+	})
+	go func() {
+		value, err := retryQueryFetch(ctx, fetch, config)
+		c.finish(entry, generation, value, err)
+	}()
+}
+
+func retryQueryFetch(ctx context.Context, fetch func(context.Context) (any, error), config queryConfig) (any, error) {
+	for attempt := 0; ; attempt++ {
+		value, err := runResourceLoader(ctx, fetch)
+		if err == nil || ctx.Err() != nil || attempt >= config.retry {
+			return value, err
+		}
+		timer := time.NewTimer(config.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *QueryClient) finish(entry *queryEntry, generation uint64, value any, err error) {
+	c.mu.Lock()
+	if generation != entry.generation {
+		c.mu.Unlock()
+		return
+	}
+	entry.inFlight = false
+	entry.cancel = nil
+	if err == nil {
+		entry.hasData = true
+		entry.updated = time.Now()
+		entry.invalid = false
+		resourceShared.Lock()
+		resourceShared.cache[entry.key] = resourceCacheEntry{value: value}
+		resourceShared.Unlock()
+	}
+	c.mu.Unlock()
+
+	Batch(func() {
+		if err != nil {
+			entry.err.Set(err)
+			entry.status.Set(ResourceError)
+		} else {
+			entry.value.Set(value)
+			entry.err.Set(nil)
+			entry.status.Set(ResourceReady)
+		}
+		entry.fetching.Set(false)
+	})
+}
+
+func matchQueryKey(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+	return key == pattern
+}
+
+// matching returns the observed entries whose key matches pattern.
+func (c *QueryClient) matching(pattern string, staleOnly bool) []*queryEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var active []*queryEntry
+	for key, entry := range c.entries {
+		if !matchQueryKey(pattern, key) {
+			continue
+		}
+		if entry.observers > 0 && (!staleOnly || entry.staleLocked(now)) {
+			active = append(active, entry)
+		}
+	}
+	return active
+}
+
+// Invalidate marks every key matching pattern as stale and refetches the
+// observed ones, replacing fetches already in flight. A pattern ending in
+// "*" matches keys by prefix; otherwise it must equal the key. It is
+// InvalidateResources, so keyed resources and other clients' queries with a
+// matching key reload too.
+func (c *QueryClient) Invalidate(pattern string) {
+	InvalidateResources(pattern)
+}
+
+// Refetch refetches the observed keys matching pattern regardless of
+// staleness. hostclient.RefetchOnReconnect uses it after the SSC connection
+// recovers.
+func (c *QueryClient) Refetch(pattern string) {
+	for _, entry := range c.matching(pattern, false) {
+		c.fetchEntry(entry, false)
+	}
+}
+
+// RefetchStale refetches every observed key whose data is stale, as on
+// window focus.
+func (c *QueryClient) RefetchStale() {
+	for _, entry := range c.matching("*", true) {
+		c.fetchEntry(entry, false)
+	}
+}
+
+// release drops an observer and schedules garbage collection of the entry
+// once none remain.
+func (c *QueryClient) release(entry *queryEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.observers--
+	if entry.observers > 0 {
+		return
+	}
+	entry.gcTimer = time.AfterFunc(entry.config.gcTime, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if entry.observers > 0 || c.entries[entry.key] != entry {
+			return
+		}
+		delete(c.entries, entry.key)
+		entry.generation++
+		if entry.cancel != nil {
+			entry.cancel()
+			entry.cancel = nil
+		}
+		unregisterKeyedResource(entry.key, entry)
+		ClearResourceCache(entry.key)
+	})
+}
+
+// applyShared shows a value set through SetResourceData, or Query.Mutate,
+// and drops the fetch in flight so it cannot overwrite it.
+func (e *queryEntry) applyShared(value any) {
+	c := e.client
+	c.mu.Lock()
+	e.generation++
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+	e.inFlight = false
+	e.hasData = true
+	e.updated = time.Now()
+	e.invalid = false
+	c.mu.Unlock()
+	Batch(func() {
+		e.value.Set(value)
+		e.err.Set(nil)
+		e.status.Set(ResourceReady)
+		e.fetching.Set(false)
+	})
+}
+
+// reloadShared marks the entry stale after InvalidateResources and refetches
+// it while observed, replacing a fetch in flight.
+func (e *queryEntry) reloadShared() {
+	c := e.client
+	c.mu.Lock()
+	e.invalid = true
+	observed := e.observers > 0
+	c.mu.Unlock()
+	if observed {
+		c.fetchEntry(e, true)
+	}
+}
+
+// Key returns the cache key observed by the query.
+func (q *Query[T]) Key() string { return q.entry.key }
+
+// Read returns the value, the fetch error, or ErrResourcePending, like
+// Resource.Read.
+func (q *Query[T]) Read() (T, error) {
+	switch q.entry.status.Get() {
+	case ResourceReady:
+		return q.Value(), nil
+	case ResourceError:
+		return q.Value(), q.entry.err.Get()
+	default:
+		var zero T
+		return zero, ErrResourcePending
+	}
+}
+
+// Value returns the latest successful value.
+func (q *Query[T]) Value() T {
+	value, _ := q.entry.value.Get().(T)
+	return value
+}
+
+// Status returns the reactive status. It stays ResourceReady while cached
+// data is revalidated.
+func (q *Query[T]) Status() ResourceStatus { return q.entry.status.Get() }
+
+// Error returns the error of the last failed fetch.
+func (q *Query[T]) Error() error { return q.entry.err.Get() }
+
+// Loading reports whether the first fetch is in progress and no data is
+// available yet.
+func (q *Query[T]) Loading() bool { return q.Status() == ResourceLoading }
+
+// Fetching reports whether any fetch, including a background revalidation,
+// is in progress.
+func (q *Query[T]) Fetching() bool { return q.entry.fetching.Get() }
+
+// Refetch fetches the key again, joining a fetch already in flight.
+func (q *Query[T]) Refetch() { q.client.fetchEntry(q.entry, false) }
+
+// Mutate replaces the cached value for every observer of the key, as after a
+// successful mutation, and marks it fresh. A fetch in flight is cancelled so
+// it cannot overwrite the value. It is SetResourceData for the query's key.
+func (q *Query[T]) Mutate(value T) {
+	SetResourceData(q.entry.key, value)
+}
+
+// Close stops observing the key. Its data stays cached for the GC time.
+func (q *Query[T]) Close() {
+	q.once.Do(func() { q.client.release(q.entry) })
+}
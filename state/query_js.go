@@ -0,0 +1,38 @@
+//go:build js && wasm
+
+package state
+
+import js "github.com/rfwlab/rfw/v2/js"
+
+// WatchWindow refetches stale queries when the window regains focus or the
+// page becomes visible, and all observed queries when the browser comes back
+// online. The returned function removes the listeners.
+func (c *QueryClient) WatchWindow() (stop func()) {
+	window := js.Window()
+	document := js.Document()
+	focus := js.SafeFuncOf(func(js.Value, []js.Value) any {
+		c.RefetchStale()
+		return nil
+	})
+	visibility := js.SafeFuncOf(func(js.Value, []js.Value) any {
+		if document.Get("visibilityState").String() == "visible" {
+			c.RefetchStale()
+		}
+		return nil
+	})
+	online := js.SafeFuncOf(func(js.Value, []js.Value) any {
+		c.Refetch("*")
+		return nil
+	})
+	window.Call("addEventListener", "focus", focus)
+	document.Call("addEventListener", "visibilitychange", visibility)
+	window.Call("addEventListener", "online", online)
+	return func() {
+		window.Call("removeEventListener", "focus", focus)
+		document.Call("removeEventListener", "visibilitychange", visibility)
+		window.Call("removeEventListener", "online", online)
+		focus.Release()
+		visibility.Release()
+		online.Release()
+	}
+}
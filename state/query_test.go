@@ -0,0 +1,183 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitQuery[T any](t *testing.T, query *Query[T], done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("query %q: status %q, fetching %v", query.Key(), query.Status(), query.Fetching())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// clearQueryKeys empties the shared cache for keys before and after a test.
+func clearQueryKeys(t *testing.T, keys ...string) {
+	t.Helper()
+	reset := func() {
+		for _, key := range keys {
+			ClearResourceCache(key)
+		}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestQueryServesStaleWhileRevalidating(t *testing.T) {
+	clearQueryKeys(t, "swr")
+	client := NewQueryClient()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fetch := func(context.Context) (int, error) {
+		if calls.Add(1) > 1 {
+			<-release
+		}
+		return int(calls.Load()), nil
+	}
+	first := NewQuery(client, "swr", fetch)
+	defer first.Close()
+	waitQuery(t, first, func() bool { return first.Status() == ResourceReady && !first.Fetching() })
+
+	second := NewQuery(client, "swr", fetch)
+	defer second.Close()
+	if value, err := second.Read(); err != nil || value != 1 {
+		t.Fatalf("stale value not served: %d, %v", value, err)
+	}
+	if !second.Fetching() || second.Loading() {
+		t.Fatalf("expected background fetch, loading=%v fetching=%v", second.Loading(), second.Fetching())
+	}
+	close(release)
+	waitQuery(t, first, func() bool { return first.Value() == 2 && !first.Fetching() })
+}
+
+func TestQueryRetriesWithBackoff(t *testing.T) {
+	clearQueryKeys(t, "retry")
+	client := NewQueryClient(WithRetry(2), WithRetryDelay(func(int) time.Duration { return time.Millisecond }))
+	var calls atomic.Int32
+	query := NewQuery(client, "retry", func(context.Context) (string, error) {
+		if calls.Add(1) < 3 {
+			return "", errors.New("flaky")
+		}
+		return "ok", nil
+	})
+	defer query.Close()
+	waitQuery(t, query, func() bool { return query.Status() == ResourceReady })
+	if calls.Load() != 3 || query.Value() != "ok" {
+		t.Fatalf("calls = %d, value = %q", calls.Load(), query.Value())
+	}
+}
+
+func TestQueryInvalidateByPrefix(t *testing.T) {
+	clearQueryKeys(t, "todos/1", "users/1")
+	client := NewQueryClient(WithStaleTime(time.Hour))
+	var todos, users atomic.Int32
+	todo := NewQuery(client, "todos/1", func(context.Context) (int32, error) { return todos.Add(1), nil })
+	defer todo.Close()
+	user := NewQuery(client, "users/1", func(context.Context) (int32, error) { return users.Add(1), nil })
+	defer user.Close()
+	waitQuery(t, todo, func() bool { return todo.Value() == 1 })
+	waitQuery(t, user, func() bool { return user.Value() == 1 })
+
+	client.Invalidate("todos/*")
+	waitQuery(t, todo, func() bool { return todo.Value() == 2 && !todo.Fetching() })
+	if users.Load() != 1 {
+		t.Fatalf("unrelated key refetched: %d", users.Load())
+	}
+}
+
+func TestQueryGarbageCollectsUnusedKeys(t *testing.T) {
+	clearQueryKeys(t, "gc")
+	client := NewQueryClient(WithGCTime(time.Millisecond), WithStaleTime(time.Hour))
+	var calls atomic.Int32
+	fetch := func(context.Context) (int32, error) { return calls.Add(1), nil }
+	query := NewQuery(client, "gc", fetch)
+	waitQuery(t, query, func() bool { return query.Status() == ResourceReady })
+	query.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		client.mu.Lock()
+		_, ok := client.entries["gc"]
+		client.mu.Unlock()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("unused key was not collected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	again := NewQuery(client, "gc", fetch)
+	defer again.Close()
+	if !again.Loading() {
+		t.Fatalf("collected key served cached data: %q", again.Status())
+	}
+}
+
+func TestQueryMutateDropsFetchInFlight(t *testing.T) {
+	clearQueryKeys(t, "mutate")
+	client := NewQueryClient()
+	release := make(chan struct{})
+	query := NewQuery(client, "mutate", func(context.Context) (string, error) {
+		<-release
+		return "fetched", nil
+	})
+	defer query.Close()
+	query.Mutate("optimistic")
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	if value, err := query.Read(); err != nil || value != "optimistic" {
+		t.Fatalf("fetch overwrote mutation: %q, %v", value, err)
+	}
+	if query.Fetching() {
+		t.Fatal("mutated query still fetching")
+	}
+}
+
+func TestQuerySharesResourceCache(t *testing.T) {
+	clearQueryKeys(t, "shared/list")
+	client := NewQueryClient(WithStaleTime(time.Hour))
+	var calls atomic.Int32
+	query := NewQuery(client, "shared/list", func(context.Context) (int32, error) { return calls.Add(1), nil })
+	defer query.Close()
+	waitQuery(t, query, func() bool { return query.Value() == 1 })
+	if value, ok := ResourceData[int32]("shared/list"); !ok || value != 1 {
+		t.Fatalf("ResourceData = %d, %v", value, ok)
+	}
+
+	SetResourceData[int32]("shared/list", 10)
+	if query.Value() != 10 {
+		t.Fatalf("SetResourceData not shown: %d", query.Value())
+	}
+
+	InvalidateResources("shared/*")
+	waitQuery(t, query, func() bool { return query.Value() == 2 && !query.Fetching() })
+
+	mutation := NewMutation(func(context.Context, int32) (int32, error) {
+		return 0, errors.New("rejected")
+	}).OnMutate(func(scope *MutationScope, next int32) error {
+		scope.Set("shared/list", next)
+		return nil
+	})
+	if _, err := mutation.Run(context.Background(), 42); err == nil {
+		t.Fatal("expected the mutation to fail")
+	}
+	if query.Value() != 2 {
+		t.Fatalf("rollback not applied to query: %d", query.Value())
+	}
+
+	seeded := NewQuery(NewQueryClient(), "shared/list", func(context.Context) (int32, error) {
+		return calls.Add(1), nil
+	})
+	defer seeded.Close()
+	if value, err := seeded.Read(); err != nil || value != 2 {
+		t.Fatalf("cached data not served to a new client: %d, %v", value, err)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !js || !wasm
+
+package state
+
+// WatchWindow refetches stale queries when the window regains focus and all
+// observed queries when the browser comes back online. Outside the browser it
+// does nothing.
+func (c *QueryClient) WatchWindow() (stop func()) { return func() {} }
@@ -93,3 +93,19 @@ func (w *Watcher) Deps() []string { return w.deps }
 
 // Run triggers the watcher with the provided state.
 func (w *Watcher) Run(state map[string]any) { w.run(state) }
+
+// matches reports whether a change to any of keys triggers w.
+func (w *Watcher) matches(keys []string) bool {
+	deps := w.Deps()
+	if len(deps) == 0 {
+		return true
+	}
+	for _, key := range keys {
+		for _, dep := range deps {
+			if key == dep || (w.deep && pathMatches(key, dep)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"sync"
 	"time"
+	"weak"
 )
 
 var (
@@ -67,13 +69,27 @@ type resourceFlight struct {
 	closed  bool
 }
 
+// keyedResource is an open resource registered under its cache key, so
+// SetResourceData and InvalidateResources reach it.
+type keyedResource interface {
+	applyShared(value any)
+	reloadShared()
+}
+
+// The registry holds keyed resources weakly: one that nothing else references,
+// such as the resource of an unmounted component that was never closed, is
+// collected and leaves the registry instead of being reloaded forever.
 var resourceShared = struct {
 	sync.Mutex
 	cache   map[string]resourceCacheEntry
 	flights map[string]*resourceFlight
+	// live maps each key to the weak pointers of its open resources, each
+	// with a function returning the resource while it is alive
+	live map[string]map[any]func() keyedResource
 }{
 	cache:   make(map[string]resourceCacheEntry),
 	flights: make(map[string]*resourceFlight),
+	live:    make(map[string]map[any]func() keyedResource),
 }
 
 // ClearResourceCache removes a shared resource cache entry.
@@ -83,6 +99,115 @@ func ClearResourceCache(key string) {
 	resourceShared.Unlock()
 }
 
+func registerKeyedResource[R any, P interface {
+	*R
+	keyedResource
+}](key string, r P) {
+	if key == "" {
+		return
+	}
+	ref := weak.Make((*R)(r))
+	resourceShared.Lock()
+	if resourceShared.live[key] == nil {
+		resourceShared.live[key] = make(map[any]func() keyedResource)
+	}
+	resourceShared.live[key][ref] = func() keyedResource {
+		if r := ref.Value(); r != nil {
+			return P(r)
+		}
+		return nil
+	}
+	resourceShared.Unlock()
+	runtime.AddCleanup((*R)(r), func(ref weak.Pointer[R]) { dropKeyedResource(key, ref) }, ref)
+}
+
+func unregisterKeyedResource[R any, P interface {
+	*R
+	keyedResource
+}](key string, r P) {
+	if key == "" {
+		return
+	}
+	dropKeyedResource(key, weak.Make((*R)(r)))
+}
+
+func dropKeyedResource(key string, ref any) {
+	resourceShared.Lock()
+	delete(resourceShared.live[key], ref)
+	if len(resourceShared.live[key]) == 0 {
+		delete(resourceShared.live, key)
+	}
+	resourceShared.Unlock()
+}
+
+func liveResources(key string) []keyedResource {
+	resourceShared.Lock()
+	defer resourceShared.Unlock()
+	out := make([]keyedResource, 0, len(resourceShared.live[key]))
+	for _, get := range resourceShared.live[key] {
+		if r := get(); r != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ResourceData returns the cached value for key when one of type T exists.
+func ResourceData[T any](key string) (T, bool) {
+	return loadResourceCache[T](key)
+}
+
+// SetResourceData replaces the cached value for key, keeping its expiry,
+// and shows value in every open resource with that key.
+func SetResourceData[T any](key string, value T) {
+	if key == "" {
+		return
+	}
+	resourceShared.Lock()
+	entry := resourceShared.cache[key]
+	entry.value = value
+	resourceShared.cache[key] = entry
+	resourceShared.Unlock()
+	for _, r := range liveResources(key) {
+		r.applyShared(value)
+	}
+}
+
+// InvalidateResources clears the cached values of keys matching pattern and
+// reloads the open resources using them. A pattern ending in "*" matches
+// keys by prefix; otherwise it must equal the key.
+func InvalidateResources(pattern string) {
+	resourceShared.Lock()
+	keys := make(map[string]bool)
+	for key := range resourceShared.cache {
+		if matchQueryKey(pattern, key) {
+			keys[key] = true
+			delete(resourceShared.cache, key)
+		}
+	}
+	for key := range resourceShared.live {
+		if matchQueryKey(pattern, key) {
+			keys[key] = true
+		}
+	}
+	resourceShared.Unlock()
+	for key := range keys {
+		for _, r := range liveResources(key) {
+			r.reloadShared()
+		}
+	}
+}
+
+// AnyResource is a Resource seen without its value type, as RTML @await
+// blocks read it.
+type AnyResource interface {
+	Status() ResourceStatus
+	Error() error
+	ReadValue() any
+}
+
+var _ AnyResource = (*Resource[any])(nil)
+
 // Resource wraps cancellable asynchronous data in reactive signals.
 type Resource[T any] struct {
 	mu         sync.Mutex
@@ -112,6 +237,7 @@ func NewResource[T any](loader func(context.Context) (T, error), opts ...Resourc
 		status: NewSignal(ResourceIdle),
 		err:    NewSignal[error](nil),
 	}
+	registerKeyedResource(resource.key, resource)
 	if config.immediate {
 		resource.Load(context.Background())
 	}
@@ -323,6 +449,35 @@ func (r *Resource[T]) Mutate(value T) {
 	})
 }
 
+func (r *Resource[T]) applyShared(value any) {
+	typed, ok := value.(T)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.generation++
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.mu.Unlock()
+	Batch(func() {
+		r.value.Set(typed)
+		r.err.Set(nil)
+		r.status.Set(ResourceReady)
+	})
+}
+
+func (r *Resource[T]) reloadShared() {
+	if r.Status() != ResourceIdle {
+		r.Load(context.Background())
+	}
+}
+
 // Invalidate clears the cache and returns the resource to idle.
 func (r *Resource[T]) Invalidate() {
 	if r == nil {
@@ -367,6 +522,9 @@ func (r *Resource[T]) Value() T {
 	return r.value.Get()
 }
 
+// ReadValue returns Value untyped, for use without knowing T.
+func (r *Resource[T]) ReadValue() any { return r.Value() }
+
 // Status returns the current reactive status.
 func (r *Resource[T]) Status() ResourceStatus {
 	if r == nil {
@@ -403,5 +561,6 @@ func (r *Resource[T]) Close() {
 		r.cancel = nil
 	}
 	r.mu.Unlock()
+	unregisterKeyedResource(r.key, r)
 	r.status.Set(ResourceIdle)
 }
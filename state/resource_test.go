@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync/atomic"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -97,3 +98,37 @@ func TestResourceLoaderPanicBecomesError(t *testing.T) {
 		t.Fatal("resource panic did not become an error")
 	}
 }
+
+// A keyed resource nothing references any more is dropped from the registry
+// SetResourceData and InvalidateResources walk, even without Close.
+func TestUnreferencedKeyedResourcesAreDropped(t *testing.T) {
+	const key = "test:resource:dropped"
+	loads := 0
+	func() {
+		r := NewResource(func(context.Context) (int, error) {
+			loads++
+			return loads, nil
+		}, WithResourceKey(key), WithoutImmediateLoad())
+		if got := len(liveResources(key)); got != 1 {
+			t.Fatalf("live resources = %d", got)
+		}
+		runtime.KeepAlive(r)
+	}()
+	for i := 0; i < 20 && len(liveResources(key)) > 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if got := len(liveResources(key)); got != 0 {
+		t.Fatalf("unreferenced resource still live: %d", got)
+	}
+	InvalidateResources(key)
+	if loads != 0 {
+		t.Fatalf("dropped resource reloaded %d times", loads)
+	}
+	resourceShared.Lock()
+	_, tracked := resourceShared.live[key]
+	resourceShared.Unlock()
+	if tracked {
+		t.Fatal("registry entry not removed")
+	}
+}
@@ -62,3 +62,39 @@ func TestMemoTracksDependencies(t *testing.T) {
 		t.Fatalf("updated memo = %d", total.Get())
 	}
 }
+
+func TestEffectPriorityDefersAndCoalescesReruns(t *testing.T) {
+	count := NewSignal(0)
+	var seen []int
+	stop := Effect(func() func() {
+		seen = append(seen, count.Get())
+		return nil
+	}, WithPriority(PriorityIdle))
+	defer stop()
+
+	count.Set(1)
+	count.Set(2)
+	if len(seen) != 1 {
+		t.Fatalf("idle effect re-ran synchronously: %v", seen)
+	}
+	FlushEffects()
+	if len(seen) != 2 || seen[1] != 2 {
+		t.Fatalf("runs = %v, want one deferred run with the latest value", seen)
+	}
+}
+
+func TestStoppedEffectSkipsQueuedRun(t *testing.T) {
+	count := NewSignal(0)
+	runs := 0
+	stop := Effect(func() func() {
+		_ = count.Get()
+		runs++
+		return nil
+	}, WithPriority(PriorityMicrotask))
+	count.Set(1)
+	stop()
+	FlushEffects()
+	if runs != 1 || count.SubCount() != 0 {
+		t.Fatalf("stopped effect ran: runs %d, subscriptions %d", runs, count.SubCount())
+	}
+}
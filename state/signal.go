@@ -2,6 +2,7 @@ package state
 
 import (
 	"encoding/json"
+	"reflect"
 	"sync"
 	"sync/atomic"
 )
@@ -13,6 +14,10 @@ type effect struct {
 	mu      sync.Mutex
 	deps    []subscriber
 	cleanup func()
+	stopped bool
+	debugID uint64
+
+	priority EffectPriority
 }
 
 type subscriber interface {
@@ -56,11 +61,15 @@ type Signal[T any] struct {
 	onChange   []func(T)
 	ch         chan T
 	chCreated  bool
+
+	debugID uint64
 }
 
 // NewSignal creates a new Signal with the given initial value.
 func NewSignal[T any](initial T) *Signal[T] {
-	return &Signal[T]{value: initial, subs: make(map[*effect]struct{})}
+	s := &Signal[T]{value: initial, subs: make(map[*effect]struct{})}
+	registerSignal(s)
+	return s
 }
 
 // Get returns the current value of the signal and registers the calling effect.
@@ -188,9 +197,11 @@ func (s *Signal[T]) OnChange(fn func(T)) *Subscription {
 	idx := len(s.onChange)
 	s.onChange = append(s.onChange, fn)
 	s.onChangeMu.Unlock()
+	debugID := registerGraphNode(NodeListener, "OnChange", s.debugID, nil)
 
 	sub := &Subscription{
 		cancel: func() {
+			unregisterGraphNode(debugID)
 			s.onChangeMu.Lock()
 			defer s.onChangeMu.Unlock()
 			if idx < len(s.onChange) {
@@ -271,6 +282,12 @@ func (e *effect) detach() {
 }
 
 func (e *effect) runEffect() {
+	e.mu.Lock()
+	stopped := e.stopped
+	e.mu.Unlock()
+	if stopped {
+		return
+	}
 	e.detach()
 	prev := currentEffect.Load()
 	currentEffect.Store(e)
@@ -282,16 +299,29 @@ func (e *effect) runEffect() {
 }
 
 func (e *effect) stop() {
+	e.mu.Lock()
+	e.stopped = true
+	e.mu.Unlock()
+	unregisterGraphNode(e.debugID)
 	e.detach()
 }
 
 // Effect registers a reactive computation that automatically re-runs when its
 // dependent signals change. The provided function may return a cleanup function
 // that will run before the next execution and when the effect is stopped.
-func Effect(fn func() func()) func() {
-	e := &effect{run: fn}
+// WithPriority defers re-runs to a later scheduler tick.
+func Effect(fn func() func(), opts ...EffectOption) func() {
+	var config effectConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return startEffect(&effect{run: fn, priority: config.priority}).stop
+}
+
+func startEffect(e *effect) *effect {
+	registerEffect(e)
 	e.runEffect()
-	return e.stop
+	return e
 }
 
 // Batch defers dependent effects until fn completes and runs each effect once.
@@ -307,6 +337,10 @@ func Batch(fn func()) {
 }
 
 func scheduleEffect(e *effect) {
+	if e.priority != PrioritySync {
+		enqueueEffect(e)
+		return
+	}
 	effectScheduler.Lock()
 	if effectScheduler.depth > 0 {
 		effectScheduler.pending[e] = struct{}{}
@@ -353,10 +387,14 @@ func Memo[T any](compute func() T) *MemoValue[T] {
 	var zero T
 	signal := NewSignal(zero)
 	memo := &MemoValue[T]{signal: signal}
-	memo.stop = Effect(func() func() {
+	eff := startEffect(&effect{run: func() func() {
 		signal.Set(compute())
 		return nil
-	})
+	}})
+	memo.stop = eff.stop
+	if signal.debugID != 0 {
+		linkMemo(signal.debugID, eff.debugID, "Memo["+reflect.TypeFor[T]().String()+"]")
+	}
 	return memo
 }
 
@@ -80,12 +80,23 @@ type StoreOption func(*Store)
 // WithModule namespaces a store under the provided module.
 func WithModule(module string) StoreOption { return func(s *Store) { s.module = module } }
 
-// WithPersistence enables localStorage persistence for the store.
-func WithPersistence() StoreOption { return func(s *Store) { s.persist = true } }
+// WithPersistence persists the store state, by default to localStorage under
+// "module:name". Options set the schema version and migrations, filter keys,
+// replace the backend and debounce writes.
+func WithPersistence(opts ...PersistOption) StoreOption {
+	return func(s *Store) { s.persistence = newPersistence(opts) }
+}
 
-// WithDevTools enables logging of state mutations for development.
+// WithDevTools enables logging of state mutations for development. Writes,
+// including those rejected by middleware, are logged by a Logging middleware
+// placed first in the chain.
 func WithDevTools() StoreOption { return func(s *Store) { s.devTools = true } }
 
+// WithMiddleware appends middleware to the store's write chain.
+func WithMiddleware(middleware ...StoreMiddleware) StoreOption {
+	return func(s *Store) { s.middleware = append(s.middleware, middleware...) }
+}
+
 // WithHistory enables mutation history with the provided limit.
 // The limit controls how many past mutations are retained for undo/redo.
 func WithHistory(limit int) StoreOption {
@@ -111,18 +122,26 @@ type Store struct {
 	listenerID int
 	computeds  map[string]*Computed
 	watchers   []*Watcher
-	persist    bool
 	devTools   bool
 
+	middleware []StoreMiddleware
+	handler    WriteHandler
+
+	persistence *persistence
+	tabSync     *tabSync
+
 	history      []*mutation
 	future       []*mutation
 	historyLimit int
 }
 
+// mutation records a single Set, or a group of them in batch that undo and
+// redo apply as one step.
 type mutation struct {
 	key      string
 	previous any
 	next     any
+	batch    []*mutation
 }
 
 // StoreManager groups stores by module and name.
@@ -159,13 +178,18 @@ func (sm *StoreManager) NewStore(name string, opts ...StoreOption) *Store {
 	for _, opt := range opts {
 		opt(store)
 	}
+	if store.devTools {
+		store.middleware = append([]StoreMiddleware{Logging(logger)}, store.middleware...)
+	}
+	store.handler = chainMiddleware(store.middleware)
 
 	sm.RegisterStore(store.module, name, store)
 
-	if store.persist {
-		if state := loadPersistedState(store.storageKey()); state != nil {
-			store.state = state
-		}
+	if store.persistence != nil {
+		store.persistence.load(store)
+	}
+	if store.tabSync != nil {
+		store.tabSync.start(store)
 	}
 
 	return store
@@ -243,47 +267,172 @@ func (s *Store) Snapshot() map[string]any {
 
 func (s *Store) storageKey() string { return s.module + ":" + s.name }
 
-// Set stores a value and notifies dependents.
+// Set stores a value and notifies dependents. A write rejected by middleware
+// is dropped and logged; use Transaction to receive the error.
 func (s *Store) Set(key string, value any) {
-	s.set(key, value, true)
+	s.write(OriginSet, sourceLocal, []string{key}, []any{value})
+}
+
+// write commits keys and logs a middleware rejection.
+func (s *Store) write(origin WriteOrigin, source mutationSource, keys []string, values []any) bool {
+	if err := s.commit(origin, source, keys, values); err != nil {
+		logger.Debug("[rfw] store %s/%s: %s rejected: %v", s.module, s.name, origin, err)
+		return false
+	}
+	return true
 }
 
-// set applies a mutation under the lock, then fires listeners, watchers and
-// persistence outside it so callbacks can safely call back into the store.
-func (s *Store) set(key string, value any, recordHistory bool) {
+// mutationSource describes where a write came from, which decides whether it
+// is recorded in history and published to other tabs.
+type mutationSource int
+
+const (
+	// sourceLocal is a direct Set: recorded and published.
+	sourceLocal mutationSource = iota
+	// sourceUntracked is an undo or redo write: published only.
+	sourceUntracked
+	// sourceRemote is a write received from another tab: neither.
+	sourceRemote
+)
+
+// commit offers the writes to the middleware chain and, if every write is
+// accepted, applies them under one lock: listeners see the final values,
+// computeds and watchers run once, and a local commit is recorded as a single
+// history entry. Callbacks, persistence and tab sync run outside the lock so
+// they can safely call back into the store.
+func (s *Store) commit(origin WriteOrigin, source mutationSource, keys []string, values []any) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	s.mu.RLock()
+	handler := s.handler
+	writes := make([]*StoreWrite, len(keys))
+	for i, key := range keys {
+		writes[i] = &StoreWrite{Module: s.module, Store: s.name, Key: key, Previous: s.state[key], Value: values[i], Origin: origin}
+	}
+	s.mu.RUnlock()
+	if handler != nil {
+		for _, w := range writes {
+			if err := handler(w); err != nil {
+				return err
+			}
+		}
+	}
+
 	s.mu.Lock()
-	old := s.state[key]
-	s.state[key] = value
-	if recordHistory && s.historyLimit > 0 {
-		s.history = append(s.history, &mutation{key: key, previous: old, next: value})
-		if len(s.history) > s.historyLimit {
-			s.history = s.history[len(s.history)-s.historyLimit:]
+	batch := make([]*mutation, len(writes))
+	var notifs []func()
+	for i, w := range writes {
+		batch[i] = &mutation{key: w.Key, previous: s.state[w.Key], next: w.Value}
+		s.state[w.Key] = w.Value
+		notifs = append(notifs, s.listenerNotifsLocked(w.Key, w.Value)...)
+	}
+	notifs = append(notifs, s.evaluateDependentsLocked(keys...)...)
+	if source == sourceLocal {
+		if len(batch) == 1 {
+			s.recordLocked(batch[0])
+		} else {
+			s.recordLocked(&mutation{batch: batch})
 		}
-		s.future = nil
 	}
-	notifs := s.listenerNotifsLocked(key, value)
-	notifs = append(notifs, s.evaluateDependentsLocked(key)...)
 	var persisted map[string]any
-	if s.persist {
-		persisted = make(map[string]any, len(s.state))
-		for k, v := range s.state {
-			persisted[k] = v
+	if p := s.persistence; p != nil {
+		if p.loaded {
+			persisted = s.snapshotLocked()
+		} else {
+			p.holdLocked(keys)
 		}
 	}
 	s.mu.Unlock()
 
-	if s.devTools {
-		logger.Debug("%s/%s -> %s: %v", s.module, s.name, key, value)
-	}
 	if StoreHook != nil {
-		StoreHook(s.module, s.name, key, value)
+		for _, w := range writes {
+			StoreHook(s.module, s.name, w.Key, w.Value)
+		}
 	}
 	for _, fn := range notifs {
 		fn()
 	}
 	if persisted != nil {
-		saveState(s.storageKey(), persisted)
+		s.persistence.save(s, persisted)
+	}
+	if s.tabSync != nil && source != sourceRemote {
+		for _, w := range writes {
+			s.tabSync.publish(s, w.Key, w.Value)
+		}
 	}
+	return nil
+}
+
+// restore merges loaded persisted state without recording history or
+// persisting it again, notifying listeners of keys restored after
+// registration. Keys set before the load finished keep their newer values;
+// restore reports whether there were any, as their saves were held back.
+func (s *Store) restore(state map[string]any) bool {
+	s.mu.Lock()
+	var written map[string]bool
+	if p := s.persistence; p != nil {
+		written = p.written
+		p.loaded, p.written = true, nil
+	}
+	var notifs []func()
+	keys := make([]string, 0, len(state))
+	for key, value := range state {
+		if written[key] {
+			continue
+		}
+		s.state[key] = value
+		keys = append(keys, key)
+		notifs = append(notifs, s.listenerNotifsLocked(key, value)...)
+	}
+	notifs = append(notifs, s.evaluateDependentsLocked(keys...)...)
+	s.mu.Unlock()
+	for _, fn := range notifs {
+		fn()
+	}
+	return len(written) > 0
+}
+
+// FlushPersistence writes a pending debounced save immediately.
+func (s *Store) FlushPersistence() {
+	if s.persistence != nil {
+		s.persistence.flush(s)
+	}
+}
+
+// recordLocked appends m to the history and clears the redo stack. Callers
+// must hold s.mu.
+func (s *Store) recordLocked(m *mutation) {
+	if s.historyLimit <= 0 {
+		return
+	}
+	s.history = append(s.history, m)
+	if len(s.history) > s.historyLimit {
+		s.history = s.history[len(s.history)-s.historyLimit:]
+	}
+	s.future = nil
+}
+
+// writes flattens m into the keys and values that undo or redo it.
+func (m *mutation) writes(undo bool) ([]string, []any) {
+	if m.batch == nil {
+		if undo {
+			return []string{m.key}, []any{m.previous}
+		}
+		return []string{m.key}, []any{m.next}
+	}
+	var keys []string
+	var values []any
+	for i := range m.batch {
+		child := m.batch[i]
+		if undo {
+			child = m.batch[len(m.batch)-1-i]
+		}
+		k, v := child.writes(undo)
+		keys = append(keys, k...)
+		values = append(values, v...)
+	}
+	return keys, values
 }
 
 // listenerNotifsLocked snapshots the listeners registered for key as
@@ -311,7 +460,8 @@ func (s *Store) Get(key string) any {
 	return s.state[key]
 }
 
-// Undo reverts the last mutation recorded in the store's history.
+// Undo reverts the last mutation recorded in the store's history. If
+// middleware rejects the reverting writes, the history is left unchanged.
 func (s *Store) Undo() {
 	s.mu.Lock()
 	if len(s.history) == 0 {
@@ -319,10 +469,17 @@ func (s *Store) Undo() {
 		return
 	}
 	m := s.history[len(s.history)-1]
-	s.history = s.history[:len(s.history)-1]
+	s.mu.Unlock()
+	keys, values := m.writes(true)
+	if !s.write(OriginUndo, sourceUntracked, keys, values) {
+		return
+	}
+	s.mu.Lock()
+	if n := len(s.history); n > 0 && s.history[n-1] == m {
+		s.history = s.history[:n-1]
+	}
 	s.future = append(s.future, m)
 	s.mu.Unlock()
-	s.set(m.key, m.previous, false)
 }
 
 // Redo reapplies the last mutation that was undone.
@@ -333,13 +490,20 @@ func (s *Store) Redo() {
 		return
 	}
 	m := s.future[len(s.future)-1]
-	s.future = s.future[:len(s.future)-1]
+	s.mu.Unlock()
+	keys, values := m.writes(false)
+	if !s.write(OriginRedo, sourceUntracked, keys, values) {
+		return
+	}
+	s.mu.Lock()
+	if n := len(s.future); n > 0 && s.future[n-1] == m {
+		s.future = s.future[:n-1]
+	}
 	s.history = append(s.history, m)
 	if s.historyLimit > 0 && len(s.history) > s.historyLimit {
 		s.history = s.history[len(s.history)-s.historyLimit:]
 	}
 	s.mu.Unlock()
-	s.set(m.key, m.next, false)
 }
 
 // OnChange registers a listener and returns its unsubscribe function.
@@ -357,7 +521,10 @@ func (s *Store) OnChange(key string, listener func(any)) func() {
 		logger.Debug("[rfw] store %s.%s: listener registered for key %s", s.module, s.name, key)
 	}
 
+	debugID := registerStoreListener(s, key)
+
 	return func() {
+		unregisterGraphNode(debugID)
 		s.mu.Lock()
 		delete(s.listeners[key], id)
 		s.mu.Unlock()
@@ -446,14 +613,20 @@ func (s *Store) snapshotLocked() map[string]any {
 	return snap
 }
 
-// evaluateDependentsLocked re-evaluates computed values for a given key and
-// collects listener/watcher notifications as closures to run after the lock is
-// released. Watchers receive a consistent snapshot of the state taken at
-// evaluation time. Callers must hold s.mu.
-func (s *Store) evaluateDependentsLocked(key string) []func() {
+// evaluateDependentsLocked re-evaluates computed values depending on keys,
+// transitively, and collects listener/watcher notifications as closures to
+// run after the lock is released. Each watcher runs at most once and receives
+// a consistent snapshot of the state taken after every computed settled.
+// Callers must hold s.mu.
+func (s *Store) evaluateDependentsLocked(keys ...string) []func() {
 	var notifs []func()
-	for _, c := range s.computeds {
-		if contains(c.Deps(), key) {
+	changed := append([]string(nil), keys...)
+	for i := 0; i < len(changed); i++ {
+		key := changed[i]
+		for _, c := range s.computeds {
+			if !contains(c.Deps(), key) {
+				continue
+			}
 			current := snapshotDeps(s.state, c.Deps())
 			if c.lastDeps == nil || depsChanged(current, c.lastDeps) {
 				val := c.Evaluate(s.state)
@@ -461,37 +634,20 @@ func (s *Store) evaluateDependentsLocked(key string) []func() {
 				c.lastDeps = current
 				notifs = append(notifs, s.listenerNotifsLocked(c.Key(), val)...)
 				// propagate to computeds/watchers depending on this key
-				notifs = append(notifs, s.evaluateDependentsLocked(c.Key())...)
+				changed = append(changed, c.Key())
 			}
 		}
 	}
 	var watcherSnap map[string]any
-	runWatcher := func(w *Watcher) {
-		if watcherSnap == nil {
-			watcherSnap = s.snapshotLocked()
-		}
-		snap := watcherSnap
-		notifs = append(notifs, func() { w.Run(snap) })
-	}
 	for _, w := range s.watchers {
-		deps := w.Deps()
-		if len(deps) == 0 {
-			runWatcher(w)
+		if !w.matches(changed) {
 			continue
 		}
-		for _, dep := range deps {
-			if w.deep {
-				if pathMatches(key, dep) {
-					runWatcher(w)
-					break
-				}
-			} else {
-				if key == dep {
-					runWatcher(w)
-					break
-				}
-			}
+		if watcherSnap == nil {
+			watcherSnap = s.snapshotLocked()
 		}
+		w, snap := w, watcherSnap
+		notifs = append(notifs, func() { w.Run(snap) })
 	}
 	return notifs
 }
@@ -0,0 +1,241 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// SyncTransport carries store mutations between browser tabs. Publish must not
+// deliver a message back to the transport that sent it.
+type SyncTransport interface {
+	Publish(data []byte) error
+	Subscribe(fn func(data []byte)) (unsubscribe func())
+}
+
+// SyncMerge resolves a remote write to key against the local value and
+// returns the value to keep. It should be deterministic so every tab
+// converges on the same result.
+type SyncMerge func(key string, local, remote any) any
+
+// SyncOption configures cross-tab synchronization.
+type SyncOption func(*tabSync)
+
+// WithSyncChannel names the channel shared by the tabs; it defaults to
+// "rfw:module:name".
+func WithSyncChannel(name string) SyncOption {
+	return func(ts *tabSync) { ts.channel = name }
+}
+
+// WithSyncTransport replaces the BroadcastChannel transport.
+func WithSyncTransport(transport SyncTransport) SyncOption {
+	return func(ts *tabSync) { ts.transport = transport }
+}
+
+// WithSyncMerge resolves every remote write with merge instead of
+// last-writer-wins.
+func WithSyncMerge(merge SyncMerge) SyncOption {
+	return func(ts *tabSync) { ts.merge = merge }
+}
+
+// WithTabSync publishes the store's mutations to other tabs of the same
+// origin and applies theirs. Remote writes are not echoed back and are not
+// recorded in the local undo history; undo and redo publish the values they
+// restore. By default the write with the highest logical clock wins, ties
+// broken by tab id.
+func WithTabSync(opts ...SyncOption) StoreOption {
+	return func(s *Store) {
+		ts := &tabSync{id: newTabID(), stamps: make(map[string]syncStamp)}
+		for _, opt := range opts {
+			opt(ts)
+		}
+		s.tabSync = ts
+	}
+}
+
+type syncStamp struct {
+	clock uint64
+	tab   string
+}
+
+func (a syncStamp) after(b syncStamp) bool {
+	return a.clock > b.clock || (a.clock == b.clock && a.tab > b.tab)
+}
+
+type syncMessage struct {
+	Tab   string          `json:"tab"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+	Clock uint64          `json:"clock"`
+}
+
+type tabSync struct {
+	id        string
+	channel   string
+	transport SyncTransport
+	merge     SyncMerge
+
+	mu          sync.Mutex
+	clock       uint64
+	stamps      map[string]syncStamp
+	unsubscribe func()
+	stopped     bool
+}
+
+func newTabID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (ts *tabSync) start(s *Store) {
+	if ts.channel == "" {
+		ts.channel = "rfw:" + s.storageKey()
+	}
+	if ts.transport == nil {
+		ts.transport = BroadcastChannelTransport(ts.channel)
+	}
+	unsubscribe := ts.transport.Subscribe(func(data []byte) { ts.receive(s, data) })
+	ts.mu.Lock()
+	ts.unsubscribe = unsubscribe
+	ts.mu.Unlock()
+}
+
+func (ts *tabSync) publish(s *Store, key string, value any) {
+	ts.mu.Lock()
+	if ts.stopped {
+		ts.mu.Unlock()
+		return
+	}
+	ts.clock++
+	stamp := syncStamp{clock: ts.clock, tab: ts.id}
+	ts.stamps[key] = stamp
+	ts.mu.Unlock()
+	raw, err := json.Marshal(value)
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(syncMessage{Tab: ts.id, Key: key, Value: raw, Clock: stamp.clock})
+	}
+	if err != nil {
+		logger.Debug("[rfw] store %s/%s: serialize sync message for %s: %v", s.module, s.name, key, err)
+		return
+	}
+	if err := ts.transport.Publish(data); err != nil {
+		logger.Debug("[rfw] store %s/%s: publish %s: %v", s.module, s.name, key, err)
+	}
+}
+
+func (ts *tabSync) receive(s *Store, data []byte) {
+	var msg syncMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Tab == ts.id || msg.Key == "" {
+		return
+	}
+	remote := syncStamp{clock: msg.Clock, tab: msg.Tab}
+	ts.mu.Lock()
+	if ts.stopped {
+		ts.mu.Unlock()
+		return
+	}
+	if msg.Clock > ts.clock {
+		ts.clock = msg.Clock
+	}
+	local := ts.stamps[msg.Key]
+	if ts.merge == nil && !remote.after(local) {
+		ts.mu.Unlock()
+		return
+	}
+	if remote.after(local) {
+		ts.stamps[msg.Key] = remote
+	}
+	ts.mu.Unlock()
+	current := s.Get(msg.Key)
+	value := decodeLike(current, msg.Value)
+	if ts.merge != nil {
+		value = ts.merge(msg.Key, current, value)
+	}
+	s.write(OriginRemote, sourceRemote, []string{msg.Key}, []any{value})
+}
+
+// decodeLike decodes a remote value into the Go type local has, so an int
+// stays an int in every tab. A null stays nil, and the value falls back to the
+// types encoding/json decodes into an any when local is nil or the value does
+// not fit its type.
+func decodeLike(local any, raw json.RawMessage) any {
+	if local != nil && string(raw) != "null" {
+		ptr := reflect.New(reflect.TypeOf(local))
+		if json.Unmarshal(raw, ptr.Interface()) == nil {
+			return ptr.Elem().Interface()
+		}
+	}
+	var value any
+	_ = json.Unmarshal(raw, &value)
+	return value
+}
+
+// StopTabSync stops publishing and applying cross-tab mutations.
+func (s *Store) StopTabSync() {
+	if s.tabSync == nil {
+		return
+	}
+	s.tabSync.mu.Lock()
+	unsubscribe := s.tabSync.unsubscribe
+	s.tabSync.unsubscribe = nil
+	s.tabSync.stopped = true
+	s.tabSync.mu.Unlock()
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// MemorySyncBus connects in-process SyncTransports, standing in for
+// BroadcastChannel in native tests.
+type MemorySyncBus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]memorySub
+}
+
+type memorySub struct {
+	transport *memoryTransport
+	fn        func([]byte)
+}
+
+type memoryTransport struct{ bus *MemorySyncBus }
+
+// NewMemorySyncBus creates an empty bus.
+func NewMemorySyncBus() *MemorySyncBus {
+	return &MemorySyncBus{subs: make(map[int]memorySub)}
+}
+
+// Transport returns a transport acting as one tab on the bus.
+func (b *MemorySyncBus) Transport() SyncTransport { return &memoryTransport{bus: b} }
+
+func (t *memoryTransport) Publish(data []byte) error {
+	t.bus.mu.Lock()
+	var targets []func([]byte)
+	for _, sub := range t.bus.subs {
+		if sub.transport != t {
+			targets = append(targets, sub.fn)
+		}
+	}
+	t.bus.mu.Unlock()
+	for _, fn := range targets {
+		fn(append([]byte(nil), data...))
+	}
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(fn func([]byte)) func() {
+	t.bus.mu.Lock()
+	t.bus.nextID++
+	id := t.bus.nextID
+	t.bus.subs[id] = memorySub{transport: t, fn: fn}
+	t.bus.mu.Unlock()
+	return func() {
+		t.bus.mu.Lock()
+		delete(t.bus.subs, id)
+		t.bus.mu.Unlock()
+	}
+}
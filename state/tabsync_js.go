@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+package state
+
+import (
+	"errors"
+
+	js "github.com/rfwlab/rfw/v2/js"
+)
+
+// BroadcastChannelTransport connects tabs through a BroadcastChannel named
+// channel, falling back to localStorage `storage` events in browsers without
+// BroadcastChannel.
+func BroadcastChannelTransport(channel string) SyncTransport {
+	if ctor := js.Get("BroadcastChannel"); ctor.Truthy() {
+		var ch js.Value
+		if err := callJS(func() { ch = ctor.New(channel) }); err == nil {
+			return broadcastTransport{channel: ch}
+		}
+	}
+	return storageEventTransport{key: "rfw-sync:" + channel}
+}
+
+type broadcastTransport struct{ channel js.Value }
+
+func (t broadcastTransport) Publish(data []byte) error {
+	return callJS(func() { t.channel.Call("postMessage", string(data)) })
+}
+
+func (t broadcastTransport) Subscribe(fn func([]byte)) func() {
+	handler := js.SafeFuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) > 0 {
+			if data := args[0].Get("data"); data.Type() == js.TypeString {
+				fn([]byte(data.String()))
+			}
+		}
+		return nil
+	})
+	t.channel.Call("addEventListener", "message", handler)
+	return func() {
+		t.channel.Call("removeEventListener", "message", handler)
+		handler.Release()
+	}
+}
+
+// storageEventTransport writes each message to a localStorage key; other tabs
+// observe the write as a storage event. Every message carries a fresh clock,
+// so consecutive writes always change the stored value.
+type storageEventTransport struct{ key string }
+
+func (t storageEventTransport) Publish(data []byte) error {
+	storage := js.LocalStorage()
+	if !storage.Truthy() {
+		return errors.New("localStorage is not available")
+	}
+	return callJS(func() { storage.Call("setItem", t.key, string(data)) })
+}
+
+func (t storageEventTransport) Subscribe(fn func([]byte)) func() {
+	handler := js.SafeFuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Get("key").String() != t.key {
+			return nil
+		}
+		if value := args[0].Get("newValue"); value.Type() == js.TypeString {
+			fn([]byte(value.String()))
+		}
+		return nil
+	})
+	window := js.Window()
+	window.Call("addEventListener", "storage", handler)
+	return func() {
+		window.Call("removeEventListener", "storage", handler)
+		handler.Release()
+	}
+}
@@ -0,0 +1,91 @@
+package state
+
+import "testing"
+
+func newSyncedPair(t *testing.T, opts ...SyncOption) (*Store, *Store) {
+	t.Helper()
+	bus := NewMemorySyncBus()
+	a := NewStoreManager().NewStore("shared", WithHistory(10),
+		WithTabSync(append([]SyncOption{WithSyncTransport(bus.Transport())}, opts...)...))
+	b := NewStoreManager().NewStore("shared", WithHistory(10),
+		WithTabSync(append([]SyncOption{WithSyncTransport(bus.Transport())}, opts...)...))
+	return a, b
+}
+
+func TestTabSyncAppliesRemoteWritesWithoutEcho(t *testing.T) {
+	a, b := newSyncedPair(t)
+	var notified int
+	a.OnChange("count", func(any) { notified++ })
+	b.Set("count", 2)
+	if a.Get("count") != float64(2) {
+		t.Fatalf("remote write not applied: %v", a.Get("count"))
+	}
+	if notified != 1 {
+		t.Fatalf("expected one local notification, got %d", notified)
+	}
+	a.Undo()
+	if a.Get("count") != float64(2) {
+		t.Fatal("remote write recorded in local history")
+	}
+	b.Undo()
+	if a.Get("count") != nil || b.Get("count") != nil {
+		t.Fatalf("undo not synced: %v %v", a.Get("count"), b.Get("count"))
+	}
+}
+
+func TestTabSyncLastWriterWins(t *testing.T) {
+	a, b := newSyncedPair(t)
+	a.Set("x", "a1")
+	a.Set("x", "a2")
+	// A stale message from b with a lower clock must not overwrite a2.
+	b.tabSync.receive(b, []byte(`{"tab":"zz","key":"x","value":"stale","clock":1}`))
+	if b.Get("x") != "a2" {
+		t.Fatalf("stale write applied: %v", b.Get("x"))
+	}
+	b.Set("x", "b1")
+	if a.Get("x") != "b1" {
+		t.Fatalf("newer write not applied: %v", a.Get("x"))
+	}
+}
+
+func TestTabSyncCustomMergeAndStop(t *testing.T) {
+	sum := func(_ string, local, remote any) any {
+		l, _ := local.(float64)
+		r, _ := remote.(float64)
+		return l + r
+	}
+	a, b := newSyncedPair(t, WithSyncMerge(sum))
+	a.restore(map[string]any{"n": float64(1)})
+	b.Set("n", float64(2))
+	if a.Get("n") != float64(3) {
+		t.Fatalf("merge not applied: %v", a.Get("n"))
+	}
+	a.StopTabSync()
+	b.Set("n", float64(5))
+	if a.Get("n") != float64(3) {
+		t.Fatalf("stopped store received write: %v", a.Get("n"))
+	}
+}
+
+func TestTabSyncKeepsLocalTypes(t *testing.T) {
+	type item struct {
+		Name string
+		Qty  int
+	}
+	a, b := newSyncedPair(t)
+	a.Set("count", 1)
+	a.Set("item", item{Name: "pen"})
+	a.Set("label", 1)
+	b.Set("count", 2)
+	b.Set("item", item{Name: "ink", Qty: 3})
+	b.Set("label", "two")
+	if v, ok := a.Get("count").(int); !ok || v != 2 {
+		t.Fatalf("count = %#v, want int 2", a.Get("count"))
+	}
+	if v, ok := a.Get("item").(item); !ok || v != (item{Name: "ink", Qty: 3}) {
+		t.Fatalf("item = %#v", a.Get("item"))
+	}
+	if a.Get("label") != "two" {
+		t.Fatalf("label = %#v, want the remote string", a.Get("label"))
+	}
+}
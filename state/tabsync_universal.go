@@ -0,0 +1,12 @@
+//go:build !js || !wasm
+
+package state
+
+// BroadcastChannelTransport connects tabs through a BroadcastChannel. Outside
+// the browser it delivers nothing; use NewMemorySyncBus in native tests.
+func BroadcastChannelTransport(string) SyncTransport { return noopTransport{} }
+
+type noopTransport struct{}
+
+func (noopTransport) Publish([]byte) error          { return nil }
+func (noopTransport) Subscribe(func([]byte)) func() { return func() {} }
@@ -0,0 +1,55 @@
+package state
+
+import "errors"
+
+// ErrTransactionAborted is returned by Transaction after Tx.Abort.
+var ErrTransactionAborted = errors.New("state: transaction aborted")
+
+// Tx stages writes for Store.Transaction. It is only valid inside the
+// transaction function.
+type Tx struct {
+	store   *Store
+	keys    []string
+	values  map[string]any
+	aborted bool
+}
+
+// Set stages a write. Writing the same key again replaces the staged value.
+func (tx *Tx) Set(key string, value any) {
+	if _, staged := tx.values[key]; !staged {
+		tx.keys = append(tx.keys, key)
+	}
+	tx.values[key] = value
+}
+
+// Get returns the staged value of key, or the store's current value.
+func (tx *Tx) Get(key string) any {
+	if value, staged := tx.values[key]; staged {
+		return value
+	}
+	return tx.store.Get(key)
+}
+
+// Abort discards the staged writes.
+func (tx *Tx) Abort() { tx.aborted = true }
+
+// Transaction stages the writes made by fn and commits them together: they
+// pass through middleware first, then land under one lock, notify listeners
+// with the final values, run each computed and watcher once, persist once and
+// record a single history entry. Nothing is written when fn returns an
+// error, calls Abort or panics, or when middleware rejects any write; the
+// error is returned.
+func (s *Store) Transaction(fn func(tx *Tx) error) error {
+	tx := &Tx{store: s, values: make(map[string]any)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.aborted {
+		return ErrTransactionAborted
+	}
+	values := make([]any, len(tx.keys))
+	for i, key := range tx.keys {
+		values[i] = tx.values[key]
+	}
+	return s.commit(OriginTransaction, sourceLocal, tx.keys, values)
+}
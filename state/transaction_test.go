@@ -0,0 +1,68 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransactionCommitsOnce(t *testing.T) {
+	s := NewStoreManager().NewStore("tx", WithHistory(10))
+	s.Set("first", "Ada")
+	s.Set("last", "Byron")
+	s.RegisterComputed(NewComputed("full", []string{"first", "last"}, func(m map[string]any) any {
+		return m["first"].(string) + " " + m["last"].(string)
+	}))
+	watcherRuns, listenerRuns := 0, 0
+	var seen any
+	s.RegisterWatcher(NewWatcher([]string{"first", "last"}, func(m map[string]any) {
+		watcherRuns++
+		seen = m["full"]
+	}))
+	s.OnChange("full", func(any) { listenerRuns++ })
+
+	err := s.Transaction(func(tx *Tx) error {
+		tx.Set("first", "Augusta")
+		tx.Set("last", "King")
+		if tx.Get("first") != "Augusta" || s.Get("first") != "Ada" {
+			t.Fatal("staged writes leaked before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if watcherRuns != 1 || listenerRuns != 1 || seen != "Augusta King" {
+		t.Fatalf("watcher runs %d, listener runs %d, saw %v", watcherRuns, listenerRuns, seen)
+	}
+
+	s.Undo()
+	if s.Get("first") != "Ada" || s.Get("last") != "Byron" || s.Get("full") != "Ada Byron" {
+		t.Fatalf("undo did not revert the transaction: %v", s.Snapshot())
+	}
+	s.Redo()
+	if s.Get("full") != "Augusta King" {
+		t.Fatalf("redo did not reapply the transaction: %v", s.Snapshot())
+	}
+}
+
+func TestTransactionAbortWritesNothing(t *testing.T) {
+	s := NewStoreManager().NewStore("abort")
+	s.Set("count", 1)
+	failure := errors.New("nope")
+	if err := s.Transaction(func(tx *Tx) error {
+		tx.Set("count", 2)
+		return failure
+	}); !errors.Is(err, failure) {
+		t.Fatalf("error = %v", err)
+	}
+	if err := s.Transaction(func(tx *Tx) error {
+		tx.Set("count", 3)
+		tx.Abort()
+		return nil
+	}); !errors.Is(err, ErrTransactionAborted) {
+		t.Fatalf("error = %v", err)
+	}
+	if s.Get("count") != 1 {
+		t.Fatalf("aborted writes landed: %v", s.Get("count"))
+	}
+}
@@ -0,0 +1,204 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypedStore is a Store whose state is described by the struct type T. Each
+// exported field is kept in the underlying Store under its JSON name (or Go
+// name when untagged), so RTML `@store:module.name.field` placeholders,
+// StoreManager.Snapshot, history, persistence and devtools all see the same
+// keys. Unexported fields and fields tagged `json:"-"` are not stored.
+type TypedStore[T any] struct {
+	store  *Store
+	fields []typedField
+}
+
+type typedField struct {
+	key   string
+	index int
+	typ   reflect.Type
+}
+
+// NewTypedStore creates a typed store registered on GlobalStoreManager. It
+// accepts the same options as NewStore and panics if T is not a struct.
+func NewTypedStore[T any](name string, initial T, opts ...StoreOption) *TypedStore[T] {
+	return NewTypedStoreIn(GlobalStoreManager, name, initial, opts...)
+}
+
+// NewTypedStoreIn creates a typed store registered on sm. Persisted values
+// take precedence over initial and are converted to the field types.
+func NewTypedStoreIn[T any](sm *StoreManager, name string, initial T, opts ...StoreOption) *TypedStore[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("state: TypedStore requires a struct type, got %s", t))
+	}
+	ts := &TypedStore[T]{store: sm.NewStore(name, opts...), fields: typedFields(t)}
+	value := reflect.ValueOf(initial)
+	ts.store.mu.Lock()
+	for _, f := range ts.fields {
+		if persisted, ok := ts.store.state[f.key]; ok {
+			ts.store.state[f.key] = convertTo(persisted, f.typ).Interface()
+			continue
+		}
+		ts.store.state[f.key] = value.Field(f.index).Interface()
+	}
+	ts.store.mu.Unlock()
+	return ts
+}
+
+// typedFields lists the stored fields of the struct type t.
+func typedFields(t reflect.Type) []typedField {
+	fields := make([]typedField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				key = name
+			}
+		}
+		fields = append(fields, typedField{key: key, index: i, typ: field.Type})
+	}
+	return fields
+}
+
+// convertTo returns v as a value of type t. Values written through the
+// untyped Store API or restored from JSON are converted numerically or by a
+// JSON round trip; anything that cannot be converted yields the zero value.
+func convertTo(v any, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv
+	}
+	if isNumeric(rv.Kind()) && isNumeric(t.Kind()) {
+		return rv.Convert(t)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return reflect.Zero(t)
+	}
+	out := reflect.New(t)
+	if err := json.Unmarshal(data, out.Interface()); err != nil {
+		return reflect.Zero(t)
+	}
+	return out.Elem()
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// Store returns the underlying map-based store for APIs such as computed
+// values and watchers.
+func (ts *TypedStore[T]) Store() *Store { return ts.store }
+
+// Module reports the module namespace of the store.
+func (ts *TypedStore[T]) Module() string { return ts.store.module }
+
+// Name returns the store name within its module namespace.
+func (ts *TypedStore[T]) Name() string { return ts.store.name }
+
+// Get returns the current state as a T.
+func (ts *TypedStore[T]) Get() T {
+	var out T
+	value := reflect.ValueOf(&out).Elem()
+	ts.store.mu.RLock()
+	defer ts.store.mu.RUnlock()
+	for _, f := range ts.fields {
+		value.Field(f.index).Set(convertTo(ts.store.state[f.key], f.typ))
+	}
+	return out
+}
+
+// Set replaces the state with value. Only fields that changed are written
+// and notified, and they are recorded as a single history entry.
+func (ts *TypedStore[T]) Set(value T) {
+	current := ts.Get()
+	cv, nv := reflect.ValueOf(current), reflect.ValueOf(value)
+	var keys []string
+	var values []any
+	for _, f := range ts.fields {
+		next := nv.Field(f.index).Interface()
+		if valEqual(cv.Field(f.index).Interface(), next) {
+			continue
+		}
+		keys = append(keys, f.key)
+		values = append(values, next)
+	}
+	ts.store.write(OriginSet, sourceLocal, keys, values)
+}
+
+// Update applies fn to a copy of the current state and stores the result.
+func (ts *TypedStore[T]) Update(fn func(*T)) {
+	value := ts.Get()
+	fn(&value)
+	ts.Set(value)
+}
+
+// Undo reverts the last mutation, including a whole Set, when history is
+// enabled.
+func (ts *TypedStore[T]) Undo() { ts.store.Undo() }
+
+// Redo reapplies the last mutation that was undone.
+func (ts *TypedStore[T]) Redo() { ts.store.Redo() }
+
+// StoreField is a typed handle on one field of a TypedStore.
+type StoreField[T, V any] struct {
+	store *TypedStore[T]
+	key   string
+	typ   reflect.Type
+}
+
+// Select returns a handle on the field whose address field returns, for
+// example Select(store, func(s *Counter) *int { return &s.Count }). It panics
+// if the selector does not return a stored top-level field of T.
+func Select[T, V any](ts *TypedStore[T], field func(*T) *V) *StoreField[T, V] {
+	var probe T
+	base := reflect.ValueOf(&probe).Elem()
+	target := reflect.ValueOf(field(&probe)).Pointer()
+	typ := reflect.TypeFor[V]()
+	for _, f := range ts.fields {
+		fv := base.Field(f.index)
+		if f.typ == typ && fv.Addr().Pointer() == target {
+			return &StoreField[T, V]{store: ts, key: f.key, typ: typ}
+		}
+	}
+	panic(fmt.Sprintf("state: selector does not address a stored field of %s", base.Type()))
+}
+
+// Key returns the store key of the field, as used by `@store:` placeholders.
+func (f *StoreField[T, V]) Key() string { return f.key }
+
+// Get returns the field value.
+func (f *StoreField[T, V]) Get() V {
+	return f.convert(f.store.store.Get(f.key))
+}
+
+// Set stores the field value and notifies its listeners.
+func (f *StoreField[T, V]) Set(value V) { f.store.store.Set(f.key, value) }
+
+// OnChange registers a listener for this field and returns its unsubscribe
+// function.
+func (f *StoreField[T, V]) OnChange(listener func(V)) func() {
+	return f.store.store.OnChange(f.key, func(v any) {
+		listener(f.convert(v))
+	})
+}
+
+func (f *StoreField[T, V]) convert(v any) V {
+	out, _ := convertTo(v, f.typ).Interface().(V)
+	return out
+}
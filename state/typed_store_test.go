@@ -0,0 +1,79 @@
+package state
+
+import "testing"
+
+type typedCounter struct {
+	Count int    `json:"count"`
+	Label string `json:"label"`
+	Step  int
+	skip  int
+}
+
+func TestTypedStoreFieldsAndSnapshot(t *testing.T) {
+	sm := NewStoreManager()
+	ts := NewTypedStoreIn(sm, "counter", typedCounter{Count: 1, Label: "clicks", Step: 2}, WithModule("app"))
+	count := Select(ts, func(s *typedCounter) *int { return &s.Count })
+	step := Select(ts, func(s *typedCounter) *int { return &s.Step })
+	if count.Key() != "count" || step.Key() != "Step" {
+		t.Fatalf("unexpected keys %q %q", count.Key(), step.Key())
+	}
+
+	var seen []int
+	unsubscribe := count.OnChange(func(v int) { seen = append(seen, v) })
+	count.Set(5)
+	step.Set(3)
+	unsubscribe()
+	count.Set(6)
+	if len(seen) != 1 || seen[0] != 5 {
+		t.Fatalf("unexpected notifications %v", seen)
+	}
+
+	snap := sm.Snapshot()["app"]["counter"]
+	if snap["count"] != 6 || snap["label"] != "clicks" || snap["Step"] != 3 {
+		t.Fatalf("unexpected snapshot %v", snap)
+	}
+	if _, ok := snap["skip"]; ok {
+		t.Fatalf("unexported field stored: %v", snap)
+	}
+	if sm.GetStore("app", "counter") != ts.Store() {
+		t.Fatal("typed store not registered")
+	}
+}
+
+func TestTypedStoreConvertsUntypedWrites(t *testing.T) {
+	ts := NewTypedStoreIn(NewStoreManager(), "convert", typedCounter{})
+	ts.Store().Set("count", float64(4))
+	if got := ts.Get().Count; got != 4 {
+		t.Fatalf("expected 4, got %d", got)
+	}
+	ts.Store().Set("label", []string{"bad"})
+	if got := ts.Get().Label; got != "" {
+		t.Fatalf("expected zero label, got %q", got)
+	}
+}
+
+func TestTypedStoreSetUndoesAsOneStep(t *testing.T) {
+	ts := NewTypedStoreIn(NewStoreManager(), "hist", typedCounter{Count: 1, Label: "a"}, WithHistory(10))
+	ts.Update(func(s *typedCounter) {
+		s.Count = 2
+		s.Label = "b"
+	})
+	ts.Undo()
+	if got := ts.Get(); got.Count != 1 || got.Label != "a" {
+		t.Fatalf("undo did not revert whole set: %+v", got)
+	}
+	ts.Redo()
+	if got := ts.Get(); got.Count != 2 || got.Label != "b" {
+		t.Fatalf("redo did not reapply whole set: %+v", got)
+	}
+}
+
+func TestSelectRejectsNonField(t *testing.T) {
+	ts := NewTypedStoreIn(NewStoreManager(), "bad", typedCounter{})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for unexported field selector")
+		}
+	}()
+	Select(ts, func(s *typedCounter) *int { return &s.skip })
+}
@@ -94,3 +94,13 @@ func (harness *Harness) Unmount() {
 	harness.component = nil
 	harness.container = dom.Element{}
 }
+
+// AssertMountCycle mounts and unmounts component, then fails if any effect
+// or listener it created during the cycle is still live.
+func AssertMountCycle(t TestingT, component core.Component) {
+	t.Helper()
+	TrackReactiveGraph(t)
+	harness := Mount(t, component)
+	harness.Unmount()
+	AssertNoLeaks(t, component.GetID())
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/rfwlab/rfw/v2/core"
+	"github.com/rfwlab/rfw/v2/state"
 )
 
 func TestBrowserHarnessMountsAndQueries(t *testing.T) {
@@ -18,3 +19,18 @@ func TestBrowserHarnessMountsAndQueries(t *testing.T) {
 		t.Fatalf("query returned wrong element: %s", harness.HTML())
 	}
 }
+
+func TestAssertMountCycleAcceptsScopedEffects(t *testing.T) {
+	count := state.NewSignal(0)
+	component := core.NewHTMLComponent("Cycle", []byte(`<root><p>cycle</p></root>`), nil)
+	component.SetComponent(component)
+	component.Init(nil)
+	component.SetOnMount(func(c *core.HTMLComponent) {
+		c.Effect(func() func() {
+			_ = count.Get()
+			return nil
+		})
+	})
+
+	AssertMountCycle(t, component)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/rfwlab/rfw/v2/core"
+	"github.com/rfwlab/rfw/v2/state"
 )
 
 // TestingT is the subset of testing.T used by this package.
@@ -45,3 +46,27 @@ func Eventually(t TestingT, timeout time.Duration, condition func() bool) {
 		time.Sleep(time.Millisecond)
 	}
 }
+
+// TrackReactiveGraph records reactive nodes for the rest of the test so
+// AssertNoLeaks can inspect them.
+func TrackReactiveGraph(t TestingT) {
+	t.Helper()
+	previous := state.GraphDebugEnabled()
+	state.EnableGraphDebug(true)
+	t.Cleanup(func() { state.EnableGraphDebug(previous) })
+}
+
+// AssertNoLeaks fails when effects or listeners created under owner, normally
+// a component ID, are still live. Nodes are only recorded after
+// TrackReactiveGraph.
+func AssertNoLeaks(t TestingT, owner string) {
+	t.Helper()
+	leaked := state.DebugGraph().Owned(owner, state.NodeEffect, state.NodeListener, state.NodeStoreListener)
+	var report strings.Builder
+	for _, node := range leaked {
+		report.WriteString("\n" + string(node.Kind) + " " + node.Label + " created at:\n" + node.Stack)
+	}
+	if report.Len() > 0 {
+		t.Fatalf("reactive nodes of %q survived:%s", owner, report.String())
+	}
+}
@@ -3,9 +3,13 @@
 package testkit
 
 import (
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/rfwlab/rfw/v2/state"
 )
 
 type renderComponent struct{}
@@ -23,3 +27,31 @@ func TestRenderAndEventually(t *testing.T) {
 	}()
 	Eventually(t, time.Second, ready.Load)
 }
+
+type recordingT struct {
+	*testing.T
+	failure string
+}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestAssertNoLeaksReportsSurvivingEffects(t *testing.T) {
+	TrackReactiveGraph(t)
+	restore := state.SetDebugOwner("widget")
+	stop := state.Effect(func() func() { return nil })
+	restore()
+
+	recorder := &recordingT{T: t}
+	AssertNoLeaks(recorder, "widget")
+	if !strings.Contains(recorder.failure, "effect Effect created at") {
+		t.Fatalf("leak not reported: %q", recorder.failure)
+	}
+	stop()
+	recorder.failure = ""
+	AssertNoLeaks(recorder, "widget")
+	if recorder.failure != "" {
+		t.Fatalf("stopped effect reported: %q", recorder.failure)
+	}
+}